@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+
+	"puissance4/protocol"
+)
+
+// accountFile conserve le pseudo et le jeton du dernier compte utilisé, pour s'y
+// reconnecter automatiquement sans redemander le mot de passe.
+const accountFile = "compte.json"
+
+// Étapes de la saisie de connexion à un compte dans le lobby.
+const (
+	loginNone = iota
+	loginNameStep
+	loginPasswordStep
+)
+
+// savedAccount est le contenu de accountFile.
+type savedAccount struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// loadSavedAccount lit le compte enregistré dans accountFile, s'il y en a un.
+func loadSavedAccount() (savedAccount, bool) {
+	var saved savedAccount
+	data, err := os.ReadFile(accountFile)
+	if err != nil {
+		return saved, false
+	}
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Name == "" || saved.Token == "" {
+		return saved, false
+	}
+	return saved, true
+}
+
+// saveAccount enregistre le pseudo et le jeton du compte dans accountFile.
+func saveAccount(saved savedAccount) {
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err == nil {
+		err = os.WriteFile(accountFile, data, 0o600)
+	}
+	if err != nil {
+		log.Printf("Impossible d'enregistrer le compte : %v\n", err)
+	}
+}
+
+// autoLogin reconnecte le joueur au compte enregistré, si le serveur gère les comptes.
+func (g *game) autoLogin() {
+	if !g.serverSupports(protocol.CapabilityAccounts) {
+		return
+	}
+	saved, ok := loadSavedAccount()
+	if !ok {
+		return
+	}
+	g.loginName = saved.Name
+	if err := g.client.LoginToken(saved.Name, saved.Token); err != nil {
+		log.Printf("Reconnexion au compte %s impossible : %v\n", saved.Name, err)
+	}
+}
+
+// loginUpdate gère la saisie du pseudo puis du mot de passe du compte. Entrée passe
+// à l'étape suivante et envoie la demande de connexion ; Échap annule la saisie.
+func (g *game) loginUpdate() {
+	field := &g.loginName
+	if g.loginStep == loginPasswordStep {
+		field = &g.loginPassword
+	}
+	for _, char := range ebiten.AppendInputChars(nil) {
+		*field += string(char)
+	}
+
+	duration := inpututil.KeyPressDuration(ebiten.KeyBackspace)
+	if duration == 1 || (duration > 30 && duration%3 == 0) {
+		if runes := []rune(*field); len(runes) > 0 {
+			*field = string(runes[:len(runes)-1])
+		}
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.loginStep = loginNone
+		g.loginPassword = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) && g.loginStep == loginNameStep:
+		g.loginName = strings.TrimSpace(g.loginName)
+		if g.loginName != "" {
+			g.loginStep = loginPasswordStep
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		g.errorMessage = ""
+		if err := g.client.Login(g.loginName, g.loginPassword); err != nil {
+			g.errorMessage = "Connexion au compte impossible : " + err.Error()
+		}
+		g.loginStep = loginNone
+		g.loginPassword = ""
+	}
+}
+
+func (g *game) onLoggedIn(account protocol.AccountPayload) {
+	g.accountName = account.Name
+	g.accountStats = account.Stats
+	g.loginName = account.Name
+	if account.Token != "" {
+		saveAccount(savedAccount{Name: account.Name, Token: account.Token})
+	}
+	if account.Created {
+		log.Printf("Compte %s créé\n", account.Name)
+	}
+	log.Printf("Connecté au compte %s (cote %d)\n", account.Name, account.Stats.Rating)
+}
+
+func (g *game) onRatingUpdate(update protocol.RatingUpdatePayload) {
+	g.accountStats = update.Stats
+	g.ratingChange = update.Change
+	g.ratingRated = true
+	log.Printf("Partie classée contre %s : cote %d (%+d)\n", update.Opponent, update.Stats.Rating, update.Change)
+}
+
+func (g *game) onRoomPlayers(players []protocol.PlayerInfo) {
+	g.roomPlayers = players
+}
+
+// roomPlayer renvoie les informations du joueur id de la salle, annoncées par le serveur.
+func (g game) roomPlayer(id int) (protocol.PlayerInfo, bool) {
+	for _, p := range g.roomPlayers {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return protocol.PlayerInfo{}, false
+}
+
+// playerLabel renvoie le nom du joueur p suivi de sa cote, s'il est connecté à un compte.
+func playerLabel(p protocol.PlayerInfo) string {
+	if p.Rating == 0 {
+		return p.Name
+	}
+	return fmt.Sprintf("%s (%d)", p.Name, p.Rating)
+}
+
+// accountLine renvoie la ligne du lobby qui présente le compte du joueur ou la saisie en cours.
+func (g game) accountLine() string {
+	switch {
+	case g.loginStep == loginNameStep:
+		return "Pseudo : " + g.loginName
+	case g.loginStep == loginPasswordStep:
+		return "Mot de passe du compte " + g.loginName + " : " + strings.Repeat("*", len([]rune(g.loginPassword)))
+	case g.accountName != "":
+		stats := g.accountStats
+		return fmt.Sprintf("Connecté : %s (cote %d ± %d, %d V / %d D / %d N)",
+			g.accountName, stats.Rating, stats.Deviation, stats.Wins, stats.Losses, stats.Draws)
+	}
+	return "Anonyme : parties non classées (L pour se connecter ou créer un compte)"
+}
+
+// drawAccountLine affiche la ligne du compte sous la liste des salles.
+func (g game) drawAccountLine(screen *ebiten.Image, y int) {
+	line := g.accountLine()
+	lineColor := globalTextColorBright
+	if g.loginStep != loginNone {
+		lineColor = globalTextColorYellow
+		if (g.stateFrame/30)%2 == 0 {
+			line += "_"
+		}
+	}
+	width, _ := getTextDimensions(line, smallFont)
+	text.Draw(screen, line, smallFont, (globalWidth-width)/2, y, lineColor)
+}
+
+// ratingLines renvoie les lignes du panneau des scores consacrées au classement : la cote
+// de chaque joueur connecté à un compte, et la variation de la cote du joueur après une
+// partie classée.
+func (g game) ratingLines() []string {
+	if g.offline || g.client == nil {
+		return nil
+	}
+	var lines []string
+	if p, ok := g.roomPlayer(g.playerID); ok && p.Rating != 0 {
+		lines = append(lines, fmt.Sprintf("VOUS : %d", p.Rating))
+	}
+	if p, ok := g.roomPlayer(g.client.Opponent()); ok && p.Rating != 0 {
+		lines = append(lines, fmt.Sprintf("%s : %d", strings.ToUpper(p.Name), p.Rating))
+	}
+	if g.ratingRated && g.gameState == resultState {
+		lines = append(lines, fmt.Sprintf("PARTIE CLASSEE : %+d", g.ratingChange))
+	}
+	return lines
+}
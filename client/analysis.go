@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/analysis"
+)
+
+// gameAnalysis est l'analyse des coups d'une partie, calculée en arrière-plan à partir
+// du dernier coup : les derniers coups, résolus exactement en quelques millisecondes,
+// s'affichent tout de suite, les premiers coups ensuite.
+type gameAnalysis struct {
+	columns []int       // Colonnes jouées de la partie analysée
+	stop    atomic.Bool // Demande l'arrêt du calcul, quand une autre partie est analysée
+
+	mu    sync.Mutex
+	moves []*analysis.Move // Analyse de chaque coup, nil tant qu'elle n'est pas calculée
+	done  int              // Nombre de coups analysés
+	err   error            // Erreur qui a interrompu l'analyse
+}
+
+// newGameAnalysis lance l'analyse de la partie columns.
+func newGameAnalysis(columns []int) *gameAnalysis {
+	a := &gameAnalysis{
+		columns: columns,
+		moves:   make([]*analysis.Move, len(columns)),
+	}
+	go a.run()
+	return a
+}
+
+// run analyse les coups du dernier au premier.
+func (a *gameAnalysis) run() {
+	analyzer := analysis.New()
+	for n := len(a.columns) - 1; n >= 0 && !a.stop.Load(); n-- {
+		m, err := analyzer.Move(a.columns, n)
+		a.mu.Lock()
+		if err != nil {
+			a.err = err
+			a.mu.Unlock()
+			log.Println("Analyse de la partie interrompue :", err)
+			return
+		}
+		a.moves[n] = &m
+		a.done++
+		a.mu.Unlock()
+	}
+}
+
+// move renvoie l'analyse du coup d'indice n, ou nil si elle n'est pas encore calculée.
+func (a *gameAnalysis) move(n int) *analysis.Move {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if n < 0 || n >= len(a.moves) {
+		return nil
+	}
+	return a.moves[n]
+}
+
+// progress renvoie le nombre de coups analysés et l'erreur qui a interrompu l'analyse.
+func (a *gameAnalysis) progress() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.done, a.err
+}
+
+// replayColumns renvoie les colonnes jouées de la partie du replay.
+func (g *game) replayColumns() []int {
+	columns := make([]int, len(g.viewer.moves))
+	for i, m := range g.viewer.moves {
+		columns[i] = m.X
+	}
+	return columns
+}
+
+// updateAnalysis lance l'analyse de la partie du replay si elle est demandée et n'a pas
+// déjà été calculée. L'analyse d'une partie précédente est abandonnée.
+func (g *game) updateAnalysis() {
+	if !g.viewer.analyse || len(g.viewer.moves) == 0 {
+		return
+	}
+	columns := g.replayColumns()
+	if g.moveAnalysis != nil && slices.Equal(g.moveAnalysis.columns, columns) {
+		return
+	}
+	if g.moveAnalysis != nil {
+		g.moveAnalysis.stop.Store(true)
+	}
+	g.moveAnalysis = newGameAnalysis(columns)
+}
+
+// verdictColor renvoie la couleur d'un coup analysé.
+func verdictColor(m *analysis.Move) color.Color {
+	switch {
+	case m == nil:
+		return globalTextColorBright
+	case m.Verdict == analysis.Blunder:
+		return globalTextRed
+	case m.Verdict == analysis.MissedWin:
+		return globalTextColorYellow
+	case m.Optimal:
+		return globalTextColorGreen
+	default:
+		return globalTextColorBright
+	}
+}
+
+// drawAnalysis affiche l'analyse du dernier coup posé sur la grille du replay, et
+// entoure la case du meilleur coup sur la grille quand le coup joué était moins bon.
+func (g game) drawAnalysis(screen *ebiten.Image, y int) {
+	a := g.moveAnalysis
+	if !g.viewer.analyse || a == nil {
+		return
+	}
+
+	done, err := a.progress()
+	message := ""
+	var m *analysis.Move
+	switch {
+	case err != nil:
+		message = "Analyse impossible : " + err.Error()
+	case g.viewer.shown == 0:
+		message = fmt.Sprintf("Analyse : %d/%d coups", done, len(a.columns))
+	default:
+		m = a.move(g.viewer.shown - 1)
+		if m == nil {
+			message = fmt.Sprintf("Coup %d en cours d'analyse (%d/%d coups analysés)", g.viewer.shown, done, len(a.columns))
+		} else {
+			message = analysisMessage(m)
+		}
+	}
+	width, _ := getTextDimensions(message, mediumFontError)
+	text.Draw(screen, message, mediumFontError, (globalWidth-width)/2, y, verdictColor(m))
+
+	if m == nil || m.Optimal {
+		return
+	}
+	// Cercle sur la case où le meilleur coup aurait été posé
+	row, ok := g.board.LandingRow(m.Best)
+	if !ok {
+		return
+	}
+	startX, startY, _, _ := replayGridBounds()
+	vector.StrokeCircle(
+		screen,
+		float32(startX+m.Best*globalTileSize+globalTileSize/2),
+		float32(startY+row*globalTileSize+globalTileSize/2),
+		float32(globalTileSize/2-globalCircleMargin),
+		4,
+		globalTextColorGreen,
+		true,
+	)
+}
+
+// analysisMessage décrit un coup analysé pour le joueur.
+func analysisMessage(m *analysis.Move) string {
+	message := fmt.Sprintf("Coup %d (colonne %d) : %s", m.Number, m.Column+1, m.Verdict)
+	switch {
+	case m.Verdict == analysis.Blunder:
+		message += fmt.Sprintf(", la position devient perdue. Meilleur coup : colonne %d", m.Best+1)
+	case m.Verdict == analysis.MissedWin:
+		message += fmt.Sprintf(", la victoire était assurée. Meilleur coup : colonne %d", m.Best+1)
+	case m.Optimal:
+		message = fmt.Sprintf("Coup %d (colonne %d) : meilleur coup", m.Number, m.Column+1)
+	default:
+		message += fmt.Sprintf(", colonne %d était plus forte", m.Best+1)
+	}
+	if !m.Exact {
+		message += " (estimation)"
+	}
+	return message
+}
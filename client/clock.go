@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/protocol"
+)
+
+// timeControlChoices sont les cadences proposées à la création d'une salle ou d'un
+// tournoi, la première étant une partie sans limite de temps.
+var timeControlChoices = []protocol.TimeControl{
+	{},
+	{PerMove: 20},
+	{Initial: 60, Increment: 2},
+	{Initial: 180, Increment: 2},
+	{Initial: 300},
+	{Initial: 600, Increment: 5},
+}
+
+// lowTime est le temps restant sous lequel une pendule s'affiche en rouge, et celle du
+// joueur clignote à son tour.
+const lowTime = 10 * time.Second
+
+// timeControl renvoie la cadence choisie pour les salles et les tournois créés.
+func (g game) timeControl() protocol.TimeControl {
+	return timeControlChoices[g.timeControlChoice]
+}
+
+// nextTimeControl passe à la cadence suivante de timeControlChoices.
+func (g *game) nextTimeControl() {
+	g.timeControlChoice = (g.timeControlChoice + 1) % len(timeControlChoices)
+}
+
+// timeControlLabel décrit la cadence tc pour le lobby : "sans limite", "20 s par coup",
+// "3 min + 2 s"...
+func timeControlLabel(tc protocol.TimeControl) string {
+	switch {
+	case tc.PerMove > 0:
+		return secondsLabel(tc.PerMove) + " par coup"
+	case tc.Initial > 0 && tc.Increment > 0:
+		return secondsLabel(tc.Initial) + " + " + secondsLabel(tc.Increment)
+	case tc.Initial > 0:
+		return secondsLabel(tc.Initial)
+	}
+	return "sans limite"
+}
+
+// secondsLabel écrit une durée en secondes, en minutes si elle en compte un nombre entier.
+func secondsLabel(seconds int) string {
+	if seconds%60 == 0 {
+		return fmt.Sprintf("%d min", seconds/60)
+	}
+	return fmt.Sprintf("%d s", seconds)
+}
+
+func (g *game) onClock(clock protocol.ClockPayload) {
+	// Les pendules sont décomptées localement jusqu'au message suivant
+	g.clock = &clock
+	g.clockReceived = time.Now()
+}
+
+// clockRemaining renvoie le temps restant du joueur id, décompté depuis la réception
+// du dernier état des pendules si la sienne tourne.
+func (g game) clockRemaining(id int) (time.Duration, bool) {
+	if g.clock == nil {
+		return 0, false
+	}
+	remaining, ok := g.clock.Remaining(id)
+	if !ok {
+		return 0, false
+	}
+	if g.clock.Running == id {
+		remaining -= time.Since(g.clockReceived)
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// formatClock écrit le temps d'une pendule : minutes et secondes, puis les dixièmes
+// de seconde lorsqu'il devient court.
+func formatClock(d time.Duration) string {
+	switch {
+	case d < lowTime:
+		return fmt.Sprintf("%.1f", d.Seconds())
+	case d >= time.Hour:
+		return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// drawClocks dessine les pendules du joueur puis de son adversaire sous le score, à
+// partir de la ligne lineY, et renvoie la ligne suivante. La pendule qui tourne est
+// sur fond vert ; sous lowTime, elle passe au rouge et celle du joueur clignote.
+func (g game) drawClocks(screen *ebiten.Image, textX, lineY int) int {
+	if g.clock == nil || g.client == nil {
+		return lineY
+	}
+	clocks := []struct {
+		id    int
+		label string
+	}{
+		{g.playerID, "VOTRE TEMPS"},
+		{g.client.Opponent(), "ADVERSAIRE"},
+	}
+	for _, c := range clocks {
+		remaining, ok := g.clockRemaining(c.id)
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("%s : %s", c.label, formatClock(remaining))
+		background := globalTextColorBright
+		switch {
+		case remaining < lowTime && c.id == g.playerID && g.clock.Running == c.id && (g.stateFrame/15)%2 == 1:
+			background = globalTextDarkRed
+		case remaining < lowTime:
+			background = globalTextRed
+		case g.clock.Running == c.id:
+			background = globalTextColorGreen
+		}
+		lineWidth, lineHeight := getTextDimensions(line, mediumFontError)
+		vector.DrawFilledRect(screen, float32(textX-10), float32(lineY-25), float32(lineWidth+20), float32(lineHeight), background, true)
+		text.Draw(screen, line, mediumFontError, textX, lineY, globalTextColor)
+		lineY += 50
+	}
+	return lineY
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/ai"
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// computerThinkDelay est la durée minimale avant que l'ordinateur joue, pour que
+// son coup ne tombe pas au même instant que celui du joueur.
+const computerThinkDelay = 500 * time.Millisecond
+
+// computerPlayerID identifie les coups de l'ordinateur dans l'historique d'une partie hors ligne.
+const computerPlayerID = -2
+
+// Mise à jour de l'écran de choix du niveau de l'ordinateur : Haut/Bas pour
+// choisir, Entrée pour commencer la partie, Échap pour revenir à l'écran titre.
+func (g *game) computerSelectUpdate() {
+	if g.chatIsFocus {
+		return
+	}
+
+	index := 0
+	for i, level := range ai.Levels {
+		if level == g.computerLevel {
+			index = i
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		index = (index + 1) % len(ai.Levels)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		index = (index - 1 + len(ai.Levels)) % len(ai.Levels)
+	}
+	g.computerLevel = ai.Levels[index]
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.gameState = titleState
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.startOfflineGame()
+		return
+	}
+
+	// Le bouton du menu du haut lance la partie
+	mouseX, mouseY := ebiten.CursorPosition()
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && g.mouseReleased {
+		textWidth, _ := getTextDimensions("JOUER", firstTitleMinusFont)
+		smallRectWidth := textWidth + 60*2
+		smallRectX, smallRectY := centerPosition(smallRectWidth, largeRectHeight, globalWidth, largeRectHeight)
+		if mouseX >= smallRectX && mouseX <= smallRectX+smallRectWidth &&
+			mouseY >= smallRectY && mouseY <= smallRectY+largeRectHeight {
+			g.mouseReleased = false
+			g.startOfflineGame()
+		}
+	}
+}
+
+// startOfflineGame prépare une partie locale contre l'ordinateur, sans serveur :
+// le joueur choisit sa couleur puis commence la première partie.
+func (g *game) startOfflineGame() {
+	g.offline = true
+	g.computer = ai.NewPlayer(g.computerLevel, time.Now().UnixNano())
+	g.computerMove = nil
+	g.resetGrid()
+	g.turn = p1Turn
+	g.firstPlayer = p1Turn
+	g.restartOk = true
+	g.result = noToken
+	g.posWinner = nil
+	g.tokenPosition = 0
+	g.adversaryTokenPosition = 0
+	g.resetHints()
+	g.nbPartieWin = 0
+	g.nbPartieAdversaireWin = 0
+	g.p1ColorValidate = -1
+	g.p2Color = -1
+	g.p2CursorColor = -1
+	g.errorMessage = ""
+	g.gameState = colorSelectState
+	log.Printf("Partie hors ligne contre l'ordinateur (niveau %s)\n", g.computerLevel)
+}
+
+// quitOfflineGame abandonne la partie contre l'ordinateur et revient à l'écran titre.
+func (g *game) quitOfflineGame() {
+	g.offline = false
+	g.computer = nil
+	g.computerMove = nil // Le calcul en cours se termine sans être lu
+	g.resetGrid()
+	g.turn = noToken
+	g.firstPlayer = noToken
+	g.restartOk = true
+	g.result = noToken
+	g.posWinner = nil
+	g.blinking = false
+	g.tokenPosition = 0
+	g.adversaryTokenPosition = 0
+	g.nbPartieWin = 0
+	g.nbPartieAdversaireWin = 0
+	g.p1Color = 0
+	g.p1ColorValidate = -1
+	g.p2Color = -1
+	g.p2CursorColor = 0
+	g.errorMessage = ""
+	g.gameState = titleState
+}
+
+// computerColor choisit la couleur des pions de l'ordinateur, différente de celle du joueur.
+func computerColor(playerColor int) int {
+	return (playerColor + 1) % globalNumColor
+}
+
+// computerUpdate fait jouer l'ordinateur : la recherche du coup est lancée dans une
+// goroutine pour ne pas bloquer l'affichage, puis le coup est posé sur la grille dès
+// qu'il est connu. Elle renvoie la position du pion posé, ou -1, -1 tant qu'il réfléchit.
+func (g *game) computerUpdate() (int, int) {
+	if g.computerMove == nil {
+		board, err := engine.FromMoves(g.board.Moves())
+		if err != nil {
+			log.Printf("Erreur lors de la copie de la grille pour l'ordinateur : %v\n", err)
+			return -1, -1
+		}
+		move := make(chan int, 1)
+		player := g.computer
+		go func() {
+			x, err := player.ChooseMove(board, p2Token)
+			if err != nil {
+				log.Printf("L'ordinateur ne peut pas jouer : %v\n", err)
+			}
+			move <- x
+		}()
+		g.computerMove = move
+		g.computerThinkingSince = time.Now()
+		return -1, -1
+	}
+
+	if time.Since(g.computerThinkingSince) < computerThinkDelay {
+		return -1, -1
+	}
+	select {
+	case x := <-g.computerMove:
+		g.computerMove = nil
+		updated, yPos := g.updateGrid(p2Token, x)
+		if !updated {
+			return -1, -1
+		}
+		g.adversaryTokenPosition = x
+		g.turn = p1Turn
+		return x, yPos
+	default:
+		return -1, -1
+	}
+}
+
+// loadLocalHistory remplit l'historique avec les coups de la partie hors ligne,
+// comme le ferait la réponse du serveur (indices compris), afin de la rejouer.
+func (g *game) loadLocalHistory() {
+	for key := range history {
+		delete(history, key)
+	}
+	for i, m := range g.board.Moves() {
+		id := computerPlayerID
+		if m.Token == p1Token {
+			id = g.playerID
+		}
+		history[i] = protocol.Coordinate{ID: id, X: m.X, Y: m.Y, Hint: g.hintedMoves[i]}
+	}
+}
+
+// Affichage de l'écran de choix du niveau de l'ordinateur.
+func (g game) computerSelectDraw(screen *ebiten.Image) {
+	g.topMenuButton(screen, "JOUER")
+
+	title := "Jouer contre l'ordinateur"
+	titleWidth, titleHeight := getTextDimensions(title, firstTitleSmallerFont)
+	titleX := (globalWidth - titleWidth) / 2
+	titleY := globalHeight/4 + titleHeight
+	text.Draw(screen, title, firstTitleSmallerFont, titleX, titleY, globalTextColorYellow)
+
+	lineY := titleY + 80
+	for _, level := range ai.Levels {
+		line := fmt.Sprintf("%s  (profondeur %d)", level, level.Depth())
+		width, height := getTextDimensions(line, smallFont)
+		x := (globalWidth - width) / 2
+
+		textColor := globalTextColorBright
+		if level == g.computerLevel {
+			vector.DrawFilledRect(screen, float32(x-20), float32(lineY-height+10), float32(width+40), float32(height), globalTextColorGreen, true)
+			textColor = globalTextColor
+		}
+		text.Draw(screen, line, smallFont, x, lineY, textColor)
+		lineY += height + 10
+	}
+
+	help := "Haut/Bas : choisir le niveau   Entrée : jouer   Échap : retour"
+	helpWidth, _ := getTextDimensions(help, mediumFontError)
+	text.Draw(screen, help, mediumFontError, (globalWidth-helpWidth)/2, globalHeight-100, globalTextColorBright)
+}
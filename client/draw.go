@@ -37,6 +37,10 @@ func (g game) Draw(screen *ebiten.Image) {
 
 	if g.isReset {
 		g.errorMessageDisplay(screen, "L'autre joueur s'est déconnecté")
+	} else if g.reconnecting {
+		g.errorMessageDisplay(screen, "Connexion perdue, reconnexion en cours...")
+	} else if message := g.opponentReconnectMessage(); message != "" {
+		g.errorMessageDisplay(screen, message)
 	}
 
 	if g.debugMode {
@@ -57,10 +61,20 @@ func (g game) Draw(screen *ebiten.Image) {
 		g.drawIntroTexte(screen)
 	case titleState:
 		g.titleDraw(screen)
+	case computerSelectState:
+		g.computerSelectDraw(screen)
 	case themeState:
 		g.themeDraw(screen)
 	case inputServerState:
 		g.inputServerDraw(screen)
+	case lobbyState:
+		g.lobbyDraw(screen)
+	case statsState:
+		g.statsDraw(screen)
+	case tournamentState:
+		g.tournamentDraw(screen)
+	case spectatorState:
+		g.spectatorDraw(screen)
 	case waitingState:
 		g.waitingDraw(screen)
 	case colorSelectState:
@@ -152,6 +166,19 @@ func (g game) titleDraw(screen *ebiten.Image) {
 	blinkX := (globalWidth - blinkTextWidth) / 2
 	blinkY := globalHeight - blinkTextHeight - 20 // 20 pixels de marge par rapport au bas
 
+	// Partie hors ligne, au-dessus du message clignotant
+	offlineMessage := "O : jouer contre l'ordinateur   T : classements"
+	offlineWidth, _ := getTextDimensions(offlineMessage, mediumFontError)
+	text.Draw(screen, offlineMessage, mediumFontError, (globalWidth-offlineWidth)/2, blinkY-blinkTextHeight-30, globalTextColorBright)
+
+	// Replay d'une partie notée, au-dessus
+	loadMessage := "Glissez une partie (.p4n) sur la fenêtre pour la revoir"
+	if g.exportMessage != "" {
+		loadMessage = g.exportMessage
+	}
+	loadWidth, loadHeight := getTextDimensions(loadMessage, mediumFontError)
+	text.Draw(screen, loadMessage, mediumFontError, (globalWidth-loadWidth)/2, blinkY-blinkTextHeight-loadHeight-40, globalTextColorBright)
+
 	// Dimensions du rectangle
 	rectX := blinkX - padding - 20
 	rectY := blinkY - padding - 25
@@ -373,10 +400,20 @@ func (g game) playDraw(screen *ebiten.Image) {
 		globalTokenColors[g.p1Color],
 		true,
 	)
+	g.drawHint(screen, pionX, pionY)
+	g.drawOfferKeys(screen)
 
 	// Afficher les messages d'erreur, le cas échéant
 	if !g.restartOk {
 		g.errorMessageDisplay(screen, "En attente de l'autre joueur")
+	} else if g.offline && g.turn == p2Turn {
+		g.errorMessageDisplay(screen, "L'ordinateur réfléchit...")
+	} else if offer := g.offerMessage(); offer != "" {
+		g.errorMessageDisplay(screen, offer)
+	} else if g.errorMessage != "" {
+		g.errorMessageDisplay(screen, g.errorMessage)
+	} else if hint := g.hintMessage(); hint != "" {
+		g.errorMessageDisplay(screen, hint)
 	}
 
 	if g.chatIsFocus {
@@ -402,12 +439,7 @@ func (g game) resultDraw(screen *ebiten.Image) {
 	screen.DrawImage(offScreenImage, options)
 
 	// Afficher le message de résultat
-	message := "Il y a Egalite"
-	if g.result == p1wins {
-		message = "Vous avez Gagne !"
-	} else if g.result == p2wins {
-		message = "Vous avez Perdu"
-	}
+	message := g.resultMessage()
 	textWidth, _ := getTextDimensions(message, firstTitleSmallFont)
 	textX := (globalWidth - textWidth) / 2
 	textY := globalHeight/2 - 50
@@ -429,6 +461,14 @@ func (g game) resultDraw(screen *ebiten.Image) {
 		text.Draw(screen, blinkMessage, smallFont, blinkTextX, blinkTextY, globalTextColor)
 	}
 
+	// Export de la partie en notation texte
+	exportMessage := "E : exporter la partie   A : analyser les coups   Maj+clic sur replay : revoir la dernière partie exportée"
+	if g.exportMessage != "" {
+		exportMessage = g.exportMessage
+	}
+	exportWidth, _ := getTextDimensions(exportMessage, mediumFontError)
+	text.Draw(screen, exportMessage, mediumFontError, (globalWidth-exportWidth)/2, globalHeight-30, globalTextColorBright)
+
 	// Afficher le message d'attente pour rematch au-dessus
 	if g.messageWaitRematch != "" {
 		msg := "L'adversaire est en attente de rematch !"
@@ -479,7 +519,7 @@ func (g game) drawGrid(screen *ebiten.Image) {
 	for x := 0; x < globalNumTilesX; x++ {
 		for y := 0; y < globalNumTilesY; y++ {
 			var tileColor color.Color
-			switch g.grid[x][y] {
+			switch g.board.Cell(x, y) {
 			case p1Token:
 				tileColor = globalTokenColors[g.p1Color]
 			case p2Token:
@@ -2,55 +2,132 @@ package main
 
 import (
 	"log"
-	"net"
+	"time"
+
+	"puissance4/ai"
+	"puissance4/analysis"
+	"puissance4/client"
+	"puissance4/engine"
+	"puissance4/protocol"
 )
 
 // Structure de données pour représenter l'état courant du jeu.
 type game struct {
-	playerID               int
-	gameState              int
-	stateFrame             int
-	grid                   [globalNumTilesX][globalNumTilesY]int
-	p1Color                int
-	p1ColorValidate        int
-	p2Color                int
-	p2CursorColor          int
-	turn                   int
-	firstPlayer            int
-	tokenPosition          int
-	result                 int
-	serverAddress          string
-	serverReady            bool
-	connectionMessage      string
-	errorConnection        string
-	gameReady              bool
-	conn                   net.Conn
-	errorMessage           string
-	restartOk              bool
-	nbJoueurConnecte       int
-	messageWaitRematch     string
-	stateFrameIntro        int
-	mouseReleased          bool
-	debugMode              bool
-	adversaryTokenPosition int
-	isMuted                bool
-	nbPartieWin            int
-	nbPartieAdversaireWin  int
-	nbBackground           int
-	nbBackgroundTheme      int
-	posWinner              [][2]int
-	lastUpdateTime         int
-	blinking               bool
-	chatMessages           []string // Historique des messages de chat
-	chatInput              string
-	chatIsFocus            bool
-	chatNewMessage         bool
-	isReset                bool
-	selected               string
-	adversaryChoice        string    // Choix de l'adversaire dans le shifumi
-	shifumiResult         string    // Résultat du shifumi (Gagné/Perdu/Égalité)
-	showShifumiResult     bool      // Indique si on doit afficher le résultat
-	shifumiResultTimer    int       // Timer pour l'affichage du résultat
+	playerID                  int
+	gameState                 int
+	stateFrame                int
+	board                     engine.Board
+	p1Color                   int
+	p1ColorValidate           int
+	p2Color                   int
+	p2CursorColor             int
+	turn                      int
+	firstPlayer               int
+	tokenPosition             int
+	result                    int
+	serverAddress             string
+	serverReady               bool
+	connectionMessage         string
+	errorConnection           string
+	gameReady                 bool
+	client                    *client.Client
+	errorMessage              string
+	restartOk                 bool
+	nbJoueurConnecte          int
+	messageWaitRematch        string
+	stateFrameIntro           int
+	mouseReleased             bool
+	debugMode                 bool
+	adversaryTokenPosition    int
+	isMuted                   bool
+	nbPartieWin               int
+	nbPartieAdversaireWin     int
+	nbBackground              int
+	nbBackgroundTheme         int
+	posWinner                 [][2]int
+	blinking                  bool
+	chatMessages              []string // Historique des messages de chat
+	chatInput                 string
+	chatIsFocus               bool
+	chatNewMessage            bool
+	isReset                   bool
+	selected                  string
+	adversaryChoice           string                // Choix de l'adversaire dans le shifumi
+	shifumiResult             string                // Résultat du shifumi (Gagné/Perdu/Égalité)
+	showShifumiResult         bool                  // Indique si on doit afficher le résultat
+	shifumiResultTimer        int                   // Timer pour l'affichage du résultat
+	rooms                     []protocol.RoomInfo   // Salles ouvertes sur le serveur, affichées dans le lobby
+	selectedRoom              int                   // Index de la salle sélectionnée dans le lobby
+	roomID                    int                   // Identifiant de la salle rejointe, -1 dans le lobby
+	roomName                  string                // Nom de la salle rejointe
+	inQueue                   bool                  // Indique si le joueur attend une partie rapide
+	queuePosition             int                   // Position dans la file de partie rapide
+	queueWaiting              int                   // Nombre de joueurs dans la file de partie rapide
+	queueEstimatedWait        int                   // Attente estimée (en secondes) annoncée par le serveur
+	queueJoinedAt             time.Time             // Heure d'entrée dans la file de partie rapide
+	spectatePlayers           []protocol.PlayerInfo // Joueurs de la partie observée en mode spectateur
+	spectateTurn              int                   // ID du joueur dont c'est le tour dans la partie observée
+	spectateGameOver          bool                  // Indique si la partie observée est terminée
+	sessionToken              string                // Jeton de reprise de session envoyé par le serveur
+	sessionGrace              int                   // Délai (en secondes) pendant lequel le serveur garde notre place
+	pendingToken              string                // Jeton de la nouvelle connexion, utilisé si la reprise échoue
+	reconnecting              bool                  // Indique qu'une reprise de session est en cours
+	opponentReconnectDeadline time.Time             // Fin du délai de reconnexion de l'adversaire, zéro s'il est connecté
+	serverCapabilities        []string              // Capacités annoncées par le serveur lors de la poignée de main
+	serverPassword            string                // Mot de passe du serveur, envoyé lors de la poignée de main
+	useTLS                    bool                  // Indique si la connexion au serveur est chiffrée
+	networkEvents             chan func()           // Événements du réseau, appliqués au jeu par Update
+	roomPassword              string                // Mot de passe utilisé pour créer, rejoindre ou observer une salle
+	roomPasswordFocus         bool                  // Indique si la saisie du mot de passe de salle est active
+	offline                   bool                  // Partie locale contre l'ordinateur, sans serveur
+	computerLevel             ai.Level              // Niveau de l'ordinateur choisi à l'écran titre
+	computer                  *ai.Player            // Ordinateur qui joue les pions du joueur 2 hors ligne
+	computerMove              chan int              // Coup en cours de calcul par l'ordinateur, nil s'il ne réfléchit pas
+	computerThinkingSince     time.Time             // Début de la réflexion de l'ordinateur
+	exportMessage             string                // Résultat du dernier export ou chargement d'une partie notée
+	replayTitle               string                // Joueurs et résultat de la partie chargée depuis un fichier, affichés pendant son replay
+	savedGame                 *savedGame            // Partie affichée avant le replay d'une partie chargée, nil sinon
+	viewer                    replayViewer          // État du replay : coups, coup affiché, lecture et vitesse
+	moveAnalysis              *gameAnalysis         // Analyse des coups de la partie du replay, nil si elle n'a pas été demandée
+	hintAnalyzer              *analysis.Analyzer    // Moteur qui calcule les indices, créé au premier indice demandé
+	hintMove                  chan int              // Indice en cours de calcul, nil si aucun n'est demandé
+	hintColumn                int                   // Colonne conseillée par le dernier indice
+	hintFor                   int                   // Numéro du coup pour lequel l'indice vaut, 0 sans indice
+	hintedMoves               map[int]bool          // Coups de la partie joués après un indice, indexés à partir de 0 (hors ligne)
+	roomNoHints               bool                  // Crée les salles sans indices, pour une partie classée
+	loginStep                 int                   // Étape de la saisie de connexion au compte (loginNone, loginNameStep, loginPasswordStep)
+	loginName                 string                // Pseudo saisi pour se connecter à un compte
+	loginPassword             string                // Mot de passe saisi pour se connecter à un compte
+	accountName               string                // Pseudo du compte connecté, vide pour un joueur anonyme
+	accountStats              protocol.PlayerStats  // Classement du compte connecté
+	roomPlayers               []protocol.PlayerInfo // Joueurs de la salle avec leur nom et leur cote
+	ratingChange              int                   // Variation de la cote après la dernière partie classée
+	ratingRated               bool                  // Indique si la partie terminée a été classée
+
+	// Écran des classements
+	statsPending  bool                         // Ouvre l'écran des classements dès la connexion au serveur
+	statsBoard    int                          // Classement affiché (ratingsBoard, streaksBoard, mostGamesBoard)
+	selectedEntry int                          // Index du joueur choisi dans le classement affiché
+	leaderboard   *protocol.LeaderboardPayload // Classements reçus du serveur, nil avant leur arrivée
+	profile       *protocol.PlayerProfile      // Statistiques du joueur affiché, nil si aucune n'a été demandée
+
+	// Écran des tournois
+	tournaments        []protocol.TournamentInfo   // Tournois annoncés par le serveur
+	selectedTournament int                         // Index du tournoi choisi dans la liste
+	tournament         *protocol.TournamentPayload // Tournoi suivi ou auquel le joueur participe, nil sinon
+	tournamentKnockout bool                        // Crée les tournois en élimination directe plutôt qu'en toutes rondes
+	tournamentBestOf   int                         // Parties par match des tournois créés
+
+	// Pendules
+	timeControlChoice int                    // Index dans timeControlChoices de la cadence des salles et tournois créés
+	clock             *protocol.ClockPayload // Dernier état des pendules reçu, nil dans une salle sans cadence
+	clockReceived     time.Time              // Réception de clock, d'où la pendule qui tourne est décomptée
+
+	// Abandon, nulle et reprise
+	resignConfirm   bool   // La touche d'abandon a été pressée une fois, l'abandon attend sa confirmation
+	drawOffer       int    // Proposition de nulle en cours (noOffer, offerSent, offerReceived)
+	takebackRequest int    // Demande de reprise en cours (noOffer, offerSent, offerReceived)
+	gameOverReason  string // Raison de la fin de la dernière partie donnée par le serveur (protocol.ReasonTimeout...)
 }
 
 // Constantes pour représenter la séquence de jeu actuelle (écran titre,
@@ -68,14 +145,20 @@ const (
 	waitingState
 	waitingColorSelect
 	shifumiState
+	lobbyState
+	spectatorState
+	computerSelectState
+	statsState
+	tournamentState
 )
 
 // Constantes pour représenter les pions dans la grille de puissance 4
 // (absence de pion, pion du joueur 1, pion du joueur 2).
+// Le pion du joueur 1 est toujours celui du joueur local.
 const (
-	noToken int = iota
-	p1Token
-	p2Token
+	noToken = engine.NoToken
+	p1Token = engine.P1Token
+	p2Token = engine.P2Token
 )
 
 // Constantes pour représenter le tour de jeu (joueur 1 ou joueur 2).
@@ -88,41 +171,40 @@ const (
 // la grille a été remplie sans qu'un joueur n'ait gagné, joueur 1
 // gagnant ou joueur 2 gagnant).
 const (
-	equality int = iota
-	p1wins
-	p2wins
+	equality = engine.Equality
+	p1wins   = engine.P1Wins
+	p2wins   = engine.P2Wins
 )
 
 // Remise à 0 du jeu pour recommencer une partie. Le joueur qui a
 // perdu la dernière partie commence.
 func (g *game) reset() {
 	// Informer le serveur que la partie est terminer et que l'on est pret a rejouer
-	if g.conn != nil {
-		err := sendJSONMessage(g.conn, "restartReady", nil)
+	if g.client != nil {
+		err := g.client.Rematch()
 		if err != nil {
 			log.Printf("Erreur lors de l'envoi de 'end' : %v\n", err)
 		} else {
 			log.Println("Message 'end' envoyé au serveur.")
 		}
 	}
+	if g.offline {
+		g.restartOk = true // Pas d'adversaire à attendre
+	}
 
 	// Réinitialiser la grille
-	for x := 0; x < globalNumTilesX; x++ {
-		for y := 0; y < globalNumTilesY; y++ {
-			g.grid[x][y] = noToken
-		}
-	} // Réinitialiser la grille
-	for x := 0; x < globalNumTilesX; x++ {
-		for y := 0; y < globalNumTilesY; y++ {
-			g.grid[x][y] = noToken
-		}
-	}
+	g.board.Reset()
 
 	// Réinitialiser les variables du jeu
 	g.stateFrame = 0    // Réinitialiser le compteur d'états
 	g.tokenPosition = 0 // Réinitialiser la position du jeton
 	g.result = noToken  // Aucun gagnant pour la nouvelle partie
+	g.exportMessage = ""
 	g.adversaryTokenPosition = 0
+	g.resetHints()
+	g.ratingRated = false
+	g.gameOverReason = ""
+	g.clearOffers()
 
 	log.Printf("Grille réinitialisée. Votre tour : %v\n", g.turn == p1Turn)
 }
@@ -130,16 +212,7 @@ func (g *game) reset() {
 func (g *game) resetGrid() {
 
 	// Réinitialiser la grille
-	for x := 0; x < globalNumTilesX; x++ {
-		for y := 0; y < globalNumTilesY; y++ {
-			g.grid[x][y] = noToken
-		}
-	} // Réinitialiser la grille
-	for x := 0; x < globalNumTilesX; x++ {
-		for y := 0; y < globalNumTilesY; y++ {
-			g.grid[x][y] = noToken
-		}
-	}
+	g.board.Reset()
 }
 
 // determineShifumiWinner détermine le résultat du shifumi
@@ -5,12 +5,15 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"golang.org/x/image/font"
+
+	"puissance4/engine"
+	"puissance4/protocol"
 )
 
 // Constantes définissant les paramètres généraux du programme.
 const (
-	globalNumTilesX       = 7
-	globalNumTilesY       = 6
+	globalNumTilesX       = engine.Columns
+	globalNumTilesY       = engine.Rows
 	globalCircleMargin    = 5
 	globalBlinkDuration   = 60
 	globalNumColorLine    = 3
@@ -80,10 +83,10 @@ var (
 	chat              *ebiten.Image
 	close             *ebiten.Image
 	chatWarning       *ebiten.Image
-	pierreImg              *ebiten.Image
-	papierImg              *ebiten.Image
-	ciseauxImg             *ebiten.Image
-	losangeImg             *ebiten.Image
+	pierreImg         *ebiten.Image
+	papierImg         *ebiten.Image
+	ciseauxImg        *ebiten.Image
+	losangeImg        *ebiten.Image
 	globalWidth       = 1920
 	globalHeight      = 1080
 	baseFontSizeError float64
@@ -93,29 +96,5 @@ var (
 	baseFontTitle     float64
 	sizeP1            = 0.0
 	sizeP2            = 0.0
-	history           = make(map[int]Coordinate)
+	history           = make(map[int]protocol.Coordinate)
 )
-
-type Message struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"`
-}
-
-type ColorPayload struct {
-	Color int `json:"color"`
-}
-
-type MovePayload struct {
-	X int `json:"x"`
-	Y int `json:"y"`
-}
-
-type Coordinate struct {
-	ID int
-	X  int
-	Y  int
-}
-
-type ChatMessage struct {
-	Text string `json:"text"`
-}
@@ -6,6 +6,7 @@ require (
 	github.com/hajimehoshi/ebiten v1.12.12
 	github.com/hajimehoshi/ebiten/v2 v2.6.2
 	golang.org/x/image v0.12.0
+	puissance4 v0.0.0-00010101000000-000000000000
 )
 
 require (
@@ -22,3 +23,5 @@ require (
 	golang.org/x/sys v0.12.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 )
+
+replace puissance4 => ../puissance4
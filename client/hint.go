@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/analysis"
+)
+
+// Réglages des indices, plus modestes que ceux de l'analyse de partie pour que l'indice
+// arrive vite : profondeur de la recherche avant que le solveur prenne le relais, et
+// durée accordée au solveur, au-delà de laquelle l'indice vient de cette recherche.
+const (
+	hintDepth  = 8
+	hintBudget = 300 * time.Millisecond
+)
+
+// hintUpdate gère la touche H pendant la partie : elle demande un indice au moteur
+// local pour le coup à jouer, puis place l'indicateur de position sur la colonne conseillée.
+// En réseau, le serveur est prévenu pour que le coup soit marqué dans l'historique ;
+// les salles classées interdisent les indices.
+func (g *game) hintUpdate() {
+	if g.hintMove != nil {
+		g.receiveHint()
+		return
+	}
+	if !inpututil.IsKeyJustPressed(ebiten.KeyH) || g.chatIsFocus || g.turn != p1Turn {
+		return
+	}
+	if finished, _, _ := g.board.Winner(); finished {
+		return
+	}
+
+	if g.client != nil {
+		if err := g.client.Hint(); err != nil {
+			g.errorMessage = "Indice impossible : " + err.Error()
+			return
+		}
+	}
+	if g.hintedMoves == nil {
+		g.hintedMoves = make(map[int]bool)
+	}
+	g.hintedMoves[g.board.MoveCount()] = true
+
+	if g.hintAnalyzer == nil {
+		g.hintAnalyzer = analysis.New()
+		g.hintAnalyzer.Depth = hintDepth
+		g.hintAnalyzer.Budget = hintBudget
+	}
+	columns := g.board.ColumnSequence()
+	analyzer := g.hintAnalyzer
+	move := make(chan int, 1)
+	go func() {
+		x, err := analyzer.BestColumn(columns)
+		if err != nil {
+			log.Printf("Impossible de calculer un indice : %v\n", err)
+		}
+		move <- x
+	}()
+	g.hintMove = move
+	g.hintFor = g.board.MoveCount() + 1
+}
+
+// receiveHint récupère l'indice en cours de calcul, s'il est prêt. Un indice qui arrive
+// après que la grille a changé est ignoré.
+func (g *game) receiveHint() {
+	select {
+	case x := <-g.hintMove:
+		g.hintMove = nil
+		if x < 0 || g.hintFor != g.board.MoveCount()+1 {
+			g.hintFor = 0
+			return
+		}
+		g.hintColumn = x
+		g.tokenPosition = x
+		if g.client != nil {
+			sendTokenUpdateToServer(g.client, g.tokenPosition)
+		}
+	default:
+	}
+}
+
+// hintShown indique si l'indice de la colonne g.hintColumn vaut pour la grille affichée.
+func (g game) hintShown() bool {
+	return g.hintMove == nil && g.hintFor == g.board.MoveCount()+1 && g.turn == p1Turn
+}
+
+// hintMessage renvoie le message affiché sous la grille pendant la recherche d'un
+// indice ou tant qu'il vaut, et une chaîne vide sinon.
+func (g game) hintMessage() string {
+	switch {
+	case g.hintMove != nil:
+		return "Recherche d'un indice..."
+	case g.hintShown():
+		return fmt.Sprintf("Indice : colonne %d", g.hintColumn+1)
+	}
+	return ""
+}
+
+// drawHint entoure l'indicateur de position, dessiné en (pionX, pionY), quand il est
+// sur la colonne conseillée par l'indice.
+func (g game) drawHint(screen *ebiten.Image, pionX, pionY float32) {
+	if !g.hintShown() || g.tokenPosition != g.hintColumn {
+		return
+	}
+	vector.StrokeCircle(screen, pionX, pionY, float32(globalTileSize/2-globalCircleMargin)+8, 4, globalTextColorGreen, true)
+}
+
+// resetHints oublie les indices de la partie terminée.
+func (g *game) resetHints() {
+	g.hintFor = 0
+	g.hintedMoves = nil
+}
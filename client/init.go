@@ -446,4 +446,6 @@ func (g *game) initGame() {
 	g.adversaryTokenPosition = g.tokenPosition
 	g.isReset = false
 	g.mouseReleased = true
+	g.roomID = -1
+	g.networkEvents = make(chan func(), networkEventBuffer)
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/protocol"
+)
+
+// Intervalle (en frames) entre deux rafraîchissements automatiques de la liste des salles.
+const lobbyRefreshFrames = 120
+
+// Mise à jour de l'état du jeu dans le lobby : choix d'une salle avec les flèches,
+// Entrée pour la rejoindre, S pour l'observer en spectateur, C pour en créer une nouvelle,
+// Q pour une partie rapide, R pour rafraîchir la liste, P pour saisir un mot de passe de salle,
+// I pour créer les salles avec ou sans indices, M pour choisir leur cadence, L pour se
+// connecter à un compte, T pour consulter les classements, O pour les tournois.
+func (g *game) lobbyUpdate() {
+	if g.chatIsFocus || g.client == nil {
+		return
+	}
+
+	if g.roomPasswordFocus {
+		g.roomPasswordUpdate()
+		return
+	}
+
+	if g.loginStep != loginNone {
+		g.loginUpdate()
+		return
+	}
+
+	if g.stateFrame%lobbyRefreshFrames == 0 {
+		sendListRooms(g.client)
+	}
+
+	if len(g.rooms) > 0 {
+		if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+			g.selectedRoom = (g.selectedRoom + 1) % len(g.rooms)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+			g.selectedRoom = (g.selectedRoom - 1 + len(g.rooms)) % len(g.rooms)
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		sendListRooms(g.client)
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.roomPasswordFocus = true
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		g.roomNoHints = !g.roomNoHints
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) && g.serverSupports(protocol.CapabilityClock) {
+		g.nextTimeControl()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) && g.serverSupports(protocol.CapabilityStats) {
+		g.openStats()
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) && g.serverSupports(protocol.CapabilityTournaments) {
+		g.openTournaments()
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) && g.serverSupports(protocol.CapabilityAccounts) {
+		g.loginStep = loginNameStep
+		g.loginPassword = ""
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		g.errorMessage = ""
+		sendCreateRoom(g.client, "", g.roomPassword, g.roomNoHints, g.timeControl())
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) && len(g.rooms) > 0 && g.serverSupports(protocol.CapabilitySpectate) {
+		g.errorMessage = ""
+		sendSpectate(g.client, g.rooms[g.selectedRoom].ID, g.roomPassword)
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyQ) && g.serverSupports(protocol.CapabilityQuickPlay) {
+		g.joinQuickPlay()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.errorMessage = ""
+		if len(g.rooms) == 0 {
+			sendCreateRoom(g.client, "", g.roomPassword, g.roomNoHints, g.timeControl())
+		} else {
+			sendJoinRoom(g.client, g.rooms[g.selectedRoom].ID, g.roomPassword)
+		}
+	}
+
+	// Le bouton du menu du haut crée une salle
+	mouseX, mouseY := ebiten.CursorPosition()
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && g.mouseReleased {
+		textWidth, _ := getTextDimensions("CREER", firstTitleMinusFont)
+		smallRectWidth := textWidth + 60*2
+		smallRectX, smallRectY := centerPosition(smallRectWidth, largeRectHeight, globalWidth, largeRectHeight)
+		if mouseX >= smallRectX && mouseX <= smallRectX+smallRectWidth &&
+			mouseY >= smallRectY && mouseY <= smallRectY+largeRectHeight {
+			g.mouseReleased = false
+			g.errorMessage = ""
+			sendCreateRoom(g.client, "", g.roomPassword, g.roomNoHints, g.timeControl())
+		}
+	}
+}
+
+// roomPasswordUpdate gère la saisie du mot de passe de salle : il sert à créer une salle
+// verrouillée, ou à rejoindre et observer une salle qui l'est. Entrée ou Échap termine la saisie.
+func (g *game) roomPasswordUpdate() {
+	for _, char := range ebiten.AppendInputChars(nil) {
+		g.roomPassword += string(char)
+	}
+
+	duration := inpututil.KeyPressDuration(ebiten.KeyBackspace)
+	if duration == 1 || (duration > 30 && duration%3 == 0) {
+		if runes := []rune(g.roomPassword); len(runes) > 0 {
+			g.roomPassword = string(runes[:len(runes)-1])
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.roomPasswordFocus = false
+	}
+}
+
+// serverSupports indique si le serveur a annoncé la capacité name lors de la poignée de main.
+func (g game) serverSupports(name string) bool {
+	for _, c := range g.serverCapabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// canAddBot indique si le joueur, en attente dans sa salle, peut y inviter un robot du serveur.
+// Les matchs de tournoi se jouent sans robot.
+func (g game) canAddBot() bool {
+	_, match := g.tournamentMatch()
+	return g.roomID != -1 && !g.inQueue && !match && g.serverSupports(protocol.CapabilityBots)
+}
+
+// joinQuickPlay place le joueur dans la file de partie rapide : le serveur
+// l'associera au prochain joueur disponible dans une nouvelle salle.
+func (g *game) joinQuickPlay() {
+	g.errorMessage = ""
+	g.inQueue = true
+	g.queuePosition = 0
+	g.queueWaiting = 0
+	g.queueEstimatedWait = 0
+	g.queueJoinedAt = time.Now()
+	g.stateFrame = 0
+	g.gameState = waitingState
+	sendQuickPlay(g.client)
+}
+
+// Affichage du lobby : liste des salles ouvertes sur le serveur.
+func (g game) lobbyDraw(screen *ebiten.Image) {
+	g.topMenuButton(screen, "CREER")
+
+	title := "Choisissez une salle"
+	titleWidth, titleHeight := getTextDimensions(title, firstTitleSmallerFont)
+	titleX := (globalWidth - titleWidth) / 2
+	titleY := globalHeight/4 + titleHeight
+	text.Draw(screen, title, firstTitleSmallerFont, titleX, titleY, globalTextColorYellow)
+
+	lineY := titleY + 80
+	if len(g.rooms) == 0 {
+		message := "Aucune salle ouverte, appuyez sur Entrée pour en créer une"
+		width, _ := getTextDimensions(message, smallFont)
+		text.Draw(screen, message, smallFont, (globalWidth-width)/2, lineY, globalTextColorBright)
+	}
+
+	for i, r := range g.rooms {
+		line := fmt.Sprintf("#%d  %s  (%d/%d)", r.ID, r.Name, r.Players, r.MaxPlayers)
+		if r.Locked {
+			line += "  [verrouillée]"
+		}
+		if r.NoHints {
+			line += "  [sans indices]"
+		}
+		if r.Clock.Enabled() {
+			line += "  [" + timeControlLabel(r.Clock) + "]"
+		}
+		if r.Spectators > 0 {
+			line += fmt.Sprintf("  %d spectateur(s)", r.Spectators)
+		}
+		if len(r.Members) > 0 {
+			names := make([]string, len(r.Members))
+			for i, p := range r.Members {
+				names[i] = playerLabel(p)
+			}
+			line += "  " + strings.Join(names, " - ")
+		}
+		width, height := getTextDimensions(line, smallFont)
+		x := (globalWidth - width) / 2
+
+		textColor := globalTextColorBright
+		if i == g.selectedRoom {
+			vector.DrawFilledRect(screen, float32(x-20), float32(lineY-height+10), float32(width+40), float32(height), globalTextColorGreen, true)
+			textColor = globalTextColor
+		} else if r.Players >= r.MaxPlayers {
+			textColor = globalTextRed
+		}
+		text.Draw(screen, line, smallFont, x, lineY, textColor)
+		lineY += height + 10
+	}
+
+	// Compte du joueur, ou saisie du pseudo et du mot de passe
+	if g.serverSupports(protocol.CapabilityAccounts) {
+		g.drawAccountLine(screen, globalHeight-190)
+	}
+
+	// Mot de passe utilisé pour les salles verrouillées, masqué à l'affichage
+	password := "Mot de passe de salle (P) : " + strings.Repeat("*", len([]rune(g.roomPassword)))
+	passwordColor := globalTextColorBright
+	if g.roomPasswordFocus {
+		passwordColor = globalTextColorYellow
+		if (g.stateFrame/30)%2 == 0 {
+			password += "_"
+		}
+	}
+	passwordWidth, _ := getTextDimensions(password, smallFont)
+	text.Draw(screen, password, smallFont, (globalWidth-passwordWidth)/2, globalHeight-160, passwordColor)
+
+	// Réglage des indices et de la cadence des salles créées
+	hints := "Salle créée : indices autorisés (I)"
+	if g.roomNoHints {
+		hints = "Salle créée : sans indices (I)"
+	}
+	if g.serverSupports(protocol.CapabilityClock) {
+		hints += ", cadence " + timeControlLabel(g.timeControl()) + " (M)"
+	}
+	hintsWidth, _ := getTextDimensions(hints, mediumFontError)
+	text.Draw(screen, hints, mediumFontError, (globalWidth-hintsWidth)/2, globalHeight-130, globalTextColorBright)
+
+	help := "Haut/Bas : choisir   Entrée : rejoindre   "
+	if g.serverSupports(protocol.CapabilitySpectate) {
+		help += "S : observer   "
+	}
+	help += "C : créer   "
+	if g.serverSupports(protocol.CapabilityQuickPlay) {
+		help += "Q : partie rapide   "
+	}
+	help += "R : actualiser   P : mot de passe"
+	if g.serverSupports(protocol.CapabilityAccounts) {
+		help += "   L : compte"
+	}
+	if g.serverSupports(protocol.CapabilityStats) {
+		help += "   T : classements"
+	}
+	if g.serverSupports(protocol.CapabilityTournaments) {
+		help += "   O : tournois"
+	}
+	helpWidth, _ := getTextDimensions(help, mediumFontError)
+	text.Draw(screen, help, mediumFontError, (globalWidth-helpWidth)/2, globalHeight-100, globalTextColorBright)
+
+	if g.errorMessage != "" {
+		g.errorMessageDisplay(screen, g.errorMessage)
+	}
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"flag"
 	"github.com/hajimehoshi/ebiten/v2"
 	"log"
 )
 
 // Création, paramétrage et lancement du jeu.
 func main() {
+	flag.BoolVar(&tlsInsecure, "tls-insecure", false, "accepte le certificat auto-signé d'un serveur lancé avec -tls-dev")
+	flag.StringVar(&tlsCAFile, "tls-ca", "", "certificat (PEM) du serveur ou de son autorité à reconnaître")
+	gameFile := flag.String("partie", "", "partie notée (.p4n) à revoir au lancement")
+	flag.Parse()
 
 	initResolution(true)
 
@@ -19,6 +24,12 @@ func main() {
 	// Initialiser le jeu
 	g := game{}
 	g.initGame()
+	if *gameFile != "" {
+		g.gameState = titleState
+		if err := g.loadGameFile(*gameFile); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// Initialiser les ressources nécessaires
 	initFonts()
@@ -1,329 +1,516 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"log"
 	"net"
 	"strings"
 	"time"
+
+	"puissance4/client"
+	"puissance4/protocol"
 )
 
-// Connecter le joueur au serveur
+// networkEventBuffer est le nombre d'événements du réseau en attente de la boucle du jeu ;
+// au-delà, la lecture des messages du serveur attend la prochaine image.
+const networkEventBuffer = 64
+
+// Connecter le joueur au serveur. La connexion est ouverte dans une goroutine, puis le
+// résultat est appliqué au jeu par Update comme les autres événements du réseau.
 func connectToServer(g *game) {
+	// Séparer le mot de passe du serveur et le préfixe TLS de l'adresse
+	g.serverAddress, g.serverPassword, g.useTLS = parseServerAddress(g.serverAddress)
+
 	// Vérifier si l'adresse contient déjà un port (si elle contient ":")
 	if !strings.Contains(g.serverAddress, ":") {
 		// Ajouter le port par défaut :8080
-		g.serverAddress += ":8080"
+		g.serverAddress += ":" + client.DefaultPort
 	}
 
-	conn, err := net.Dial("tcp", g.serverAddress)
+	address, useTLS := g.serverAddress, g.useTLS
+	go func() {
+		conn, err := dialServer(address, useTLS)
+		g.postNetworkEvent(func() { g.onDialed(conn, err) })
+	}()
+}
+
+// onDialed branche le jeu sur la connexion ouverte par connectToServer, ou revient à la
+// saisie de l'adresse si elle a échoué.
+func (g *game) onDialed(conn net.Conn, err error) {
 	if err != nil {
 		log.Println("Erreur de connexion au serveur :", err)
-		g.errorConnection = "Erreur : Adresse incorrecte."
+		g.errorConnection = "Adresse introuvable"
+		if g.useTLS {
+			g.errorConnection = "Connexion sécurisée impossible"
+		}
 		g.gameState = inputServerState // Retour à l'état de saisie
 		g.serverAddress = ""
 
 		return
 	}
 
-	g.conn = conn
+	g.startClient(conn, "")
 	g.connectionMessage = "Connecté au serveur. En attente d'autres joueurs..."
 	g.nbJoueurConnecte++
 	log.Println(g.connectionMessage)
-
-	// Écouter les messages du serveur
-	go listenToServer(conn, g)
 }
 
-func listenToServer(conn net.Conn, g *game) {
-	reader := bufio.NewReader(conn)
+// startClient branche le jeu sur la connexion conn et lance l'écoute des messages
+// du serveur. Un jeton resumeToken non vide demande la reprise de cette session.
+func (g *game) startClient(conn net.Conn, resumeToken string) {
+	handlers := g.handlers()
+	var c *client.Client
+	handlers.OnDisconnect = queued(g, func(err error) { g.onDisconnect(c, err) })
+	c = client.New(conn, handlers, client.Options{
+		Password:    g.serverPassword,
+		ResumeToken: resumeToken,
+	})
+	g.client = c
+	go c.Run()
+}
 
-	// Goroutine pour la surveillance de l'état et envoi automatique de messages
-	go func() {
-		messageSent := false
-		for {
-			if g.gameState == waitingColorSelect && !messageSent {
-				err := sendJSONMessage(conn, "choix effectué", nil) // Envoyer un message initial si nécessaire
-				if err != nil {
-					log.Printf("Erreur lors de l'envoi automatique du message : %v\n", err)
-				}
-				messageSent = true
-			}
-			time.Sleep(100 * time.Millisecond)
-		}
-	}()
+// postNetworkEvent confie l'événement event à la boucle du jeu. Elle est appelée depuis
+// les goroutines du réseau, qui ne doivent jamais modifier le jeu elles-mêmes.
+func (g *game) postNetworkEvent(event func()) {
+	g.networkEvents <- event
+}
 
-	// Boucle principale pour lire et traiter les messages du serveur
+// applyNetworkEvents applique au jeu, dans l'ordre d'arrivée, les événements du réseau
+// reçus depuis l'image précédente. Elle est appelée par Update.
+func (g *game) applyNetworkEvents() {
 	for {
-		message, err := reader.ReadString('\n')
-		if err != nil {
-			log.Printf("Erreur de lecture : %v\n", err)
-			g.connectionMessage = "Erreur de communication. Entrez une nouvelle adresse."
-			g.gameState = inputServerState
+		select {
+		case event := <-g.networkEvents:
+			event()
+		default:
 			return
 		}
+	}
+}
 
-		message = strings.TrimSpace(message)
+// queued renvoie un gestionnaire d'événement du client réseau qui, au lieu de traiter
+// l'événement dans la goroutine de lecture, le confie à la boucle du jeu.
+func queued[T any](g *game, handle func(T)) func(T) {
+	return func(value T) {
+		g.postNetworkEvent(func() { handle(value) })
+	}
+}
 
-		// Désérialiser le message JSON
-		var msg Message
-		err = json.Unmarshal([]byte(message), &msg)
-		if err != nil {
-			log.Printf("Erreur de décodage JSON : %v\n", err)
-			continue
-		}
+// queuedSignal fait de même pour un événement sans charge utile.
+func queuedSignal(g *game, handle func()) func() {
+	return func() {
+		g.postNetworkEvent(handle)
+	}
+}
 
-		// Gérer le message JSON
-		handleServerMessage(msg, g)
+// handlers associe les événements du client réseau aux méthodes du jeu, appelées par
+// Update pour que le jeu ne soit jamais modifié depuis la goroutine de lecture.
+// En mode spectateur, les coups et résultats de la partie observée sont traités à part.
+func (g *game) handlers() client.Handlers {
+	return client.Handlers{
+		OnWelcome:   queued(g, g.onWelcome),
+		OnConnected: queued(g, g.onConnected),
+		OnRefused:   queued(g, g.connectionFailed),
+		OnError:     queued(g, g.onError),
+
+		OnRoomList:      queued(g, g.onRoomList),
+		OnRoomJoined:    queued(g, g.onRoomJoined),
+		OnRoomLeft:      queuedSignal(g, g.onRoomLeft),
+		OnQueuePosition: queued(g, g.onQueuePosition),
+		OnQueueLeft:     queuedSignal(g, g.onQueueLeft),
+		OnSpectate:      queued(g, g.applySpectateState),
+
+		OnResumed:              queued(g, g.restoreSession),
+		OnResumeFailed:         queued(g, g.onResumeFailed),
+		OnOpponentReconnecting: queued(g, g.onOpponentReconnecting),
+		OnOpponentReconnected:  queuedSignal(g, g.onOpponentReconnected),
+		OnOpponentLeft:         queuedSignal(g, g.onOpponentLeft),
+
+		OnReady:          queued(g, g.onReady),
+		OnCursor:         queued(g, g.onCursor),
+		OnColor:          queued(g, g.onColor),
+		OnColorsComplete: queued(g, g.onColorsComplete),
+		OnShifumi:        queued(g, g.onShifumi),
+
+		OnTokenPosition:  queued(g, g.onTokenPosition),
+		OnMove:           queued(g, g.onMove),
+		OnMoveRejected:   queued(g, g.onMoveRejected),
+		OnGameOver:       queued(g, g.onGameOver),
+		OnRematchWaiting: queued(g, g.onRematchWaiting),
+		OnRematch:        queued(g, g.onRematch),
+		OnChat:           queued(g, g.onChat),
+		OnHistory:        queued(g, g.onHistory),
+		OnClock:          queued(g, g.onClock),
+
+		OnDrawOffered:       queued(g, g.onDrawOffered),
+		OnDrawDeclined:      queued(g, g.onDrawDeclined),
+		OnTakebackRequested: queued(g, g.onTakebackRequested),
+		OnTakebackDeclined:  queued(g, g.onTakebackDeclined),
+		OnTakeback:          queued(g, g.onTakeback),
+
+		OnLoggedIn:     queued(g, g.onLoggedIn),
+		OnRatingUpdate: queued(g, g.onRatingUpdate),
+		OnRoomPlayers:  queued(g, g.onRoomPlayers),
+
+		OnLeaderboard: queued(g, g.onLeaderboard),
+		OnPlayerStats: queued(g, g.onPlayerStats),
+
+		OnTournamentList: queued(g, g.onTournamentList),
+		OnTournament:     queued(g, g.onTournament),
 	}
 }
 
-func handleServerMessage(msg Message, g *game) {
-	switch msg.Type {
-	case "id":
-		// Récupérer l'ID du joueur
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if id, ok := payload["id"].(float64); ok {
-				g.playerID = int(id)
-				log.Printf("ID reçu : %d\n", g.playerID)
-				err := sendJSONMessage(g.conn, "ready", nil)
-				if err != nil {
-					return
-				} // Informer le serveur que le client est prêt
-			}
-		}
-	case "color":
-		// Récupérer la couleur de l'autre joueur
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if color, ok := payload["color"].(float64); ok {
-				g.p2Color = int(color)
-				log.Printf("Couleur de l'autre joueur reçue : %d\n", g.p2Color)
-			}
-		}
-	case "move":
-		// Mettre à jour la grille avec le mouvement de l'autre joueur
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if x, ok := payload["x"].(float64); ok {
-				if y, ok := payload["y"].(float64); ok {
-					log.Printf("Mouvement reçu : (%d, %d)\n", int(x), int(y))
-					updated, _ := g.updateGrid(p2Token, int(x))
-					if updated {
-						finished, result, posWinnerCheck := g.checkGameEnd(int(x), int(y))
-						if posWinnerCheck != nil {
-							g.posWinner = posWinnerCheck
-							log.Println("posWinnerCheck", g.posWinner)
-						}
-						if finished {
-							g.result = result
-							if g.result == p1wins {
-								g.nbPartieWin++
-								g.turn = p2Turn
-							} else if g.result == p2wins {
-								g.nbPartieAdversaireWin++
-								g.turn = p1Turn
-							} else {
-								g.nbPartieWin++
-								g.nbPartieAdversaireWin++
-								g.turn = g.firstPlayer
-							}
-							g.gameState = resultState
-							g.restartOk = false
-						} else {
-							g.turn = p1Turn // C'est maintenant au tour du joueur 1
-						}
-					} else {
-						log.Println("Erreur : Mise à jour de la grille échouée.")
-					}
-				}
-			}
-		}
-	case "chat":
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if text, ok := payload["text"].(string); ok {
-				if id, ok := payload["id"].(float64); ok {
-					if id == float64(g.playerID) {
-						g.addChatMessage(text, "You:")
-					} else {
-						g.addChatMessage(text, "Other:")
-						g.chatNewMessage = true
-					}
-				}
-			}
-		}
+// onDisconnect traite la fin de la connexion c : reprise de la session si le joueur
+// était assis dans une salle, retour à la saisie de l'adresse sinon.
+func (g *game) onDisconnect(c *client.Client, err error) {
+	if err != nil {
+		log.Printf("Erreur de lecture : %v\n", err)
+	}
+	if c != g.client {
+		return // La connexion a été remplacée par une reprise de session
+	}
+	if err != nil && g.canResume() {
+		g.reconnect()
+		return
+	}
+	g.connectionMessage = "Erreur de communication. Entrez une nouvelle adresse."
+	g.gameState = inputServerState
+}
 
-	case "rematch_waiting":
-		// Afficher le message d'attente pour le rematch
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if message, ok := payload["message"].(string); ok {
-				g.messageWaitRematch = message
-				log.Println(message)
-			}
-		}
-	case "restart_ok":
-		// Indiquer que le jeu peut redémarrer
-		g.restartOk = true
-		g.messageWaitRematch = ""
-		log.Println("Le jeu peut redémarrer.")
-	case "ready":
-		// Indiquer que le serveur est prêt
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if message, ok := payload["message"].(string); ok {
-				g.connectionMessage = message
-				g.serverReady = true
-				g.gameState = colorSelectState
-				if g.nbJoueurConnecte < 2 {
-					g.nbJoueurConnecte++
-				}
-				log.Println(message)
-			}
+func (g *game) onWelcome(welcome client.Welcome) {
+	// Récupérer l'ID du joueur
+	g.playerID = welcome.ID
+	g.sessionGrace = int(welcome.Grace / time.Second)
+	log.Printf("ID reçu : %d\n", g.playerID)
+	// Reconnexion après une coupure : la reprise de la session a été demandée
+	if g.reconnecting {
+		g.pendingToken = welcome.Token
+		return
+	}
+	g.sessionToken = welcome.Token
+	// Arriver dans le lobby et demander la liste des salles
+	g.gameState = lobbyState
+	sendListRooms(g.client)
+}
+
+func (g *game) onConnected(hello protocol.HelloPayload) {
+	g.serverCapabilities = hello.Capabilities
+	log.Printf("Serveur %s, protocole v%d, capacités %v\n", hello.Software, hello.Version, hello.Capabilities)
+	if !g.reconnecting {
+		// Après une reprise, le serveur a conservé le compte de la session
+		g.autoLogin()
+	}
+	if g.statsPending {
+		g.statsPending = false
+		g.openStats()
+	}
+}
+
+func (g *game) onError(payload protocol.ErrorPayload) {
+	log.Printf("Erreur du serveur (%s, message %q) : %s\n", payload.Code, payload.Type, payload.Message)
+	g.errorMessage = payload.Message
+	if payload.Code == protocol.ErrCodeRoomPassword {
+		// Salle verrouillée : proposer la saisie du mot de passe
+		g.roomPasswordFocus = true
+	}
+	switch payload.Code {
+	case protocol.ErrCodeRoom, protocol.ErrCodeRoomPassword, protocol.ErrCodeDisabled:
+		// Opération sur les salles impossible ou désactivée : revenir à la liste à jour
+		if g.inQueue {
+			g.inQueue = false
+			g.gameState = lobbyState
 		}
-	case "color_select_complete":
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if starterID, ok := payload["firstPlayer"].(float64); ok {
-				// Déterminer qui commence
-				if int(starterID) == g.playerID {
-					g.turn = p1Turn // Ce client commence
-					g.firstPlayer = p1Turn
-				} else {
-					g.turn = p2Turn // L'autre joueur commence
-					g.firstPlayer = p2Turn
-				}
-
-				// Mettre à jour l'état du jeu
-				g.connectionMessage = "La partie commence. Préparez-vous !"
-				g.gameState = shifumiState
-
-				log.Printf("Le joueur %d commence la partie. Votre tour : %v\n", int(starterID), g.turn == p1Turn)
-			} else {
-				log.Printf("Erreur : ID du premier joueur manquant dans le payload.")
-			}
+		sendListRooms(g.client)
+	}
+}
+
+func (g *game) onResumeFailed(message string) {
+	// La place n'a pas été conservée : repartir du lobby avec la nouvelle session
+	g.reconnecting = false
+	g.sessionToken = g.pendingToken
+	g.roomID = -1
+	g.roomName = ""
+	g.resetGrid()
+	g.nbPartieWin = 0
+	g.nbPartieAdversaireWin = 0
+	g.errorMessage = "Reprise impossible, la partie a été perdue"
+	g.gameState = lobbyState
+	sendListRooms(g.client)
+}
+
+func (g *game) onOpponentReconnecting(seconds int) {
+	g.opponentReconnectDeadline = time.Now().Add(time.Duration(seconds) * time.Second)
+	log.Printf("L'adversaire s'est déconnecté, reconnexion attendue pendant %ds\n", seconds)
+}
+
+func (g *game) onOpponentReconnected() {
+	g.opponentReconnectDeadline = time.Time{}
+	log.Println("L'adversaire s'est reconnecté")
+}
+
+func (g *game) onRoomList(rooms []protocol.RoomInfo) {
+	g.rooms = rooms
+	if g.selectedRoom >= len(g.rooms) {
+		g.selectedRoom = 0
+	}
+}
+
+func (g *game) onRoomJoined(room protocol.RoomJoinedPayload) {
+	// Le client réseau a déjà informé le serveur que le joueur est prêt
+	g.inQueue = false
+	g.roomID = room.ID
+	g.roomName = room.Name
+	g.clock = nil
+	g.gameState = waitingState
+	log.Printf("Salle %d (%s) rejointe\n", g.roomID, g.roomName)
+}
+
+func (g *game) onRoomLeft() {
+	_, match := g.tournamentMatch()
+	g.roomID = -1
+	g.roomName = ""
+	g.spectatePlayers = nil
+	g.roomPlayers = nil
+	g.clock = nil
+	if match {
+		// Fin d'un match de tournoi : revenir au tableau du tournoi
+		g.gameState = tournamentState
+		return
+	}
+	g.gameState = lobbyState
+	sendListRooms(g.client)
+}
+
+func (g *game) onQueuePosition(position protocol.QueuePositionPayload) {
+	// Position dans la file de partie rapide et attente estimée
+	g.queuePosition = position.Position
+	g.queueWaiting = position.Waiting
+	g.queueEstimatedWait = position.EstimatedWait
+}
+
+func (g *game) onQueueLeft() {
+	g.inQueue = false
+	g.gameState = lobbyState
+	sendListRooms(g.client)
+}
+
+func (g *game) onColor(color protocol.ColorPayload) {
+	// Récupérer la couleur de l'autre joueur
+	g.p2Color = color.Color
+	log.Printf("Couleur de l'autre joueur reçue : %d\n", g.p2Color)
+}
+
+func (g *game) onMove(move protocol.MovePayload) {
+	if g.gameState == spectatorState {
+		g.spectatorMove(move)
+		return
+	}
+
+	// Un coup joué fait tomber les propositions en attente
+	g.clearOffers()
+
+	// Mettre à jour la grille avec le mouvement de l'autre joueur
+	log.Printf("Mouvement reçu : (%d, %d)\n", move.X, move.Y)
+	updated, _ := g.updateGrid(p2Token, move.X)
+	if !updated {
+		log.Println("Erreur : Mise à jour de la grille échouée.")
+		return
+	}
+	finished, result, posWinnerCheck := g.checkGameEnd(move.X, move.Y)
+	if posWinnerCheck != nil {
+		g.posWinner = posWinnerCheck
+		log.Println("posWinnerCheck", g.posWinner)
+	}
+	if finished {
+		g.result = result
+		if g.result == p1wins {
+			g.nbPartieWin++
+			g.turn = p2Turn
+		} else if g.result == p2wins {
+			g.nbPartieAdversaireWin++
+			g.turn = p1Turn
 		} else {
-			log.Printf("Erreur : Structure de payload invalide pour 'color_select_complete'.")
-		}
-	case "cursor_update":
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if color, ok := payload["color"].(float64); ok {
-				g.p2CursorColor = int(color)
-			}
+			g.nbPartieWin++
+			g.nbPartieAdversaireWin++
+			g.turn = g.firstPlayer
 		}
-	case "token_update":
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if position, ok := payload["position"].(float64); ok {
-				g.adversaryTokenPosition = int(position)
-				log.Printf("Position adversaire: %d\n", g.adversaryTokenPosition)
-			}
+		g.gameState = resultState
+		g.restartOk = false
+	} else {
+		g.turn = p1Turn // C'est maintenant au tour du joueur 1
+	}
+}
+
+func (g *game) onMoveRejected(rejected protocol.MoveRejectedPayload) {
+	// Le serveur a refusé notre dernier coup : retirer le pion posé localement
+	log.Printf("Coup refusé par le serveur : %s\n", rejected.Reason)
+	g.removeTopToken(p1Token, rejected.X)
+	if rejected.YourTurn {
+		g.turn = p1Turn
+	}
+	g.errorMessage = "Coup refusé : " + rejected.Reason
+}
+
+func (g *game) onGameOver(over protocol.GameOverPayload) {
+	if g.gameState == spectatorState {
+		g.spectatorGameOver(over)
+		return
+	}
+
+	// Le serveur fait foi sur la fin de partie et l'alignement gagnant
+	if over.Cells != nil {
+		g.posWinner = over.Cells
+	}
+	g.gameOverReason = over.Reason
+	g.clearOffers()
+	if g.gameState == playState {
+		// La partie n'a pas encore été terminée localement
+		switch over.Winner {
+		case -1:
+			g.result = equality
+			g.nbPartieWin++
+			g.nbPartieAdversaireWin++
+			g.turn = g.firstPlayer
+		case g.playerID:
+			g.result = p1wins
+			g.nbPartieWin++
+			g.turn = p2Turn
+		default:
+			g.result = p2wins
+			g.nbPartieAdversaireWin++
+			g.turn = p1Turn
 		}
-	case "sent_history":
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			// Re-sérialiser le payload en JSON
-			jsonData, err := json.Marshal(payload)
-			if err != nil {
-				log.Printf("Erreur lors de la re-sérialisation de l'historique : %v\n", err)
-				return
-			}
+		g.gameState = resultState
+		g.restartOk = false
+	}
+	log.Printf("Fin de partie annoncée par le serveur : %v\n", over.Result)
+}
 
-			// Désérialiser dans une map[string]Coordinate
-			tempHistory := make(map[string]Coordinate)
-			if err := json.Unmarshal(jsonData, &tempHistory); err != nil {
-				log.Printf("Erreur lors du décodage de l'historique : %v\n", err)
-				return
-			}
+func (g *game) onChat(message protocol.ChatMessage) {
+	if message.ID == g.playerID {
+		g.addChatMessage(message.Text, "You:")
+	} else {
+		g.addChatMessage(message.Text, "Other:")
+		g.chatNewMessage = true
+	}
+}
 
-			// Convertir les clés string en int et stocker dans votre variable globale
-			for keyStr, coord := range tempHistory {
-				var keyInt int
-				_, err := fmt.Sscanf(keyStr, "%d", &keyInt)
-				if err != nil {
-					log.Printf("Erreur de conversion de clé '%s' en int : %v\n", keyStr, err)
-					continue
-				}
-				history[keyInt] = coord
-			}
+func (g *game) onRematchWaiting(message string) {
+	// Afficher le message d'attente pour le rematch
+	g.messageWaitRematch = message
+	log.Println(message)
+}
+
+func (g *game) onRematch(string) {
+	// Indiquer que le jeu peut redémarrer
+	g.restartOk = true
+	g.messageWaitRematch = ""
+	log.Println("Le jeu peut redémarrer.")
+}
+
+func (g *game) onReady(message string) {
+	// Indiquer que le serveur est prêt
+	g.connectionMessage = message
+	g.serverReady = true
+	g.gameState = colorSelectState
+	if g.nbJoueurConnecte < 2 {
+		g.nbJoueurConnecte++
+	}
+	log.Println(message)
+}
+
+func (g *game) onColorsComplete(starterID int) {
+	// Déterminer qui commence
+	if starterID == g.playerID {
+		g.turn = p1Turn // Ce client commence
+		g.firstPlayer = p1Turn
+	} else {
+		g.turn = p2Turn // L'autre joueur commence
+		g.firstPlayer = p2Turn
+	}
+
+	// Mettre à jour l'état du jeu
+	g.connectionMessage = "La partie commence. Préparez-vous !"
+	g.gameState = shifumiState
+
+	log.Printf("Le joueur %d commence la partie. Votre tour : %v\n", starterID, g.turn == p1Turn)
+}
+
+func (g *game) onCursor(color int) {
+	g.p2CursorColor = color
+}
+
+func (g *game) onTokenPosition(update protocol.TokenUpdatePayload) {
+	if g.gameState == spectatorState {
+		g.spectatorTokenPosition(update)
+		return
+	}
+	g.adversaryTokenPosition = update.Position
+	log.Printf("Position adversaire: %d\n", g.adversaryTokenPosition)
+}
+
+func (g *game) onHistory(payload protocol.HistoryPayload) {
+	for turn, coord := range payload {
+		history[turn] = coord
+	}
+	log.Println("Historique mis à jour côté client :", history)
+}
 
-			log.Println("Historique mis à jour côté client :", history)
+func (g *game) onOpponentLeft() {
+	log.Println("L'autre joueur s'est deconnecté")
+	g.disconnectClient()
+}
+
+func (g *game) onShifumi(result client.Shifumi) {
+	if !result.Draw {
+		if result.Winner == g.playerID {
+			g.turn = p1Turn
+			g.firstPlayer = p1Turn
 		} else {
-			log.Println("Payload invalide pour 'sent_history'")
-		}
-	case "other_disconnected":
-		log.Println("L'autre joueur s'est deconnecté")
-		g.disconnectClient()
-	case "selected":
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if selected, ok := payload["selected"].(string); ok {
-				g.selected = selected
-				log.Printf("Sélection reçue : %s\n", selected)
-			}
-		}
-	case "shifumi_result":
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if result, ok := payload["result"].(string); ok {
-				// Stocker le résultat
-				g.shifumiResult = result
-				g.showShifumiResult = true
-				g.shifumiResultTimer = 120 // environ 2 secondes à 60 FPS
-
-				// Gérer les sélections des joueurs
-				if p1, ok := payload["player1"].(map[string]interface{}); ok {
-					if p1Selection, ok := p1["selection"].(string); ok {
-						if g.playerID == 1 {
-							g.selected = p1Selection
-						} else {
-							g.adversaryChoice = p1Selection
-						}
-					}
-				}
-				if p2, ok := payload["player2"].(map[string]interface{}); ok {
-					if p2Selection, ok := p2["selection"].(string); ok {
-						if g.playerID == 2 {
-							g.selected = p2Selection
-						} else {
-							g.adversaryChoice = p2Selection
-						}
-					}
-				}
-
-				// Déterminer le résultat local
-				g.determineShifumiWinner()
-
-				if result == "draw" {
-					// En cas d'égalité, rester dans l'état shifumi pour rejouer
-					g.gameState = shifumiState
-					g.selected = "" // Réinitialiser la sélection pour le prochain tour
-					g.adversaryChoice = ""
-				}
-				log.Printf("Résultat Shifumi - Joueur: %s, Adversaire: %s, Résultat: %s\n",
-					g.selected, g.adversaryChoice, g.shifumiResult)
-			}
+			g.turn = p2Turn
+			g.firstPlayer = p2Turn
 		}
-	case "shifumi_complete":
-		if payload, ok := msg.Payload.(map[string]interface{}); ok {
-			if winnerID, ok := payload["winner"].(float64); ok {
-				if int(winnerID) == g.playerID {
-					g.turn = p1Turn
-					g.firstPlayer = p1Turn
-				} else {
-					g.turn = p2Turn
-					g.firstPlayer = p2Turn
-				}
-				// Passer à l'état de sélection des couleurs
-
-				g.gameReady = true
-				g.gameState = playState
-				g.connectionMessage = "Shifumi terminé ! Sélectionnez votre couleur."
-				log.Printf("Le joueur %d a gagné le shifumi et commence la partie\n", int(winnerID))
-			}
-		}
-	default:
-		log.Printf("Type de message inconnu : %s\n", msg.Type)
-
+		// Passer à la partie
+		g.gameReady = true
+		g.gameState = playState
+		g.connectionMessage = "Shifumi terminé ! Sélectionnez votre couleur."
+		log.Printf("Le joueur %d a gagné le shifumi et commence la partie\n", result.Winner)
+		return
 	}
+
+	// Égalité : afficher le résultat puis rejouer
+	g.shifumiResult = protocol.ResultDraw
+	g.showShifumiResult = true
+	g.shifumiResultTimer = 120 // environ 2 secondes à 60 FPS
+	g.selected = result.Mine
+	g.adversaryChoice = result.Theirs
+
+	// Déterminer le résultat local
+	g.determineShifumiWinner()
+
+	log.Printf("Résultat Shifumi - Joueur: %s, Adversaire: %s, Résultat: %s\n",
+		g.selected, g.adversaryChoice, g.shifumiResult)
+
+	// En cas d'égalité, rester dans l'état shifumi pour rejouer
+	g.gameState = shifumiState
+	g.selected = "" // Réinitialiser la sélection pour le prochain tour
+	g.adversaryChoice = ""
+}
+
+// connectionFailed ferme la connexion refusée par le serveur et revient à la saisie
+// de l'adresse en affichant la raison du refus.
+func (g *game) connectionFailed(reason string) {
+	log.Printf("Connexion refusée : %s\n", reason)
+	g.sessionToken = "" // Pas de reprise de session possible
+	g.reconnecting = false
+	g.disconnectClient()
+	g.errorConnection = reason
+	g.gameState = inputServerState
 }
 
 func (g *game) disconnectClient() {
-	err := g.conn.Close()
+	err := g.client.Close()
 	if err != nil {
 		return
 	}
@@ -360,6 +547,18 @@ func (g *game) disconnectClient() {
 	g.adversaryTokenPosition = g.tokenPosition
 	g.nbPartieWin = 0
 	g.nbPartieAdversaireWin = 0
+	g.roomID = -1
+	g.roomName = ""
+	g.rooms = nil
+	g.selectedRoom = 0
+	g.inQueue = false
+	g.sessionToken = ""
+	g.serverCapabilities = nil
+	g.serverPassword = ""
+	g.useTLS = false
+	g.roomPassword = ""
+	g.roomPasswordFocus = false
+	g.opponentReconnectDeadline = time.Time{}
 	g.chatMessages = []string{} // Historique des messages de chat
 	g.chatInput = ""
 	g.chatIsFocus = false
@@ -437,86 +636,98 @@ func calculateMessageHeight(message string, maxWidth int) int {
 	return len(lines) * 20 // Chaque ligne fait 20 pixels de hauteur (ou ajustez selon votre font)
 }
 
-func sendChatMessage(conn net.Conn, text string) {
-	payload := ChatMessage{Text: text}
-	err := sendJSONMessage(conn, "chat", payload)
-	if err != nil {
+func sendChatMessage(c *client.Client, text string) {
+	if err := c.Chat(text); err != nil {
 		log.Printf("Erreur lors de l'envoi du message de chat : %v\n", err)
 	}
 }
 
-func requestHistory(conn net.Conn) error {
-	return sendJSONMessage(conn, "require_history", nil)
+func sendListRooms(c *client.Client) {
+	if err := c.ListRooms(); err != nil {
+		log.Printf("Erreur lors de la demande de la liste des salles : %v\n", err)
+	}
 }
 
-func sendCursorUpdateToServer(conn net.Conn, color int) {
-	payload := map[string]int{"color": color}
-	err := sendJSONMessage(conn, "cursor_update", payload)
-	if err != nil {
-		log.Printf("Erreur lors de l'envoi de la position du curseur : %v\n", err)
+func sendCreateRoom(c *client.Client, name, password string, noHints bool, clock protocol.TimeControl) {
+	room := protocol.CreateRoomPayload{Name: name, Password: password, NoHints: noHints, Clock: clock}
+	if err := c.CreateRoomWith(room); err != nil {
+		log.Printf("Erreur lors de la création de la salle : %v\n", err)
 	}
 }
 
-func sendTokenUpdateToServer(conn net.Conn, position int) {
-	// Créer un payload contenant la position du curseur
-	payload := map[string]int{
-		"position": position,
+func sendJoinRoom(c *client.Client, roomID int, password string) {
+	if err := c.Join(roomID, password); err != nil {
+		log.Printf("Erreur lors de la demande pour rejoindre la salle %d : %v\n", roomID, err)
 	}
+}
 
-	// Envoyer un message JSON de type "cursor_update" avec la position
-	err := sendJSONMessage(conn, "token_update", payload)
-	if err != nil {
-		log.Printf("Erreur lors de l'envoi de la mise à jour du curseur : %v\n", err)
+func sendQuickPlay(c *client.Client) {
+	if err := c.QuickPlay(); err != nil {
+		log.Printf("Erreur lors de la demande de partie rapide : %v\n", err)
 	}
-	log.Println("token envoyé au serveur")
 }
 
-func sendColorToServer(conn net.Conn, color int) {
-	payload := ColorPayload{Color: color}
-	err := sendJSONMessage(conn, "color", payload)
-	if err != nil {
-		log.Printf("Erreur lors de l'envoi de la couleur : %v\n", err)
+func sendCancelQuickPlay(c *client.Client) {
+	if err := c.CancelQuickPlay(); err != nil {
+		log.Printf("Erreur lors de la sortie de la file d'attente : %v\n", err)
 	}
-	log.Printf("Couleur envoyée au serveur : %d\n", color)
 }
 
-func sendMoveToServer(conn net.Conn, x int, y int) {
-	payload := MovePayload{X: x, Y: y}
-	err := sendJSONMessage(conn, "move", payload)
-	if err != nil {
-		log.Printf("Erreur lors de l'envoi du mouvement : %v\n", err)
+func sendSpectate(c *client.Client, roomID int, password string) {
+	if err := c.Spectate(roomID, password); err != nil {
+		log.Printf("Erreur lors de la demande pour observer la salle %d : %v\n", roomID, err)
 	}
-	log.Printf("Mouvement envoyé : (%d, %d)\n", x, y)
 }
 
-func sendJSONMessage(conn net.Conn, messageType string, payload interface{}) error {
-	message := Message{
-		Type:    messageType,
-		Payload: payload,
+// sendAddBot demande au serveur d'inviter un robot du niveau level à la place libre de la salle.
+func sendAddBot(c *client.Client, level string) {
+	if err := c.AddBot(level); err != nil {
+		log.Printf("Erreur lors de l'invitation d'un robot : %v\n", err)
 	}
+}
 
-	jsonData, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("erreur de sérialisation JSON : %w", err)
+func sendLeaveRoom(c *client.Client) {
+	if err := c.Leave(); err != nil {
+		log.Printf("Erreur lors de la sortie de la salle : %v\n", err)
 	}
+}
 
-	_, err = conn.Write(append(jsonData, '\n')) // Ajout de '\n' pour marquer la fin du message
-	if err != nil {
-		return fmt.Errorf("erreur lors de l'envoi du message JSON : %w", err)
+func requestHistory(c *client.Client) error {
+	return c.RequestHistory()
+}
+
+func sendCursorUpdateToServer(c *client.Client, color int) {
+	if err := c.Cursor(color); err != nil {
+		log.Printf("Erreur lors de l'envoi de la position du curseur : %v\n", err)
+	}
+}
+
+func sendTokenUpdateToServer(c *client.Client, position int) {
+	// Envoyer la position du pion au-dessus de la grille
+	if err := c.TokenPosition(position); err != nil {
+		log.Printf("Erreur lors de l'envoi de la mise à jour du curseur : %v\n", err)
 	}
+	log.Println("token envoyé au serveur")
+}
 
-	return nil
+func sendColorToServer(c *client.Client, color int) {
+	if err := c.PickColor(color); err != nil {
+		log.Printf("Erreur lors de l'envoi de la couleur : %v\n", err)
+	}
+	log.Printf("Couleur envoyée au serveur : %d\n", color)
 }
 
-// SelectedPayload représente les données de sélection à envoyer au serveur
-type SelectedPayload struct {
-	Selected string `json:"selected"`
+// sendMoveToServer envoie le coup joué dans la colonne x, déjà posé sur la grille
+// du jeu en ligne y. Le client réseau le rejoue sur sa propre grille.
+func sendMoveToServer(c *client.Client, x int, y int) {
+	if err := c.Play(x); err != nil {
+		log.Printf("Erreur lors de l'envoi du mouvement : %v\n", err)
+	}
+	log.Printf("Mouvement envoyé : (%d, %d)\n", x, y)
 }
 
-func sendSelectedToServer(conn net.Conn, selected string) {
-	payload := SelectedPayload{Selected: selected}
-	err := sendJSONMessage(conn, "selected", payload)
-	if err != nil {
+func sendSelectedToServer(c *client.Client, selected string) {
+	if err := c.Shifumi(selected); err != nil {
 		log.Printf("Erreur lors de l'envoi de la sélection : %v\n", err)
 	}
 	log.Printf("Sélection envoyée : %s\n", selected)
@@ -9,6 +9,7 @@ import (
 	"golang.org/x/image/font"
 	"image/color"
 	"strings"
+	"time"
 )
 
 func getTextDimensions(text string, fontFace font.Face) (width, height int) {
@@ -292,7 +293,7 @@ func (g game) inputServerDraw(screen *ebiten.Image) {
 	text.Draw(screen, mainText, firstTitleSmallerFont, mainX, mainY, globalTextColorYellow)
 
 	// Afficher l'adresse actuelle ou une suggestion par défaut
-	addressToShow := g.serverAddress
+	addressToShow := maskServerAddress(g.serverAddress)
 	if addressToShow == "" {
 		addressToShow = "localhost:8080 (par défaut)"
 	}
@@ -315,7 +316,7 @@ func (g game) inputServerDraw(screen *ebiten.Image) {
 
 	// Afficher le message de connexion ou d'erreur
 	if g.errorConnection != "" {
-		g.errorMessageDisplay(screen, "Adresse introuvable")
+		g.errorMessageDisplay(screen, g.errorConnection)
 	}
 
 }
@@ -397,6 +398,45 @@ func (g game) waitingDraw(screen *ebiten.Image) {
 
 	// Dessiner le texte centré
 	text.Draw(screen, message, firstTitleSmallerFont, textX, textY, globalTextColorYellow)
+
+	// Position dans la file de partie rapide et attente estimée
+	if g.inQueue {
+		g.queueDraw(screen, textY+textHeight+20)
+		return
+	}
+
+	// Rappeler la salle rejointe et comment revenir au lobby
+	if g.roomID != -1 {
+		roomText := fmt.Sprintf("%s - Echap pour revenir au lobby", g.roomName)
+		roomWidth, _ := getTextDimensions(roomText, smallFont)
+		text.Draw(screen, roomText, smallFont, (globalWidth-roomWidth)/2, textY+textHeight+20, globalTextColorBright)
+	}
+	if g.canAddBot() {
+		botText := fmt.Sprintf("B : jouer contre un robot (niveau %s)", g.computerLevel)
+		botWidth, botHeight := getTextDimensions(botText, smallFont)
+		text.Draw(screen, botText, smallFont, (globalWidth-botWidth)/2, textY+textHeight+30+botHeight, globalTextColorBright)
+	}
+}
+
+// queueDraw affiche, sous le message d'attente, la position du joueur dans la file
+// de partie rapide, le temps déjà écoulé et l'attente estimée par le serveur.
+func (g game) queueDraw(screen *ebiten.Image, y int) {
+	lines := []string{"Recherche d'un adversaire - Echap pour annuler"}
+	if g.queuePosition > 0 {
+		lines = append(lines, fmt.Sprintf("Position dans la file : %d/%d", g.queuePosition, g.queueWaiting))
+	}
+	elapsed := int(time.Since(g.queueJoinedAt).Seconds())
+	waitLine := fmt.Sprintf("Attente : %ds", elapsed)
+	if g.queuePosition > 0 {
+		waitLine += fmt.Sprintf("   Estimée : ~%ds", g.queueEstimatedWait)
+	}
+	lines = append(lines, waitLine)
+
+	for _, line := range lines {
+		width, height := getTextDimensions(line, smallFont)
+		text.Draw(screen, line, smallFont, (globalWidth-width)/2, y, globalTextColorBright)
+		y += height + 10
+	}
 }
 
 func (g game) drawFullscreenButton(screen *ebiten.Image) {
@@ -456,7 +496,7 @@ func (g game) drawNbPlayer(screen *ebiten.Image) {
 	// Dessiner le rectangle derrière le texte
 
 	// Dessiner le texte par-dessus le fond
-	if g.gameState != titleState {
+	if g.gameState != titleState && g.gameState != computerSelectState && !g.offline {
 		vector.DrawFilledRect(screen, float32(rectX), float32(rectY), float32(rectWidth), float32(rectHeight), globalTextColorBright, true)
 
 		text.Draw(screen, playerText, mediumFontError, textX, textY, globalTextColor)
@@ -504,6 +544,16 @@ func (g game) drawScore(screen *ebiten.Image) {
 	// Dessiner le texte par-dessus le fond
 	vector.DrawFilledRect(screen, float32(rectX2), float32(rectY2), float32(rectWidth2), float32(rectHeight2), globalTextColorBright, true)
 	text.Draw(screen, playerText2, mediumFontError, textX2, textY2, globalTextColor)
+
+	// Pendules des joueurs dans une salle avec une cadence, cotes des joueurs connectés
+	// à un compte, puis variation après une partie classée et score du match de tournoi
+	lineY := g.drawClocks(screen, textX, textY2+50)
+	for _, line := range append(g.ratingLines(), g.tournamentLines()...) {
+		lineWidth, lineHeight := getTextDimensions(line, mediumFontError)
+		vector.DrawFilledRect(screen, float32(textX-padding), float32(lineY-25), float32(lineWidth+20), float32(lineHeight), globalTextColorBright, true)
+		text.Draw(screen, line, mediumFontError, textX, lineY, globalTextColor)
+		lineY += 50
+	}
 }
 
 func (g game) drawThemeButton(screen *ebiten.Image) {
@@ -571,44 +621,19 @@ func (g game) drawReplay(screen *ebiten.Image) {
 	g.topMenuButton(screen, "RETOUR")
 	g.drawGrid(screen)
 
-	message := "Il y a Egalite"
-	if g.result == p1wins {
-		message = "Vous avez Gagne !"
-	} else if g.result == p2wins {
-		message = "Vous avez Perdu"
+	message := g.resultMessage()
+	if g.replayTitle != "" {
+		message = g.replayTitle // Partie chargée depuis un fichier
 	}
 	textWidth, _ := getTextDimensions(message, firstTitleSmallerFont)
 	textX := (globalWidth - textWidth) / 2
 	textY := globalHeight/2 - ((globalNumTilesY * globalTileSize) / 2) + 20
 	text.Draw(screen, message, firstTitleSmallerFont, textX, textY, globalTextColorYellow)
 
-	// Calculer la position et les dimensions de la grille
-	gridWidth := globalTileSize * globalNumTilesX
-	gridHeight := globalTileSize * globalNumTilesY
-	startX := (globalWidth - gridWidth) / 2
-	startY := (globalHeight-gridHeight)/2 + 50
-
 	if g.blinking {
-		// Dessiner les cercles pour chaque cellule
-		for _, pos := range g.posWinner {
-			x, y := pos[0], pos[1]
-
-			centerX := float32(startX + globalTileSize/2 + x*globalTileSize)
-			centerY := float32(startY + globalTileSize/2 + y*globalTileSize)
-
-			if g.stateFrame%60 < 10 { // Clignote toutes les 30 frames (1/2 seconde à 60 FPS)
-				vector.DrawFilledCircle(
-					screen,
-					centerX,
-					centerY,
-					float32(globalTileSize/2-globalCircleMargin),
-					globalTextRed,
-					true,
-				)
-			}
-
-		}
+		g.drawWinningCells(screen)
 	}
+	g.drawReplayControls(screen)
 
 	if g.chatIsFocus {
 		g.drawChat(screen)
@@ -619,6 +644,34 @@ func (g game) drawReplay(screen *ebiten.Image) {
 
 }
 
+// drawWinningCells fait clignoter les pions de l'alignement gagnant (posWinner).
+func (g game) drawWinningCells(screen *ebiten.Image) {
+	// Calculer la position et les dimensions de la grille
+	gridWidth := globalTileSize * globalNumTilesX
+	gridHeight := globalTileSize * globalNumTilesY
+	startX := (globalWidth - gridWidth) / 2
+	startY := (globalHeight-gridHeight)/2 + 50
+
+	// Dessiner les cercles pour chaque cellule
+	for _, pos := range g.posWinner {
+		x, y := pos[0], pos[1]
+
+		centerX := float32(startX + globalTileSize/2 + x*globalTileSize)
+		centerY := float32(startY + globalTileSize/2 + y*globalTileSize)
+
+		if g.stateFrame%60 < 10 { // Clignote toutes les 30 frames (1/2 seconde à 60 FPS)
+			vector.DrawFilledCircle(
+				screen,
+				centerX,
+				centerY,
+				float32(globalTileSize/2-globalCircleMargin),
+				globalTextRed,
+				true,
+			)
+		}
+	}
+}
+
 func (g game) drawChat(screen *ebiten.Image) {
 
 	// Dessiner une bordure autour de la zone de saisie
@@ -651,6 +704,11 @@ func (g game) drawChat(screen *ebiten.Image) {
 }
 
 func (g game) drawChatButton(screen *ebiten.Image) {
+	// Pas de chat hors ligne
+	if g.offline {
+		return
+	}
+
 	// Déterminer l'icône à afficher
 	var icon *ebiten.Image = chat
 	var iconWarn = chatWarning
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"puissance4/engine"
+	"puissance4/notation"
+	"puissance4/protocol"
+)
+
+// gamesDir est le répertoire où les parties sont exportées, et où Maj+clic sur le
+// bouton de replay va chercher la plus récente.
+const gamesDir = "parties"
+
+// gameFileExt est l'extension des fichiers de parties notées.
+const gameFileExt = ".p4n"
+
+// loadedOpponentID identifie les coups du second joueur d'une partie chargée depuis
+// un fichier : le premier joueur est affiché comme le joueur local.
+const loadedOpponentID = -3
+
+// savedGame conserve la partie affichée pendant le replay d'une partie chargée depuis
+// un fichier, pour la retrouver à la fin du replay.
+type savedGame struct {
+	state     int
+	moves     []engine.Move
+	p1Color   int
+	p2Color   int
+	result    int
+	posWinner [][2]int
+}
+
+// exportGame enregistre la partie de la grille en notation texte dans gamesDir.
+func (g *game) exportGame() {
+	if g.board.MoveCount() == 0 {
+		return
+	}
+	game := notation.FromBoard(&g.board)
+	event, site := g.roomName, g.serverAddress
+	if g.offline {
+		event, site = "Partie contre l'ordinateur", "hors ligne"
+	}
+	game.Set(notation.TagEvent, event)
+	game.Set(notation.TagSite, site)
+	game.SetDate(time.Now())
+
+	// Le premier coup n'est pas forcément celui du joueur local
+	players := [2]string{g.localName(), g.opponentName()}
+	colors := [2]int{g.p1Color, g.p2Color}
+	if first := g.board.Moves()[0]; first.Token == p2Token {
+		players[0], players[1] = players[1], players[0]
+		colors[0], colors[1] = colors[1], colors[0]
+	}
+	game.Set(notation.TagFirst, players[0])
+	game.Set(notation.TagSecond, players[1])
+	game.Set(notation.TagFirstColor, strconv.Itoa(colors[0]))
+	game.Set(notation.TagSecondColor, strconv.Itoa(colors[1]))
+
+	path := filepath.Join(gamesDir, time.Now().Format("partie-20060102-150405")+gameFileExt)
+	err := os.MkdirAll(gamesDir, 0o755)
+	if err == nil {
+		err = os.WriteFile(path, []byte(game.String()), 0o644)
+	}
+	if err != nil {
+		log.Printf("Erreur lors de l'export de la partie : %v\n", err)
+		g.exportMessage = "Export impossible : " + err.Error()
+		return
+	}
+	log.Printf("Partie exportée dans %s : %s\n", path, game.Compact())
+	g.exportMessage = "Partie enregistrée dans " + path
+}
+
+// localName renvoie le nom du joueur local dans une partie exportée.
+func (g *game) localName() string {
+	if g.offline {
+		return "Joueur"
+	}
+	if g.accountName != "" {
+		return g.accountName
+	}
+	return fmt.Sprintf("Joueur %d", g.playerID)
+}
+
+// opponentName renvoie le nom de l'adversaire dans une partie exportée.
+func (g *game) opponentName() string {
+	if g.offline {
+		return "Ordinateur (" + g.computerLevel.String() + ")"
+	}
+	if g.client != nil {
+		if id := g.client.Opponent(); id >= 0 {
+			if p, ok := g.roomPlayer(id); ok && p.Name != "" {
+				return p.Name
+			}
+			return fmt.Sprintf("Joueur %d", id)
+		}
+	}
+	return "Adversaire"
+}
+
+// loadDroppedGame rejoue la partie notée déposée sur la fenêtre, s'il y en a une.
+func (g *game) loadDroppedGame() {
+	dropped := ebiten.DroppedFiles()
+	if dropped == nil {
+		return
+	}
+	entries, err := fs.ReadDir(dropped, ".")
+	if err != nil || len(entries) == 0 {
+		return
+	}
+	data, err := fs.ReadFile(dropped, entries[0].Name())
+	if err != nil {
+		g.exportMessage = "Lecture impossible : " + err.Error()
+		return
+	}
+	g.replayGameText(entries[0].Name(), string(data))
+}
+
+// loadGameFile rejoue la partie notée du fichier path.
+func (g *game) loadGameFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !g.replayGameText(path, string(data)) {
+		return fmt.Errorf("%s : %s", path, g.exportMessage)
+	}
+	return nil
+}
+
+// latestGameFile renvoie la partie la plus récente de gamesDir.
+func latestGameFile() (string, error) {
+	entries, err := os.ReadDir(gamesDir)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), gameFileExt) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("aucune partie dans %s", gamesDir)
+	}
+	// Les noms horodatés des parties exportées se trient dans l'ordre chronologique
+	sort.Strings(names)
+	return filepath.Join(gamesDir, names[len(names)-1]), nil
+}
+
+// replayGameText lit la partie notée text, nommée name, et lance son replay à partir de
+// l'historique, comme pour la dernière partie jouée. La partie affichée est conservée
+// et retrouvée à la fin du replay. Elle renvoie false si la partie est illisible.
+func (g *game) replayGameText(name, text string) bool {
+	game, err := notation.Parse(text)
+	if err != nil {
+		log.Printf("Partie %s illisible : %v\n", name, err)
+		g.exportMessage = "Partie illisible : " + err.Error()
+		return false
+	}
+	b, err := game.Board()
+	if err != nil {
+		g.exportMessage = "Partie illisible : " + err.Error()
+		return false
+	}
+
+	if g.savedGame == nil {
+		g.savedGame = &savedGame{
+			state:     g.gameState,
+			moves:     g.board.Moves(),
+			p1Color:   g.p1Color,
+			p2Color:   g.p2Color,
+			result:    g.result,
+			posWinner: g.posWinner,
+		}
+	}
+
+	for key := range history {
+		delete(history, key)
+	}
+	for i, m := range b.Moves() {
+		id := loadedOpponentID
+		if m.Token == p1Token {
+			id = g.playerID
+		}
+		history[i] = protocol.Coordinate{ID: id, X: m.X, Y: m.Y}
+	}
+
+	g.p1Color = tokenColorTag(game.Get(notation.TagFirstColor), 0)
+	g.p2Color = tokenColorTag(game.Get(notation.TagSecondColor), 1)
+	if g.p2Color == g.p1Color {
+		g.p2Color = (g.p1Color + 1) % globalNumColor
+	}
+	_, g.result, g.posWinner = b.Winner()
+	switch game.Result {
+	case notation.ResultFirst:
+		g.result = p1wins
+	case notation.ResultSecond:
+		g.result = p2wins
+	case notation.ResultDraw:
+		g.result = equality
+	}
+	g.replayTitle = fmt.Sprintf("%s - %s  %s", orDefault(game.Get(notation.TagFirst), "Joueur 1"),
+		orDefault(game.Get(notation.TagSecond), "Joueur 2"), game.Result)
+
+	log.Printf("Replay de la partie %s : %s\n", name, game.Compact())
+	g.exportMessage = ""
+	g.startReplay()
+	g.gameState = replayState
+	return true
+}
+
+// endLoadedReplay retrouve la partie affichée avant le replay d'une partie chargée
+// depuis un fichier, et renvoie l'écran à réafficher.
+func (g *game) endLoadedReplay() int {
+	saved := g.savedGame
+	g.savedGame = nil
+	g.replayTitle = ""
+	for key := range history {
+		delete(history, key)
+	}
+
+	g.board.Reset()
+	if b, err := engine.FromMoves(saved.moves); err == nil {
+		g.board = *b
+	}
+	g.p1Color, g.p2Color = saved.p1Color, saved.p2Color
+	g.result, g.posWinner = saved.result, saved.posWinner
+	return saved.state
+}
+
+// tokenColorTag lit la couleur de pion d'un en-tête, ou renvoie fallback si elle est
+// absente ou hors de la palette.
+func tokenColorTag(value string, fallback int) int {
+	color, err := strconv.Atoi(value)
+	if err != nil || color < 0 || color >= globalNumColor {
+		return fallback
+	}
+	return color
+}
+
+// orDefault renvoie value, ou fallback si value est vide.
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+
+	"puissance4/protocol"
+)
+
+// État d'une proposition de nulle ou d'une demande de reprise pendant la partie.
+const (
+	noOffer       int = iota // Aucune proposition en cours
+	offerSent                // Le joueur attend la réponse de son adversaire
+	offerReceived            // L'adversaire attend la réponse du joueur
+)
+
+// offersUpdate gère pendant une partie en réseau les touches d'abandon (A, à presser
+// deux fois, Échap pour renoncer), de proposition de nulle (D), de demande de reprise
+// (R) et de réponse à la proposition de l'adversaire (O pour accepter, N pour refuser).
+// Le serveur annonce chaque proposition aux deux joueurs, c'est lui qui tient leur état.
+func (g *game) offersUpdate() {
+	if g.client == nil || g.chatIsFocus || !g.serverSupports(protocol.CapabilityOffers) {
+		return
+	}
+
+	var err error
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyA):
+		if !g.resignConfirm {
+			g.resignConfirm = true
+			return
+		}
+		g.resignConfirm = false
+		err = g.client.Resign()
+	case g.resignConfirm && inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.resignConfirm = false
+	case inpututil.IsKeyJustPressed(ebiten.KeyD):
+		err = g.client.OfferDraw()
+	case inpututil.IsKeyJustPressed(ebiten.KeyR):
+		err = g.client.RequestTakeback()
+	case inpututil.IsKeyJustPressed(ebiten.KeyO):
+		err = g.answerOffer(true)
+	case inpututil.IsKeyJustPressed(ebiten.KeyN):
+		err = g.answerOffer(false)
+	}
+	if err != nil {
+		g.errorMessage = "Envoi impossible : " + err.Error()
+	}
+}
+
+// answerOffer répond à la proposition de nulle de l'adversaire, ou à défaut à sa
+// demande de reprise. Elle ne fait rien si l'adversaire n'attend aucune réponse.
+func (g *game) answerOffer(accept bool) error {
+	switch {
+	case g.drawOffer == offerReceived:
+		g.drawOffer = noOffer
+		return g.client.AnswerDraw(accept)
+	case g.takebackRequest == offerReceived:
+		g.takebackRequest = noOffer
+		return g.client.AnswerTakeback(accept)
+	}
+	return nil
+}
+
+// offerState renvoie l'état de la proposition faite par le joueur id, vu de ce client.
+func (g game) offerState(id int) int {
+	if id == g.playerID {
+		return offerSent
+	}
+	return offerReceived
+}
+
+func (g *game) onDrawOffered(offer protocol.OfferPayload) {
+	g.drawOffer = g.offerState(offer.ID)
+}
+
+func (g *game) onDrawDeclined(offer protocol.OfferPayload) {
+	g.drawOffer = noOffer
+	if offer.ID == g.playerID {
+		g.errorMessage = "Votre adversaire refuse la nulle"
+	}
+}
+
+func (g *game) onTakebackRequested(offer protocol.OfferPayload) {
+	g.takebackRequest = g.offerState(offer.ID)
+}
+
+func (g *game) onTakebackDeclined(offer protocol.OfferPayload) {
+	g.takebackRequest = noOffer
+	if offer.ID == g.playerID {
+		g.errorMessage = "Votre adversaire refuse la reprise"
+	}
+}
+
+// onTakeback retire de la grille les coups repris, du plus récent au plus ancien, et
+// rend le trait au joueur qui a demandé la reprise.
+func (g *game) onTakeback(takeback protocol.TakebackPayload) {
+	for range takeback.Moves {
+		if _, err := g.board.Undo(); err != nil {
+			log.Printf("Reprise impossible sur la grille : %v\n", err)
+			break
+		}
+	}
+	g.clearOffers()
+	g.hintFor = 0 // L'indice valait pour une position qui n'existe plus
+	if takeback.CurrentTurn == g.playerID {
+		g.turn = p1Turn
+		g.errorMessage = "Reprise acceptée, rejouez votre coup"
+	} else {
+		g.turn = p2Turn
+		g.errorMessage = "Votre adversaire reprend son coup"
+	}
+	log.Printf("%d coup(s) repris, votre tour : %v\n", len(takeback.Moves), g.turn == p1Turn)
+}
+
+// clearOffers oublie les propositions en attente, qui tombent dès qu'un coup est joué.
+func (g *game) clearOffers() {
+	g.resignConfirm = false
+	g.drawOffer = noOffer
+	g.takebackRequest = noOffer
+}
+
+// offerMessage renvoie le message affiché sous la grille pendant une confirmation
+// d'abandon ou une proposition en attente, et une chaîne vide sinon.
+func (g game) offerMessage() string {
+	switch {
+	case g.resignConfirm:
+		return "Appuyez de nouveau sur A pour abandonner, Échap pour continuer"
+	case g.drawOffer == offerReceived:
+		return "Votre adversaire propose la nulle : O pour accepter, N pour refuser"
+	case g.takebackRequest == offerReceived:
+		return "Votre adversaire demande à reprendre son coup : O pour accepter, N pour refuser"
+	case g.drawOffer == offerSent:
+		return "Nulle proposée, en attente de la réponse de votre adversaire"
+	case g.takebackRequest == offerSent:
+		return "Reprise demandée, en attente de la réponse de votre adversaire"
+	}
+	return ""
+}
+
+// drawOfferKeys rappelle en bas de l'écran de jeu les touches d'abandon, de nulle et
+// de reprise, sur un serveur qui les accepte.
+func (g game) drawOfferKeys(screen *ebiten.Image) {
+	if g.client == nil || !g.serverSupports(protocol.CapabilityOffers) {
+		return
+	}
+	keys := "A : abandonner   D : proposer la nulle   R : reprendre son coup"
+	if g.drawOffer == offerReceived || g.takebackRequest == offerReceived {
+		keys = "O : accepter   N : refuser"
+	}
+	keysWidth, _ := getTextDimensions(keys, mediumFontError)
+	text.Draw(screen, keys, mediumFontError, (globalWidth-keysWidth)/2, globalHeight-30, globalTextColorBright)
+}
+
+// resultMessage renvoie le résultat de la dernière partie affiché à l'écran des résultats.
+func (g game) resultMessage() string {
+	switch {
+	case g.result == p1wins && g.gameOverReason == protocol.ReasonTimeout:
+		return "Vous avez Gagne au temps !"
+	case g.result == p2wins && g.gameOverReason == protocol.ReasonTimeout:
+		return "Vous avez Perdu au temps"
+	case g.result == p1wins && g.gameOverReason == protocol.ReasonResign:
+		return "Vous avez Gagne par abandon !"
+	case g.result == p2wins && g.gameOverReason == protocol.ReasonResign:
+		return "Vous avez Abandonne"
+	case g.result == p1wins:
+		return "Vous avez Gagne !"
+	case g.result == p2wins:
+		return "Vous avez Perdu"
+	case g.gameOverReason == protocol.ReasonAgreement:
+		return "Nulle par accord"
+	}
+	return "Il y a Egalite"
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/protocol"
+)
+
+// replaySpeeds sont les délais entre deux coups du replay, du plus lent au plus rapide.
+var replaySpeeds = []time.Duration{
+	1200 * time.Millisecond,
+	700 * time.Millisecond,
+	350 * time.Millisecond,
+	150 * time.Millisecond,
+	50 * time.Millisecond,
+}
+
+// defaultReplaySpeed est l'indice dans replaySpeeds de la vitesse de départ du replay.
+const defaultReplaySpeed = 2
+
+// Dimensions du panneau des coups, à gauche de la grille, et de la barre de progression.
+const (
+	replayListWidth     = 240 // Largeur du panneau des coups
+	replayListMargin    = 30  // Espace entre le panneau et la grille
+	replayListRowHeight = 26  // Hauteur d'une ligne du panneau (une paire de coups)
+	replayBarHeight     = 12  // Hauteur de la barre de progression
+	replayBarMargin     = 25  // Espace entre la grille et la barre de progression
+)
+
+// replayViewer est l'état du replay de la dernière partie ou d'une partie chargée.
+// L'historique n'est jamais consommé : la grille est reconstruite à partir des
+// shown premiers coups, ce qui permet de revenir en arrière et de revoir la partie.
+type replayViewer struct {
+	moves     []protocol.Coordinate // Coups de l'historique, dans l'ordre
+	shown     int                   // Nombre de coups posés sur la grille
+	playing   bool                  // Lecture automatique en cours
+	speed     int                   // Indice de la vitesse dans replaySpeeds
+	lastStep  time.Time             // Moment du dernier coup posé en lecture automatique
+	jumpInput string                // Numéro de coup en cours de saisie
+	scrubbing bool                  // Glissement en cours sur la barre de progression
+	analyse   bool                  // Analyse des coups affichée
+}
+
+// startReplay prépare le replay de l'historique, depuis la grille vide.
+func (g *game) startReplay() {
+	g.viewer = replayViewer{
+		playing:  true,
+		speed:    defaultReplaySpeed,
+		lastStep: time.Now(),
+	}
+	g.resetGrid()
+	g.blinking = false
+	g.syncReplayMoves()
+}
+
+// syncReplayMoves recopie l'historique dans le replay s'il a changé : celui du serveur
+// arrive après l'entrée dans l'écran de replay.
+func (g *game) syncReplayMoves() {
+	if len(history) == len(g.viewer.moves) {
+		return
+	}
+	turns := make([]int, 0, len(history))
+	for turn := range history {
+		turns = append(turns, turn)
+	}
+	sort.Ints(turns)
+	g.viewer.moves = g.viewer.moves[:0]
+	for _, turn := range turns {
+		g.viewer.moves = append(g.viewer.moves, history[turn])
+	}
+	g.showReplayMove(g.viewer.shown)
+}
+
+// showReplayMove affiche la grille après les n premiers coups. L'alignement gagnant
+// ne clignote qu'au dernier coup.
+func (g *game) showReplayMove(n int) {
+	n = max(0, min(n, len(g.viewer.moves)))
+	g.viewer.shown = n
+	g.resetGrid()
+	for _, m := range g.viewer.moves[:n] {
+		g.updateGridExtend(m.ID, m.X, m.Y)
+	}
+	g.blinking = n == len(g.viewer.moves) && n > 0 && g.posWinner != nil
+}
+
+// replayControlsUpdate gère les commandes du replay : lecture et pause, coup par coup,
+// saut à un coup, vitesse, barre de progression et panneau des coups.
+func (g *game) replayControlsUpdate() {
+	g.syncReplayMoves()
+	v := &g.viewer
+	total := len(v.moves)
+
+	if !g.chatIsFocus {
+		switch {
+		case inpututil.IsKeyJustPressed(ebiten.KeyP):
+			v.playing = !v.playing
+			if v.playing && v.shown == total {
+				g.showReplayMove(0) // Revoir la partie depuis le début
+			}
+			v.lastStep = time.Now()
+		case inpututil.IsKeyJustPressed(ebiten.KeyRight):
+			v.playing = false
+			g.showReplayMove(v.shown + 1)
+		case inpututil.IsKeyJustPressed(ebiten.KeyLeft):
+			v.playing = false
+			g.showReplayMove(v.shown - 1)
+		case inpututil.IsKeyJustPressed(ebiten.KeyHome):
+			v.playing = false
+			g.showReplayMove(0)
+		case inpututil.IsKeyJustPressed(ebiten.KeyEnd):
+			v.playing = false
+			g.showReplayMove(total)
+		case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+			v.speed = min(v.speed+1, len(replaySpeeds)-1)
+		case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+			v.speed = max(v.speed-1, 0)
+		case inpututil.IsKeyJustPressed(ebiten.KeyA):
+			v.analyse = !v.analyse
+		case inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && v.jumpInput != "":
+			v.jumpInput = v.jumpInput[:len(v.jumpInput)-1]
+		case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter):
+			if n, err := strconv.Atoi(v.jumpInput); err == nil {
+				v.playing = false
+				g.showReplayMove(n)
+			}
+			v.jumpInput = ""
+		}
+		// Saisie du numéro de coup auquel sauter
+		for _, r := range ebiten.AppendInputChars(nil) {
+			if r >= '0' && r <= '9' && len(v.jumpInput) < 3 {
+				v.jumpInput += string(r)
+			}
+		}
+	}
+
+	g.replayMouseUpdate()
+	g.updateAnalysis()
+
+	if v.playing && time.Since(v.lastStep) >= replaySpeeds[v.speed] {
+		v.lastStep = time.Now()
+		if v.shown < total {
+			g.showReplayMove(v.shown + 1)
+		}
+		if v.shown == total && total > 0 {
+			v.playing = false
+		}
+	}
+}
+
+// replayMouseUpdate gère les clics sur la barre de progression et sur le panneau des coups.
+func (g *game) replayMouseUpdate() {
+	v := &g.viewer
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		v.scrubbing = false
+		return
+	}
+	if g.chatIsFocus {
+		return
+	}
+	x, y := ebiten.CursorPosition()
+
+	barX, barY, barWidth := replayBarBounds()
+	onBar := x >= barX && x <= barX+barWidth && y >= barY-8 && y <= barY+replayBarHeight+8
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && onBar {
+		v.scrubbing = true
+	}
+	if v.scrubbing {
+		v.playing = false
+		ratio := float64(x-barX) / float64(barWidth)
+		g.showReplayMove(int(ratio*float64(len(v.moves)) + 0.5))
+		return
+	}
+
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	listX, listY, rows := replayListBounds()
+	if x < listX || x > listX+replayListWidth || y < listY {
+		return
+	}
+	row := (y-listY)/replayListRowHeight + g.replayFirstRow(rows)
+	if row >= (len(v.moves)+1)/2 {
+		return
+	}
+	// Le coup du second joueur occupe la moitié droite de la ligne
+	move := row*2 + 1
+	if x >= listX+replayListWidth/2 && move < len(v.moves) {
+		move++
+	}
+	v.playing = false
+	g.showReplayMove(move)
+}
+
+// replayGridBounds renvoie la position et les dimensions de la grille affichée.
+func replayGridBounds() (startX, startY, gridWidth, gridHeight int) {
+	gridWidth = globalTileSize * globalNumTilesX
+	gridHeight = globalTileSize * globalNumTilesY
+	startX = (globalWidth - gridWidth) / 2
+	startY = (globalHeight-gridHeight)/2 + 50
+	return startX, startY, gridWidth, gridHeight
+}
+
+// replayBarBounds renvoie la position et la largeur de la barre de progression, sous la grille.
+func replayBarBounds() (x, y, width int) {
+	startX, startY, gridWidth, gridHeight := replayGridBounds()
+	return startX, startY + gridHeight + replayBarMargin, gridWidth
+}
+
+// replayListBounds renvoie la position du panneau des coups et son nombre de lignes visibles.
+func replayListBounds() (x, y, rows int) {
+	startX, startY, _, gridHeight := replayGridBounds()
+	return startX - replayListMargin - replayListWidth, startY, gridHeight / replayListRowHeight
+}
+
+// replayFirstRow renvoie la première ligne affichée du panneau des coups, pour que le
+// coup courant reste visible dans une longue partie.
+func (g game) replayFirstRow(rows int) int {
+	current := max(g.viewer.shown-1, 0) / 2
+	total := (len(g.viewer.moves) + 1) / 2
+	return max(0, min(current-rows/2, total-rows))
+}
+
+// drawReplayControls dessine le panneau des coups, la barre de progression et l'état du replay.
+func (g game) drawReplayControls(screen *ebiten.Image) {
+	v := g.viewer
+	total := len(v.moves)
+
+	// Panneau des coups : une ligne par paire, le coup courant en surbrillance
+	listX, listY, rows := replayListBounds()
+	first := g.replayFirstRow(rows)
+	for row := first; row < first+rows && row*2 < total; row++ {
+		y := listY + (row-first)*replayListRowHeight
+		text.Draw(screen, fmt.Sprintf("%d.", row+1), mediumFontError, listX, y+18, globalTextColorBright)
+		for i := row * 2; i < row*2+2 && i < total; i++ {
+			x := listX + 50 + (i%2)*(replayListWidth/2-20)
+			if i == v.shown-1 {
+				vector.DrawFilledRect(screen, float32(x-6), float32(y), float32(replayListWidth/2-30), replayListRowHeight-2, globalTextColor, true)
+			}
+			label, labelColor := strconv.Itoa(v.moves[i].X+1), globalTextColorBright
+			if v.moves[i].Hint {
+				label += "*" // Coup joué après un indice
+			}
+			if v.analyse && g.moveAnalysis != nil {
+				if m := g.moveAnalysis.move(i); m != nil {
+					label, labelColor = label+m.Verdict.Symbol(), verdictColor(m)
+				}
+			}
+			vector.DrawFilledCircle(screen, float32(x+4), float32(y+12), 7, g.replayTokenColor(v.moves[i].ID), true)
+			text.Draw(screen, label, mediumFontError, x+20, y+18, labelColor)
+		}
+	}
+
+	// Barre de progression
+	barX, barY, barWidth := replayBarBounds()
+	vector.DrawFilledRect(screen, float32(barX), float32(barY), float32(barWidth), replayBarHeight, globalTextColor, true)
+	if total > 0 {
+		done := float32(barWidth) * float32(v.shown) / float32(total)
+		vector.DrawFilledRect(screen, float32(barX), float32(barY), done, replayBarHeight, globalTextColorYellow, true)
+		vector.DrawFilledCircle(screen, float32(barX)+done, float32(barY+replayBarHeight/2), replayBarHeight, globalTextColorBright, true)
+	}
+
+	// État et commandes
+	state := "Pause"
+	if v.playing {
+		state = "Lecture"
+	}
+	status := fmt.Sprintf("Coup %d/%d  -  %s  -  vitesse %d/%d", v.shown, total, state, v.speed+1, len(replaySpeeds))
+	if v.shown > 0 && v.moves[v.shown-1].Hint {
+		status += "  -  joué avec un indice"
+	}
+	if v.jumpInput != "" {
+		status += "  -  aller au coup " + v.jumpInput
+	}
+	statusWidth, _ := getTextDimensions(status, mediumFontError)
+	text.Draw(screen, status, mediumFontError, (globalWidth-statusWidth)/2, barY+replayBarHeight+30, globalTextColorBright)
+
+	// L'analyse des coups remplace l'aide des commandes
+	if v.analyse {
+		g.drawAnalysis(screen, barY+replayBarHeight+55)
+		return
+	}
+	help := "P : lecture/pause   Gauche/Droite : coup par coup   Haut/Bas : vitesse   N + Entrée : aller au coup N   A : analyse"
+	helpWidth, _ := getTextDimensions(help, smallFont)
+	text.Draw(screen, help, smallFont, (globalWidth-helpWidth)/2, barY+replayBarHeight+55, globalTextColorBright)
+}
+
+// replayTokenColor renvoie la couleur du pion du joueur id dans le replay.
+func (g game) replayTokenColor(id int) color.Color {
+	if id == g.playerID {
+		return globalTokenColors[g.p1Color]
+	}
+	return globalTokenColors[g.p2Color]
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// tlsScheme est le préfixe de l'adresse du serveur qui active TLS.
+const tlsScheme = "tls://"
+
+// dialTimeout est la durée maximale d'une tentative de connexion au serveur.
+const dialTimeout = 10 * time.Second
+
+var (
+	tlsInsecure bool   // Accepte un certificat non vérifié (serveur lancé avec -tls-dev)
+	tlsCAFile   string // Certificat (PEM) à reconnaître en plus des autorités du système
+)
+
+// parseServerAddress découpe l'adresse saisie par le joueur, de la forme
+// [tls://][motdepasse@]hôte[:port], en adresse réseau, mot de passe du serveur et usage de TLS.
+func parseServerAddress(input string) (address, password string, useTLS bool) {
+	address = strings.TrimSpace(input)
+	if strings.HasPrefix(strings.ToLower(address), tlsScheme) {
+		useTLS = true
+		address = address[len(tlsScheme):]
+	}
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		password = address[:i]
+		address = address[i+1:]
+	}
+	return address, password, useTLS
+}
+
+// maskServerAddress renvoie l'adresse saisie en remplaçant le mot de passe par des étoiles.
+func maskServerAddress(input string) string {
+	i := strings.LastIndex(input, "@")
+	if i < 0 {
+		return input
+	}
+	start := 0
+	if strings.HasPrefix(strings.ToLower(input), tlsScheme) {
+		start = len(tlsScheme)
+	}
+	if i < start {
+		return input
+	}
+	return input[:start] + strings.Repeat("*", len([]rune(input[start:i]))) + input[i:]
+}
+
+// dialServer ouvre une connexion vers address, chiffrée si useTLS est vrai.
+func dialServer(address string, useTLS bool) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if !useTLS {
+		return dialer.Dial("tcp", address)
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: tlsInsecure,
+	}
+	if tlsCAFile != "" {
+		pem, err := os.ReadFile(tlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("lecture du certificat %s : %w", tlsCAFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("aucun certificat valide dans %s", tlsCAFile)
+		}
+		config.RootCAs = pool
+	}
+	return tls.DialWithDialer(dialer, "tcp", address, config)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"puissance4/protocol"
+)
+
+// reconnectInterval est le délai entre deux tentatives de reconnexion au serveur.
+const reconnectInterval = 2 * time.Second
+
+// canResume indique si la connexion perdue peut être reprise : le joueur doit avoir
+// reçu un jeton de session et être assis dans une salle.
+func (g *game) canResume() bool {
+	if g.sessionToken == "" || g.roomID == -1 {
+		return false
+	}
+	switch g.gameState {
+	case waitingState, colorSelectState, waitingColorSelect, shifumiState, playState, resultState, replayState:
+		return true
+	}
+	return false
+}
+
+// reconnect tente de se reconnecter au serveur jusqu'à la fin du délai de grâce annoncé
+// par le serveur. Une fois connecté, le client réseau envoie le jeton de reprise dès la poignée de main.
+// Les tentatives ont lieu dans une goroutine, qui confie leur résultat à la boucle du jeu.
+func (g *game) reconnect() {
+	g.reconnecting = true
+	deadline := time.Now().Add(time.Duration(g.sessionGrace) * time.Second)
+	log.Printf("Connexion perdue, tentative de reprise de la session jusqu'à %s\n", deadline.Format("15:04:05"))
+
+	address, useTLS := g.serverAddress, g.useTLS
+	go func() {
+		for time.Now().Before(deadline) {
+			conn, err := dialServer(address, useTLS)
+			if err == nil {
+				g.postNetworkEvent(func() { g.startClient(conn, g.sessionToken) })
+				return
+			}
+			log.Printf("Reconnexion impossible : %v\n", err)
+			time.Sleep(reconnectInterval)
+		}
+		g.postNetworkEvent(g.reconnectFailed)
+	}()
+}
+
+// reconnectFailed renvoie à la saisie de l'adresse un joueur qui n'a pas pu reprendre
+// sa session avant la fin du délai de grâce.
+func (g *game) reconnectFailed() {
+	g.reconnecting = false
+	g.sessionToken = ""
+	g.connectionMessage = "Erreur de communication. Entrez une nouvelle adresse."
+	g.gameState = inputServerState
+}
+
+// restoreSession remet le jeu exactement dans l'état décrit par le serveur après une reprise :
+// grille, tour, couleurs, scores et derniers messages du chat.
+func (g *game) restoreSession(state protocol.ResumePayload) {
+	g.reconnecting = false
+	g.playerID = state.ID
+	g.sessionToken = state.Token
+	g.roomID = state.RoomID
+	g.roomName = state.RoomName
+	g.errorMessage = ""
+	g.isReset = false
+	g.clock, g.clockReceived = state.Clock, time.Now()
+
+	if state.RoomID == -1 {
+		g.gameState = lobbyState
+		sendListRooms(g.client)
+		return
+	}
+
+	// Rejouer les coups de la partie en cours
+	g.board.Reset()
+	for _, coord := range state.Moves {
+		if !g.updateGridExtend(coord.ID, coord.X, coord.Y) {
+			log.Printf("Coup (%d, %d) du joueur %d impossible à rétablir\n", coord.X, coord.Y, coord.ID)
+		}
+	}
+
+	if state.YourColor >= 0 {
+		g.p1Color = state.YourColor
+		g.p1ColorValidate = state.YourColor
+	}
+	if state.OpponentColor >= 0 {
+		g.p2Color = state.OpponentColor
+	}
+	g.turn = p2Turn
+	if state.YourTurn {
+		g.turn = p1Turn
+	}
+	g.firstPlayer = p2Turn
+	if state.FirstPlayer == state.ID {
+		g.firstPlayer = p1Turn
+	}
+	g.nbPartieWin = state.YourWins
+	g.nbPartieAdversaireWin = state.OpponentWins
+
+	g.chatMessages = []string{}
+	for _, entry := range state.Chat {
+		if entry.ID == state.ID {
+			g.addChatMessage(entry.Text, "You:")
+		} else {
+			g.addChatMessage(entry.Text, "Other:")
+		}
+	}
+
+	g.serverReady = state.Phase != protocol.PhaseWaiting
+	g.gameReady = g.serverReady
+	g.nbJoueurConnecte = 1
+	if g.serverReady {
+		g.nbJoueurConnecte = 2
+	}
+	g.restartOk = true
+	g.posWinner = nil
+
+	switch state.Phase {
+	case protocol.PhaseWaiting:
+		g.gameState = waitingState
+	case protocol.PhaseColor:
+		g.gameState = colorSelectState
+		if state.YourColor >= 0 {
+			g.gameState = waitingColorSelect
+		}
+	case protocol.PhaseShifumi:
+		g.selected = ""
+		g.gameState = shifumiState
+	case protocol.PhasePlaying:
+		g.gameState = playState
+	case protocol.PhaseOver:
+		g.result = equality
+		if last, ok := g.board.LastMove(); ok {
+			_, g.result, g.posWinner = g.checkGameEnd(last.X, last.Y)
+		}
+		g.restartOk = false
+		g.gameState = resultState
+	}
+	g.stateFrame = 0
+	log.Printf("Session reprise dans la salle %d (%s), étape %s\n", g.roomID, g.roomName, state.Phase)
+}
+
+// opponentReconnectMessage renvoie le compte à rebours affiché pendant que l'adversaire
+// tente de se reconnecter, ou une chaîne vide.
+func (g game) opponentReconnectMessage() string {
+	if g.opponentReconnectDeadline.IsZero() {
+		return ""
+	}
+	remaining := int(time.Until(g.opponentReconnectDeadline).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return fmt.Sprintf("En attente de la reconnexion de l'adversaire : %ds", remaining)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// Mise à jour de l'état du jeu en mode spectateur : la partie est en lecture seule,
+// seuls la touche Echap et le bouton du menu du haut permettent de revenir au lobby.
+func (g *game) spectatorUpdate() {
+	if g.chatIsFocus || g.client == nil {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		sendLeaveRoom(g.client)
+	}
+
+	mouseX, mouseY := ebiten.CursorPosition()
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && g.mouseReleased {
+		textWidth, _ := getTextDimensions("QUITTER", firstTitleMinusFont)
+		smallRectWidth := textWidth + 60*2
+		smallRectX, smallRectY := centerPosition(smallRectWidth, largeRectHeight, globalWidth, largeRectHeight)
+		if mouseX >= smallRectX && mouseX <= smallRectX+smallRectWidth &&
+			mouseY >= smallRectY && mouseY <= smallRectY+largeRectHeight {
+			g.mouseReleased = false
+			sendLeaveRoom(g.client)
+		}
+	}
+}
+
+// spectatorMove rejoue sur la grille le coup d'un des joueurs de la partie observée.
+func (g *game) spectatorMove(move protocol.MovePayload) {
+	if _, err := g.board.Play(move.Token, move.X); err != nil {
+		// La grille locale n'est plus synchronisée : redemander l'état complet
+		log.Printf("Coup du joueur %d impossible à rejouer : %v\n", move.ID, err)
+		sendSpectate(g.client, g.roomID, g.roomPassword)
+		return
+	}
+	g.spectateTurn = g.spectateOpponent(move.ID)
+}
+
+// spectatorTokenPosition déplace le pion au-dessus de la grille du joueur qui l'a bougé.
+func (g *game) spectatorTokenPosition(update protocol.TokenUpdatePayload) {
+	switch update.Token {
+	case p1Token:
+		g.tokenPosition = update.Position
+	case p2Token:
+		g.adversaryTokenPosition = update.Position
+	}
+}
+
+// spectatorGameOver affiche le résultat de la partie observée.
+func (g *game) spectatorGameOver(over protocol.GameOverPayload) {
+	g.spectateGameOver = true
+	g.spectateTurn = -1
+	g.result = equality
+	if over.Winner != -1 {
+		g.result = g.spectateToken(over.Winner)
+	}
+	if last, ok := g.board.LastMove(); ok {
+		_, _, g.posWinner = g.board.CheckEnd(last.X, last.Y)
+	}
+	g.blinking = g.posWinner != nil
+}
+
+// applySpectateState reconstruit la partie observée à partir de l'état complet envoyé par le serveur.
+// Le joueur qui a le pion engine.P1Token est affiché avec p1Color, son adversaire avec p2Color.
+func (g *game) applySpectateState(state protocol.SpectatePayload) {
+	board, err := engine.FromMoves(state.Moves)
+	if err != nil {
+		log.Printf("Historique de la salle %d invalide : %v\n", state.RoomID, err)
+		return
+	}
+
+	g.board = *board
+	g.roomID = state.RoomID
+	g.roomName = state.RoomName
+	g.spectatePlayers = state.Players
+	g.spectateTurn = state.CurrentTurn
+	g.spectateGameOver = state.GameOver
+	g.result = equality
+	g.posWinner = nil
+	g.blinking = false
+
+	for _, p := range state.Players {
+		switch p.Token {
+		case p1Token:
+			g.p1Color = max(p.Color, 0)
+		case p2Token:
+			g.p2Color = max(p.Color, 0)
+		}
+	}
+
+	if last, ok := g.board.LastMove(); ok && state.GameOver {
+		_, g.result, g.posWinner = g.board.CheckEnd(last.X, last.Y)
+		g.blinking = g.posWinner != nil
+	}
+
+	if g.gameState != spectatorState {
+		g.stateFrame = 0
+		g.gameState = spectatorState
+		log.Printf("Observation de la salle %d (%s)\n", g.roomID, g.roomName)
+	}
+}
+
+// spectateToken renvoie le pion du joueur id dans la partie observée.
+func (g *game) spectateToken(id int) int {
+	for _, p := range g.spectatePlayers {
+		if p.ID == id {
+			return p.Token
+		}
+	}
+	return noToken
+}
+
+// spectateOpponent renvoie l'ID de l'adversaire du joueur id dans la partie observée, ou -1.
+func (g *game) spectateOpponent(id int) int {
+	for _, p := range g.spectatePlayers {
+		if p.ID != id {
+			return p.ID
+		}
+	}
+	return -1
+}
+
+// spectatePlayer renvoie le joueur de la partie observée qui a le pion token.
+func (g game) spectatePlayer(token int) (protocol.PlayerInfo, bool) {
+	for _, p := range g.spectatePlayers {
+		if p.Token == token {
+			return p, true
+		}
+	}
+	return protocol.PlayerInfo{}, false
+}
+
+// Affichage de la partie observée : la grille, le pion au-dessus de la grille du joueur
+// dont c'est le tour, le nom et la couleur des deux joueurs et le résultat.
+func (g game) spectatorDraw(screen *ebiten.Image) {
+	g.topMenuButton(screen, "QUITTER")
+
+	gridWidth := globalTileSize * globalNumTilesX
+	gridHeight := globalTileSize * globalNumTilesY
+	startX := (globalWidth - gridWidth) / 2
+	startY := (globalHeight-gridHeight)/2 + 50
+
+	// Le contour de la grille prend la couleur du joueur dont c'est le tour
+	turnToken := g.spectateToken(g.spectateTurn)
+	borderColor := globalTextColorBright
+	switch turnToken {
+	case p1Token:
+		borderColor = globalTokenColors[g.p1Color]
+	case p2Token:
+		borderColor = globalTokenColors[g.p2Color]
+	}
+	drawRoundedRectangle(screen, float32(startX)-7.5, float32(startY)-7.5, float32(gridWidth)+15, float32(gridHeight)+15, 25, borderColor)
+
+	g.drawGrid(screen)
+
+	// Pion du joueur dont c'est le tour, au-dessus de la grille
+	if turnToken != noToken && !g.spectateGameOver {
+		position, tokenColor := g.tokenPosition, g.p1Color
+		if turnToken == p2Token {
+			position, tokenColor = g.adversaryTokenPosition, g.p2Color
+		}
+		vector.DrawFilledCircle(
+			screen,
+			float32(startX+globalTileSize/2+position*globalTileSize),
+			float32(startY-globalTileSize/2)-20,
+			float32(globalTileSize/2-globalCircleMargin),
+			globalTokenColors[tokenColor],
+			true,
+		)
+	}
+
+	// Noms et couleurs des deux joueurs de part et d'autre de la grille
+	for _, token := range []int{p1Token, p2Token} {
+		p, ok := g.spectatePlayer(token)
+		if !ok {
+			continue
+		}
+		tokenColor, x := g.p1Color, startX/2
+		if token == p2Token {
+			tokenColor, x = g.p2Color, startX+gridWidth+startX/2
+		}
+		width, _ := getTextDimensions(p.Name, smallFont)
+		vector.DrawFilledCircle(screen, float32(x), float32(globalHeight/2), float32(globalTileSize/2-globalCircleMargin), globalTokenColors[tokenColor], true)
+		text.Draw(screen, p.Name, smallFont, x-width/2, globalHeight/2+globalTileSize, globalTextColorBright)
+	}
+
+	// Message d'état de la partie
+	message := fmt.Sprintf("%s - Echap pour revenir au lobby", g.roomName)
+	switch {
+	case len(g.spectatePlayers) < 2:
+		message = "En attente des joueurs"
+	case g.spectateGameOver && g.result == equality:
+		message = "Egalite"
+	case g.spectateGameOver:
+		if p, ok := g.spectatePlayer(g.result); ok {
+			message = p.Name + " a gagne !"
+		}
+	case turnToken == noToken:
+		message = "Partie en preparation"
+	}
+	width, _ := getTextDimensions(message, smallFont)
+	text.Draw(screen, message, smallFont, (globalWidth-width)/2, startY-globalTileSize-30, globalTextColorYellow)
+
+	if g.blinking {
+		g.drawWinningCells(screen)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/protocol"
+)
+
+// leaderboardSize est le nombre de joueurs affichés dans chaque classement.
+const leaderboardSize = 10
+
+// Classements affichés par l'écran des statistiques, dans l'ordre des onglets.
+const (
+	ratingsBoard = iota
+	streaksBoard
+	mostGamesBoard
+	boardCount
+)
+
+// boardTitles sont les titres des onglets de l'écran des statistiques.
+var boardTitles = [boardCount]string{"Cote", "Séries de victoires", "Parties jouées"}
+
+// openStats affiche l'écran des classements et demande au serveur les classements, ainsi
+// que les statistiques du joueur s'il est connecté à un compte.
+func (g *game) openStats() {
+	if !g.serverSupports(protocol.CapabilityStats) {
+		g.errorMessage = "Ce serveur ne tient pas de classement"
+		g.gameState = lobbyState
+		return
+	}
+	g.errorMessage = ""
+	g.selectedEntry = 0
+	g.gameState = statsState
+	if err := g.client.Leaderboard(leaderboardSize); err != nil {
+		log.Printf("Erreur lors de la demande des classements : %v\n", err)
+	}
+	if g.accountName != "" {
+		if err := g.client.PlayerStats(""); err != nil {
+			log.Printf("Erreur lors de la demande des statistiques : %v\n", err)
+		}
+	}
+}
+
+// closeStats revient au lobby.
+func (g *game) closeStats() {
+	g.errorMessage = ""
+	g.gameState = lobbyState
+	sendListRooms(g.client)
+}
+
+func (g *game) onLeaderboard(board protocol.LeaderboardPayload) {
+	g.leaderboard = &board
+	g.selectedEntry = min(g.selectedEntry, max(len(g.boardEntries())-1, 0))
+}
+
+func (g *game) onPlayerStats(profile protocol.PlayerProfile) {
+	g.profile = &profile
+}
+
+// boardEntries renvoie les lignes du classement de l'onglet affiché.
+func (g game) boardEntries() []protocol.LeaderboardEntry {
+	if g.leaderboard == nil {
+		return nil
+	}
+	switch g.statsBoard {
+	case streaksBoard:
+		return g.leaderboard.Streaks
+	case mostGamesBoard:
+		return g.leaderboard.MostGames
+	}
+	return g.leaderboard.Ratings
+}
+
+// statsUpdate gère l'écran des statistiques : Gauche/Droite changent de classement,
+// Haut/Bas choisissent un joueur, Entrée affiche ses statistiques, R actualise et
+// Échap revient au lobby.
+func (g *game) statsUpdate() {
+	if g.chatIsFocus || g.client == nil {
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+		g.statsBoard = (g.statsBoard + 1) % boardCount
+		g.selectedEntry = 0
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+		g.statsBoard = (g.statsBoard - 1 + boardCount) % boardCount
+		g.selectedEntry = 0
+	}
+
+	entries := g.boardEntries()
+	if len(entries) > 0 {
+		if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+			g.selectedEntry = (g.selectedEntry + 1) % len(entries)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+			g.selectedEntry = (g.selectedEntry - 1 + len(entries)) % len(entries)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+			g.errorMessage = ""
+			if err := g.client.PlayerStats(entries[g.selectedEntry].Name); err != nil {
+				log.Printf("Erreur lors de la demande des statistiques : %v\n", err)
+			}
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		g.openStats()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.closeStats()
+		return
+	}
+
+	// Le bouton du menu du haut revient au lobby
+	mouseX, mouseY := ebiten.CursorPosition()
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && g.mouseReleased {
+		textWidth, _ := getTextDimensions("RETOUR", firstTitleMinusFont)
+		smallRectWidth := textWidth + 60*2
+		smallRectX, smallRectY := centerPosition(smallRectWidth, largeRectHeight, globalWidth, largeRectHeight)
+		if mouseX >= smallRectX && mouseX <= smallRectX+smallRectWidth &&
+			mouseY >= smallRectY && mouseY <= smallRectY+largeRectHeight {
+			g.mouseReleased = false
+			g.closeStats()
+		}
+	}
+}
+
+// entryLine renvoie la ligne d'un joueur dans le classement affiché.
+func (g game) entryLine(entry protocol.LeaderboardEntry) string {
+	switch g.statsBoard {
+	case streaksBoard:
+		return fmt.Sprintf("%2d. %s  %d victoire(s) d'affilée", entry.Rank, entry.Name, entry.Value)
+	case mostGamesBoard:
+		return fmt.Sprintf("%2d. %s  %d partie(s)", entry.Rank, entry.Name, entry.Value)
+	}
+	line := fmt.Sprintf("%2d. %s  %d", entry.Rank, entry.Name, entry.Value)
+	if entry.Provisional {
+		line += " (provisoire)"
+	}
+	return line
+}
+
+// profileLines renvoie les lignes du panneau des statistiques du joueur affiché.
+func profileLines(p protocol.PlayerProfile) []string {
+	lines := []string{
+		p.Name,
+		fmt.Sprintf("Cote %d ± %d (%d partie(s) classée(s))", p.Rating.Rating, p.Rating.Deviation, p.Rating.Games),
+		fmt.Sprintf("%d partie(s) : %d V / %d D / %d N", p.Games, p.Wins, p.Losses, p.Draws),
+		fmt.Sprintf("En premier : %d V / %d D / %d N", p.First.Wins, p.First.Losses, p.First.Draws),
+		fmt.Sprintf("En second : %d V / %d D / %d N", p.Second.Wins, p.Second.Losses, p.Second.Draws),
+	}
+	if p.Games > 0 {
+		lines = append(lines, fmt.Sprintf("Partie moyenne : %.1f coups, %d s", p.AverageMoves, p.AverageSeconds))
+	}
+	if p.FavouriteOpening >= 0 {
+		lines = append(lines, fmt.Sprintf("Ouverture préférée : colonne %d", p.FavouriteOpening+1))
+	}
+	lines = append(lines,
+		fmt.Sprintf("Pierre/feuille/ciseaux : %d gagné(s), %d perdu(s)", p.ShifumiWins, p.ShifumiLosses),
+		fmt.Sprintf("Série en cours : %d, record : %d", p.Streak, p.BestStreak),
+	)
+	if !p.LastGame.IsZero() {
+		lines = append(lines, "Dernière partie le "+p.LastGame.Local().Format("02/01/2006 à 15:04"))
+	}
+	return lines
+}
+
+// Affichage de l'écran des statistiques : le classement choisi à gauche, les
+// statistiques du joueur demandé à droite.
+func (g game) statsDraw(screen *ebiten.Image) {
+	g.topMenuButton(screen, "RETOUR")
+
+	title := "Classements"
+	titleWidth, titleHeight := getTextDimensions(title, firstTitleSmallerFont)
+	titleY := globalHeight/6 + titleHeight
+	text.Draw(screen, title, firstTitleSmallerFont, (globalWidth-titleWidth)/2, titleY, globalTextColorYellow)
+
+	// Onglets des classements
+	tabs := ""
+	for i, name := range boardTitles {
+		if i == g.statsBoard {
+			name = "[" + name + "]"
+		}
+		if i > 0 {
+			tabs += "   "
+		}
+		tabs += name
+	}
+	tabsWidth, _ := getTextDimensions(tabs, smallFont)
+	text.Draw(screen, tabs, smallFont, (globalWidth-tabsWidth)/2, titleY+60, globalTextColorBright)
+
+	listX := globalWidth / 8
+	lineY := titleY + 130
+	entries := g.boardEntries()
+	switch {
+	case g.leaderboard == nil:
+		text.Draw(screen, "Chargement...", smallFont, listX, lineY, globalTextColorBright)
+	case len(entries) == 0:
+		text.Draw(screen, "Aucun joueur classé pour l'instant", smallFont, listX, lineY, globalTextColorBright)
+	}
+	for i, entry := range entries {
+		line := g.entryLine(entry)
+		width, height := getTextDimensions(line, smallFont)
+		textColor := globalTextColorBright
+		if i == g.selectedEntry {
+			vector.DrawFilledRect(screen, float32(listX-20), float32(lineY-height+10), float32(width+40), float32(height), globalTextColorGreen, true)
+			textColor = globalTextColor
+		}
+		text.Draw(screen, line, smallFont, listX, lineY, textColor)
+		lineY += height + 10
+	}
+
+	// Statistiques du joueur demandé
+	profileX := globalWidth/2 + globalWidth/16
+	lineY = titleY + 130
+	if g.profile != nil {
+		for i, line := range profileLines(*g.profile) {
+			font := mediumFontError
+			if i == 0 {
+				font = smallFont
+			}
+			_, height := getTextDimensions(line, font)
+			text.Draw(screen, line, font, profileX, lineY, globalTextColorBright)
+			lineY += height + 12
+		}
+	} else {
+		text.Draw(screen, "Entrée : statistiques du joueur choisi", mediumFontError, profileX, lineY, globalTextColorBright)
+	}
+
+	if g.leaderboard != nil {
+		players := fmt.Sprintf("%d joueur(s) ayant terminé une partie", g.leaderboard.Players)
+		playersWidth, _ := getTextDimensions(players, mediumFontError)
+		text.Draw(screen, players, mediumFontError, (globalWidth-playersWidth)/2, globalHeight-130, globalTextColorBright)
+	}
+
+	help := "Gauche/Droite : classement   Haut/Bas : choisir   Entrée : statistiques   R : actualiser   Échap : retour"
+	helpWidth, _ := getTextDimensions(help, mediumFontError)
+	text.Draw(screen, help, mediumFontError, (globalWidth-helpWidth)/2, globalHeight-100, globalTextColorBright)
+
+	if g.errorMessage != "" {
+		g.errorMessageDisplay(screen, g.errorMessage)
+	}
+}
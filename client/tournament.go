@@ -0,0 +1,425 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"puissance4/protocol"
+)
+
+// bestOfChoices sont les nombres de parties par match proposés à la création d'un tournoi.
+var bestOfChoices = []int{1, 3, 5, 7}
+
+// maxStandingsLines est le nombre de joueurs affichés au classement d'un tournoi.
+const maxStandingsLines = 12
+
+// openTournaments affiche l'écran des tournois et demande leur liste au serveur.
+func (g *game) openTournaments() {
+	if !g.serverSupports(protocol.CapabilityTournaments) {
+		g.errorMessage = "Ce serveur n'organise pas de tournois"
+		g.gameState = lobbyState
+		return
+	}
+	g.errorMessage = ""
+	g.gameState = tournamentState
+	if g.tournamentBestOf == 0 {
+		g.tournamentBestOf = 1
+	}
+	if err := g.client.ListTournaments(); err != nil {
+		log.Printf("Erreur lors de la demande des tournois : %v\n", err)
+	}
+}
+
+// closeTournaments revient au lobby.
+func (g *game) closeTournaments() {
+	g.errorMessage = ""
+	g.gameState = lobbyState
+	sendListRooms(g.client)
+}
+
+func (g *game) onTournamentList(list protocol.TournamentListPayload) {
+	g.tournaments = list.Tournaments
+	if g.selectedTournament >= len(g.tournaments) {
+		g.selectedTournament = max(len(g.tournaments)-1, 0)
+	}
+}
+
+func (g *game) onTournament(t protocol.TournamentPayload) {
+	g.tournament = &t
+	// Le résumé de la liste suit l'état reçu
+	for i := range g.tournaments {
+		if g.tournaments[i].ID == t.ID {
+			g.tournaments[i] = t.TournamentInfo
+		}
+	}
+}
+
+// tournamentEntrant renvoie l'inscrit du tournoi affiché qui correspond au joueur.
+func (g game) tournamentEntrant() (protocol.TournamentPlayer, bool) {
+	if g.tournament == nil {
+		return protocol.TournamentPlayer{}, false
+	}
+	for _, p := range g.tournament.Entrants {
+		if p.ID == g.playerID {
+			return p, true
+		}
+	}
+	return protocol.TournamentPlayer{}, false
+}
+
+// tournamentMatch renvoie le match de tournoi joué dans la salle du joueur.
+func (g game) tournamentMatch() (protocol.TournamentMatch, bool) {
+	if g.tournament == nil || g.roomID == -1 {
+		return protocol.TournamentMatch{}, false
+	}
+	for _, m := range g.tournament.Matches {
+		// Un match terminé n'a plus de salle, mais ses joueurs y sont encore quelques secondes
+		if m.RoomID == g.roomID || (m.Status == protocol.MatchFinished && m.Round == g.tournament.Round &&
+			m.Players[1] != -1 && (m.Players[0] == g.playerID || m.Players[1] == g.playerID)) {
+			return m, true
+		}
+	}
+	return protocol.TournamentMatch{}, false
+}
+
+// tournamentLines renvoie les lignes du panneau des scores consacrées au match de
+// tournoi joué dans la salle : le score du match et son issue.
+func (g game) tournamentLines() []string {
+	m, ok := g.tournamentMatch()
+	if !ok || g.offline {
+		return nil
+	}
+	mine := 0
+	if m.Players[1] == g.playerID {
+		mine = 1
+	}
+	lines := []string{fmt.Sprintf("MATCH : %s - %s (BO%d)", formatPoints(m.Scores[mine]), formatPoints(m.Scores[1-mine]), g.tournament.BestOf)}
+	if m.Status == protocol.MatchFinished {
+		switch m.Winner {
+		case g.playerID:
+			lines = append(lines, "MATCH GAGNE")
+		case -1:
+			lines = append(lines, "MATCH NUL")
+		default:
+			lines = append(lines, "MATCH PERDU")
+		}
+	}
+	return lines
+}
+
+// formatPoints écrit un nombre de points entier sans décimale, un demi-point avec une.
+func formatPoints(points float64) string {
+	if points == float64(int(points)) {
+		return fmt.Sprintf("%d", int(points))
+	}
+	return fmt.Sprintf("%.1f", points)
+}
+
+// tournamentUpdate gère l'écran des tournois : Haut/Bas choisissent un tournoi, Entrée
+// l'affiche, F change le format, B le nombre de parties par match et M la cadence du
+// tournoi créé avec N, J inscrit le joueur, X le désinscrit ou le fait abandonner, D lance le tournoi
+// organisé par le joueur, R actualise et Échap revient au lobby.
+func (g *game) tournamentUpdate() {
+	if g.chatIsFocus || g.client == nil {
+		return
+	}
+
+	if len(g.tournaments) > 0 {
+		if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+			g.selectedTournament = (g.selectedTournament + 1) % len(g.tournaments)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+			g.selectedTournament = (g.selectedTournament - 1 + len(g.tournaments)) % len(g.tournaments)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+			g.errorMessage = ""
+			if err := g.client.FollowTournament(g.tournaments[g.selectedTournament].ID); err != nil {
+				log.Printf("Erreur lors de la demande du tournoi : %v\n", err)
+			}
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyJ) {
+			g.errorMessage = ""
+			if err := g.client.JoinTournament(g.tournaments[g.selectedTournament].ID); err != nil {
+				log.Printf("Erreur lors de l'inscription au tournoi : %v\n", err)
+			}
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		g.tournamentKnockout = !g.tournamentKnockout
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		for i, n := range bestOfChoices {
+			if n == g.tournamentBestOf {
+				g.tournamentBestOf = bestOfChoices[(i+1)%len(bestOfChoices)]
+				break
+			}
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) && g.serverSupports(protocol.CapabilityClock) {
+		g.nextTimeControl()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) {
+		g.errorMessage = ""
+		format := protocol.FormatRoundRobin
+		if g.tournamentKnockout {
+			format = protocol.FormatKnockout
+		}
+		err := g.client.CreateTournament(protocol.CreateTournamentPayload{Format: format, BestOf: g.tournamentBestOf, Clock: g.timeControl()})
+		if err != nil {
+			log.Printf("Erreur lors de la création du tournoi : %v\n", err)
+		}
+	}
+
+	if g.tournament != nil {
+		if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+			g.errorMessage = ""
+			if err := g.client.LeaveTournament(g.tournament.ID); err != nil {
+				log.Printf("Erreur lors de la désinscription du tournoi : %v\n", err)
+			}
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyD) && g.tournament.OrganizerID == g.playerID {
+			g.errorMessage = ""
+			if err := g.client.StartTournament(g.tournament.ID); err != nil {
+				log.Printf("Erreur lors du lancement du tournoi : %v\n", err)
+			}
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		g.openTournaments()
+		if g.tournament != nil {
+			if err := g.client.FollowTournament(g.tournament.ID); err != nil {
+				log.Printf("Erreur lors de la demande du tournoi : %v\n", err)
+			}
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.closeTournaments()
+		return
+	}
+
+	// Le bouton du menu du haut revient au lobby
+	mouseX, mouseY := ebiten.CursorPosition()
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && g.mouseReleased {
+		textWidth, _ := getTextDimensions("RETOUR", firstTitleMinusFont)
+		smallRectWidth := textWidth + 60*2
+		smallRectX, smallRectY := centerPosition(smallRectWidth, largeRectHeight, globalWidth, largeRectHeight)
+		if mouseX >= smallRectX && mouseX <= smallRectX+smallRectWidth &&
+			mouseY >= smallRectY && mouseY <= smallRectY+largeRectHeight {
+			g.mouseReleased = false
+			g.closeTournaments()
+		}
+	}
+}
+
+// formatLabel renvoie le nom affiché d'un format de tournoi.
+func formatLabel(format string) string {
+	if format == protocol.FormatKnockout {
+		return "élimination directe"
+	}
+	return "toutes rondes"
+}
+
+// statusLabel renvoie l'état affiché d'un tournoi.
+func statusLabel(t protocol.TournamentInfo) string {
+	switch t.Status {
+	case protocol.TournamentRegistration:
+		return fmt.Sprintf("inscriptions %d/%d", t.Players, t.MaxPlayers)
+	case protocol.TournamentRunning:
+		return fmt.Sprintf("ronde %d/%d", t.Round, t.Rounds)
+	case protocol.TournamentCancelled:
+		return "annulé"
+	}
+	return "terminé"
+}
+
+// matchLine renvoie la ligne d'un match du tableau : ses joueurs et son score.
+func matchLine(m protocol.TournamentMatch) string {
+	if m.Players[1] == -1 {
+		return m.Names[0] + " (exempté)"
+	}
+	score := "-"
+	if m.Games > 0 || m.Status == protocol.MatchFinished {
+		score = formatPoints(m.Scores[0]) + " - " + formatPoints(m.Scores[1])
+	}
+	line := fmt.Sprintf("%s  %s  %s", m.Names[0], score, m.Names[1])
+	switch {
+	case m.Forfeit:
+		line += " (forfait)"
+	case m.Status == protocol.MatchPlaying:
+		line += " (en cours)"
+	}
+	return line
+}
+
+// standingLine renvoie la ligne d'un inscrit au classement du tournoi.
+func standingLine(p protocol.TournamentPlayer) string {
+	line := fmt.Sprintf("%2d. %s  %s pt(s)  %d V / %d N / %d D", p.Rank, p.Name, formatPoints(p.Points), p.Wins, p.Draws, p.Losses)
+	if p.Withdrawn {
+		line += "  (abandon)"
+	}
+	return line
+}
+
+// Affichage de l'écran des tournois : la liste des tournois à gauche, le tournoi suivi
+// à droite, avec ses inscrits, son classement en toutes rondes ou son tableau en
+// élimination directe.
+func (g game) tournamentDraw(screen *ebiten.Image) {
+	g.topMenuButton(screen, "RETOUR")
+
+	title := "Tournois"
+	titleWidth, titleHeight := getTextDimensions(title, firstTitleSmallerFont)
+	titleY := globalHeight/6 + titleHeight
+	text.Draw(screen, title, firstTitleSmallerFont, (globalWidth-titleWidth)/2, titleY, globalTextColorYellow)
+
+	listX := globalWidth / 16
+	lineY := titleY + 80
+	if len(g.tournaments) == 0 {
+		text.Draw(screen, "Aucun tournoi, N pour en créer un", smallFont, listX, lineY, globalTextColorBright)
+	}
+	for i, t := range g.tournaments {
+		line := fmt.Sprintf("#%d  %s  (%s)", t.ID, t.Name, statusLabel(t))
+		width, height := getTextDimensions(line, mediumFontError)
+		textColor := globalTextColorBright
+		if i == g.selectedTournament {
+			vector.DrawFilledRect(screen, float32(listX-20), float32(lineY-height+10), float32(width+40), float32(height), globalTextColorGreen, true)
+			textColor = globalTextColor
+		}
+		text.Draw(screen, line, mediumFontError, listX, lineY, textColor)
+		lineY += height + 10
+	}
+
+	// Réglages du tournoi créé avec N
+	format := protocol.FormatRoundRobin
+	if g.tournamentKnockout {
+		format = protocol.FormatKnockout
+	}
+	settings := fmt.Sprintf("Tournoi créé : %s (F), au meilleur de %d partie(s) (B)", formatLabel(format), g.tournamentBestOf)
+	if g.serverSupports(protocol.CapabilityClock) {
+		settings += ", cadence " + timeControlLabel(g.timeControl()) + " (M)"
+	}
+	text.Draw(screen, settings, mediumFontError, listX, globalHeight-160, globalTextColorBright)
+
+	if g.tournament != nil {
+		g.drawTournament(screen, globalWidth*2/5, titleY+80)
+	} else {
+		text.Draw(screen, "Entrée : suivre le tournoi choisi", mediumFontError, globalWidth*2/5, titleY+80, globalTextColorBright)
+	}
+
+	help := "Haut/Bas : choisir   Entrée : suivre   J : s'inscrire   X : se retirer   N : créer   D : lancer   R : actualiser   Échap : retour"
+	helpWidth, _ := getTextDimensions(help, mediumFontError)
+	text.Draw(screen, help, mediumFontError, (globalWidth-helpWidth)/2, globalHeight-100, globalTextColorBright)
+
+	if g.errorMessage != "" {
+		g.errorMessageDisplay(screen, g.errorMessage)
+	}
+}
+
+// drawTournament affiche le tournoi suivi à partir de la position (x, y).
+func (g game) drawTournament(screen *ebiten.Image, x, y int) {
+	t := g.tournament
+	header := fmt.Sprintf("%s : %s, au meilleur de %d", t.Name, formatLabel(t.Format), t.BestOf)
+	if t.Clock.Enabled() {
+		header += ", " + timeControlLabel(t.Clock)
+	}
+	header += ", " + statusLabel(t.TournamentInfo)
+	_, height := getTextDimensions(header, smallFont)
+	text.Draw(screen, header, smallFont, x, y, globalTextColorYellow)
+	y += height + 10
+
+	info := "Organisé par " + t.Organizer
+	if t.Winner != "" {
+		info += "   Vainqueur : " + t.Winner
+	}
+	if p, ok := g.tournamentEntrant(); ok {
+		switch {
+		case p.Withdrawn:
+			info += "   Vous avez abandonné"
+		case p.Eliminated:
+			info += "   Vous êtes éliminé"
+		default:
+			info += "   Vous êtes inscrit"
+		}
+	}
+	text.Draw(screen, info, mediumFontError, x, y, globalTextColorBright)
+	y += 50
+
+	if t.Status == protocol.TournamentRegistration {
+		names := make([]string, len(t.Entrants))
+		for i, p := range t.Entrants {
+			names[i] = playerLabel(protocol.PlayerInfo{Name: p.Name, Rating: p.Rating})
+		}
+		g.drawTournamentLines(screen, x, y, append([]string{"Inscrits :"}, names...))
+		return
+	}
+
+	if t.Format == protocol.FormatKnockout {
+		g.drawBracket(screen, x, y)
+		return
+	}
+
+	// Toutes rondes : classement, puis matchs de la ronde en cours
+	lines := []string{"Classement :"}
+	for i, p := range t.Entrants {
+		if i == maxStandingsLines {
+			break
+		}
+		lines = append(lines, standingLine(p))
+	}
+	lines = append(lines, "", fmt.Sprintf("Ronde %d :", t.Round))
+	for _, m := range t.Matches {
+		if m.Round == t.Round {
+			lines = append(lines, matchLine(m))
+		}
+	}
+	g.drawTournamentLines(screen, x, y, lines)
+}
+
+// drawBracket affiche le tableau d'un tournoi à élimination directe, une colonne par ronde.
+func (g game) drawBracket(screen *ebiten.Image, x, y int) {
+	t := g.tournament
+	rounds := max(t.Rounds, 1)
+	columnWidth := (globalWidth - x - globalWidth/32) / rounds
+	for round := 1; round <= rounds; round++ {
+		lines := []string{fmt.Sprintf("Ronde %d", round)}
+		if round == rounds {
+			lines[0] = "Finale"
+		}
+		for _, m := range t.Matches {
+			if m.Round == round {
+				lines = append(lines, matchLine(m))
+			}
+		}
+		g.drawTournamentLines(screen, x+(round-1)*columnWidth, y, lines)
+	}
+}
+
+// drawTournamentLines affiche des lignes les unes sous les autres, en surlignant celles
+// qui nomment le joueur.
+func (g game) drawTournamentLines(screen *ebiten.Image, x, y int, lines []string) {
+	name := g.accountName
+	if p, ok := g.tournamentEntrant(); ok {
+		name = p.Name
+	}
+	for _, line := range lines {
+		if line == "" {
+			y += 20
+			continue
+		}
+		_, height := getTextDimensions(line, mediumFontError)
+		textColor := globalTextColorBright
+		if name != "" && strings.Contains(line, name) {
+			textColor = globalTextColorGreen
+		}
+		text.Draw(screen, line, mediumFontError, x, y, textColor)
+		y += height + 8
+	}
+}
@@ -1,18 +1,19 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"log"
 	"strings"
-	"time"
+
+	"puissance4/protocol"
 )
 
 // Mise à jour de l'état du jeu en fonction des entrées au clavier.
 func (g *game) Update() error {
+	g.applyNetworkEvents()
+
 	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
 		x, y := ebiten.CursorPosition()
 		g.handleMouseClick(x, y)
@@ -26,6 +27,11 @@ func (g *game) Update() error {
 
 	g.stateFrame++
 
+	// Échap abandonne la partie contre l'ordinateur
+	if g.offline && inpututil.IsKeyJustPressed(ebiten.KeyEscape) && !g.chatIsFocus {
+		g.quitOfflineGame()
+	}
+
 	switch g.gameState {
 	case introStateLogo:
 		if g.introStateLogo() {
@@ -38,9 +44,12 @@ func (g *game) Update() error {
 			g.stateFrame = 0
 		}
 	case titleState:
+		g.loadDroppedGame()
 		if g.titleUpdate() {
 			g.gameState = inputServerState
 		}
+	case computerSelectState:
+		g.computerSelectUpdate()
 	case themeState:
 		if g.UpdateThemesPage() {
 			g.gameState = titleState
@@ -48,18 +57,42 @@ func (g *game) Update() error {
 	case inputServerState:
 		if g.inputServerUpdate() {
 			g.gameState = waitingState
-			go connectToServer(g) // Lancer la connexion au serveur
-		}
+			connectToServer(g) // Lancer la connexion au serveur
+		}
+	case lobbyState:
+		g.lobbyUpdate()
+	case statsState:
+		g.statsUpdate()
+	case tournamentState:
+		g.tournamentUpdate()
+	case spectatorState:
+		g.spectatorUpdate()
 	case waitingState:
 
 		if g.serverReady {
 			// La transition vers colorSelectState est déjà gérée par connectToServer
 		}
+		// Quitter la file de partie rapide ou la salle pour revenir au lobby
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) && !g.chatIsFocus {
+			if g.inQueue {
+				sendCancelQuickPlay(g.client)
+			} else if g.roomID != -1 {
+				sendLeaveRoom(g.client)
+			}
+		}
+		// Inviter un robot du niveau choisi pour l'ordinateur à la place libre
+		if inpututil.IsKeyJustPressed(ebiten.KeyB) && !g.chatIsFocus && g.canAddBot() {
+			g.errorMessage = ""
+			sendAddBot(g.client, g.computerLevel.String())
+		}
 	case colorSelectState:
 		if g.colorSelectUpdate() {
 			g.gameState = waitingColorSelect
 			if g.gameState == waitingColorSelect {
 			}
+			if g.offline {
+				g.gameState = playState // Pas d'adversaire à attendre
+			}
 		}
 	case waitingColorSelect:
 		if g.gameReady {
@@ -73,6 +106,8 @@ func (g *game) Update() error {
 		}
 		g.p1Color = g.p1ColorValidate
 		g.tokenPosUpdate()
+		g.hintUpdate()
+		g.offersUpdate()
 		var lastXPositionPlayed, lastYPositionPlayed int
 		if g.turn == p1Turn {
 			lastXPositionPlayed, lastYPositionPlayed = g.p1Update()
@@ -109,7 +144,11 @@ func (g *game) Update() error {
 		}
 	case replayState:
 		if g.replayDrawUpdate() {
+			g.showReplayMove(len(g.viewer.moves)) // L'écran de résultat montre la grille finale
 			g.gameState = resultState
+			if g.savedGame != nil {
+				g.gameState = g.endLoadedReplay()
+			}
 			g.blinking = false
 		}
 	}
@@ -280,23 +319,45 @@ func (g *game) UpdateReplayButton() error {
 
 		// Vérifier si le clic est sur le bouton
 		if x >= buttonX && x <= buttonX+int(scaledWidth) && y >= buttonY && y <= buttonY+int(scaledHeight) {
-			g.gameState = replayState
-			g.resetGrid()
-			err := requestHistory(g.conn)
-			if err != nil {
-				return err
+			// Maj+clic rejoue la dernière partie exportée ou copiée dans le répertoire des parties
+			if ebiten.IsKeyPressed(ebiten.KeyShift) {
+				path, err := latestGameFile()
+				if err == nil {
+					err = g.loadGameFile(path)
+				}
+				if err != nil {
+					g.exportMessage = err.Error()
+				}
+				return nil
 			}
-			g.blinking = false
+			return g.openReplay()
 		}
 	}
 	return nil
 }
 
+// openReplay passe à l'écran de replay de la dernière partie, dont l'historique est
+// demandé au serveur, ou repris de la grille hors ligne.
+func (g *game) openReplay() error {
+	g.gameState = replayState
+	if g.offline {
+		g.loadLocalHistory()
+		g.startReplay()
+		return nil
+	}
+	// L'historique du serveur remplace celui de la partie précédente à son arrivée
+	for key := range history {
+		delete(history, key)
+	}
+	g.startReplay()
+	return requestHistory(g.client)
+}
+
 func (g *game) UpdateDebug() error {
 	// ... votre code update existant ...
 
 	// Activer/désactiver le mode debug avec F3 par exemple
-	if inpututil.IsKeyJustPressed(ebiten.KeySpace) && !g.chatIsFocus {
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) && !g.chatIsFocus && g.loginStep == loginNone {
 		g.debugMode = !g.debugMode
 	}
 
@@ -359,6 +420,17 @@ func (g *game) titleUpdate() bool {
 			g.gameState = inputServerState // Remplacez "nextState" par l'état que vous voulez
 		}
 	}
+
+	// Jouer contre l'ordinateur, sans serveur
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) && !g.chatIsFocus {
+		g.gameState = computerSelectState
+		return false
+	}
+	// Consulter les classements : ils sont affichés dès la connexion au serveur
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) && !g.chatIsFocus {
+		g.statsPending = true
+		return true
+	}
 	return inpututil.IsKeyJustPressed(ebiten.KeyEnter) && !g.chatIsFocus
 
 }
@@ -452,7 +524,9 @@ func (g *game) colorSelectUpdate() bool {
 	}
 
 	g.p1Color = line*globalNumColorLine + col
-	sendCursorUpdateToServer(g.conn, g.p1Color)
+	if g.client != nil {
+		sendCursorUpdateToServer(g.client, g.p1Color)
+	}
 
 	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && !g.chatIsFocus {
 		if g.p1Color == g.p2Color {
@@ -460,9 +534,15 @@ func (g *game) colorSelectUpdate() bool {
 			g.errorMessage = "Couleur deja choisi par l'autre joueur"
 			return false // Ne pas permettre de continuer
 		}
+		// Hors ligne, l'ordinateur prend une autre couleur
+		if g.offline {
+			g.p1ColorValidate = g.p1Color
+			g.p2Color = computerColor(g.p1Color)
+			return true
+		}
 		// Envoyer la couleur au serveur
-		if g.conn != nil {
-			sendColorToServer(g.conn, g.p1Color)
+		if g.client != nil {
+			sendColorToServer(g.client, g.p1Color)
 			g.p1ColorValidate = g.p1Color
 		}
 		if g.p2Color != -1 {
@@ -479,12 +559,16 @@ func (g *game) colorSelectUpdate() bool {
 func (g *game) tokenPosUpdate() {
 	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) && !g.chatIsFocus {
 		g.tokenPosition = (g.tokenPosition - 1 + globalNumTilesX) % globalNumTilesX
-		sendTokenUpdateToServer(g.conn, g.tokenPosition)
+		if g.client != nil {
+			sendTokenUpdateToServer(g.client, g.tokenPosition)
+		}
 	}
 
 	if inpututil.IsKeyJustPressed(ebiten.KeyRight) && !g.chatIsFocus {
 		g.tokenPosition = (g.tokenPosition + 1) % globalNumTilesX
-		sendTokenUpdateToServer(g.conn, g.tokenPosition)
+		if g.client != nil {
+			sendTokenUpdateToServer(g.client, g.tokenPosition)
+		}
 	}
 }
 
@@ -494,13 +578,15 @@ func (g *game) p1Update() (int, int) {
 	lastYPositionPlayed := -1
 	if (inpututil.IsKeyJustPressed(ebiten.KeyDown) || inpututil.IsKeyJustPressed(ebiten.KeyEnter)) && !g.chatIsFocus {
 		if updated, yPos := g.updateGrid(p1Token, g.tokenPosition); updated {
+			g.errorMessage = ""
+			g.clearOffers()
 			g.turn = p2Turn
 			lastXPositionPlayed = g.tokenPosition
 			lastYPositionPlayed = yPos
 
 			// Envoyer la position au serveur
-			if g.conn != nil {
-				sendMoveToServer(g.conn, lastXPositionPlayed, lastYPositionPlayed)
+			if g.client != nil {
+				sendMoveToServer(g.client, lastXPositionPlayed, lastYPositionPlayed)
 			}
 		}
 	}
@@ -508,8 +594,11 @@ func (g *game) p1Update() (int, int) {
 }
 
 // Gestion de la position du prochain pion joué par le joueur 2 et
-// du moment où ce pion est joué.
+// du moment où ce pion est joué. Hors ligne, c'est l'ordinateur qui joue.
 func (g *game) p2Update() (int, int) {
+	if g.offline {
+		return g.computerUpdate()
+	}
 	// Ne fait rien, attend que le serveur mette à jour la grille
 	return -1, -1
 }
@@ -517,6 +606,18 @@ func (g *game) p2Update() (int, int) {
 // Mise à jour de l'état du jeu à l'écran des résultats.
 func (g *game) resultUpdate() bool {
 	g.UpdateReplayButton()
+	g.loadDroppedGame()
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) && !g.chatIsFocus {
+		g.exportGame()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) && !g.chatIsFocus {
+		// Analyse de la partie, affichée dans le replay
+		if err := g.openReplay(); err != nil {
+			log.Println("Erreur lors de la demande d'historique :", err)
+		}
+		g.viewer.analyse = true
+		return false
+	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && !g.chatIsFocus {
 		return true
 	}
@@ -546,17 +647,23 @@ func (g *game) resultUpdate() bool {
 // Mise à jour de la grille de jeu lorsqu'un pion est inséré dans la
 // colonne de coordonnée (x) position.
 func (g *game) updateGrid(token, position int) (updated bool, yPos int) {
-	for y := globalNumTilesY - 1; y >= 0; y-- {
-		if g.grid[position][y] == noToken {
-			updated = true
-			yPos = y
-			g.grid[position][y] = token
-			return
-		}
+	yPos, err := g.board.Play(token, position)
+	if err != nil {
+		return false, 0
 	}
-	return
+	return true, yPos
 }
 
+// Retrait du dernier pion joué s'il s'agit d'un pion token posé dans la
+// colonne (x) position, par exemple lorsque le serveur refuse un coup.
+func (g *game) removeTopToken(token, position int) {
+	if last, ok := g.board.LastMove(); ok && last.Token == token && last.X == position {
+		g.board.Undo()
+	}
+}
+
+// Mise à jour de la grille de jeu en posant directement un pion à la position
+// (positionX, positionY) reçue dans l'historique, sans appliquer la gravité.
 func (g *game) updateGridExtend(id, positionX int, positionY int) (updated bool) {
 	token := noToken
 	if id == g.playerID {
@@ -565,102 +672,13 @@ func (g *game) updateGridExtend(id, positionX int, positionY int) (updated bool)
 		token = p2Token
 	}
 
-	if g.grid[positionX][positionY] == noToken {
-		updated = true
-		g.grid[positionX][positionY] = token
-		return
-	}
-	return
+	return g.board.Place(token, positionX, positionY) == nil
 }
 
+// Vérification de la fin de partie après qu'un pion a été posé en (xPos, yPos).
+// Les règles sont celles du paquet engine partagé avec le serveur.
 func (g game) checkGameEnd(xPos, yPos int) (finished bool, result int, winningPositions [][2]int) {
-
-	tokenType := g.grid[xPos][yPos]
-
-	// Horizontal
-	count := 0
-	var tempPositions [][2]int
-
-	for x := xPos; x < globalNumTilesX && g.grid[x][yPos] == tokenType; x++ {
-		count++
-		tempPositions = append(tempPositions, [2]int{x, yPos})
-	}
-	for x := xPos - 1; x >= 0 && g.grid[x][yPos] == tokenType; x-- {
-		count++
-		tempPositions = append(tempPositions, [2]int{x, yPos})
-	}
-
-	if count >= 4 {
-		if tokenType == p1Token {
-			return true, p1wins, tempPositions
-		}
-		return true, p2wins, tempPositions
-	}
-
-	// Vertical
-	count = 0
-	tempPositions = nil
-	for y := yPos; y < globalNumTilesY && g.grid[xPos][y] == tokenType; y++ {
-		count++
-		tempPositions = append(tempPositions, [2]int{xPos, y})
-	}
-
-	if count >= 4 {
-		if tokenType == p1Token {
-			return true, p1wins, tempPositions
-		}
-		return true, p2wins, tempPositions
-	}
-
-	// Diagonal haut gauche / bas droit
-	count = 0
-	tempPositions = nil
-	for x, y := xPos, yPos; x < globalNumTilesX && y < globalNumTilesY && g.grid[x][y] == tokenType; x, y = x+1, y+1 {
-		count++
-		tempPositions = append(tempPositions, [2]int{x, y})
-	}
-	for x, y := xPos-1, yPos-1; x >= 0 && y >= 0 && g.grid[x][y] == tokenType; x, y = x-1, y-1 {
-		count++
-		tempPositions = append(tempPositions, [2]int{x, y})
-	}
-
-	if count >= 4 {
-		if tokenType == p1Token {
-			return true, p1wins, tempPositions
-		}
-		return true, p2wins, tempPositions
-	}
-
-	// Diagonal haut droit / bas gauche
-	count = 0
-	tempPositions = nil
-	for x, y := xPos, yPos; x >= 0 && y < globalNumTilesY && g.grid[x][y] == tokenType; x, y = x-1, y+1 {
-		count++
-		tempPositions = append(tempPositions, [2]int{x, y})
-	}
-	for x, y := xPos+1, yPos-1; x < globalNumTilesX && y >= 0 && g.grid[x][y] == tokenType; x, y = x+1, y-1 {
-		count++
-		tempPositions = append(tempPositions, [2]int{x, y})
-	}
-
-	if count >= 4 {
-		if tokenType == p1Token {
-			return true, p1wins, tempPositions
-		}
-		return true, p2wins, tempPositions
-	}
-
-	// Égalité ?
-	if yPos == 0 {
-		for x := 0; x < globalNumTilesX; x++ {
-			if g.grid[x][0] == noToken {
-				return
-			}
-		}
-		return true, equality, nil
-	}
-
-	return
+	return g.board.CheckEnd(xPos, yPos)
 }
 
 func (g *game) replayDrawUpdate() bool {
@@ -683,38 +701,8 @@ func (g *game) replayDrawUpdate() bool {
 		}
 	}
 
-	// Gestion du délai entre les mises à jour
-	const delay = 350 // Délai en millisecondes (500 ms = 0,5 s)
-	if g.lastUpdateTime == 0 {
-		g.lastUpdateTime = int(time.Now().UnixMilli())
-	}
-
-	currentTime := int(time.Now().UnixMilli())
-	if currentTime-g.lastUpdateTime >= delay {
-		// Identifier la clé la plus basse
-		var minKey int
-		found := false
-		for key := range history {
-			if !found || key < minKey {
-				minKey = key
-				found = true
-			}
-		}
-
-		// Effectuer la mise à jour si une clé est trouvée
-		if found {
-			value := history[minKey]
-			g.updateGridExtend(value.ID, value.X, value.Y)
-			delete(history, minKey) // Supprimer après traitement
-		} else if len(history) == 0 && g.posWinner != nil { // Si tout est affiché et pas encore clignotant
-			g.blinking = true // Lancer le clignotement des gagnants
-		}
-
-		g.lastUpdateTime = currentTime
-	}
-
+	g.replayControlsUpdate()
 	return false
-
 }
 
 func (g *game) updateChat() {
@@ -745,14 +733,14 @@ func (g *game) updateChat() {
 
 	// Envoyer le message si Enter est pressé
 	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && len(g.chatInput) > 0 && g.chatIsFocus {
-		sendChatMessage(g.conn, g.chatInput)
+		sendChatMessage(g.client, g.chatInput)
 		g.chatInput = "" // Réinitialiser l'entrée
 	}
 
 }
 
 func (g *game) updateChatButton() error {
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !g.chatIsFocus {
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !g.chatIsFocus && !g.offline {
 		x, y := ebiten.CursorPosition()
 		var icon *ebiten.Image = chat
 		// Facteur d'échelle pour redimensionner le logo
@@ -807,17 +795,8 @@ func (g *game) UpdateShifumi() {
 		g.handleMouseClick(x, y)
 
 		// Si un choix a été fait, envoyer au serveur
-		if g.selected != "" {
-			message := Message{
-				Type: "selected",
-				Payload: SelectedPayload{
-					Selected: strings.ToLower(g.selected),
-				},
-			}
-			jsonData, err := json.Marshal(message)
-			if err == nil {
-				fmt.Fprintf(g.conn, string(jsonData)+"\n")
-			}
+		if g.selected != "" && g.client != nil {
+			sendSelectedToServer(g.client, g.selected)
 		}
 	}
 
@@ -834,7 +813,7 @@ func (g *game) UpdateShifumi() {
 	}
 }
 
-func (g *game) handleShifumiResult(message Message) {
+func (g *game) handleShifumiResult(message protocol.Message) {
 	payload, ok := message.Payload.(map[string]interface{})
 	if !ok {
 		return
@@ -854,9 +833,9 @@ func (g *game) handleShifumiResult(message Message) {
 	g.adversaryChoice = adversarySelection
 
 	// Déterminer le résultat
-	if message.Type == "shifumi_result" {
+	if message.Type == protocol.TypeShifumiResult {
 		g.shifumiResult = "Égalité"
-	} else if message.Type == "shifumi_complete" {
+	} else if message.Type == protocol.TypeShifumiComplete {
 		winnerID := int(payload["winner"].(float64))
 		if winnerID == g.playerID {
 			g.shifumiResult = "Gagné"
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level représente le niveau de difficulté de l'ordinateur.
+type Level int
+
+// Niveaux de difficulté, du plus faible au plus fort.
+const (
+	Easy Level = iota
+	Medium
+	Hard
+	Expert
+)
+
+// Levels liste les niveaux disponibles, dans l'ordre croissant de difficulté.
+var Levels = []Level{Easy, Medium, Hard, Expert}
+
+// levelSettings décrit le comportement de l'ordinateur pour un niveau.
+type levelSettings struct {
+	name    string  // Nom affiché du niveau
+	depth   int     // Nombre de demi-coups explorés par la recherche
+	blunder float64 // Probabilité de jouer un coup au hasard au lieu du meilleur
+}
+
+var levelTable = map[Level]levelSettings{
+	Easy:   {name: "Facile", depth: 2, blunder: 0.35},
+	Medium: {name: "Moyen", depth: 4, blunder: 0.12},
+	Hard:   {name: "Difficile", depth: 6, blunder: 0.03},
+	Expert: {name: "Expert", depth: 8, blunder: 0},
+}
+
+// Valid indique si l est un niveau connu.
+func (l Level) Valid() bool {
+	_, ok := levelTable[l]
+	return ok
+}
+
+// String renvoie le nom affiché du niveau.
+func (l Level) String() string {
+	if s, ok := levelTable[l]; ok {
+		return s.name
+	}
+	return fmt.Sprintf("niveau %d", int(l))
+}
+
+// Depth renvoie la profondeur de recherche du niveau, en demi-coups.
+func (l Level) Depth() int {
+	return levelTable[l].depth
+}
+
+// ParseLevel lit un niveau à partir de son nom (« facile », « moyen »,
+// « difficile », « expert ») ou de son numéro, à partir de 0.
+func ParseLevel(s string) (Level, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if l := Level(n); l.Valid() {
+			return l, nil
+		}
+		return 0, fmt.Errorf("niveau %d inconnu (0 à %d)", n, len(Levels)-1)
+	}
+	for _, l := range Levels {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("niveau %q inconnu (facile, moyen, difficile ou expert)", s)
+}
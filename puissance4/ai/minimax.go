@@ -0,0 +1,323 @@
+// Package ai fournit un adversaire artificiel pour le puissance 4 : une recherche
+// minimax avec élagage alpha-bêta, dont la profondeur et la part de coups joués au
+// hasard dépendent du niveau de difficulté.
+// Comme le paquet engine, il ne dépend d'aucune interface graphique ni du réseau,
+// afin d'être utilisé aussi bien par le client hors ligne que par le serveur.
+package ai
+
+import (
+	"errors"
+	"math/rand"
+
+	"puissance4/engine"
+)
+
+// ErrNoMove est renvoyée lorsque la partie est terminée ou que le pion est invalide.
+var ErrNoMove = errors.New("aucun coup possible")
+
+// Scores extrêmes de la recherche. Une victoire vaut winScore, augmenté de la
+// profondeur restante pour préférer les victoires rapides et les défaites lentes.
+const (
+	winScore = 1_000_000
+	infinity = 2 * winScore
+)
+
+// columnOrder explore d'abord les colonnes centrales, qui mènent le plus souvent
+// aux meilleurs coups et rendent l'élagage plus efficace.
+var columnOrder = [engine.Columns]int{3, 2, 4, 1, 5, 0, 6}
+
+// Player est un joueur contrôlé par l'ordinateur. Il n'est pas prévu pour être
+// utilisé par plusieurs goroutines à la fois.
+type Player struct {
+	level Level
+	rng   *rand.Rand
+}
+
+// NewPlayer crée un joueur du niveau level. La graine seed rend ses choix
+// reproductibles : deux joueurs de même graine jouent les mêmes coups.
+func NewPlayer(level Level, seed int64) *Player {
+	if !level.Valid() {
+		level = Medium
+	}
+	return &Player{level: level, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Level renvoie le niveau de difficulté du joueur.
+func (p *Player) Level() Level {
+	return p.level
+}
+
+// ChooseMove renvoie la colonne que le joueur joue avec le pion token sur la grille b.
+// La grille n'est pas modifiée. Aux niveaux faibles, le meilleur coup est parfois
+// remplacé par un coup au hasard ; entre plusieurs coups de même valeur, le choix est aléatoire.
+func (p *Player) ChooseMove(b *engine.Board, token int) (int, error) {
+	pos, err := newPosition(b, token)
+	if err != nil {
+		return -1, err
+	}
+
+	settings := levelTable[p.level]
+	if settings.blunder > 0 && p.rng.Float64() < settings.blunder {
+		playable := pos.playableColumns()
+		return playable[p.rng.Intn(len(playable))], nil
+	}
+
+	scores := pos.rootScores(token, settings.depth)
+	best := -infinity
+	var candidates []int
+	for _, x := range columnOrder {
+		score, ok := scores[x]
+		if !ok {
+			continue
+		}
+		if score > best {
+			best = score
+			candidates = candidates[:0]
+		}
+		if score == best {
+			candidates = append(candidates, x)
+		}
+	}
+	return candidates[p.rng.Intn(len(candidates))], nil
+}
+
+// ScoreColumns évalue chaque colonne jouable pour token sur la grille b avec une recherche
+// de depth demi-coups. Contrairement à ChooseMove, chaque coup reçoit son score exact à
+// cette profondeur, ce qui permet de comparer un coup joué au meilleur (analyse de partie).
+func ScoreColumns(b *engine.Board, token, depth int) (map[int]int, error) {
+	pos, err := newPosition(b, token)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[int]int, engine.Columns)
+	for _, x := range columnOrder {
+		if pos.canPlay(x) {
+			scores[x] = pos.scoreMove(x, token, depth, -infinity, infinity)
+		}
+	}
+	return scores, nil
+}
+
+// Forced indique si un score de ScoreColumns est une victoire forcée (1) ou une défaite
+// forcée (-1) dans l'horizon de la recherche, et renvoie 0 si l'issue reste ouverte.
+func Forced(score int) int {
+	switch {
+	case score >= winScore:
+		return 1
+	case score <= -winScore:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// position est une copie légère de la grille utilisée pendant la recherche,
+// qui joue et annule les coups sans allocation.
+type position struct {
+	grid    [engine.Columns][engine.Rows]int
+	heights [engine.Columns]int // Nombre de pions dans chaque colonne
+	count   int                 // Nombre total de pions
+}
+
+// newPosition copie la grille b pour une recherche du point de vue de token.
+func newPosition(b *engine.Board, token int) (*position, error) {
+	if token != engine.P1Token && token != engine.P2Token {
+		return nil, ErrNoMove
+	}
+	if finished, _, _ := b.Winner(); finished {
+		return nil, ErrNoMove
+	}
+	pos := &position{grid: b.Grid()}
+	for x := 0; x < engine.Columns; x++ {
+		for y := 0; y < engine.Rows; y++ {
+			if pos.grid[x][y] != engine.NoToken {
+				pos.heights[x]++
+				pos.count++
+			}
+		}
+	}
+	return pos, nil
+}
+
+func (pos *position) canPlay(x int) bool {
+	return pos.heights[x] < engine.Rows
+}
+
+func (pos *position) playableColumns() []int {
+	var columns []int
+	for x := 0; x < engine.Columns; x++ {
+		if pos.canPlay(x) {
+			columns = append(columns, x)
+		}
+	}
+	return columns
+}
+
+// play pose token dans la colonne x et renvoie la ligne atteinte (0 en haut).
+func (pos *position) play(x, token int) int {
+	y := engine.Rows - 1 - pos.heights[x]
+	pos.grid[x][y] = token
+	pos.heights[x]++
+	pos.count++
+	return y
+}
+
+// undo retire le pion du haut de la colonne x.
+func (pos *position) undo(x int) {
+	pos.heights[x]--
+	pos.count--
+	pos.grid[x][engine.Rows-1-pos.heights[x]] = engine.NoToken
+}
+
+// wins indique si le pion posé en (x, y) aligne WinLength pions.
+func (pos *position) wins(x, y int) bool {
+	token := pos.grid[x][y]
+	for _, d := range [4][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}} {
+		n := 1 + pos.run(x, y, d[0], d[1], token) + pos.run(x, y, -d[0], -d[1], token)
+		if n >= engine.WinLength {
+			return true
+		}
+	}
+	return false
+}
+
+// run compte les pions token consécutifs à partir de (x, y), sans l'inclure.
+func (pos *position) run(x, y, dx, dy, token int) int {
+	n := 0
+	for {
+		x, y = x+dx, y+dy
+		if x < 0 || x >= engine.Columns || y < 0 || y >= engine.Rows || pos.grid[x][y] != token {
+			return n
+		}
+		n++
+	}
+}
+
+// rootScores évalue chaque colonne jouable pour token avec une recherche de depth demi-coups.
+// La fenêtre de recherche s'arrête juste sous le meilleur score trouvé : les coups aussi bons
+// que le meilleur reçoivent leur score exact, les autres un score inférieur.
+func (pos *position) rootScores(token, depth int) map[int]int {
+	scores := make(map[int]int, engine.Columns)
+	best := -infinity
+	for _, x := range columnOrder {
+		if !pos.canPlay(x) {
+			continue
+		}
+		scores[x] = pos.scoreMove(x, token, depth, best-1, infinity)
+		if scores[x] > best {
+			best = scores[x]
+		}
+	}
+	return scores
+}
+
+// scoreMove joue token en x, évalue la position obtenue puis annule le coup.
+func (pos *position) scoreMove(x, token, depth, alpha, beta int) int {
+	y := pos.play(x, token)
+	defer pos.undo(x)
+	if pos.wins(x, y) {
+		return winScore + depth
+	}
+	return -pos.negamax(engine.Opponent(token), depth-1, -beta, -alpha)
+}
+
+// negamax renvoie la valeur de la position pour token, le joueur au trait.
+func (pos *position) negamax(token, depth, alpha, beta int) int {
+	if pos.count == engine.Columns*engine.Rows {
+		return 0
+	}
+	if depth <= 0 {
+		return pos.evaluate(token)
+	}
+	best := -infinity
+	for _, x := range columnOrder {
+		if !pos.canPlay(x) {
+			continue
+		}
+		score := pos.scoreMove(x, token, depth, alpha, beta)
+		if score > best {
+			best = score
+		}
+		if best > alpha {
+			alpha = best
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// evaluate estime la position pour token sans chercher plus loin : les pions au
+// centre et les alignements encore réalisables rapportent des points.
+func (pos *position) evaluate(token int) int {
+	opponent := engine.Opponent(token)
+	score := 0
+	center := engine.Columns / 2
+	for y := 0; y < engine.Rows; y++ {
+		switch pos.grid[center][y] {
+		case token:
+			score += 3
+		case opponent:
+			score -= 3
+		}
+	}
+
+	for _, w := range windows {
+		score += pos.windowScore(w, token, opponent)
+	}
+	return score
+}
+
+// windowScore évalue une fenêtre de WinLength cases alignées.
+// Une fenêtre occupée par les deux joueurs ne rapporte rien.
+func (pos *position) windowScore(w window, token, opponent int) int {
+	mine, theirs := 0, 0
+	for _, cell := range w {
+		switch pos.grid[cell[0]][cell[1]] {
+		case token:
+			mine++
+		case opponent:
+			theirs++
+		}
+	}
+	switch {
+	case mine > 0 && theirs > 0:
+		return 0
+	case mine == 3:
+		return 5
+	case mine == 2:
+		return 2
+	case theirs == 3:
+		return -4
+	case theirs == 2:
+		return -1
+	}
+	return 0
+}
+
+// window regroupe les coordonnées de WinLength cases alignées de la grille.
+type window [engine.WinLength][2]int
+
+// windows liste toutes les fenêtres de la grille, calculées une seule fois.
+var windows = allWindows()
+
+func allWindows() []window {
+	var all []window
+	for x := 0; x < engine.Columns; x++ {
+		for y := 0; y < engine.Rows; y++ {
+			for _, d := range [4][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}} {
+				endX, endY := x+d[0]*(engine.WinLength-1), y+d[1]*(engine.WinLength-1)
+				if endX < 0 || endX >= engine.Columns || endY < 0 || endY >= engine.Rows {
+					continue
+				}
+				var w window
+				for i := range w {
+					w[i] = [2]int{x + i*d[0], y + i*d[1]}
+				}
+				all = append(all, w)
+			}
+		}
+	}
+	return all
+}
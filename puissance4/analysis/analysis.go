@@ -0,0 +1,233 @@
+// Package analysis évalue chaque coup d'une partie de puissance 4 : il compare le coup
+// joué au meilleur coup de la position, signale les gaffes (le coup fait perdre une
+// position qui ne l'était pas) et les victoires manquées, et indique la meilleure colonne.
+//
+// À partir de ExactFrom pions posés, la valeur des coups est exacte (paquet solver). Avant,
+// le solveur serait trop lent : les coups sont estimés par une recherche de Depth demi-coups
+// (paquet ai), qui ne voit que les victoires et défaites forcées dans cet horizon. Une
+// position que le solveur ne résout pas en Budget est estimée de la même façon.
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"puissance4/ai"
+	"puissance4/engine"
+	"puissance4/solver"
+)
+
+// Réglages par défaut de l'analyse.
+const (
+	DefaultExactFrom = 12              // Pions posés à partir desquels le solveur est utilisé
+	DefaultDepth     = 10              // Profondeur de la recherche des premiers coups, en demi-coups
+	DefaultBudget    = 1 * time.Second // Durée accordée au solveur pour chaque position
+)
+
+// columnOrder départage les coups de même valeur en faveur des colonnes centrales.
+var columnOrder = [engine.Columns]int{3, 2, 4, 1, 5, 0, 6}
+
+// ErrNoMove est renvoyée pour un coup qui n'existe pas dans la partie.
+var ErrNoMove = errors.New("coup absent de la partie")
+
+// Verdict est l'appréciation d'un coup.
+type Verdict int
+
+// Appréciations possibles, de la meilleure à la pire.
+const (
+	Good      Verdict = iota // Le coup garde l'issue de la position
+	MissedWin                // La position était gagnante, le coup ne l'est plus
+	Blunder                  // Le coup perd une position qui n'était pas perdue
+)
+
+// String renvoie le nom de l'appréciation.
+func (v Verdict) String() string {
+	switch v {
+	case MissedWin:
+		return "victoire manquée"
+	case Blunder:
+		return "gaffe"
+	default:
+		return "bon coup"
+	}
+}
+
+// Symbol renvoie l'annotation du coup à la manière des échecs : "?!" pour une victoire
+// manquée, "??" pour une gaffe, rien pour un bon coup.
+func (v Verdict) Symbol() string {
+	switch v {
+	case MissedWin:
+		return "?!"
+	case Blunder:
+		return "??"
+	default:
+		return ""
+	}
+}
+
+// Move est l'analyse d'un coup de la partie. Les issues sont vues par le joueur qui joue le coup.
+type Move struct {
+	Number      int            // Numéro du coup, à partir de 1
+	Column      int            // Colonne jouée, à partir de 0
+	Best        int            // Meilleure colonne de la position, la plus centrale en cas d'égalité
+	Outcome     solver.Outcome // Issue après le coup joué
+	BestOutcome solver.Outcome // Issue après le meilleur coup
+	Optimal     bool           // Le coup joué vaut autant que le meilleur
+	Exact       bool           // Issues calculées par le solveur ; sinon, Draw signifie « issue ouverte »
+	Verdict     Verdict
+}
+
+// String décrit le coup, par exemple « coup 7, colonne 3 : gaffe (nulle → défaite), meilleur coup : colonne 4 ».
+func (m Move) String() string {
+	s := fmt.Sprintf("coup %d, colonne %d : %s", m.Number, m.Column+1, m.Verdict)
+	if m.Verdict != Good {
+		s += fmt.Sprintf(" (%s → %s), meilleur coup : colonne %d", m.BestOutcome, m.Outcome, m.Best+1)
+	}
+	if !m.Exact {
+		s += " (estimation)"
+	}
+	return s
+}
+
+// Analyzer analyse les coups de parties. Il conserve la table de transposition de son
+// solveur d'une position à l'autre : analyser une partie en partant de la fin est
+// nettement plus rapide. Un Analyzer n'est pas prévu pour être utilisé par plusieurs
+// goroutines à la fois.
+type Analyzer struct {
+	ExactFrom int           // Pions posés à partir desquels le solveur est utilisé
+	Depth     int           // Profondeur de la recherche avant ExactFrom
+	Budget    time.Duration // Durée accordée au solveur par position, au-delà de laquelle la position est estimée ; 0 sans limite
+
+	solver *solver.Solver
+}
+
+// New crée un analyseur avec les réglages par défaut.
+func New() *Analyzer {
+	return &Analyzer{
+		ExactFrom: DefaultExactFrom,
+		Depth:     DefaultDepth,
+		Budget:    DefaultBudget,
+		solver:    solver.New(),
+	}
+}
+
+// Game analyse tous les coups de la partie columns (colonnes jouées à partir de 0, le
+// premier joueur commençant), dans l'ordre de la partie.
+func (a *Analyzer) Game(columns []int) ([]Move, error) {
+	moves := make([]Move, len(columns))
+	for n := len(columns) - 1; n >= 0; n-- {
+		m, err := a.Move(columns, n)
+		if err != nil {
+			return nil, err
+		}
+		moves[n] = m
+	}
+	return moves, nil
+}
+
+// Move analyse le coup d'indice n (à partir de 0) de la partie columns.
+func (a *Analyzer) Move(columns []int, n int) (Move, error) {
+	if n < 0 || n >= len(columns) {
+		return Move{}, ErrNoMove
+	}
+	m := Move{Number: n + 1, Column: columns[n]}
+	outcomes, scores, exact, err := a.evaluate(columns[:n])
+	m.Exact = exact
+	if err != nil {
+		return Move{}, fmt.Errorf("coup %d : %w", n+1, err)
+	}
+	played, ok := outcomes[m.Column]
+	if !ok {
+		return Move{}, fmt.Errorf("coup %d : colonne %d injouable", n+1, m.Column+1)
+	}
+
+	m.Best = bestColumn(scores)
+	m.Outcome, m.BestOutcome = played, outcomes[m.Best]
+	m.Optimal = scores[m.Column] == scores[m.Best]
+	switch {
+	case played == solver.Loss && m.BestOutcome != solver.Loss:
+		m.Verdict = Blunder
+	case m.BestOutcome == solver.Win && played != solver.Win:
+		m.Verdict = MissedWin
+	}
+	return m, nil
+}
+
+// BestColumn renvoie la meilleure colonne pour le joueur au trait après les coups
+// played, par exemple pour donner un indice en cours de partie.
+func (a *Analyzer) BestColumn(played []int) (int, error) {
+	_, scores, _, err := a.evaluate(played)
+	if err != nil {
+		return -1, err
+	}
+	return bestColumn(scores), nil
+}
+
+// evaluate renvoie l'issue et le score de chaque coup jouable après played : exacts à
+// partir de a.ExactFrom pions si le solveur conclut dans son budget, estimés sinon.
+// Le booléen indique si les valeurs sont exactes.
+func (a *Analyzer) evaluate(played []int) (map[int]solver.Outcome, map[int]int, bool, error) {
+	if len(played) >= a.ExactFrom {
+		outcomes, scores, err := a.exact(played)
+		if !errors.Is(err, solver.ErrBudgetExceeded) {
+			return outcomes, scores, true, err
+		}
+	}
+	outcomes, scores, err := a.estimate(played)
+	return outcomes, scores, false, err
+}
+
+// bestColumn renvoie la colonne de meilleur score, la plus centrale en cas d'égalité.
+func bestColumn(scores map[int]int) int {
+	best := -1
+	for _, x := range columnOrder {
+		if score, ok := scores[x]; ok && (best < 0 || score > scores[best]) {
+			best = x
+		}
+	}
+	return best
+}
+
+// exact renvoie l'issue et le score exacts de chaque coup jouable après played.
+func (a *Analyzer) exact(played []int) (map[int]solver.Outcome, map[int]int, error) {
+	p, err := solver.FromColumns(played)
+	if err != nil {
+		return nil, nil, err
+	}
+	a.solver.Timeout = a.Budget
+	results, err := a.solver.Analyze(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	outcomes := make(map[int]solver.Outcome, len(results))
+	scores := make(map[int]int, len(results))
+	for _, r := range results {
+		outcomes[r.Column] = r.Result.Outcome
+		scores[r.Column] = r.Result.Score
+	}
+	return outcomes, scores, nil
+}
+
+// estimate estime l'issue et le score de chaque coup jouable après played par une
+// recherche de a.Depth demi-coups. Une issue qui n'est pas forcée dans l'horizon de
+// la recherche est notée solver.Draw.
+func (a *Analyzer) estimate(played []int) (map[int]solver.Outcome, map[int]int, error) {
+	b, err := engine.FromColumns(engine.P1Token, played)
+	if err != nil {
+		return nil, nil, err
+	}
+	token := engine.P1Token
+	if len(played)%2 == 1 {
+		token = engine.P2Token
+	}
+	scores, err := ai.ScoreColumns(b, token, a.Depth)
+	if err != nil {
+		return nil, nil, err
+	}
+	outcomes := make(map[int]solver.Outcome, len(scores))
+	for x, score := range scores {
+		outcomes[x] = solver.Outcome(ai.Forced(score))
+	}
+	return outcomes, scores, nil
+}
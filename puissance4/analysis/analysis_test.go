@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"testing"
+
+	"puissance4/solver"
+)
+
+// testDepth suffit à voir les menaces des parties de test, bien plus vite que DefaultDepth.
+const testDepth = 4
+
+// newTestAnalyzer crée un analyseur dont les estimations se limitent à testDepth demi-coups.
+func newTestAnalyzer() *Analyzer {
+	a := New()
+	a.Depth = testDepth
+	return a
+}
+
+// drawnGame remplit toute la grille sans qu'aucun joueur n'aligne quatre pions.
+var drawnGame = []int{
+	2, 5, 3, 5, 6, 3, 5, 6, 5, 0, 0, 2, 4, 2, 5, 5, 4, 4, 3, 2, 6,
+	6, 4, 0, 2, 6, 2, 0, 3, 1, 0, 4, 0, 6, 1, 3, 4, 3, 1, 1, 1, 1,
+}
+
+func TestMissedWin(t *testing.T) {
+	// Le premier joueur, avec deux pions en bas au centre, pouvait en poser un troisième
+	// à côté et menacer les deux extrémités de la ligne
+	game := []int{3, 3, 4, 4, 0}
+	m, err := newTestAnalyzer().Move(game, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Verdict != MissedWin || m.BestOutcome != solver.Win || (m.Best != 2 && m.Best != 5) || m.Exact {
+		t.Errorf("%s : %+v, attendu une victoire manquée estimée", m, m)
+	}
+}
+
+func TestBlunder(t *testing.T) {
+	// Le second joueur laisse le premier poser un troisième pion en bas avec les deux extrémités libres
+	game := []int{3, 3, 4, 0}
+	m, err := newTestAnalyzer().Move(game, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Verdict != Blunder || m.Outcome != solver.Loss || m.BestOutcome == solver.Loss || m.Optimal {
+		t.Errorf("%s : %+v, attendu une gaffe", m, m)
+	}
+}
+
+func TestExactMoves(t *testing.T) {
+	a := newTestAnalyzer()
+	moves, err := a.Game(drawnGame[:24])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range moves {
+		if m.Exact != (m.Number > DefaultExactFrom) {
+			t.Errorf("%s : exact %v", m, m.Exact)
+		}
+	}
+
+	// La fin de la partie est nulle quoi qu'on joue : chaque coup est bon
+	for n := 36; n < len(drawnGame); n++ {
+		m, err := a.Move(drawnGame, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !m.Exact || m.Verdict != Good || m.Outcome != solver.Draw {
+			t.Errorf("%s : %+v, attendu un bon coup exact", m, m)
+		}
+	}
+}
+
+func TestBudgetFallsBackToEstimate(t *testing.T) {
+	// Position de 8 pions que le solveur met plus d'un million de positions à résoudre
+	game := []int{3, 2, 3, 3, 4, 4, 4, 4, 0}
+	a := newTestAnalyzer()
+	a.ExactFrom = 8
+	a.Budget = 1 // 1 ns : le solveur n'a pas le temps de conclure
+	m, err := a.Move(game, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Exact {
+		t.Errorf("%s : valeur exacte malgré le budget dépassé", m)
+	}
+	if _, err := a.BestColumn(game[:8]); err != nil {
+		t.Errorf("BestColumn : %v", err)
+	}
+}
+
+func TestMoveErrors(t *testing.T) {
+	a := newTestAnalyzer()
+	if _, err := a.Move(drawnGame, len(drawnGame)); err != ErrNoMove {
+		t.Errorf("coup hors de la partie : %v, attendu %v", err, ErrNoMove)
+	}
+	if _, err := a.Move([]int{0, 0, 0, 0, 0, 0, 0}, 6); err == nil {
+		t.Error("un coup dans une colonne pleine est accepté")
+	}
+}
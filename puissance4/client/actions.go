@@ -0,0 +1,248 @@
+package client
+
+import (
+	"strings"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// Choix possibles au pierre/feuille/ciseaux.
+const (
+	Rock     = "pierre"
+	Paper    = "papier"
+	Scissors = "ciseaux"
+)
+
+// ListRooms demande la liste des salles, reçue par OnRoomList.
+func (c *Client) ListRooms() error {
+	return c.send(protocol.TypeListRooms, nil)
+}
+
+// CreateRoom crée une salle et y entre. Un mot de passe vide laisse la salle ouverte.
+func (c *Client) CreateRoom(name, password string) error {
+	return c.CreateRoomWith(protocol.CreateRoomPayload{Name: name, Password: password})
+}
+
+// CreateRoomWith crée une salle décrite par room et y entre, par exemple une salle
+// sans indices (room.NoHints) pour une partie classée, ou dont les parties se jouent
+// à une cadence (room.Clock).
+func (c *Client) CreateRoomWith(room protocol.CreateRoomPayload) error {
+	return c.send(protocol.TypeCreateRoom, room)
+}
+
+// Join entre dans la salle id.
+func (c *Client) Join(id int, password string) error {
+	return c.send(protocol.TypeJoinRoom, protocol.RoomRequest{ID: id, Password: password})
+}
+
+// Spectate observe la salle id.
+func (c *Client) Spectate(id int, password string) error {
+	return c.send(protocol.TypeSpectate, protocol.RoomRequest{ID: id, Password: password})
+}
+
+// Leave quitte la salle et revient au lobby.
+func (c *Client) Leave() error {
+	return c.send(protocol.TypeLeaveRoom, nil)
+}
+
+// QuickPlay entre dans la file de partie rapide.
+func (c *Client) QuickPlay() error {
+	return c.send(protocol.TypeQuickPlay, nil)
+}
+
+// CancelQuickPlay quitte la file de partie rapide.
+func (c *Client) CancelQuickPlay() error {
+	return c.send(protocol.TypeCancelQuickPlay, nil)
+}
+
+// AddBot invite un robot du serveur à la place libre de la salle. Un niveau vide
+// laisse le serveur choisir.
+func (c *Client) AddBot(level string) error {
+	return c.send(protocol.TypeAddBot, protocol.AddBotPayload{Level: level})
+}
+
+// Cursor indique à l'adversaire la couleur survolée sur la grille des couleurs.
+func (c *Client) Cursor(color int) error {
+	return c.send(protocol.TypeCursorUpdate, protocol.CursorPayload{Color: color})
+}
+
+// PickColor choisit la couleur color pour la partie.
+func (c *Client) PickColor(color int) error {
+	return c.send(protocol.TypeColor, protocol.ColorPayload{Color: color})
+}
+
+// Shifumi envoie le choix au pierre/feuille/ciseaux (Rock, Paper ou Scissors).
+func (c *Client) Shifumi(choice string) error {
+	return c.send(protocol.TypeSelected, protocol.SelectedPayload{Selected: strings.ToLower(choice)})
+}
+
+// TokenPosition indique à l'adversaire la colonne au-dessus de laquelle se trouve le pion.
+func (c *Client) TokenPosition(column int) error {
+	return c.send(protocol.TypeTokenUpdate, protocol.TokenUpdatePayload{Position: column})
+}
+
+// Play joue un pion dans la colonne column. Le coup est posé aussitôt sur la grille du
+// client, puis retiré si le serveur le refuse (OnMoveRejected).
+func (c *Client) Play(column int) error {
+	c.mu.Lock()
+	if c.spectating {
+		c.mu.Unlock()
+		return ErrSpectator
+	}
+	if c.myToken == engine.NoToken || c.gameOver {
+		c.mu.Unlock()
+		return ErrNoGame
+	}
+	y, err := c.board.Play(c.myToken, column)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	c.myTurn = false
+	if finished, _, _ := c.board.Winner(); finished {
+		c.gameOver = true
+	}
+	c.mu.Unlock()
+
+	return c.send(protocol.TypeMove, protocol.MovePayload{X: column, Y: y})
+}
+
+// Hint signale au serveur que le joueur a demandé un indice pour le coup en cours, ce
+// qui est noté dans l'historique de la partie. L'indice lui-même est calculé localement,
+// par exemple avec le paquet analysis. Elle renvoie ErrNoHints si la salle les interdit.
+func (c *Client) Hint() error {
+	if !c.HintsAllowed() {
+		return ErrNoHints
+	}
+	return c.send(protocol.TypeHint, nil)
+}
+
+// Rematch annonce que le client est prêt pour une nouvelle partie après la fin de
+// la précédente. La partie commence à la réception de OnRematch.
+func (c *Client) Rematch() error {
+	return c.send(protocol.TypeRestartReady, nil)
+}
+
+// Resign abandonne la partie en cours, perdue aussitôt ; le client reste dans la salle
+// et reçoit OnGameOver comme pour toute fin de partie.
+func (c *Client) Resign() error {
+	return c.send(protocol.TypeResign, nil)
+}
+
+// OfferDraw propose la nulle à l'adversaire, qui répond avec AnswerDraw. Si l'adversaire
+// l'avait déjà proposée, la partie se termine aussitôt par une égalité. La proposition
+// tombe dès qu'un coup est joué.
+func (c *Client) OfferDraw() error {
+	return c.send(protocol.TypeOfferDraw, nil)
+}
+
+// AnswerDraw accepte ou refuse la nulle proposée par l'adversaire (OnDrawOffered).
+func (c *Client) AnswerDraw(accept bool) error {
+	return c.send(protocol.TypeAnswerDraw, protocol.AnswerPayload{Accept: accept})
+}
+
+// RequestTakeback demande à l'adversaire de reprendre le dernier coup du client, avec
+// la réponse de l'adversaire s'il a déjà joué. La demande tombe dès qu'un coup est joué ;
+// acceptée, elle est suivie de OnTakeback.
+func (c *Client) RequestTakeback() error {
+	return c.send(protocol.TypeRequestTakeback, nil)
+}
+
+// AnswerTakeback accepte ou refuse la reprise demandée par l'adversaire (OnTakebackRequested).
+func (c *Client) AnswerTakeback(accept bool) error {
+	return c.send(protocol.TypeAnswerTakeback, protocol.AnswerPayload{Accept: accept})
+}
+
+// Chat envoie un message dans le chat de la salle.
+func (c *Client) Chat(text string) error {
+	return c.send(protocol.TypeChat, protocol.ChatMessage{Text: text})
+}
+
+// RequestHistory demande l'historique de la partie, reçu par OnHistory.
+func (c *Client) RequestHistory() error {
+	return c.send(protocol.TypeRequireHistory, nil)
+}
+
+// ListGames demande une page de la liste des parties archivées par le serveur, des plus
+// récentes aux plus anciennes, reçue par OnGameList. limit vaut 0 pour la taille de page
+// par défaut du serveur.
+func (c *Client) ListGames(offset, limit int) error {
+	return c.send(protocol.TypeListGames, protocol.ListGamesRequest{Offset: offset, Limit: limit})
+}
+
+// FetchGame demande la partie archivée numéro id, reçue par OnGameRecord.
+func (c *Client) FetchGame(id int) error {
+	return c.send(protocol.TypeGetGame, protocol.GameRequest{ID: id})
+}
+
+// ImportGame ajoute à l'archive du serveur la partie notée text (voir le paquet notation).
+// La partie archivée, avec son numéro, est reçue par OnGameRecord.
+func (c *Client) ImportGame(text string) error {
+	return c.send(protocol.TypeImportGame, protocol.ImportGamePayload{Notation: text})
+}
+
+// Leaderboard demande les classements des joueurs (cote, séries de victoires, nombre de
+// parties), reçus par OnLeaderboard. limit vaut 0 pour la taille par défaut du serveur.
+func (c *Client) Leaderboard(limit int) error {
+	return c.send(protocol.TypeGetLeaderboard, protocol.LeaderboardRequest{Limit: limit})
+}
+
+// PlayerStats demande les statistiques du joueur name, ou celles du compte du client si
+// name est vide. Elles sont reçues par OnPlayerStats, ou par OnError (code
+// protocol.ErrCodeStats) si le joueur est inconnu.
+func (c *Client) PlayerStats(name string) error {
+	return c.send(protocol.TypeGetPlayerStats, protocol.PlayerStatsRequest{Name: name})
+}
+
+// ListTournaments demande la liste des tournois du serveur, reçue par OnTournamentList.
+func (c *Client) ListTournaments() error {
+	return c.send(protocol.TypeListTournaments, nil)
+}
+
+// CreateTournament crée un tournoi dont le client est l'organisateur et le premier inscrit.
+// Son état est reçu par OnTournament, puis à chaque changement.
+func (c *Client) CreateTournament(tournament protocol.CreateTournamentPayload) error {
+	return c.send(protocol.TypeCreateTournament, tournament)
+}
+
+// JoinTournament inscrit le client au tournoi id. Une fois le tournoi lancé, le serveur
+// place le client dans la salle de chacun de ses matchs.
+func (c *Client) JoinTournament(id int) error {
+	return c.send(protocol.TypeJoinTournament, protocol.TournamentRequest{ID: id})
+}
+
+// LeaveTournament désinscrit le client du tournoi id, ou le fait abandonner s'il est
+// commencé. L'organisateur annule ainsi un tournoi qui n'a pas commencé.
+func (c *Client) LeaveTournament(id int) error {
+	return c.send(protocol.TypeLeaveTournament, protocol.TournamentRequest{ID: id})
+}
+
+// StartTournament lance le tournoi id, organisé par le client.
+func (c *Client) StartTournament(id int) error {
+	return c.send(protocol.TypeStartTournament, protocol.TournamentRequest{ID: id})
+}
+
+// FollowTournament demande l'état du tournoi id, reçu par OnTournament puis à chaque changement.
+func (c *Client) FollowTournament(id int) error {
+	return c.send(protocol.TypeGetTournament, protocol.TournamentRequest{ID: id})
+}
+
+// Login connecte le client au compte name, créé avec le mot de passe password si le
+// pseudo est libre. Elle se fait depuis le lobby ; la réponse est reçue par OnLoggedIn,
+// ou par OnError (code protocol.ErrCodeAccount) si elle est refusée. Le jeton du compte
+// (AccountPayload.Token) permet de se reconnecter ensuite avec LoginToken.
+func (c *Client) Login(name, password string) error {
+	return c.send(protocol.TypeLogin, protocol.LoginPayload{Name: name, Password: password})
+}
+
+// LoginToken connecte le client au compte name avec le jeton reçu lors d'une connexion précédente.
+func (c *Client) LoginToken(name, token string) error {
+	return c.send(protocol.TypeLogin, protocol.LoginPayload{Name: name, Token: token})
+}
+
+// Resume demande la reprise de la session perdue dont le jeton est token. Le résultat
+// est reçu par OnResumed ou OnResumeFailed.
+func (c *Client) Resume(token string) error {
+	return c.send(protocol.TypeResume, protocol.ResumeRequest{Token: token})
+}
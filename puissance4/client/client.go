@@ -0,0 +1,345 @@
+// Package client est un client du serveur puissance 4 sans interface graphique.
+// Il se charge de la connexion, de la poignée de main et du suivi de la partie
+// (grille, tour, salle), et présente les messages du serveur sous forme
+// d'événements typés. Il sert à écrire des robots et des tests d'intégration
+// contre un vrai serveur, et l'interface graphique du jeu repose sur lui.
+//
+// Utilisation typique :
+//
+//	var c *client.Client
+//	c = client.New(conn, client.Handlers{
+//		OnConnected: func(protocol.HelloPayload) { c.QuickPlay() },
+//		OnReady:     func(string) { c.PickColor(3) },
+//		OnTurn:      func() { c.Play(3) },
+//	}, client.Options{})
+//	err := c.Run()
+package client
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// DefaultPort est le port du serveur utilisé lorsque l'adresse n'en précise pas.
+const DefaultPort = "8080"
+
+// dialTimeout est la durée maximale d'une tentative de connexion au serveur.
+const dialTimeout = 10 * time.Second
+
+// Erreurs renvoyées par les méthodes du client.
+var (
+	ErrClosed    = errors.New("connexion au serveur fermée")
+	ErrNoGame    = errors.New("aucune partie en cours")
+	ErrSpectator = errors.New("un spectateur ne peut pas jouer")
+	ErrNoHints   = errors.New("indices interdits dans cette salle")
+)
+
+// Options règle la connexion au serveur.
+type Options struct {
+	Password    string      // Mot de passe du serveur, envoyé lors de la poignée de main
+	Software    string      // Nom du logiciel annoncé au serveur, "client puissance4" par défaut
+	ResumeToken string      // Jeton d'une session perdue à reprendre dès la poignée de main
+	TLS         *tls.Config // Configuration TLS utilisée par Dial, nil pour une connexion en clair
+}
+
+// Client est une connexion à un serveur puissance 4. Ses méthodes peuvent être
+// appelées depuis n'importe quelle goroutine, y compris depuis les gestionnaires.
+type Client struct {
+	conn     net.Conn
+	handlers Handlers
+	options  Options
+
+	writeMu sync.Mutex // Sérialise les écritures sur conn
+
+	mu           sync.Mutex // Protège les champs qui suivent
+	closed       bool
+	id           int
+	token        string
+	grace        time.Duration
+	capabilities []string
+	roomID       int
+	roomName     string
+	spectating   bool
+	noHints      bool                  // Indices interdits dans la salle
+	players      []protocol.PlayerInfo // Joueurs de la salle, avec leur compte et leur cote
+	account      string                // Pseudo du compte du client, vide s'il est anonyme
+	stats        protocol.PlayerStats  // Classement du compte du client
+	board        engine.Board
+	myToken      int  // Pion du client dans la partie en cours, engine.NoToken hors partie
+	myTurn       bool // Indique si c'est au tour du client
+	gameOver     bool
+	opponent     int // ID de l'adversaire, -1 s'il est inconnu
+	firstPlayer  int // ID du gagnant du pierre/feuille/ciseaux, qui commence après une égalité
+	nextStarter  int // ID du joueur qui commencera la prochaine partie
+}
+
+// New crée un client sur la connexion conn, déjà ouverte. Les messages ne sont lus
+// qu'à partir de l'appel à Run.
+func New(conn net.Conn, handlers Handlers, options Options) *Client {
+	if options.Software == "" {
+		options.Software = "client puissance4"
+	}
+	c := &Client{
+		conn:     conn,
+		handlers: handlers,
+		options:  options,
+		id:       -1,
+	}
+	c.resetRoom()
+	return c
+}
+
+// Dial se connecte au serveur address, de la forme hôte[:port], en TLS si options.TLS
+// n'est pas nul, et crée le client correspondant.
+func Dial(address string, handlers Handlers, options Options) (*Client, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), DefaultPort)
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	var err error
+	if options.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, options.TLS)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return New(conn, handlers, options), nil
+}
+
+// Run lit et traite les messages du serveur jusqu'à la fin de la connexion, puis
+// appelle OnDisconnect. Elle renvoie nil si la connexion a été fermée par Close ou
+// Disconnect, l'erreur de lecture sinon.
+func (c *Client) Run() error {
+	reader := bufio.NewReader(c.conn)
+	var err error
+	for {
+		var line string
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		var msg protocol.Message
+		if json.Unmarshal([]byte(strings.TrimSpace(line)), &msg) != nil {
+			continue // Message illisible, ignoré comme le fait le serveur
+		}
+		c.dispatch(msg)
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		err = nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	c.conn.Close()
+
+	if c.handlers.OnDisconnect != nil {
+		c.handlers.OnDisconnect(err)
+	}
+	return err
+}
+
+// Close ferme la connexion sans prévenir le serveur : s'il est en partie, le joueur
+// garde sa place pendant le délai de reprise.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	return c.conn.Close()
+}
+
+// Disconnect annonce au serveur une déconnexion volontaire, qui libère aussitôt la
+// place du joueur, puis ferme la connexion.
+func (c *Client) Disconnect() error {
+	err := c.send(protocol.TypeDisconnect, nil)
+	if closeErr := c.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// send envoie un message au serveur.
+func (c *Client) send(msgType string, payload interface{}) error {
+	data, err := json.Marshal(protocol.Message{Type: msgType, Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.conn.Write(append(data, '\n'))
+	return err
+}
+
+// ID renvoie l'ID attribué par le serveur, -1 avant le message "id".
+func (c *Client) ID() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.id
+}
+
+// SessionToken renvoie le jeton permettant de reprendre la session après une coupure.
+func (c *Client) SessionToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// Grace renvoie le délai pendant lequel le serveur garde la place d'un joueur déconnecté.
+func (c *Client) Grace() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.grace
+}
+
+// Supports indique si le serveur a annoncé la capacité name lors de la poignée de main.
+func (c *Client) Supports(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.supports(name)
+}
+
+// supports indique si le serveur a annoncé la capacité name.
+// Doit être appelée avec c.mu verrouillé.
+func (c *Client) supports(name string) bool {
+	for _, capability := range c.capabilities {
+		if capability == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Room renvoie l'identifiant et le nom de la salle du client, -1 s'il est dans le lobby.
+func (c *Client) Room() (int, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.roomID, c.roomName
+}
+
+// Spectating indique si le client observe une salle.
+func (c *Client) Spectating() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spectating
+}
+
+// HintsAllowed indique si le client peut demander un indice : le serveur les prend en
+// charge et la salle du client ne les interdit pas.
+func (c *Client) HintsAllowed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.roomID != -1 && !c.spectating && !c.noHints && c.supports(protocol.CapabilityHints)
+}
+
+// Account renvoie le pseudo du compte du client et son classement. Le pseudo est vide
+// tant que le client ne s'est pas connecté à un compte avec Login ou LoginToken.
+func (c *Client) Account() (string, protocol.PlayerStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.account, c.stats
+}
+
+// RoomPlayers renvoie les joueurs de la salle du client, avec leur nom et leur cote,
+// tels que le serveur les a annoncés en dernier.
+func (c *Client) RoomPlayers() []protocol.PlayerInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]protocol.PlayerInfo(nil), c.players...)
+}
+
+// Board renvoie une copie de la grille de la partie en cours ou observée.
+func (c *Client) Board() *engine.Board {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	board, _ := engine.FromMoves(c.board.Moves())
+	return board
+}
+
+// Token renvoie le pion du client dans la partie en cours, engine.NoToken hors partie.
+func (c *Client) Token() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.myToken
+}
+
+// MyTurn indique si c'est au tour du client de jouer.
+func (c *Client) MyTurn() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.myTurn
+}
+
+// Opponent renvoie l'ID de l'adversaire, -1 s'il n'est pas encore connu.
+func (c *Client) Opponent() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opponent
+}
+
+// resetRoom oublie la partie en cours, par exemple lorsque l'adversaire quitte la salle.
+// Doit être appelée avec c.mu verrouillé (ou avant que le client ne soit partagé).
+func (c *Client) resetRoom() {
+	c.board.Reset()
+	c.myToken = engine.NoToken
+	c.myTurn = false
+	c.gameOver = false
+	c.opponent = -1
+	c.firstPlayer = -1
+	c.nextStarter = -1
+}
+
+// leaveRoomState ramène le client dans le lobby.
+// Doit être appelée avec c.mu verrouillé.
+func (c *Client) leaveRoomState() {
+	c.roomID = -1
+	c.roomName = ""
+	c.spectating = false
+	c.noHints = false
+	c.players = nil
+	c.resetRoom()
+}
+
+// startGame commence une partie dont starter joue le premier coup et renvoie
+// l'événement correspondant. Doit être appelée avec c.mu verrouillé.
+func (c *Client) startGame(starter int) GameStart {
+	c.board.Reset()
+	c.gameOver = false
+	c.myToken = engine.P2Token
+	if starter == c.id {
+		c.myToken = engine.P1Token
+	}
+	c.myTurn = starter == c.id
+	return GameStart{Starter: starter, Token: c.myToken}
+}
+
+// observe retient l'adversaire id s'il s'agit d'un autre joueur que le client.
+// Doit être appelée avec c.mu verrouillé.
+func (c *Client) observe(id int) {
+	if id >= 0 && id != c.id {
+		c.opponent = id
+	}
+}
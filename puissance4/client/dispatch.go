@@ -0,0 +1,532 @@
+package client
+
+import (
+	"time"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// dispatch met à jour l'état du client d'après le message msg, puis appelle le
+// gestionnaire correspondant. Les messages dont la charge utile est illisible sont ignorés.
+func (c *Client) dispatch(msg protocol.Message) {
+	h := c.handlers
+	if h.OnMessage != nil {
+		h.OnMessage(msg)
+	}
+
+	switch msg.Type {
+	case protocol.TypeID:
+		var payload protocol.IDPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		welcome := Welcome{ID: payload.ID, Token: payload.Token, Grace: time.Duration(payload.Grace) * time.Second}
+		c.mu.Lock()
+		c.id, c.token, c.grace = welcome.ID, welcome.Token, welcome.Grace
+		c.mu.Unlock()
+
+		hello := protocol.NewHello(c.options.Software)
+		hello.Password = c.options.Password
+		c.send(protocol.TypeHello, hello)
+		if c.options.ResumeToken != "" {
+			c.send(protocol.TypeResume, protocol.ResumeRequest{Token: c.options.ResumeToken})
+		}
+		if h.OnWelcome != nil {
+			h.OnWelcome(welcome)
+		}
+
+	case protocol.TypeHello:
+		var payload protocol.HelloPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		if err := protocol.CheckVersion(payload.Version); err != nil {
+			if h.OnRefused != nil {
+				h.OnRefused(err.Error())
+			}
+			c.Close()
+			return
+		}
+		c.mu.Lock()
+		c.capabilities = payload.Capabilities
+		c.mu.Unlock()
+		if h.OnConnected != nil {
+			h.OnConnected(payload)
+		}
+
+	case protocol.TypeError:
+		var payload protocol.ErrorPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		if payload.Fatal() {
+			if h.OnRefused != nil {
+				h.OnRefused(payload.Message)
+			}
+			c.Close()
+			return
+		}
+		if h.OnError != nil {
+			h.OnError(payload)
+		}
+
+	case protocol.TypeRoomList:
+		var payload protocol.RoomListPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnRoomList != nil {
+			h.OnRoomList(payload.Rooms)
+		}
+
+	case protocol.TypeRoomJoined:
+		var payload protocol.RoomJoinedPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		c.leaveRoomState()
+		c.roomID, c.roomName = payload.ID, payload.Name
+		c.noHints = payload.NoHints
+		c.mu.Unlock()
+		c.send(protocol.TypeReady, nil)
+		if h.OnRoomJoined != nil {
+			h.OnRoomJoined(payload)
+		}
+
+	case protocol.TypeRoomLeft:
+		c.mu.Lock()
+		c.leaveRoomState()
+		c.mu.Unlock()
+		if h.OnRoomLeft != nil {
+			h.OnRoomLeft()
+		}
+
+	case protocol.TypeQueuePosition:
+		var payload protocol.QueuePositionPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnQueuePosition != nil {
+			h.OnQueuePosition(payload)
+		}
+
+	case protocol.TypeQueueLeft:
+		if h.OnQueueLeft != nil {
+			h.OnQueueLeft()
+		}
+
+	case protocol.TypeSpectateState:
+		var payload protocol.SpectatePayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		board, err := engine.FromMoves(payload.Moves)
+		if err != nil {
+			board = engine.NewBoard()
+		}
+		c.mu.Lock()
+		c.resetRoom()
+		c.roomID, c.roomName, c.spectating = payload.RoomID, payload.RoomName, true
+		c.board = *board
+		c.gameOver = payload.GameOver
+		c.mu.Unlock()
+		if h.OnSpectate != nil {
+			h.OnSpectate(payload)
+		}
+
+	case protocol.TypeResumed:
+		var payload protocol.ResumePayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		c.resume(payload)
+		c.mu.Unlock()
+		if h.OnResumed != nil {
+			h.OnResumed(payload)
+		}
+
+	case protocol.TypeResumeFailed:
+		var payload protocol.MessagePayload
+		protocol.DecodePayload(msg.Payload, &payload)
+		if h.OnResumeFailed != nil {
+			h.OnResumeFailed(payload.Message)
+		}
+
+	case protocol.TypeOpponentReconnect:
+		var payload protocol.ReconnectingPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnOpponentReconnecting != nil {
+			h.OnOpponentReconnecting(payload.Seconds)
+		}
+
+	case protocol.TypeOpponentReconnected:
+		if h.OnOpponentReconnected != nil {
+			h.OnOpponentReconnected()
+		}
+
+	case protocol.TypeOtherDisconnected:
+		c.mu.Lock()
+		c.resetRoom()
+		c.mu.Unlock()
+		if h.OnOpponentLeft != nil {
+			h.OnOpponentLeft()
+		}
+
+	case protocol.TypeReady:
+		var payload protocol.MessagePayload
+		protocol.DecodePayload(msg.Payload, &payload)
+		if h.OnReady != nil {
+			h.OnReady(payload.Message)
+		}
+
+	case protocol.TypeCursorUpdate:
+		var payload protocol.CursorPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnCursor != nil {
+			h.OnCursor(payload.Color)
+		}
+
+	case protocol.TypeColor:
+		var payload protocol.ColorPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		c.observe(payload.ID)
+		c.mu.Unlock()
+		if h.OnColor != nil {
+			h.OnColor(payload)
+		}
+
+	case protocol.TypeColorSelectComplete:
+		var payload protocol.ColorSelectCompletePayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnColorsComplete != nil {
+			h.OnColorsComplete(payload.FirstPlayer)
+		}
+
+	case protocol.TypeShifumiResult:
+		var payload protocol.ShifumiResultPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		result := c.shifumi(payload.Player1, payload.Player2)
+		c.mu.Unlock()
+		result.Draw, result.Winner = true, -1
+		if h.OnShifumi != nil {
+			h.OnShifumi(result)
+		}
+
+	case protocol.TypeShifumiComplete:
+		var payload protocol.ShifumiCompletePayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		result := c.shifumi(payload.Player1, payload.Player2)
+		c.firstPlayer = payload.FirstPlayer
+		start := c.startGame(payload.FirstPlayer)
+		myTurn := c.myTurn
+		c.mu.Unlock()
+		result.Winner = payload.Winner
+		if h.OnShifumi != nil {
+			h.OnShifumi(result)
+		}
+		c.gameStarted(start, myTurn)
+
+	case protocol.TypeTokenUpdate:
+		var payload protocol.TokenUpdatePayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnTokenPosition != nil {
+			h.OnTokenPosition(payload)
+		}
+
+	case protocol.TypeMove:
+		var payload protocol.MovePayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		myTurn := c.applyMove(payload)
+		c.mu.Unlock()
+		if h.OnMove != nil {
+			h.OnMove(payload)
+		}
+		if myTurn && h.OnTurn != nil {
+			h.OnTurn()
+		}
+
+	case protocol.TypeMoveRejected:
+		var payload protocol.MoveRejectedPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		if last, ok := c.board.LastMove(); ok && last.Token == c.myToken && last.X == payload.X {
+			c.board.Undo()
+		}
+		c.myTurn = payload.YourTurn
+		c.mu.Unlock()
+		if h.OnMoveRejected != nil {
+			h.OnMoveRejected(payload)
+		}
+		if payload.YourTurn && h.OnTurn != nil {
+			h.OnTurn()
+		}
+
+	case protocol.TypeGameOver:
+		var payload protocol.GameOverPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		c.gameOver, c.myTurn = true, false
+		// Le perdant commence la partie suivante, le premier joueur en cas d'égalité
+		switch payload.Winner {
+		case -1:
+			c.nextStarter = c.firstPlayer
+		case c.id:
+			c.nextStarter = c.opponent
+		default:
+			c.nextStarter = c.id
+		}
+		c.mu.Unlock()
+		if h.OnGameOver != nil {
+			h.OnGameOver(payload)
+		}
+
+	case protocol.TypeClock:
+		var payload protocol.ClockPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnClock != nil {
+			h.OnClock(payload)
+		}
+
+	case protocol.TypeDrawOffered:
+		var payload protocol.OfferPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnDrawOffered != nil {
+			h.OnDrawOffered(payload)
+		}
+
+	case protocol.TypeDrawDeclined:
+		var payload protocol.OfferPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnDrawDeclined != nil {
+			h.OnDrawDeclined(payload)
+		}
+
+	case protocol.TypeTakebackRequested:
+		var payload protocol.OfferPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnTakebackRequested != nil {
+			h.OnTakebackRequested(payload)
+		}
+
+	case protocol.TypeTakebackDeclined:
+		var payload protocol.OfferPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnTakebackDeclined != nil {
+			h.OnTakebackDeclined(payload)
+		}
+
+	case protocol.TypeTakeback:
+		var payload protocol.TakebackPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		for range payload.Moves {
+			c.board.Undo()
+		}
+		c.myTurn = payload.CurrentTurn == c.id && !c.spectating
+		myTurn := c.myTurn
+		c.mu.Unlock()
+		if h.OnTakeback != nil {
+			h.OnTakeback(payload)
+		}
+		if myTurn && h.OnTurn != nil {
+			h.OnTurn()
+		}
+
+	case protocol.TypeRematchWaiting:
+		var payload protocol.MessagePayload
+		protocol.DecodePayload(msg.Payload, &payload)
+		if h.OnRematchWaiting != nil {
+			h.OnRematchWaiting(payload.Message)
+		}
+
+	case protocol.TypeRestartOK:
+		var payload protocol.MessagePayload
+		protocol.DecodePayload(msg.Payload, &payload)
+		c.mu.Lock()
+		start := c.startGame(c.nextStarter)
+		myTurn := c.myTurn
+		c.mu.Unlock()
+		if h.OnRematch != nil {
+			h.OnRematch(payload.Message)
+		}
+		c.gameStarted(start, myTurn)
+
+	case protocol.TypeChat:
+		var payload protocol.ChatMessage
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnChat != nil {
+			h.OnChat(payload)
+		}
+
+	case protocol.TypeSentHistory:
+		var payload protocol.HistoryPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnHistory != nil {
+			h.OnHistory(payload)
+		}
+
+	case protocol.TypeGameList:
+		var payload protocol.GameListPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnGameList != nil {
+			h.OnGameList(payload)
+		}
+
+	case protocol.TypeGameRecord:
+		var payload protocol.GameRecord
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnGameRecord != nil {
+			h.OnGameRecord(payload)
+		}
+
+	case protocol.TypeLoggedIn:
+		var payload protocol.AccountPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		c.account, c.stats = payload.Name, payload.Stats
+		c.mu.Unlock()
+		if h.OnLoggedIn != nil {
+			h.OnLoggedIn(payload)
+		}
+
+	case protocol.TypeRatingUpdate:
+		var payload protocol.RatingUpdatePayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		c.stats = payload.Stats
+		c.mu.Unlock()
+		if h.OnRatingUpdate != nil {
+			h.OnRatingUpdate(payload)
+		}
+
+	case protocol.TypeRoomPlayers:
+		var payload protocol.RoomPlayersPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			return
+		}
+		c.mu.Lock()
+		c.players = payload.Players
+		c.mu.Unlock()
+		if h.OnRoomPlayers != nil {
+			h.OnRoomPlayers(payload.Players)
+		}
+
+	case protocol.TypeLeaderboard:
+		var payload protocol.LeaderboardPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnLeaderboard != nil {
+			h.OnLeaderboard(payload)
+		}
+
+	case protocol.TypePlayerStats:
+		var payload protocol.PlayerProfile
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnPlayerStats != nil {
+			h.OnPlayerStats(payload)
+		}
+
+	case protocol.TypeTournamentList:
+		var payload protocol.TournamentListPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnTournamentList != nil {
+			h.OnTournamentList(payload)
+		}
+
+	case protocol.TypeTournament:
+		var payload protocol.TournamentPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && h.OnTournament != nil {
+			h.OnTournament(payload)
+		}
+	}
+}
+
+// gameStarted appelle les gestionnaires du début d'une partie.
+func (c *Client) gameStarted(start GameStart, myTurn bool) {
+	if c.handlers.OnGameStart != nil {
+		c.handlers.OnGameStart(start)
+	}
+	if myTurn && c.handlers.OnTurn != nil {
+		c.handlers.OnTurn()
+	}
+}
+
+// shifumi retient l'adversaire d'après les choix d'une manche de pierre/feuille/ciseaux
+// et renvoie le résultat vu par le client. Doit être appelée avec c.mu verrouillé.
+func (c *Client) shifumi(players ...protocol.ShifumiPlayer) Shifumi {
+	var result Shifumi
+	for _, p := range players {
+		if p.ID == c.id {
+			result.Mine = p.Selection
+		} else {
+			result.Theirs = p.Selection
+			c.observe(p.ID)
+		}
+	}
+	return result
+}
+
+// applyMove pose sur la grille un coup relayé par le serveur et indique si c'est
+// ensuite au tour du client. Doit être appelée avec c.mu verrouillé.
+func (c *Client) applyMove(move protocol.MovePayload) bool {
+	token := move.Token
+	if !c.spectating {
+		if c.myToken == engine.NoToken || move.ID == c.id {
+			return false
+		}
+		c.observe(move.ID)
+		token = engine.Opponent(c.myToken)
+	}
+	if _, err := c.board.Play(token, move.X); err != nil {
+		return false
+	}
+	if finished, _, _ := c.board.Winner(); finished {
+		c.gameOver = true
+		c.myTurn = false
+		return false
+	}
+	c.myTurn = !c.spectating
+	return c.myTurn
+}
+
+// resume rétablit l'état d'une session reprise. Doit être appelée avec c.mu verrouillé.
+func (c *Client) resume(state protocol.ResumePayload) {
+	c.leaveRoomState()
+	c.id, c.token = state.ID, state.Token
+	c.roomID, c.roomName = state.RoomID, state.RoomName
+	c.noHints = state.NoHints
+	c.firstPlayer = state.FirstPlayer
+	if state.Phase != protocol.PhasePlaying && state.Phase != protocol.PhaseOver {
+		return
+	}
+
+	c.myToken = engine.P2Token
+	if state.FirstPlayer == c.id {
+		c.myToken = engine.P1Token
+	}
+	// Le premier joueur de la partie pose les pions P1Token
+	for _, m := range state.Moves {
+		token := engine.P2Token
+		if m.ID == state.FirstPlayer {
+			token = engine.P1Token
+		}
+		c.observe(m.ID)
+		if _, err := c.board.Play(token, m.X); err != nil {
+			break
+		}
+	}
+	c.myTurn = state.YourTurn
+	c.gameOver = state.GameOver
+	if c.gameOver {
+		switch finished, result, _ := c.board.Winner(); {
+		case !finished || result == engine.Equality:
+			c.nextStarter = c.firstPlayer
+		case result == c.myToken:
+			c.nextStarter = c.opponent
+		default:
+			c.nextStarter = c.id
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"time"
+
+	"puissance4/protocol"
+)
+
+// Welcome décrit la connexion attribuée par le serveur (message "id").
+type Welcome struct {
+	ID    int           // ID attribué à la connexion
+	Token string        // Jeton de reprise de session
+	Grace time.Duration // Délai pendant lequel une session perdue peut être reprise
+}
+
+// Shifumi est le résultat d'une manche de pierre/feuille/ciseaux.
+type Shifumi struct {
+	Draw   bool   // Égalité : une nouvelle manche commence
+	Winner int    // ID du gagnant, qui commence la partie ; -1 en cas d'égalité
+	Mine   string // Choix du client
+	Theirs string // Choix de l'adversaire
+}
+
+// GameStart annonce le début d'une partie, après le pierre/feuille/ciseaux ou un rematch.
+type GameStart struct {
+	Starter int // ID du joueur qui joue le premier coup
+	Token   int // Pion du client (engine.P1Token s'il commence, engine.P2Token sinon)
+}
+
+// Handlers regroupe les fonctions appelées à la réception des messages du serveur.
+// Toutes sont facultatives. Elles sont appelées l'une après l'autre par la goroutine
+// de Run, dans l'ordre des messages, et peuvent appeler les méthodes du Client.
+// L'état du client (grille, tour, salle) est déjà à jour lorsqu'elles sont appelées.
+type Handlers struct {
+	OnMessage func(msg protocol.Message) // Tout message reçu, avant son gestionnaire
+
+	OnWelcome    func(w Welcome)                     // ID et jeton reçus ; la poignée de main est envoyée
+	OnConnected  func(hello protocol.HelloPayload)   // Poignée de main acceptée par le serveur
+	OnRefused    func(reason string)                 // Connexion refusée (version, mot de passe) ; le client se ferme
+	OnError      func(payload protocol.ErrorPayload) // Message refusé par le serveur
+	OnDisconnect func(err error)                     // Fin de la connexion, err nul après Close ou Disconnect
+
+	OnRoomList      func(rooms []protocol.RoomInfo)              // Liste des salles
+	OnRoomJoined    func(room protocol.RoomJoinedPayload)        // Arrivée dans une salle ; "ready" est envoyé
+	OnRoomLeft      func()                                       // Retour au lobby
+	OnQueuePosition func(position protocol.QueuePositionPayload) // Position dans la file de partie rapide
+	OnQueueLeft     func()                                       // Sortie de la file de partie rapide
+	OnSpectate      func(state protocol.SpectatePayload)         // État complet de la salle observée
+
+	OnResumed              func(state protocol.ResumePayload) // Session reprise
+	OnResumeFailed         func(message string)               // Reprise impossible, le client est dans le lobby
+	OnOpponentReconnecting func(seconds int)                  // Adversaire déconnecté, place conservée
+	OnOpponentReconnected  func()                             // Adversaire reconnecté
+	OnOpponentLeft         func()                             // Adversaire parti, la salle attend un autre joueur
+
+	OnReady          func(message string)              // Deux joueurs présents : choix des couleurs
+	OnCursor         func(color int)                   // Couleur survolée par l'adversaire
+	OnColor          func(color protocol.ColorPayload) // Couleur choisie par l'adversaire
+	OnColorsComplete func(firstPlayer int)             // Couleurs choisies : pierre/feuille/ciseaux
+	OnShifumi        func(result Shifumi)              // Résultat d'une manche de pierre/feuille/ciseaux
+
+	OnGameStart      func(start GameStart)                       // Début d'une partie
+	OnTurn           func()                                      // Au tour du client de jouer
+	OnTokenPosition  func(update protocol.TokenUpdatePayload)    // Position du pion de l'adversaire au-dessus de la grille
+	OnMove           func(move protocol.MovePayload)             // Coup reçu : celui de l'adversaire, ou de l'un des joueurs pour un spectateur
+	OnMoveRejected   func(rejected protocol.MoveRejectedPayload) // Coup du client refusé, déjà retiré de la grille
+	OnGameOver       func(over protocol.GameOverPayload)         // Fin de partie
+	OnRematchWaiting func(message string)                        // Adversaire en attente de rematch
+	OnRematch        func(message string)                        // Rematch accepté, suivi de OnGameStart
+	OnChat           func(message protocol.ChatMessage)          // Message du chat
+	OnHistory        func(history protocol.HistoryPayload)       // Historique de la partie
+	OnClock          func(clock protocol.ClockPayload)           // Pendules des joueurs, à chaque changement de trait dans une salle avec une cadence
+
+	OnDrawOffered       func(offer protocol.OfferPayload)       // Nulle proposée par l'adversaire, ou par le client lui-même
+	OnDrawDeclined      func(offer protocol.OfferPayload)       // Proposition de nulle refusée
+	OnTakebackRequested func(request protocol.OfferPayload)     // Reprise demandée par l'adversaire, ou par le client lui-même
+	OnTakebackDeclined  func(request protocol.OfferPayload)     // Demande de reprise refusée
+	OnTakeback          func(takeback protocol.TakebackPayload) // Reprise acceptée, coups déjà retirés de la grille
+
+	OnGameList   func(list protocol.GameListPayload) // Page de la liste des parties archivées
+	OnGameRecord func(game protocol.GameRecord)      // Partie archivée demandée avec FetchGame ou importée avec ImportGame
+
+	OnLoggedIn     func(account protocol.AccountPayload)     // Connexion au compte acceptée
+	OnRatingUpdate func(update protocol.RatingUpdatePayload) // Nouvelle cote après une partie classée
+	OnRoomPlayers  func(players []protocol.PlayerInfo)       // Noms et cotes des joueurs de la salle
+
+	OnLeaderboard func(board protocol.LeaderboardPayload) // Classements demandés avec Leaderboard
+	OnPlayerStats func(profile protocol.PlayerProfile)    // Statistiques d'un joueur demandées avec PlayerStats
+
+	OnTournamentList func(list protocol.TournamentListPayload)   // Liste des tournois demandée avec ListTournaments
+	OnTournament     func(tournament protocol.TournamentPayload) // État d'un tournoi auquel le client participe ou qu'il suit
+}
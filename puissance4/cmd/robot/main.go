@@ -0,0 +1,188 @@
+// Commande robot : joueur automatique qui se connecte à un serveur puissance 4.
+//
+// Le robot entre dans la file de partie rapide, ou dans la salle indiquée par -salle,
+// choisit sa couleur et son coup au pierre/feuille/ciseaux au hasard, puis joue avec
+// l'adversaire minimax du paquet ai. Il enchaîne les rematchs jusqu'au nombre de
+// parties demandé et se déconnecte si son adversaire s'en va. Il refuse les nulles
+// qu'on lui propose et accepte de laisser reprendre un coup.
+//
+// Utilisation :
+//
+//	robot [-niveau moyen] [-salle id] [-parties n] [-mot-de-passe mdp] [-tls] hôte[:port]
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"puissance4/ai"
+	"puissance4/client"
+	"puissance4/protocol"
+)
+
+// colorCount est le nombre de couleurs proposées aux joueurs.
+const colorCount = 9
+
+// selections sont les choix possibles au pierre/feuille/ciseaux.
+var selections = []string{client.Rock, client.Paper, client.Scissors}
+
+func main() {
+	levelName := flag.String("niveau", ai.Medium.String(), "niveau du robot (facile, moyen, difficile, expert ou 0 à 3)")
+	roomID := flag.Int("salle", -1, "salle à rejoindre, -1 pour la partie rapide")
+	roomPassword := flag.String("mot-de-passe-salle", "", "mot de passe de la salle")
+	games := flag.Int("parties", 1, "nombre de parties à jouer avant de se déconnecter")
+	password := flag.String("mot-de-passe", "", "mot de passe du serveur")
+	useTLS := flag.Bool("tls", false, "connexion chiffrée par TLS")
+	insecure := flag.Bool("tls-non-verifie", false, "accepte un certificat non vérifié (serveur lancé avec -tls-dev)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Utilisation : %s [options] hôte[:port]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	level, err := ai.ParseLevel(*levelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := client.Options{Password: *password, Software: "robot puissance4"}
+	if *useTLS {
+		options.TLS = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: *insecure}
+	}
+
+	r := &robot{
+		player:       ai.NewPlayer(level, time.Now().UnixNano()),
+		rng:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		roomID:       *roomID,
+		roomPassword: *roomPassword,
+		games:        *games,
+		opponent:     -1,
+	}
+	r.client, err = client.Dial(flag.Arg(0), r.handlers(), options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := r.client.Run(); err != nil {
+		log.Fatal(err)
+	}
+	if r.failed {
+		os.Exit(1)
+	}
+}
+
+// robot est l'état d'un joueur automatique connecté au serveur.
+type robot struct {
+	client       *client.Client
+	player       *ai.Player
+	rng          *rand.Rand
+	roomID       int
+	roomPassword string
+	games        int // Parties restant à jouer
+	opponent     int // Couleur de l'adversaire, à ne pas choisir
+	failed       bool
+}
+
+// handlers associe les événements du client aux réactions du robot.
+func (r *robot) handlers() client.Handlers {
+	return client.Handlers{
+		OnConnected: func(hello protocol.HelloPayload) {
+			log.Printf("Connecté à %s\n", hello.Software)
+			if r.roomID >= 0 {
+				r.client.Join(r.roomID, r.roomPassword)
+			} else {
+				r.client.QuickPlay()
+			}
+		},
+		OnRefused: func(reason string) {
+			log.Printf("Connexion refusée : %s\n", reason)
+			r.failed = true
+		},
+		OnError: func(payload protocol.ErrorPayload) {
+			log.Printf("Erreur du serveur : %s\n", payload.Message)
+			// Sans salle, le robot n'a plus rien à faire
+			if id, _ := r.client.Room(); id == -1 {
+				r.failed = true
+				r.client.Disconnect()
+			}
+		},
+		OnRoomJoined: func(room protocol.RoomJoinedPayload) {
+			log.Printf("Salle %d (%s) rejointe\n", room.ID, room.Name)
+		},
+		OnColor: func(color protocol.ColorPayload) {
+			r.opponent = color.Color
+		},
+		OnReady: func(string) {
+			color := r.rng.Intn(colorCount)
+			for color == r.opponent {
+				color = r.rng.Intn(colorCount)
+			}
+			r.client.PickColor(color)
+		},
+		OnColorsComplete: func(int) { r.shifumi() },
+		OnShifumi: func(result client.Shifumi) {
+			if result.Draw {
+				r.shifumi()
+			}
+		},
+		OnTurn: r.play,
+		OnMoveRejected: func(rejected protocol.MoveRejectedPayload) {
+			log.Printf("Coup refusé en colonne %d : %s\n", rejected.X, rejected.Reason)
+		},
+		OnDrawOffered: func(offer protocol.OfferPayload) {
+			if offer.ID != r.client.ID() {
+				r.client.AnswerDraw(false)
+			}
+		},
+		OnTakebackRequested: func(request protocol.OfferPayload) {
+			if request.ID != r.client.ID() {
+				r.client.AnswerTakeback(true)
+			}
+		},
+		OnGameOver: func(over protocol.GameOverPayload) {
+			switch over.Winner {
+			case -1:
+				log.Println("Égalité")
+			case r.client.ID():
+				log.Println("Partie gagnée")
+			default:
+				log.Println("Partie perdue")
+			}
+			r.games--
+			if r.games > 0 {
+				r.client.Rematch()
+			} else {
+				r.client.Disconnect()
+			}
+		},
+		OnOpponentLeft: func() {
+			log.Println("L'adversaire est parti")
+			r.client.Disconnect()
+		},
+	}
+}
+
+// shifumi envoie un choix au hasard au pierre/feuille/ciseaux.
+func (r *robot) shifumi() {
+	r.client.Shifumi(selections[r.rng.Intn(len(selections))])
+}
+
+// play calcule et joue le coup du robot.
+func (r *robot) play() {
+	x, err := r.player.ChooseMove(r.client.Board(), r.client.Token())
+	if err != nil {
+		log.Printf("Aucun coup possible : %v\n", err)
+		return
+	}
+	if err := r.client.Play(x); err != nil {
+		log.Printf("Coup en colonne %d impossible : %v\n", x, err)
+	}
+}
@@ -0,0 +1,147 @@
+// Commande solveur : calcule la valeur exacte de positions du puissance 4.
+//
+// Chaque position est donnée par la suite des colonnes jouées, numérotées de 1 à 7
+// (par exemple 4453). Les suites sont lues sur la ligne de commande ou, à défaut,
+// sur l'entrée standard à raison d'une par ligne ; seul le premier mot de chaque
+// ligne est lu.
+//
+// Avec -partie, les arguments sont des fichiers de parties notées (paquet notation) dont
+// chaque coup est évalué : les gaffes et les victoires manquées sont signalées, avec la
+// meilleure colonne.
+//
+// Utilisation :
+//
+//	solveur [-analyse] [-faible] [séquence...]
+//	solveur -partie fichier.p4n...
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"puissance4/analysis"
+	"puissance4/notation"
+	"puissance4/solver"
+)
+
+func main() {
+	analyse := flag.Bool("analyse", false, "affiche la valeur de chaque coup jouable")
+	weak := flag.Bool("faible", false, "calcule seulement l'issue (victoire, nulle ou défaite), plus rapidement")
+	game := flag.Bool("partie", false, "évalue chaque coup des parties notées données en argument")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Utilisation : %s [-analyse] [-faible] [séquence...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s -partie fichier.p4n...\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Une séquence est la suite des colonnes jouées, de 1 à 7, par exemple 4453.")
+		fmt.Fprintln(flag.CommandLine.Output(), "Sans séquence, elles sont lues sur l'entrée standard, une par ligne.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *game {
+		failed := false
+		for _, path := range flag.Args() {
+			if err := analyseGame(path); err != nil {
+				fmt.Fprintf(os.Stderr, "%s : %v\n", path, err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	s := solver.New()
+	failed := false
+	solveAll := func(sequence string) {
+		if err := solveOne(s, sequence, *analyse, *weak); err != nil {
+			fmt.Fprintf(os.Stderr, "%s : %v\n", sequence, err)
+			failed = true
+		}
+	}
+
+	if flag.NArg() > 0 {
+		for _, sequence := range flag.Args() {
+			solveAll(sequence)
+		}
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+				solveAll(fields[0])
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintln(os.Stderr, "Erreur de lecture :", err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// solveOne affiche la valeur de la position décrite par sequence.
+func solveOne(s *solver.Solver, sequence string, analyse, weak bool) error {
+	p, err := solver.ParseMoves(sequence)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	nodes := s.Nodes()
+	if weak {
+		outcome, err := s.SolveWeak(p)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\t%d positions\t%v\n", sequence, outcome, s.Nodes()-nodes, time.Since(start).Round(time.Microsecond))
+		return nil
+	}
+
+	best, err := s.BestMove(p)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\t%s\tmeilleur coup : %d\t%d positions\t%v\n", sequence, best.Result, best.Column+1, s.Nodes()-nodes, time.Since(start).Round(time.Microsecond))
+
+	if analyse {
+		// Les positions ont déjà été explorées par BestMove : la table de transposition rend ce calcul rapide
+		results, err := s.Analyze(p)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			fmt.Printf("  colonne %d : %s\n", r.Column+1, r.Result)
+		}
+	}
+	return nil
+}
+
+// analyseGame affiche l'évaluation de chaque coup de la partie notée du fichier path.
+func analyseGame(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	game, err := notation.Parse(string(data))
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	moves, err := analysis.New().Game(game.Columns)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\t%s\t%v\n", path, game.Compact(), time.Since(start).Round(time.Millisecond))
+	for _, m := range moves {
+		fmt.Printf("  %s\n", m)
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// frame accumule les lignes d'un écran avant de l'afficher d'un seul coup.
+type frame struct {
+	strings.Builder
+}
+
+// line ajoute une ligne à l'écran.
+func (f *frame) line(format string, args ...interface{}) {
+	fmt.Fprintf(f, format, args...)
+	f.WriteString(ansiReset + ansiClearLine + "\n")
+}
+
+// draw affiche l'écran courant. Les lignes sont réécrites en place plutôt qu'après
+// un effacement complet, pour éviter le scintillement.
+func (u *ui) draw(out io.Writer) {
+	var f frame
+	f.WriteString(ansiHome)
+	f.line(" %sPUISSANCE 4%s %s", ansiBold+ansiYellow, ansiReset, u.title())
+	f.line("")
+
+	switch u.screen {
+	case addressScreen:
+		u.drawAddress(&f)
+	case connectingScreen:
+		f.line(" Connexion à %s...", u.address)
+	case lobbyScreen:
+		u.drawLobby(&f)
+	case waitingScreen:
+		u.drawWaiting(&f)
+	case colorScreen:
+		u.drawColors(&f)
+	case shifumiScreen:
+		u.drawShifumi(&f)
+	case playScreen, resultScreen:
+		u.drawGame(&f)
+	case spectatorScreen:
+		u.drawSpectator(&f)
+	}
+
+	f.line("")
+	if u.error != "" {
+		f.line(" %s%s", ansiRed, u.error)
+	} else {
+		f.line(" %s%s", ansiGreen, u.status)
+	}
+	if !u.opponentReconnects.IsZero() {
+		remaining := max(int(time.Until(u.opponentReconnects).Seconds()), 0)
+		f.line(" %sEn attente de la reconnexion de l'adversaire : %ds", ansiYellow, remaining)
+	}
+	f.line(" %s%s", ansiDim, u.help())
+
+	if u.inRoom() {
+		f.line("")
+		for _, message := range u.chat {
+			f.line(" %s", message)
+		}
+		switch u.input {
+		case chatInput:
+			f.line(" %s>%s %s%s_", ansiCyan, ansiReset, u.inputText, ansiBlink)
+		default:
+			f.line(" %sTab : écrire dans le chat", ansiDim)
+		}
+	}
+	if u.input == roomPasswordInput {
+		f.line(" Mot de passe de la salle : %s%s_", strings.Repeat("*", len([]rune(u.inputText))), ansiBlink)
+	}
+	f.WriteString(ansiClearBelow)
+	io.WriteString(out, f.String())
+}
+
+// title renvoie le texte affiché à droite du titre : salle et score.
+func (u *ui) title() string {
+	switch u.screen {
+	case colorScreen, shifumiScreen, playScreen, resultScreen:
+		return fmt.Sprintf("- %s - Vous %d : %d Adversaire", u.roomName, u.wins, u.opponentWins)
+	case waitingScreen, spectatorScreen:
+		return "- " + u.roomName
+	}
+	return ""
+}
+
+// help renvoie l'aide des touches de l'écran courant.
+func (u *ui) help() string {
+	switch u.screen {
+	case addressScreen:
+		return "Entrée : se connecter   Echap : quitter"
+	case lobbyScreen:
+		return "Haut/Bas : choisir   Entrée : rejoindre   C : créer   Q : partie rapide   S : observer   P : mot de passe   Echap : déconnexion"
+	case waitingScreen:
+		if u.inQueue {
+			return "Echap : quitter la file"
+		}
+		return "B : jouer contre un robot du serveur   Echap : quitter la salle"
+	case colorScreen:
+		return "Flèches : choisir   Entrée : valider   Echap : quitter la salle"
+	case shifumiScreen:
+		return "Gauche/Droite : choisir   Entrée : valider"
+	case playScreen:
+		switch {
+		case u.drawOffered || u.takebackAsked:
+			return "O : accepter   N : refuser"
+		case u.client.Supports(protocol.CapabilityOffers):
+			return "Gauche/Droite : déplacer   Entrée/Bas : jouer   A : abandonner   D : proposer la nulle   R : reprendre un coup   Echap : quitter la salle"
+		}
+		return "Gauche/Droite : déplacer   Entrée/Bas : jouer   Echap : abandonner"
+	case resultScreen:
+		return "Entrée : rejouer   Echap : quitter la salle"
+	case spectatorScreen:
+		return "Echap : revenir au lobby"
+	}
+	return ""
+}
+
+func (u *ui) drawAddress(f *frame) {
+	f.line(" Adresse du serveur, de la forme [tls://][motdepasse@]hôte[:port] :")
+	f.line("")
+	f.line(" %s>%s %s%s_", ansiCyan, ansiReset, maskPassword(u.inputText), ansiBlink)
+}
+
+// maskPassword remplace par des étoiles le mot de passe d'une adresse en cours de saisie.
+func maskPassword(input string) string {
+	i := strings.LastIndex(input, "@")
+	if i < 0 {
+		return input
+	}
+	start := 0
+	if strings.HasPrefix(strings.ToLower(input), "tls://") {
+		start = len("tls://")
+	}
+	if i < start {
+		return input
+	}
+	return input[:start] + strings.Repeat("*", len([]rune(input[start:i]))) + input[i:]
+}
+
+func (u *ui) drawLobby(f *frame) {
+	if len(u.rooms) == 0 {
+		f.line(" Aucune salle ouverte : Entrée pour en créer une.")
+		return
+	}
+	f.line(" %s  %-24s %-8s %-11s", ansiBold, "Salle", "Joueurs", "Spectateurs")
+	for i, room := range u.rooms {
+		style, marker := "", "  "
+		if i == u.selectedRoom {
+			style, marker = ansiReverse, "> "
+		}
+		lock := ""
+		if room.Locked {
+			lock = " (verrouillée)"
+		}
+		f.line(" %s%s%-24s %d/%-6d %-11d%s", style, marker, truncate(room.Name, 24), room.Players, room.MaxPlayers, room.Spectators, lock)
+	}
+}
+
+// truncate coupe s à n caractères.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func (u *ui) drawWaiting(f *frame) {
+	if u.inQueue {
+		f.line(" Recherche d'un adversaire...")
+		if u.queue.Position > 0 {
+			f.line(" Position %d sur %d, attente estimée %ds", u.queue.Position, u.queue.Waiting, u.queue.EstimatedWait)
+		}
+		return
+	}
+	f.line(" En attente d'un adversaire...")
+}
+
+func (u *ui) drawColors(f *frame) {
+	f.line(" Choisissez la couleur de vos pions :")
+	f.line("")
+	for line := 0; line < colorsPerLine; line++ {
+		var row strings.Builder
+		for col := 0; col < colorsPerLine; col++ {
+			color := line*colorsPerLine + col
+			left, right := " ", " "
+			if color == u.cursor {
+				left, right = "[", "]"
+			}
+			mark := "  "
+			switch {
+			case color == u.opponentColor:
+				mark = ansiRed + " x"
+			case color == u.opponentCursor:
+				mark = ansiDim + " ·"
+			}
+			fmt.Fprintf(&row, "  %s%s●%s%s%s %-14s", left, tokenColor(color), ansiReset, right, mark+ansiReset, tokenColorNames[color])
+		}
+		f.line("%s", row.String())
+	}
+	f.line("")
+	if u.myColor >= 0 {
+		f.line(" Votre couleur : %s●%s %s", tokenColor(u.myColor), ansiReset, tokenColorNames[u.myColor])
+	}
+	if u.opponentColor >= 0 {
+		f.line(" Couleur de l'adversaire : %s●%s %s", tokenColor(u.opponentColor), ansiReset, tokenColorNames[u.opponentColor])
+	} else {
+		f.line(" %s· : couleur survolée par l'adversaire", ansiDim)
+	}
+}
+
+func (u *ui) drawShifumi(f *frame) {
+	f.line(" Pierre, papier ou ciseaux ?")
+	f.line("")
+	var row strings.Builder
+	for i, choice := range shifumiChoices {
+		if i == u.shifumiChoice {
+			fmt.Fprintf(&row, "   %s %s %s", ansiReverse, choice, ansiReset)
+		} else {
+			fmt.Fprintf(&row, "    %s ", choice)
+		}
+	}
+	f.line("%s", row.String())
+}
+
+// drawGame affiche la partie du joueur : le pion au-dessus de la grille, la grille et le tour.
+func (u *ui) drawGame(f *frame) {
+	board := u.client.Board()
+	mine, theirs := u.client.Token(), engine.Opponent(u.client.Token())
+	colors := map[int]int{mine: u.myColor, theirs: u.opponentColor}
+
+	// Pion de celui dont c'est le tour, au-dessus de la colonne visée
+	above := -1
+	aboveColor := u.myColor
+	if u.screen == playScreen {
+		if u.client.MyTurn() {
+			above = u.column
+		} else {
+			above, aboveColor = u.opponentColumn, u.opponentColor
+		}
+	}
+	u.drawBoard(f, board, colors, above, aboveColor, u.gameOver.Cells)
+
+	f.line("")
+	switch {
+	case u.screen == resultScreen && u.rematchSent:
+		f.line(" En attente de l'adversaire pour rejouer...")
+	case u.screen == resultScreen && u.opponentRematch:
+		f.line(" L'adversaire veut rejouer.")
+	case u.screen == resultScreen:
+		f.line("")
+	case u.client.MyTurn():
+		f.line(" %sÀ vous de jouer%s (%s●%s)", ansiBold, ansiReset, tokenColor(u.myColor), ansiReset)
+	default:
+		f.line(" Au tour de l'adversaire (%s●%s)", tokenColor(u.opponentColor), ansiReset)
+	}
+}
+
+// drawSpectator affiche la partie observée et le nom des deux joueurs.
+func (u *ui) drawSpectator(f *frame) {
+	board := u.client.Board()
+	colors := map[int]int{}
+	var names []string
+	for _, p := range u.spectate.Players {
+		colors[p.Token] = p.Color
+		names = append(names, fmt.Sprintf("%s%s●%s %s", ansiReset, tokenColor(p.Color), ansiReset, p.Name))
+	}
+
+	above, aboveColor := -1, -1
+	if p, ok := u.spectatePlayer(u.spectateTurnToken()); ok && !u.spectate.GameOver {
+		above, aboveColor = u.column, p.Color
+		if p.Token == engine.P2Token {
+			above = u.opponentColumn
+		}
+	}
+	var cells [][2]int
+	if u.spectate.GameOver {
+		if last, ok := board.LastMove(); ok {
+			_, _, cells = board.CheckEnd(last.X, last.Y)
+		}
+	}
+	u.drawBoard(f, board, colors, above, aboveColor, cells)
+
+	f.line("")
+	f.line(" %s", strings.Join(names, "   contre   "))
+	switch {
+	case len(u.spectate.Players) < 2:
+		f.line(" En attente des joueurs")
+	case u.spectate.GameOver:
+		message := "Partie terminée"
+		if _, result, _ := board.Winner(); result == engine.Equality {
+			message = "Égalité"
+		} else if p, ok := u.spectatePlayer(result); ok {
+			message = p.Name + " a gagné !"
+		}
+		f.line(" %s", message)
+	case u.spectate.CurrentTurn == -1:
+		f.line(" Partie en préparation")
+	default:
+		f.line("")
+	}
+}
+
+// spectateTurnToken renvoie le pion du joueur observé dont c'est le tour.
+func (u *ui) spectateTurnToken() int {
+	for _, p := range u.spectate.Players {
+		if p.ID == u.spectate.CurrentTurn {
+			return p.Token
+		}
+	}
+	return engine.NoToken
+}
+
+// drawBoard affiche la grille board, avec les couleurs colors de chaque pion, un pion de
+// couleur aboveColor au-dessus de la colonne above (aucun si above vaut -1) et les cases
+// de l'alignement gagnant cells en clignotant.
+func (u *ui) drawBoard(f *frame, board *engine.Board, colors map[int]int, above, aboveColor int, cells [][2]int) {
+	var row strings.Builder
+	row.WriteString("   ")
+	for x := 0; x < engine.Columns; x++ {
+		if x == above {
+			fmt.Fprintf(&row, " %s●%s ", tokenColor(aboveColor), ansiReset)
+		} else {
+			row.WriteString("   ")
+		}
+	}
+	f.line("%s", row.String())
+
+	winning := map[[2]int]bool{}
+	for _, cell := range cells {
+		winning[cell] = true
+	}
+	for y := 0; y < engine.Rows; y++ {
+		row.Reset()
+		row.WriteString("   " + ansiGridColor)
+		for x := 0; x < engine.Columns; x++ {
+			token := board.Cell(x, y)
+			switch {
+			case token == engine.NoToken:
+				row.WriteString(ansiDim + " · " + ansiReset + ansiGridColor)
+			case winning[[2]int{x, y}] && u.blink:
+				row.WriteString(" " + ansiBrightWhite + "◆" + ansiReset + ansiGridColor + " ")
+			default:
+				row.WriteString(" " + tokenColor(colors[token]) + "●" + ansiReset + ansiGridColor + " ")
+			}
+		}
+		f.line("%s", row.String())
+	}
+	row.Reset()
+	row.WriteString("   ")
+	for x := 1; x <= engine.Columns; x++ {
+		fmt.Fprintf(&row, " %d ", x)
+	}
+	f.line("%s%s", ansiDim, row.String())
+}
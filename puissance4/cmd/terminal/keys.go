@@ -0,0 +1,294 @@
+package main
+
+import (
+	"unicode"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// handleKey applique la touche k à l'écran courant.
+func (u *ui) handleKey(k key) {
+	if u.input != noInput {
+		u.editInput(k)
+		return
+	}
+	if k.code == keyTab && u.inRoom() {
+		u.input, u.inputText = chatInput, ""
+		return
+	}
+
+	switch u.screen {
+	case addressScreen:
+		u.addressKey(k)
+	case lobbyScreen:
+		u.lobbyKey(k)
+	case waitingScreen:
+		if k.code == keyRune && unicode.ToLower(k.r) == 'b' && !u.inQueue && u.client.Supports(protocol.CapabilityBots) {
+			u.client.AddBot("")
+		}
+		if k.code == keyEscape {
+			if u.inQueue {
+				u.client.CancelQuickPlay()
+			} else {
+				u.client.Leave()
+			}
+		}
+	case colorScreen:
+		u.colorKey(k)
+	case shifumiScreen:
+		u.shifumiKey(k)
+	case playScreen:
+		u.playKey(k)
+	case resultScreen:
+		if k.code == keyEnter && !u.rematchSent {
+			u.rematchSent = true
+			u.client.Rematch()
+		}
+		if k.code == keyEscape {
+			u.client.Leave()
+		}
+	case spectatorScreen:
+		if k.code == keyEscape {
+			u.client.Leave()
+		}
+	}
+}
+
+// inRoom indique si le joueur est assis dans une salle, où le chat est disponible.
+// Les spectateurs ne peuvent pas écrire dans le chat.
+func (u *ui) inRoom() bool {
+	switch u.screen {
+	case waitingScreen:
+		return !u.inQueue
+	case colorScreen, shifumiScreen, playScreen, resultScreen:
+		return true
+	}
+	return false
+}
+
+// editInput modifie la zone de saisie active : Entrée valide, Echap annule.
+func (u *ui) editInput(k key) {
+	switch k.code {
+	case keyRune:
+		if len([]rune(u.inputText)) < 120 {
+			u.inputText += string(k.r)
+		}
+	case keyBackspace:
+		if r := []rune(u.inputText); len(r) > 0 {
+			u.inputText = string(r[:len(r)-1])
+		}
+	case keyEscape, keyTab:
+		u.input, u.inputText = noInput, ""
+	case keyEnter:
+		switch u.input {
+		case chatInput:
+			if u.inputText != "" {
+				u.client.Chat(u.inputText)
+			}
+		case roomPasswordInput:
+			u.roomPassword = u.inputText
+			u.setStatus("Mot de passe de salle enregistré")
+		}
+		u.input, u.inputText = noInput, ""
+	}
+}
+
+// addressKey gère la saisie de l'adresse du serveur.
+func (u *ui) addressKey(k key) {
+	switch k.code {
+	case keyRune:
+		u.inputText += string(k.r)
+	case keyBackspace:
+		if r := []rune(u.inputText); len(r) > 0 {
+			u.inputText = string(r[:len(r)-1])
+		}
+	case keyEscape:
+		u.quit = true
+	case keyEnter:
+		if u.inputText == "" {
+			return
+		}
+		u.parseAddress(u.inputText)
+		u.inputText = ""
+		u.error = ""
+		u.screen = connectingScreen
+		u.connect("")
+	}
+}
+
+// lobbyKey gère la liste des salles : Entrée rejoint la salle choisie, C en crée une,
+// Q lance une partie rapide, S observe la salle choisie, P saisit un mot de passe de salle.
+func (u *ui) lobbyKey(k key) {
+	switch k.code {
+	case keyUp:
+		if u.selectedRoom > 0 {
+			u.selectedRoom--
+		}
+	case keyDown:
+		if u.selectedRoom < len(u.rooms)-1 {
+			u.selectedRoom++
+		}
+	case keyEnter:
+		u.error = ""
+		if len(u.rooms) == 0 {
+			u.client.CreateRoom("", u.roomPassword)
+		} else {
+			u.client.Join(u.rooms[u.selectedRoom].ID, u.roomPassword)
+		}
+	case keyEscape:
+		u.client.Disconnect()
+		u.client = nil
+		u.rooms = nil
+		u.screen = addressScreen
+	case keyRune:
+		switch unicode.ToLower(k.r) {
+		case 'c':
+			u.error = ""
+			u.client.CreateRoom("", u.roomPassword)
+		case 'q':
+			if u.client.Supports(protocol.CapabilityQuickPlay) {
+				u.error = ""
+				u.resetRoom()
+				u.inQueue = true
+				u.queue = protocol.QueuePositionPayload{}
+				u.screen = waitingScreen
+				u.client.QuickPlay()
+			}
+		case 's':
+			if len(u.rooms) > 0 && u.client.Supports(protocol.CapabilitySpectate) {
+				u.error = ""
+				u.client.Spectate(u.rooms[u.selectedRoom].ID, u.roomPassword)
+			}
+		case 'r':
+			u.client.ListRooms()
+		case 'p':
+			u.input, u.inputText = roomPasswordInput, ""
+		}
+	}
+}
+
+// colorKey gère la grille des couleurs : les flèches déplacent le curseur, Entrée valide.
+func (u *ui) colorKey(k key) {
+	if u.myColor >= 0 {
+		if k.code == keyEscape {
+			u.client.Leave()
+		}
+		return // Couleur déjà choisie, en attente de l'adversaire
+	}
+	line, col := u.cursor/colorsPerLine, u.cursor%colorsPerLine
+	switch k.code {
+	case keyLeft:
+		col = (col - 1 + colorsPerLine) % colorsPerLine
+	case keyRight:
+		col = (col + 1) % colorsPerLine
+	case keyUp:
+		line = (line - 1 + colorsPerLine) % colorsPerLine
+	case keyDown:
+		line = (line + 1) % colorsPerLine
+	case keyEnter:
+		if u.cursor == u.opponentColor {
+			u.error = "Couleur déjà choisie par l'autre joueur"
+			return
+		}
+		u.myColor = u.cursor
+		u.client.PickColor(u.cursor)
+		u.setStatus("Couleur choisie, en attente de l'adversaire")
+		return
+	case keyEscape:
+		u.client.Leave()
+		return
+	default:
+		return
+	}
+	u.cursor = line*colorsPerLine + col
+	u.error = ""
+	u.client.Cursor(u.cursor)
+}
+
+// shifumiKey gère le pierre/feuille/ciseaux : gauche et droite changent le choix, Entrée l'envoie.
+func (u *ui) shifumiKey(k key) {
+	switch k.code {
+	case keyLeft:
+		if !u.shifumiSent {
+			u.shifumiChoice = (u.shifumiChoice - 1 + len(shifumiChoices)) % len(shifumiChoices)
+		}
+	case keyRight:
+		if !u.shifumiSent {
+			u.shifumiChoice = (u.shifumiChoice + 1) % len(shifumiChoices)
+		}
+	case keyEnter:
+		if !u.shifumiSent {
+			u.shifumiSent = true
+			u.client.Shifumi(shifumiChoices[u.shifumiChoice])
+			u.setStatus("Choix envoyé, en attente de l'adversaire")
+		}
+	case keyEscape:
+		u.client.Leave()
+	}
+}
+
+// playKey gère la partie : gauche et droite déplacent le pion, Entrée ou bas le joue.
+// Les autres touches servent à abandonner, proposer la nulle ou reprendre un coup (offerKey).
+func (u *ui) playKey(k key) {
+	confirmResign := u.resignConfirm
+	u.resignConfirm = false
+	switch k.code {
+	case keyLeft:
+		u.column = (u.column - 1 + engine.Columns) % engine.Columns
+		u.client.TokenPosition(u.column)
+	case keyRight:
+		u.column = (u.column + 1) % engine.Columns
+		u.client.TokenPosition(u.column)
+	case keyEnter, keyDown:
+		if !u.client.MyTurn() {
+			return
+		}
+		if err := u.client.Play(u.column); err != nil {
+			u.error = "Coup impossible : " + err.Error()
+			return
+		}
+		u.error = ""
+	case keyRune:
+		u.offerKey(unicode.ToLower(k.r), confirmResign)
+	case keyEscape:
+		// Quitter la salle abandonne la partie
+		u.client.Leave()
+	}
+}
+
+// offerKey gère les touches d'abandon, de nulle et de reprise : A abandonne la partie
+// après confirmation, D propose la nulle, R demande à reprendre le dernier coup, O et N
+// acceptent ou refusent la proposition de l'adversaire.
+func (u *ui) offerKey(r rune, confirmResign bool) {
+	if !u.client.Supports(protocol.CapabilityOffers) {
+		return
+	}
+	switch r {
+	case 'a':
+		if !confirmResign {
+			u.resignConfirm = true
+			u.setStatus("Appuyez de nouveau sur A pour abandonner la partie")
+			return
+		}
+		u.client.Resign()
+	case 'd':
+		u.client.OfferDraw()
+	case 'r':
+		u.client.RequestTakeback()
+	case 'o', 'n':
+		u.answerOffer(r == 'o')
+	}
+}
+
+// answerOffer répond à la proposition de nulle ou, à défaut, à la demande de reprise de l'adversaire.
+func (u *ui) answerOffer(accept bool) {
+	switch {
+	case u.drawOffered:
+		u.drawOffered = false
+		u.client.AnswerDraw(accept)
+	case u.takebackAsked:
+		u.takebackAsked = false
+		u.client.AnswerTakeback(accept)
+	}
+}
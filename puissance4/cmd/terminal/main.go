@@ -0,0 +1,83 @@
+// Commande terminal : client puissance 4 en mode texte, pour jouer depuis un terminal
+// sans affichage graphique (par exemple à travers SSH).
+//
+// Il se connecte au même serveur que le client graphique et en reprend le déroulement :
+// saisie de l'adresse, lobby, choix de la couleur parmi les neuf du client graphique,
+// pierre/feuille/ciseaux, partie au clavier, chat et rematch. Les pions sont affichés
+// en couleurs ANSI (24 bits si COLORTERM l'annonce, 256 couleurs sinon). Le terminal
+// doit comprendre stty, comme ceux de Linux et de macOS.
+//
+// Utilisation :
+//
+//	terminal [-tls-non-verifie] [[tls://][motdepasse@]hôte[:port]]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// tickInterval est l'intervalle entre deux rafraîchissements de l'écran sans événement.
+const tickInterval = 500 * time.Millisecond
+
+func main() {
+	insecure := flag.Bool("tls-non-verifie", false, "accepte un certificat non vérifié (serveur lancé avec -tls-dev)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Utilisation : %s [-tls-non-verifie] [[tls://][motdepasse@]hôte[:port]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	restore, err := enterRawMode()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer restore()
+
+	u := newUI()
+	u.insecure = *insecure
+	if flag.NArg() > 0 {
+		u.parseAddress(flag.Arg(0))
+		u.screen = connectingScreen
+		u.connect("")
+	}
+
+	keys := make(chan key)
+	go readKeys(keys)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	out := bufio.NewWriter(os.Stdout)
+	for ticks := 0; !u.quit; {
+		u.draw(out)
+		out.Flush()
+
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				u.quit = true
+				break
+			}
+			u.handleKey(k)
+		case f := <-u.events:
+			f()
+		case <-ticker.C:
+			ticks++
+			u.tick(ticks)
+		case <-signals:
+			u.quit = true
+		}
+	}
+
+	if u.client != nil {
+		u.client.Disconnect()
+	}
+}
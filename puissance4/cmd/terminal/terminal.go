@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// Séquences ANSI utilisées pour piloter le terminal.
+const (
+	ansiReset       = "\x1b[0m"
+	ansiBold        = "\x1b[1m"
+	ansiDim         = "\x1b[2m"
+	ansiBlink       = "\x1b[5m"
+	ansiReverse     = "\x1b[7m"
+	ansiHome        = "\x1b[H"
+	ansiClearLine   = "\x1b[K"
+	ansiClearBelow  = "\x1b[J"
+	ansiHideCursor  = "\x1b[?25l"
+	ansiShowCursor  = "\x1b[?25h"
+	ansiAltScreen   = "\x1b[?1049h"
+	ansiMainScreen  = "\x1b[?1049l"
+	ansiGridColor   = "\x1b[48;5;25m" // Fond bleu de la grille
+	ansiYellow      = "\x1b[33m"
+	ansiRed         = "\x1b[31m"
+	ansiGreen       = "\x1b[32m"
+	ansiCyan        = "\x1b[36m"
+	ansiBrightWhite = "\x1b[97m"
+)
+
+// rgb est une couleur 24 bits.
+type rgb struct{ r, g, b uint8 }
+
+// tokenColors reprend, dans le même ordre, les couleurs de pion du client graphique
+// (globalTokenColors) : le numéro de la couleur est celui qui circule sur le réseau.
+var tokenColors = [9]rgb{
+	{255, 239, 213}, // Pêche pastel
+	{119, 221, 153}, // Vert menthe pastel
+	{174, 238, 152}, // Vert clair pastel
+	{230, 220, 170}, // Jaune sable pastel
+	{255, 178, 156}, // Orange saumon pastel
+	{255, 182, 193}, // Rose clair pastel
+	{202, 255, 219}, // Turquoise clair pastel
+	{219, 178, 255}, // Violet lavande pastel
+	{245, 255, 250}, // Blanc cassé pastel
+}
+
+// tokenColorNames sont les noms affichés des couleurs de tokenColors.
+var tokenColorNames = [9]string{
+	"Pêche", "Vert menthe", "Vert clair", "Jaune sable", "Orange saumon",
+	"Rose clair", "Turquoise", "Lavande", "Blanc cassé",
+}
+
+// trueColor indique si le terminal accepte les couleurs 24 bits. Sinon, les couleurs
+// sont ramenées à la palette de 256 couleurs, comprise par presque tous les terminaux.
+var trueColor = os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit"
+
+// fg renvoie la séquence qui donne la couleur c au texte.
+func fg(c rgb) string {
+	if trueColor {
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.r, c.g, c.b)
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm", c.ansi256())
+}
+
+// ansi256 renvoie la couleur la plus proche de c dans le cube 6×6×6 de la palette de 256 couleurs.
+func (c rgb) ansi256() int {
+	level := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	return 16 + 36*level(c.r) + 6*level(c.g) + level(c.b)
+}
+
+// tokenColor renvoie la séquence de la couleur de pion color, ou une couleur neutre
+// si color n'est pas encore choisie.
+func tokenColor(color int) string {
+	if color < 0 || color >= len(tokenColors) {
+		return ansiBrightWhite
+	}
+	return fg(tokenColors[color])
+}
+
+// stty exécute la commande stty sur le terminal de l'entrée standard.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// enterRawMode passe le terminal en mode caractère, sans écho, et bascule sur l'écran
+// secondaire. La fonction renvoyée rétablit le terminal tel qu'il était.
+func enterRawMode() (restore func(), err error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("terminal non pris en charge (stty) : %w", err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, fmt.Errorf("passage du terminal en mode caractère : %w", err)
+	}
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+	return func() {
+		fmt.Print(ansiReset + ansiShowCursor + ansiMainScreen)
+		stty(saved)
+	}, nil
+}
+
+// keyCode identifie une touche lue au clavier.
+type keyCode int
+
+const (
+	keyRune keyCode = iota // Caractère imprimable, dans key.r
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyEnter
+	keyBackspace
+	keyEscape
+	keyTab
+)
+
+// key est une touche lue au clavier.
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// readKeys lit l'entrée standard et envoie les touches reconnues sur keys.
+// Le canal est fermé à la fin de l'entrée.
+func readKeys(keys chan<- key) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// parseKeys découpe le bloc lu en une seule fois sur l'entrée standard en touches.
+// Une séquence d'échappement arrive en un seul bloc : un Echap isolé est la touche Echap.
+func parseKeys(data []byte) []key {
+	var keys []key
+	for len(data) > 0 {
+		switch b := data[0]; {
+		case b == 0x1b:
+			if len(data) == 1 {
+				keys = append(keys, key{code: keyEscape})
+				data = data[1:]
+				continue
+			}
+			// Séquence CSI (Echap [) ou SS3 (Echap O) : les flèches finissent par A à D
+			end := 1
+			if data[1] == '[' || data[1] == 'O' {
+				end = 2
+				for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+					end++
+				}
+				if end < len(data) {
+					switch data[end] {
+					case 'A':
+						keys = append(keys, key{code: keyUp})
+					case 'B':
+						keys = append(keys, key{code: keyDown})
+					case 'C':
+						keys = append(keys, key{code: keyRight})
+					case 'D':
+						keys = append(keys, key{code: keyLeft})
+					}
+					end++
+				}
+			} else {
+				keys = append(keys, key{code: keyEscape})
+			}
+			data = data[min(end, len(data)):]
+		case b == '\r' || b == '\n':
+			keys = append(keys, key{code: keyEnter})
+			data = data[1:]
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+			data = data[1:]
+		case b == '\t':
+			keys = append(keys, key{code: keyTab})
+			data = data[1:]
+		case b < 0x20:
+			data = data[1:] // Autre caractère de contrôle, ignoré
+		default:
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, key{code: keyRune, r: r})
+			data = data[size:]
+		}
+	}
+	return keys
+}
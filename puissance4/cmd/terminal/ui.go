@@ -0,0 +1,596 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"puissance4/client"
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// Écrans du client.
+const (
+	addressScreen    = iota // Saisie de l'adresse du serveur
+	connectingScreen        // Connexion en cours
+	lobbyScreen             // Liste des salles
+	waitingScreen           // Salle ou file d'attente, en attente d'un adversaire
+	colorScreen             // Choix des couleurs
+	shifumiScreen           // Pierre/feuille/ciseaux
+	playScreen              // Partie en cours
+	resultScreen            // Partie terminée
+	spectatorScreen         // Observation d'une salle
+)
+
+// Nombre de couleurs par ligne de la grille des couleurs.
+const colorsPerLine = 3
+
+// chatHistory est le nombre de messages du chat affichés.
+const chatHistory = 5
+
+// reconnectInterval est le délai entre deux tentatives de reprise de la session.
+const reconnectInterval = 2 * time.Second
+
+// shifumiChoices sont les choix proposés au pierre/feuille/ciseaux.
+var shifumiChoices = []string{client.Rock, client.Paper, client.Scissors}
+
+// Zones de saisie de texte.
+const (
+	noInput = iota
+	chatInput
+	roomPasswordInput
+)
+
+// ui est l'état du client en mode texte. Il n'est modifié que par la boucle principale :
+// les événements du client réseau lui sont transmis sous forme de fonctions par events.
+type ui struct {
+	events chan func()
+	client *client.Client
+	screen int
+	quit   bool
+
+	address    string      // Adresse saisie
+	password   string      // Mot de passe du serveur, extrait de l'adresse
+	tlsConfig  *tls.Config // Configuration TLS, nil pour une connexion en clair
+	insecure   bool        // Accepte un certificat TLS non vérifié
+	status     string      // Message d'information
+	error      string      // Message d'erreur
+	input      int         // Zone de saisie active (noInput, chatInput...)
+	inputText  string      // Texte en cours de saisie
+	resumeFrom time.Time   // Début de la reprise de session en cours, zéro sinon
+
+	rooms        []protocol.RoomInfo
+	selectedRoom int
+	roomPassword string
+	roomName     string
+	inQueue      bool
+	queue        protocol.QueuePositionPayload
+
+	cursor         int // Couleur survolée dans la grille des couleurs
+	myColor        int // Couleur validée, -1 avant le choix
+	opponentCursor int
+	opponentColor  int
+
+	shifumiChoice int
+	shifumiSent   bool
+
+	column          int // Colonne du pion au-dessus de la grille
+	opponentColumn  int
+	gameOver        protocol.GameOverPayload
+	rematchSent     bool
+	opponentRematch bool
+	wins            int
+	opponentWins    int
+	blink           bool // Alterne à chaque tic pour faire clignoter l'alignement gagnant
+	resignConfirm   bool // A pressée une fois : la suivante abandonne la partie
+	drawOffered     bool // L'adversaire propose la nulle
+	takebackAsked   bool // L'adversaire demande à reprendre son coup
+
+	spectate protocol.SpectatePayload
+
+	chat               []string
+	opponentReconnects time.Time // Fin du délai de reconnexion de l'adversaire, zéro sinon
+}
+
+// newUI crée l'état initial du client.
+func newUI() *ui {
+	u := &ui{events: make(chan func(), 64)}
+	u.resetRoom()
+	return u
+}
+
+// resetRoom oublie tout ce qui concerne la salle quittée.
+func (u *ui) resetRoom() {
+	u.roomName = ""
+	u.inQueue = false
+	u.cursor, u.myColor, u.opponentCursor, u.opponentColor = 0, -1, -1, -1
+	u.resetGame()
+	u.wins, u.opponentWins = 0, 0
+	u.chat = nil
+	u.opponentReconnects = time.Time{}
+	u.spectate = protocol.SpectatePayload{}
+}
+
+// resetGame prépare une nouvelle partie dans la même salle.
+func (u *ui) resetGame() {
+	u.shifumiChoice, u.shifumiSent = 0, false
+	u.column, u.opponentColumn = engine.Columns/2, engine.Columns/2
+	u.gameOver = protocol.GameOverPayload{}
+	u.rematchSent, u.opponentRematch = false, false
+	u.resignConfirm, u.drawOffered, u.takebackAsked = false, false, false
+}
+
+// setStatus affiche un message d'information et efface l'erreur.
+func (u *ui) setStatus(format string, args ...interface{}) {
+	u.status = fmt.Sprintf(format, args...)
+	u.error = ""
+}
+
+// connect ouvre une connexion vers u.address. La connexion est ouverte dans une
+// goroutine pour que l'interface reste active ; resumeToken demande la reprise d'une session.
+func (u *ui) connect(resumeToken string) {
+	address, password, options := u.address, u.password, client.Options{}
+	options.Password = password
+	options.Software = "terminal puissance4"
+	options.ResumeToken = resumeToken
+	options.TLS = u.tlsConfig
+
+	var c *client.Client
+	handlers := u.handlers(func() *client.Client { return c })
+	go func() {
+		var err error
+		c, err = client.Dial(address, handlers, options)
+		u.events <- func() {
+			if err != nil {
+				u.connectFailed(err)
+				return
+			}
+			u.client = c
+			go c.Run()
+		}
+	}()
+}
+
+// connectFailed traite l'échec d'une tentative de connexion.
+func (u *ui) connectFailed(err error) {
+	if !u.resumeFrom.IsZero() {
+		u.retryResume()
+		return
+	}
+	u.screen = addressScreen
+	u.error = fmt.Sprintf("Connexion impossible : %v", err)
+}
+
+// retryResume retente la reprise de la session perdue, tant que le délai du serveur n'est pas écoulé.
+func (u *ui) retryResume() {
+	if u.client == nil || time.Since(u.resumeFrom) > u.client.Grace() {
+		u.resumeFrom = time.Time{}
+		u.resetRoom()
+		u.screen = addressScreen
+		u.error = "Connexion perdue, la partie n'a pas pu être reprise"
+		return
+	}
+	token := u.client.SessionToken()
+	time.AfterFunc(reconnectInterval, func() {
+		u.events <- func() { u.connect(token) }
+	})
+}
+
+// handlers associe les événements du client réseau courant(), une fois connecté, à
+// des fonctions exécutées par la boucle principale. Les événements d'un client remplacé
+// (après une reprise de session) sont ignorés.
+func (u *ui) handlers(current func() *client.Client) client.Handlers {
+	post := func(f func()) {
+		c := current()
+		u.events <- func() {
+			if c == u.client {
+				f()
+			}
+		}
+	}
+	return client.Handlers{
+		OnConnected: func(protocol.HelloPayload) {
+			post(func() {
+				if !u.resumeFrom.IsZero() {
+					return // La reprise a été demandée, le lobby attendra sa réponse
+				}
+				u.screen = lobbyScreen
+				u.setStatus("Connecté à %s", u.address)
+				u.client.ListRooms()
+			})
+		},
+		OnRefused: func(reason string) {
+			post(func() {
+				u.resumeFrom = time.Time{}
+				u.screen = addressScreen
+				u.error = "Connexion refusée : " + reason
+			})
+		},
+		OnError: func(payload protocol.ErrorPayload) {
+			post(func() {
+				u.error = payload.Message
+				switch payload.Code {
+				case protocol.ErrCodeRoomPassword:
+					u.input, u.inputText = roomPasswordInput, ""
+				case protocol.ErrCodeRoom, protocol.ErrCodeDisabled:
+					if u.screen == waitingScreen && u.inQueue {
+						u.inQueue = false
+						u.screen = lobbyScreen
+					}
+				}
+			})
+		},
+		OnDisconnect: func(err error) {
+			post(func() { u.disconnected(err) })
+		},
+
+		OnRoomList: func(rooms []protocol.RoomInfo) {
+			post(func() {
+				u.rooms = rooms
+				if u.selectedRoom >= len(rooms) {
+					u.selectedRoom = max(len(rooms)-1, 0)
+				}
+			})
+		},
+		OnRoomJoined: func(room protocol.RoomJoinedPayload) {
+			post(func() {
+				u.resetRoom()
+				u.roomName = room.Name
+				u.screen = waitingScreen
+				u.setStatus("Salle %s rejointe", room.Name)
+			})
+		},
+		OnRoomLeft: func() {
+			post(func() {
+				u.resetRoom()
+				u.screen = lobbyScreen
+				u.client.ListRooms()
+			})
+		},
+		OnQueuePosition: func(position protocol.QueuePositionPayload) {
+			post(func() { u.queue = position })
+		},
+		OnQueueLeft: func() {
+			post(func() {
+				u.inQueue = false
+				u.screen = lobbyScreen
+				u.client.ListRooms()
+			})
+		},
+		OnSpectate: func(state protocol.SpectatePayload) {
+			post(func() {
+				if u.screen != spectatorScreen {
+					u.setStatus("Observation de la salle %s", state.RoomName)
+				}
+				u.spectate = state
+				u.roomName = state.RoomName
+				u.screen = spectatorScreen
+			})
+		},
+
+		OnResumed: func(state protocol.ResumePayload) {
+			post(func() { u.resumed(state) })
+		},
+		OnResumeFailed: func(message string) {
+			post(func() {
+				u.resumeFrom = time.Time{}
+				u.resetRoom()
+				u.screen = lobbyScreen
+				u.error = "Reprise impossible, la partie a été perdue"
+				u.client.ListRooms()
+			})
+		},
+		OnOpponentReconnecting: func(seconds int) {
+			post(func() { u.opponentReconnects = time.Now().Add(time.Duration(seconds) * time.Second) })
+		},
+		OnOpponentReconnected: func() {
+			post(func() { u.opponentReconnects = time.Time{} })
+		},
+		OnOpponentLeft: func() {
+			post(func() {
+				name := u.roomName
+				u.resetRoom()
+				u.roomName = name
+				u.screen = waitingScreen
+				u.error = "L'adversaire a quitté la salle"
+			})
+		},
+
+		OnReady: func(message string) {
+			post(func() {
+				u.screen = colorScreen
+				u.setStatus("%s", message)
+			})
+		},
+		OnCursor: func(color int) {
+			post(func() { u.opponentCursor = color })
+		},
+		OnColor: func(color protocol.ColorPayload) {
+			post(func() { u.opponentColor = color.Color })
+		},
+		OnColorsComplete: func(int) {
+			post(func() {
+				u.screen = shifumiScreen
+				u.setStatus("Pierre, papier, ciseaux : le gagnant commence")
+			})
+		},
+		OnShifumi: func(result client.Shifumi) {
+			post(func() {
+				if result.Draw {
+					u.shifumiSent = false
+					u.setStatus("Égalité (%s contre %s), rejouez", result.Mine, result.Theirs)
+					return
+				}
+				if result.Winner == u.client.ID() {
+					u.setStatus("%s bat %s : vous commencez", result.Mine, result.Theirs)
+				} else {
+					u.setStatus("%s bat %s : l'adversaire commence", result.Theirs, result.Mine)
+				}
+			})
+		},
+
+		OnGameStart: func(client.GameStart) {
+			post(func() {
+				u.resetGame()
+				u.screen = playScreen
+			})
+		},
+		OnTokenPosition: func(update protocol.TokenUpdatePayload) {
+			post(func() {
+				if u.screen == spectatorScreen {
+					u.spectateColumn(update)
+					return
+				}
+				u.opponentColumn = update.Position
+			})
+		},
+		OnMove: func(move protocol.MovePayload) {
+			post(func() {
+				if u.screen == spectatorScreen {
+					u.spectate.CurrentTurn = u.spectateOpponent(move.ID)
+					return
+				}
+				// Un coup joué fait tomber les propositions en attente
+				u.drawOffered, u.takebackAsked = false, false
+			})
+		},
+		OnMoveRejected: func(rejected protocol.MoveRejectedPayload) {
+			post(func() { u.error = "Coup refusé : " + rejected.Reason })
+		},
+		OnGameOver: func(over protocol.GameOverPayload) {
+			post(func() {
+				if u.screen == spectatorScreen {
+					u.spectate.GameOver = true
+					u.spectate.CurrentTurn = -1
+					return
+				}
+				u.gameOver = over
+				switch over.Winner {
+				case -1:
+					u.wins++
+					u.opponentWins++
+				case u.client.ID():
+					u.wins++
+				default:
+					u.opponentWins++
+				}
+				u.setStatus("%s", u.outcome(over))
+				u.screen = resultScreen
+			})
+		},
+		OnRematchWaiting: func(string) {
+			post(func() { u.opponentRematch = true })
+		},
+		OnChat: func(message protocol.ChatMessage) {
+			post(func() { u.addChat(message) })
+		},
+
+		OnDrawOffered: func(offer protocol.OfferPayload) {
+			post(func() {
+				if offer.ID == u.client.ID() {
+					u.setStatus("Nulle proposée, en attente de la réponse de l'adversaire")
+					return
+				}
+				u.drawOffered = true
+				u.setStatus("L'adversaire propose la nulle : O pour accepter, N pour refuser")
+			})
+		},
+		OnDrawDeclined: func(offer protocol.OfferPayload) {
+			post(func() {
+				if offer.ID == u.client.ID() {
+					u.error = "L'adversaire refuse la nulle"
+					return
+				}
+				u.setStatus("Nulle refusée")
+			})
+		},
+		OnTakebackRequested: func(request protocol.OfferPayload) {
+			post(func() {
+				if request.ID == u.client.ID() {
+					u.setStatus("Reprise demandée, en attente de la réponse de l'adversaire")
+					return
+				}
+				u.takebackAsked = true
+				u.setStatus("L'adversaire demande à reprendre son coup : O pour accepter, N pour refuser")
+			})
+		},
+		OnTakebackDeclined: func(request protocol.OfferPayload) {
+			post(func() {
+				if request.ID == u.client.ID() {
+					u.error = "L'adversaire refuse la reprise"
+					return
+				}
+				u.setStatus("Reprise refusée")
+			})
+		},
+		OnTakeback: func(takeback protocol.TakebackPayload) {
+			post(func() {
+				u.drawOffered, u.takebackAsked = false, false
+				if takeback.CurrentTurn == u.client.ID() {
+					u.setStatus("Reprise acceptée : rejouez votre coup")
+				} else {
+					u.setStatus("L'adversaire reprend son coup")
+				}
+			})
+		},
+	}
+}
+
+// outcome décrit le résultat de la partie terminée over, et comment elle s'est terminée
+// lorsque cela ne se lit pas sur la grille.
+func (u *ui) outcome(over protocol.GameOverPayload) string {
+	won := over.Winner == u.client.ID()
+	switch {
+	case over.Winner == -1 && over.Reason == protocol.ReasonAgreement:
+		return "Nulle acceptée"
+	case over.Winner == -1:
+		return "Égalité !"
+	case won && over.Reason == protocol.ReasonResign:
+		return "Vous avez gagné : l'adversaire abandonne !"
+	case won && over.Reason == protocol.ReasonTimeout:
+		return "Vous avez gagné au temps !"
+	case won:
+		return "Vous avez gagné !"
+	case over.Reason == protocol.ReasonResign:
+		return "Vous avez abandonné"
+	case over.Reason == protocol.ReasonTimeout:
+		return "Vous avez perdu au temps"
+	}
+	return "Vous avez perdu"
+}
+
+// disconnected traite la fin de la connexion : reprise de la session si le joueur
+// était assis dans une salle, retour à la saisie de l'adresse sinon.
+func (u *ui) disconnected(err error) {
+	if err == nil {
+		return // Déconnexion demandée par le joueur
+	}
+	switch u.screen {
+	case waitingScreen, colorScreen, shifumiScreen, playScreen, resultScreen:
+		if !u.inQueue && u.client.SessionToken() != "" {
+			if u.resumeFrom.IsZero() {
+				u.resumeFrom = time.Now()
+			}
+			u.error = "Connexion perdue, reprise de la partie..."
+			u.retryResume()
+			return
+		}
+	}
+	u.resumeFrom = time.Time{}
+	u.resetRoom()
+	u.screen = addressScreen
+	u.error = fmt.Sprintf("Connexion perdue : %v", err)
+}
+
+// resumed remet l'interface dans l'état de la partie reprise.
+func (u *ui) resumed(state protocol.ResumePayload) {
+	u.resumeFrom = time.Time{}
+	u.resetRoom()
+	u.setStatus("Partie reprise")
+	if state.RoomID == -1 {
+		u.screen = lobbyScreen
+		u.client.ListRooms()
+		return
+	}
+
+	u.roomName = state.RoomName
+	u.myColor, u.opponentColor = state.YourColor, state.OpponentColor
+	if u.myColor >= 0 {
+		u.cursor = u.myColor
+	}
+	u.wins, u.opponentWins = state.YourWins, state.OpponentWins
+	for _, message := range state.Chat {
+		u.addChat(message)
+	}
+	switch state.Phase {
+	case protocol.PhaseWaiting:
+		u.screen = waitingScreen
+	case protocol.PhaseColor:
+		u.screen = colorScreen
+	case protocol.PhaseShifumi:
+		u.screen = shifumiScreen
+	case protocol.PhasePlaying:
+		u.screen = playScreen
+	case protocol.PhaseOver:
+		u.screen = resultScreen
+		board := u.client.Board()
+		if last, ok := board.LastMove(); ok {
+			_, _, u.gameOver.Cells = board.CheckEnd(last.X, last.Y)
+		}
+		u.gameOver.Winner = -1
+		if _, result, _ := board.Winner(); result != engine.Equality {
+			u.gameOver.Winner = u.client.Opponent()
+			if result == u.client.Token() {
+				u.gameOver.Winner = u.client.ID()
+			}
+		}
+	}
+}
+
+// addChat ajoute un message au chat.
+func (u *ui) addChat(message protocol.ChatMessage) {
+	author := "Adversaire"
+	if message.ID == u.client.ID() {
+		author = "Vous"
+	}
+	u.chat = append(u.chat, fmt.Sprintf("%s %s : %s", time.Now().Format("15:04"), author, message.Text))
+	if len(u.chat) > chatHistory {
+		u.chat = u.chat[len(u.chat)-chatHistory:]
+	}
+}
+
+// spectateColumn déplace le pion au-dessus de la grille du joueur observé qui l'a bougé.
+func (u *ui) spectateColumn(update protocol.TokenUpdatePayload) {
+	if update.Token == engine.P1Token {
+		u.column = update.Position
+	} else {
+		u.opponentColumn = update.Position
+	}
+}
+
+// spectateOpponent renvoie l'ID de l'adversaire du joueur id dans la partie observée, ou -1.
+func (u *ui) spectateOpponent(id int) int {
+	for _, p := range u.spectate.Players {
+		if p.ID != id {
+			return p.ID
+		}
+	}
+	return -1
+}
+
+// spectatePlayer renvoie le joueur observé qui a le pion token.
+func (u *ui) spectatePlayer(token int) (protocol.PlayerInfo, bool) {
+	for _, p := range u.spectate.Players {
+		if p.Token == token {
+			return p, true
+		}
+	}
+	return protocol.PlayerInfo{}, false
+}
+
+// tick est appelée à intervalle régulier : clignotement, rafraîchissement du lobby.
+func (u *ui) tick(n int) {
+	u.blink = !u.blink
+	if u.screen == lobbyScreen && u.client != nil && n%4 == 0 {
+		u.client.ListRooms()
+	}
+}
+
+// parseAddress découpe l'adresse saisie, de la forme [tls://][motdepasse@]hôte[:port],
+// comme le client graphique.
+func (u *ui) parseAddress(input string) {
+	address := strings.TrimSpace(input)
+	u.tlsConfig = nil
+	if strings.HasPrefix(strings.ToLower(address), "tls://") {
+		u.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: u.insecure}
+		address = address[len("tls://"):]
+	}
+	u.password = ""
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		u.password = address[:i]
+		address = address[i+1:]
+	}
+	u.address = address
+}
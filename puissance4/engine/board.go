@@ -0,0 +1,291 @@
+// Package engine contient les règles du puissance 4, indépendamment de toute
+// interface graphique ou réseau : grille, application des coups avec gravité,
+// détection de fin de partie, annulation et sérialisation.
+// Il est utilisé à la fois par le client et par le serveur afin que les règles
+// ne puissent pas diverger entre les deux.
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Dimensions de la grille de jeu.
+const (
+	Columns   = 7 // Nombre de colonnes
+	Rows      = 6 // Nombre de lignes
+	WinLength = 4 // Nombre de pions à aligner pour gagner
+)
+
+// Constantes pour représenter le contenu d'une case de la grille
+// (absence de pion, pion du joueur 1, pion du joueur 2).
+const (
+	NoToken int = iota
+	P1Token
+	P2Token
+)
+
+// Constantes pour représenter le résultat d'une partie (égalité si
+// la grille a été remplie sans qu'un joueur n'ait gagné, joueur 1
+// gagnant ou joueur 2 gagnant). Le résultat d'une victoire a la même
+// valeur que le pion gagnant.
+const (
+	Equality int = iota
+	P1Wins
+	P2Wins
+)
+
+// Erreurs renvoyées lorsqu'un coup ne peut pas être joué.
+var (
+	ErrInvalidToken     = errors.New("pion invalide")
+	ErrColumnOutOfRange = errors.New("colonne hors de la grille")
+	ErrColumnFull       = errors.New("colonne pleine")
+	ErrCellOutOfRange   = errors.New("case hors de la grille")
+	ErrCellOccupied     = errors.New("case déjà occupée")
+	ErrNothingToUndo    = errors.New("aucun coup à annuler")
+	ErrWrongTurn        = errors.New("pion joué deux fois de suite par le même joueur")
+	ErrGameOver         = errors.New("coup joué après la fin de la partie")
+)
+
+// Move représente un pion posé sur la grille.
+type Move struct {
+	Token int `json:"token"` // Pion posé (P1Token ou P2Token)
+	X     int `json:"x"`     // Colonne
+	Y     int `json:"y"`     // Ligne (0 en haut de la grille)
+}
+
+// Board représente une grille de puissance 4 et la liste ordonnée des coups
+// qui y ont été joués. La ligne 0 est en haut de la grille, comme à l'affichage.
+// La valeur zéro est une grille vide prête à l'emploi.
+type Board struct {
+	grid  [Columns][Rows]int
+	moves []Move
+}
+
+// NewBoard crée une grille vide.
+func NewBoard() *Board {
+	return &Board{}
+}
+
+// Reset vide la grille et oublie tous les coups joués.
+func (b *Board) Reset() {
+	b.grid = [Columns][Rows]int{}
+	b.moves = nil
+}
+
+// Cell renvoie le contenu de la case (x, y), ou NoToken si elle est hors de la grille.
+func (b *Board) Cell(x, y int) int {
+	if !inside(x, y) {
+		return NoToken
+	}
+	return b.grid[x][y]
+}
+
+// Grid renvoie une copie du contenu de la grille.
+func (b *Board) Grid() [Columns][Rows]int {
+	return b.grid
+}
+
+// Moves renvoie une copie des coups joués, du plus ancien au plus récent.
+func (b *Board) Moves() []Move {
+	moves := make([]Move, len(b.moves))
+	copy(moves, b.moves)
+	return moves
+}
+
+// MoveCount renvoie le nombre de pions posés sur la grille.
+func (b *Board) MoveCount() int {
+	return len(b.moves)
+}
+
+// LastMove renvoie le dernier coup joué. Le booléen vaut false si la grille est vide.
+func (b *Board) LastMove() (Move, bool) {
+	if len(b.moves) == 0 {
+		return Move{}, false
+	}
+	return b.moves[len(b.moves)-1], true
+}
+
+// LandingRow renvoie la ligne sur laquelle tomberait un pion joué dans la colonne x.
+// Le booléen vaut false si la colonne est hors de la grille ou déjà pleine.
+func (b *Board) LandingRow(x int) (int, bool) {
+	if x < 0 || x >= Columns {
+		return -1, false
+	}
+	for y := Rows - 1; y >= 0; y-- {
+		if b.grid[x][y] == NoToken {
+			return y, true
+		}
+	}
+	return -1, false
+}
+
+// CanPlay indique si un pion peut encore être joué dans la colonne x.
+func (b *Board) CanPlay(x int) bool {
+	_, ok := b.LandingRow(x)
+	return ok
+}
+
+// Play fait tomber le pion token dans la colonne x et renvoie la ligne atteinte.
+func (b *Board) Play(token, x int) (int, error) {
+	if token != P1Token && token != P2Token {
+		return -1, ErrInvalidToken
+	}
+	if x < 0 || x >= Columns {
+		return -1, ErrColumnOutOfRange
+	}
+	y, ok := b.LandingRow(x)
+	if !ok {
+		return -1, ErrColumnFull
+	}
+	b.grid[x][y] = token
+	b.moves = append(b.moves, Move{Token: token, X: x, Y: y})
+	return y, nil
+}
+
+// Place pose le pion token directement sur la case (x, y), sans appliquer la gravité.
+// Elle sert à reconstruire une grille à partir d'un historique de coordonnées.
+func (b *Board) Place(token, x, y int) error {
+	if token != P1Token && token != P2Token {
+		return ErrInvalidToken
+	}
+	if !inside(x, y) {
+		return ErrCellOutOfRange
+	}
+	if b.grid[x][y] != NoToken {
+		return ErrCellOccupied
+	}
+	b.grid[x][y] = token
+	b.moves = append(b.moves, Move{Token: token, X: x, Y: y})
+	return nil
+}
+
+// Undo annule le dernier coup joué et le renvoie.
+func (b *Board) Undo() (Move, error) {
+	if len(b.moves) == 0 {
+		return Move{}, ErrNothingToUndo
+	}
+	last := b.moves[len(b.moves)-1]
+	b.moves = b.moves[:len(b.moves)-1]
+	b.grid[last.X][last.Y] = NoToken
+	return last, nil
+}
+
+// Full indique si la grille ne contient plus aucune case libre.
+func (b *Board) Full() bool {
+	for x := 0; x < Columns; x++ {
+		if b.grid[x][0] == NoToken {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckEnd vérifie si le pion posé en (x, y) termine la partie. Elle renvoie le
+// résultat (Equality, P1Wins ou P2Wins) ainsi que les coordonnées de l'alignement
+// gagnant, dans l'ordre de la ligne.
+func (b *Board) CheckEnd(x, y int) (finished bool, result int, winningPositions [][2]int) {
+	token := b.Cell(x, y)
+	if token == NoToken {
+		return false, Equality, nil
+	}
+
+	// Horizontal, vertical, puis les deux diagonales
+	directions := [][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}}
+	for _, d := range directions {
+		before := b.collect(x, y, -d[0], -d[1], token)
+		after := b.collect(x, y, d[0], d[1], token)
+		if len(before)+1+len(after) >= WinLength {
+			line := make([][2]int, 0, len(before)+1+len(after))
+			for i := len(before) - 1; i >= 0; i-- {
+				line = append(line, before[i])
+			}
+			line = append(line, [2]int{x, y})
+			line = append(line, after...)
+			return true, token, line
+		}
+	}
+
+	if b.Full() {
+		return true, Equality, nil
+	}
+	return false, Equality, nil
+}
+
+// Winner parcourt toute la grille et renvoie le résultat et l'alignement gagnant
+// s'il en existe un. Contrairement à CheckEnd, elle ne suppose pas connaître le dernier coup.
+func (b *Board) Winner() (finished bool, result int, winningPositions [][2]int) {
+	for x := 0; x < Columns; x++ {
+		for y := 0; y < Rows; y++ {
+			if b.grid[x][y] == NoToken {
+				continue
+			}
+			if finished, result, line := b.CheckEnd(x, y); finished && result != Equality {
+				return finished, result, line
+			}
+		}
+	}
+	if b.Full() {
+		return true, Equality, nil
+	}
+	return false, Equality, nil
+}
+
+// Opponent renvoie le pion adverse de token.
+func Opponent(token int) int {
+	if token == P1Token {
+		return P2Token
+	}
+	return P1Token
+}
+
+// collect renvoie les cases consécutives contenant token à partir de (x, y),
+// sans l'inclure, en avançant de (dx, dy).
+func (b *Board) collect(x, y, dx, dy, token int) [][2]int {
+	var cells [][2]int
+	for cx, cy := x+dx, y+dy; inside(cx, cy) && b.grid[cx][cy] == token; cx, cy = cx+dx, cy+dy {
+		cells = append(cells, [2]int{cx, cy})
+	}
+	return cells
+}
+
+// inside indique si la case (x, y) appartient à la grille.
+func inside(x, y int) bool {
+	return x >= 0 && x < Columns && y >= 0 && y < Rows
+}
+
+// String renvoie une représentation textuelle de la grille, ligne du haut en premier :
+// "." pour une case vide, "X" pour le joueur 1 et "O" pour le joueur 2.
+func (b *Board) String() string {
+	out := make([]byte, 0, (Columns+1)*Rows)
+	for y := 0; y < Rows; y++ {
+		for x := 0; x < Columns; x++ {
+			out = append(out, tokenSymbol(b.grid[x][y]))
+		}
+		out = append(out, '\n')
+	}
+	return string(out)
+}
+
+// tokenSymbol renvoie le caractère utilisé par String pour représenter un pion.
+func tokenSymbol(token int) byte {
+	switch token {
+	case P1Token:
+		return 'X'
+	case P2Token:
+		return 'O'
+	default:
+		return '.'
+	}
+}
+
+// validate vérifie qu'un coup désérialisé peut être rejoué sur la grille.
+func (m Move) validate() error {
+	if m.Token != P1Token && m.Token != P2Token {
+		return fmt.Errorf("coup (%d, %d) : %w", m.X, m.Y, ErrInvalidToken)
+	}
+	if !inside(m.X, m.Y) {
+		return fmt.Errorf("coup (%d, %d) : %w", m.X, m.Y, ErrCellOutOfRange)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// drawnGame remplit toute la grille sans qu'aucun joueur n'aligne quatre pions.
+var drawnGame = []int{
+	2, 5, 3, 5, 6, 3, 5, 6, 5, 0, 0, 2, 4, 2, 5, 5, 4, 4, 3, 2, 6,
+	6, 4, 0, 2, 6, 2, 0, 3, 1, 0, 4, 0, 6, 1, 3, 4, 3, 1, 1, 1, 1,
+}
+
+// mustColumns construit la grille des colonnes jouées, le joueur 1 commençant.
+func mustColumns(t *testing.T, columns []int) *Board {
+	t.Helper()
+	b, err := FromColumns(P1Token, columns)
+	if err != nil {
+		t.Fatalf("FromColumns(%v) : %v", columns, err)
+	}
+	return b
+}
+
+func TestPlay(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []int // Coups joués avant le coup testé
+		token   int
+		x       int
+		wantY   int
+		wantErr error
+	}{
+		{"colonne vide", nil, P1Token, 3, Rows - 1, nil},
+		{"sur un pion", []int{3}, P2Token, 3, Rows - 2, nil},
+		{"dernière case", []int{0, 0, 0, 0, 0}, P2Token, 0, 0, nil},
+		{"colonne pleine", []int{0, 0, 0, 0, 0, 0}, P1Token, 0, -1, ErrColumnFull},
+		{"colonne négative", nil, P1Token, -1, -1, ErrColumnOutOfRange},
+		{"colonne trop grande", nil, P1Token, Columns, -1, ErrColumnOutOfRange},
+		{"pion vide", nil, NoToken, 3, -1, ErrInvalidToken},
+		{"pion inconnu", nil, 3, 3, -1, ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := mustColumns(t, tt.columns)
+			landing, canPlay := b.LandingRow(tt.x)
+			y, err := b.Play(tt.token, tt.x)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Play(%d, %d) : erreur %v, attendu %v", tt.token, tt.x, err, tt.wantErr)
+			}
+			if y != tt.wantY {
+				t.Errorf("Play(%d, %d) = %d, attendu %d", tt.token, tt.x, y, tt.wantY)
+			}
+			if tt.wantErr == ErrInvalidToken {
+				return
+			}
+			if canPlay != (tt.wantErr == nil) || landing != tt.wantY {
+				t.Errorf("LandingRow(%d) = %d, %v, attendu %d, %v", tt.x, landing, canPlay, tt.wantY, tt.wantErr == nil)
+			}
+			wantCount := len(tt.columns)
+			if err == nil {
+				wantCount++
+				if got := b.Cell(tt.x, y); got != tt.token {
+					t.Errorf("Cell(%d, %d) = %d, attendu %d", tt.x, y, got, tt.token)
+				}
+			}
+			if b.MoveCount() != wantCount {
+				t.Errorf("MoveCount() = %d, attendu %d", b.MoveCount(), wantCount)
+			}
+		})
+	}
+}
+
+func TestCheckEnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		columns    []int
+		wantEnd    bool
+		wantResult int
+		wantLine   [][2]int
+	}{
+		{"partie en cours", []int{3, 3, 4}, false, Equality, nil},
+		{"trois pions seulement", []int{0, 0, 1, 1, 2}, false, Equality, nil},
+		{
+			"horizontale", []int{0, 0, 1, 1, 2, 2, 3}, true, P1Wins,
+			[][2]int{{0, 5}, {1, 5}, {2, 5}, {3, 5}},
+		},
+		{
+			"horizontale complétée au milieu", []int{0, 0, 1, 1, 3, 3, 2}, true, P1Wins,
+			[][2]int{{0, 5}, {1, 5}, {2, 5}, {3, 5}},
+		},
+		{
+			"verticale", []int{0, 1, 0, 1, 0, 1, 0}, true, P1Wins,
+			[][2]int{{0, 2}, {0, 3}, {0, 4}, {0, 5}},
+		},
+		{
+			"verticale du joueur 2", []int{6, 0, 1, 0, 1, 0, 1, 0}, true, P2Wins,
+			[][2]int{{0, 2}, {0, 3}, {0, 4}, {0, 5}},
+		},
+		{
+			"diagonale descendante", []int{3, 2, 2, 1, 1, 0, 1, 0, 0, 6, 0}, true, P1Wins,
+			[][2]int{{0, 2}, {1, 3}, {2, 4}, {3, 5}},
+		},
+		{
+			"diagonale montante", []int{0, 1, 1, 2, 2, 3, 2, 3, 3, 6, 3}, true, P1Wins,
+			[][2]int{{0, 5}, {1, 4}, {2, 3}, {3, 2}},
+		},
+		{"grille pleine", drawnGame, true, Equality, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := mustColumns(t, tt.columns)
+			last, _ := b.LastMove()
+			end, result, line := b.CheckEnd(last.X, last.Y)
+			if end != tt.wantEnd || result != tt.wantResult {
+				t.Fatalf("CheckEnd = %v, %d, attendu %v, %d\n%s", end, result, tt.wantEnd, tt.wantResult, b)
+			}
+			if !reflect.DeepEqual(line, tt.wantLine) {
+				t.Errorf("alignement %v, attendu %v", line, tt.wantLine)
+			}
+			if end, result, line = b.Winner(); end != tt.wantEnd || result != tt.wantResult || !reflect.DeepEqual(line, tt.wantLine) {
+				t.Errorf("Winner = %v, %d, %v, attendu %v, %d, %v", end, result, line, tt.wantEnd, tt.wantResult, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestCheckEndEmptyCell(t *testing.T) {
+	b := mustColumns(t, []int{3})
+	if end, _, _ := b.CheckEnd(0, Rows-1); end {
+		t.Error("CheckEnd sur une case vide termine la partie")
+	}
+}
+
+func TestFull(t *testing.T) {
+	b := NewBoard()
+	for i, x := range drawnGame {
+		if b.Full() {
+			t.Fatalf("grille pleine après %d coups", i)
+		}
+		if _, err := b.Play(P1Token+i%2, x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !b.Full() {
+		t.Fatal("grille non pleine après 42 coups")
+	}
+	for x := 0; x < Columns; x++ {
+		if b.CanPlay(x) {
+			t.Errorf("CanPlay(%d) sur une grille pleine", x)
+		}
+	}
+	b.Reset()
+	if b.Full() || b.MoveCount() != 0 {
+		t.Error("Reset ne vide pas la grille")
+	}
+}
+
+func TestUndo(t *testing.T) {
+	b := mustColumns(t, []int{3, 3, 4})
+	want := Move{Token: P1Token, X: 4, Y: Rows - 1}
+	got, err := b.Undo()
+	if err != nil || got != want {
+		t.Fatalf("Undo() = %+v, %v, attendu %+v", got, err, want)
+	}
+	if b.Cell(4, Rows-1) != NoToken || b.MoveCount() != 2 {
+		t.Errorf("le coup annulé reste sur la grille\n%s", b)
+	}
+	if last, _ := b.LastMove(); last != (Move{Token: P2Token, X: 3, Y: Rows - 2}) {
+		t.Errorf("LastMove() = %+v après Undo", last)
+	}
+
+	// Le coup annulé peut être rejoué, par l'un ou l'autre joueur
+	if y, err := b.Play(P1Token, 3); err != nil || y != Rows-3 {
+		t.Errorf("Play après Undo = %d, %v", y, err)
+	}
+
+	b.Reset()
+	if _, err := b.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo sur une grille vide : %v, attendu %v", err, ErrNothingToUndo)
+	}
+}
+
+func TestUndoWin(t *testing.T) {
+	b := mustColumns(t, []int{0, 1, 0, 1, 0, 1, 0})
+	if _, err := b.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if end, _, _ := b.Winner(); end {
+		t.Errorf("la partie reste gagnée après Undo\n%s", b)
+	}
+}
+
+func TestPlace(t *testing.T) {
+	b := NewBoard()
+	if err := b.Place(P1Token, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		token, x, y int
+		wantErr     error
+	}{
+		{P2Token, 2, 0, ErrCellOccupied},
+		{P2Token, Columns, 0, ErrCellOutOfRange},
+		{P2Token, 0, Rows, ErrCellOutOfRange},
+		{NoToken, 0, 0, ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		if err := b.Place(tt.token, tt.x, tt.y); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Place(%d, %d, %d) : %v, attendu %v", tt.token, tt.x, tt.y, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOpponent(t *testing.T) {
+	if Opponent(P1Token) != P2Token || Opponent(P2Token) != P1Token {
+		t.Error("Opponent n'échange pas les pions des deux joueurs")
+	}
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// boardJSON est la forme sérialisée d'une grille : la liste ordonnée des coups.
+// La grille elle-même est reconstruite en rejouant les coups.
+type boardJSON struct {
+	Moves []Move `json:"moves"`
+}
+
+// MarshalJSON sérialise la grille sous la forme de la liste de ses coups.
+func (b *Board) MarshalJSON() ([]byte, error) {
+	moves := b.moves
+	if moves == nil {
+		moves = []Move{}
+	}
+	return json.Marshal(boardJSON{Moves: moves})
+}
+
+// UnmarshalJSON reconstruit la grille en rejouant les coups sérialisés.
+// Chaque coup doit respecter la gravité, sinon la grille est refusée.
+func (b *Board) UnmarshalJSON(data []byte) error {
+	var raw boardJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	rebuilt, err := FromMoves(raw.Moves)
+	if err != nil {
+		return err
+	}
+	*b = *rebuilt
+	return nil
+}
+
+// FromMoves reconstruit une grille en rejouant les coups dans l'ordre.
+// Chaque coup doit atterrir sur la ligne indiquée, conformément à la gravité, les
+// joueurs doivent alterner et aucun coup ne peut suivre la fin de la partie.
+func FromMoves(moves []Move) (*Board, error) {
+	b := NewBoard()
+	for i, m := range moves {
+		if err := m.validate(); err != nil {
+			return nil, fmt.Errorf("coup %d : %w", i, err)
+		}
+		if err := b.checkNext(m.Token); err != nil {
+			return nil, fmt.Errorf("coup %d : %w", i, err)
+		}
+		y, err := b.Play(m.Token, m.X)
+		if err != nil {
+			return nil, fmt.Errorf("coup %d : %w", i, err)
+		}
+		if y != m.Y {
+			return nil, fmt.Errorf("coup %d : ligne %d attendue, %d reçue", i, y, m.Y)
+		}
+	}
+	return b, nil
+}
+
+// FromColumns reconstruit une grille à partir d'une suite de colonnes jouées
+// alternativement par le joueur first puis par son adversaire. Aucune colonne ne
+// peut suivre la fin de la partie.
+func FromColumns(first int, columns []int) (*Board, error) {
+	if first != P1Token && first != P2Token {
+		return nil, ErrInvalidToken
+	}
+	b := NewBoard()
+	token := first
+	for i, x := range columns {
+		if err := b.checkNext(token); err != nil {
+			return nil, fmt.Errorf("coup %d : %w", i, err)
+		}
+		if _, err := b.Play(token, x); err != nil {
+			return nil, fmt.Errorf("coup %d : %w", i, err)
+		}
+		token = Opponent(token)
+	}
+	return b, nil
+}
+
+// checkNext vérifie que le pion token peut être joué à la suite des coups de la grille :
+// la partie ne doit pas être terminée et token ne doit pas avoir joué le dernier coup.
+func (b *Board) checkNext(token int) error {
+	last, ok := b.LastMove()
+	if !ok {
+		return nil
+	}
+	if last.Token == token {
+		return ErrWrongTurn
+	}
+	if finished, _, _ := b.CheckEnd(last.X, last.Y); finished {
+		return ErrGameOver
+	}
+	return nil
+}
+
+// ColumnSequence renvoie la suite des colonnes jouées, du plus ancien coup au plus récent.
+func (b *Board) ColumnSequence() []int {
+	columns := make([]int, len(b.moves))
+	for i, m := range b.moves {
+		columns[i] = m.X
+	}
+	return columns
+}
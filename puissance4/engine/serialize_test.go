@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, columns := range [][]int{nil, {3}, {0, 1, 0, 1, 0, 1, 0}, drawnGame} {
+		b := mustColumns(t, columns)
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Board
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal(%s) : %v", data, err)
+		}
+		if decoded.Grid() != b.Grid() || !reflect.DeepEqual(decoded.ColumnSequence(), b.ColumnSequence()) {
+			t.Errorf("grille différente après sérialisation de %v", columns)
+		}
+	}
+}
+
+func TestFromMovesRejects(t *testing.T) {
+	bottom := Rows - 1
+	tests := []struct {
+		name    string
+		moves   []Move
+		wantErr error
+	}{
+		{"pion vide", []Move{{Token: NoToken, X: 0, Y: bottom}}, ErrInvalidToken},
+		{"colonne hors de la grille", []Move{{Token: P1Token, X: Columns, Y: bottom}}, ErrCellOutOfRange},
+		{"ligne hors de la grille", []Move{{Token: P1Token, X: 0, Y: Rows}}, ErrCellOutOfRange},
+		{
+			"même joueur deux fois",
+			[]Move{{Token: P1Token, X: 0, Y: bottom}, {Token: P1Token, X: 1, Y: bottom}},
+			ErrWrongTurn,
+		},
+		{
+			"coup après la victoire",
+			[]Move{
+				{Token: P1Token, X: 0, Y: 5}, {Token: P2Token, X: 1, Y: 5},
+				{Token: P1Token, X: 0, Y: 4}, {Token: P2Token, X: 1, Y: 4},
+				{Token: P1Token, X: 0, Y: 3}, {Token: P2Token, X: 1, Y: 3},
+				{Token: P1Token, X: 0, Y: 2}, {Token: P2Token, X: 1, Y: 2},
+			},
+			ErrGameOver,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromMoves(tt.moves); !errors.Is(err, tt.wantErr) {
+				t.Errorf("FromMoves : %v, attendu %v", err, tt.wantErr)
+			}
+			data, _ := json.Marshal(boardJSON{Moves: tt.moves})
+			var b Board
+			if err := json.Unmarshal(data, &b); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Unmarshal : %v, attendu %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFromMovesGravity(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves []Move
+	}{
+		{"pion en l'air", []Move{{Token: P1Token, X: 0, Y: 0}}},
+		{"case occupée", []Move{{Token: P1Token, X: 0, Y: Rows - 1}, {Token: P2Token, X: 0, Y: Rows - 1}}},
+	}
+	for _, tt := range tests {
+		if _, err := FromMoves(tt.moves); err == nil {
+			t.Errorf("%s : FromMoves accepte %v", tt.name, tt.moves)
+		}
+	}
+}
+
+func TestFromMovesFinishedGame(t *testing.T) {
+	// Une partie terminée sur son dernier coup reste valide
+	won := mustColumns(t, []int{0, 1, 0, 1, 0, 1, 0})
+	if _, err := FromMoves(won.Moves()); err != nil {
+		t.Errorf("FromMoves refuse une partie gagnée : %v", err)
+	}
+	drawn := mustColumns(t, drawnGame)
+	if _, err := FromMoves(drawn.Moves()); err != nil {
+		t.Errorf("FromMoves refuse une partie nulle : %v", err)
+	}
+}
+
+func TestFromColumnsRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		first   int
+		columns []int
+		wantErr error
+	}{
+		{"premier pion invalide", NoToken, []int{0}, ErrInvalidToken},
+		{"colonne hors de la grille", P1Token, []int{Columns}, ErrColumnOutOfRange},
+		{"colonne pleine", P1Token, []int{0, 0, 0, 0, 0, 0, 0}, ErrColumnFull},
+		{"coup après la victoire", P2Token, []int{0, 1, 0, 1, 0, 1, 0, 1}, ErrGameOver},
+	}
+	for _, tt := range tests {
+		if _, err := FromColumns(tt.first, tt.columns); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s : FromColumns : %v, attendu %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUnmarshalJSONMalformed(t *testing.T) {
+	var b Board
+	for _, data := range []string{`{"moves": 3}`, `[`, `{"moves": [{"token": "X"}]}`} {
+		if err := json.Unmarshal([]byte(data), &b); err == nil {
+			t.Errorf("Unmarshal(%s) accepte une grille illisible", data)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module puissance4
+
+go 1.21.3
@@ -0,0 +1,238 @@
+// Package notation lit et écrit les parties de puissance 4 sous forme de texte, dans
+// l'esprit de la notation PGN des échecs, pour les partager dans le chat, les joindre
+// à un rapport de bug ou les recharger dans un client.
+//
+// Une partie commence par des en-têtes entre crochets, suivis des coups numérotés
+// et du résultat :
+//
+//	[Event "Partie rapide 1"]
+//	[Site "localhost:8080"]
+//	[Date "2026.10.18"]
+//	[First "Joueur 0"]
+//	[Second "Joueur 1"]
+//	[Variant "standard"]
+//	[Result "1-0"]
+//
+//	1. 4 4 2. 5 3 3. 6 7 4. 2 1-0
+//
+// Chaque coup est le numéro de la colonne jouée, de 1 (à gauche) à 7 ; le joueur First
+// joue le premier coup de chaque paire. Le résultat vaut "1-0" si First gagne, "0-1" si
+// Second gagne, "1/2-1/2" en cas d'égalité et "*" si la partie n'est pas terminée. Les
+// commentaires s'écrivent entre accolades ou après un point-virgule, jusqu'à la fin de
+// la ligne. La forme compacte, sans en-tête ni numéro de coup ("4453 1-0", ou simplement
+// "4453"), tient dans un message de chat.
+package notation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"puissance4/engine"
+)
+
+// Noms des en-têtes usuels.
+const (
+	TagEvent       = "Event"       // Salle ou occasion de la partie
+	TagSite        = "Site"        // Serveur, ou "hors ligne"
+	TagDate        = "Date"        // Date de la partie, au format AAAA.MM.JJ
+	TagFirst       = "First"       // Joueur qui a joué le premier coup
+	TagSecond      = "Second"      // Son adversaire
+	TagFirstColor  = "FirstColor"  // Couleur de pion du premier joueur (numéro de la palette des clients)
+	TagSecondColor = "SecondColor" // Couleur de pion du second joueur
+	TagVariant     = "Variant"     // Règles de la partie, VariantStandard
+	TagResult      = "Result"      // Résultat, repris à la fin des coups
+	TagTimeControl = "TimeControl" // Cadence de la partie ("5m", "3m+2s", "20s/coup"), absente sans limite de temps
+	TagTermination = "Termination" // Fin de partie qui ne se lit pas sur la grille, par exemple TerminationTime
+)
+
+// Valeurs de l'en-tête Termination.
+const (
+	TerminationTime      = "time forfeit" // Partie perdue au temps
+	TerminationResign    = "resignation"  // Partie abandonnée par le perdant
+	TerminationAgreement = "agreement"    // Nulle acceptée par les deux joueurs
+	TerminationForfeit   = "abandoned"    // Partie perdue par le joueur qui a quitté la salle
+)
+
+// VariantStandard est la seule variante connue : grille de 7 colonnes sur 6 lignes,
+// quatre pions alignés pour gagner.
+const VariantStandard = "standard"
+
+// Résultats d'une partie.
+const (
+	ResultFirst   = "1-0"     // Le premier joueur a gagné
+	ResultSecond  = "0-1"     // Le second joueur a gagné
+	ResultDraw    = "1/2-1/2" // Égalité
+	ResultUnknown = "*"       // Partie en cours ou interrompue
+)
+
+// DateLayout est le format de l'en-tête Date.
+const DateLayout = "2006.01.02"
+
+// Erreurs renvoyées par Parse.
+var (
+	ErrNoMoves        = errors.New("aucun coup dans la partie")
+	ErrUnknownVariant = errors.New("variante inconnue")
+)
+
+// Tag est un en-tête de partie.
+type Tag struct {
+	Name  string // Nom de l'en-tête, par exemple TagEvent
+	Value string // Valeur, sans les guillemets
+}
+
+// Game est une partie notée.
+type Game struct {
+	Tags    []Tag  // En-têtes, dans l'ordre où ils sont écrits
+	Columns []int  // Colonnes jouées, de 0 à engine.Columns-1, dans l'ordre
+	Result  string // ResultFirst, ResultSecond, ResultDraw ou ResultUnknown
+}
+
+// Get renvoie la valeur de l'en-tête name, ou une chaîne vide s'il est absent.
+func (g *Game) Get(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// Set fixe la valeur de l'en-tête name, en l'ajoutant après les autres s'il est absent.
+func (g *Game) Set(name, value string) {
+	for i, t := range g.Tags {
+		if t.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// SetDate fixe l'en-tête Date au jour de t.
+func (g *Game) SetDate(t time.Time) {
+	g.Set(TagDate, t.Format(DateLayout))
+}
+
+// FromBoard note la partie de la grille b. Le joueur du premier coup devient First,
+// et le résultat est déduit de la grille.
+func FromBoard(b *engine.Board) *Game {
+	g := &Game{
+		Columns: b.ColumnSequence(),
+		Result:  ResultUnknown,
+	}
+	g.Set(TagVariant, VariantStandard)
+
+	moves := b.Moves()
+	if finished, result, _ := b.Winner(); finished && len(moves) > 0 {
+		switch result {
+		case engine.Equality:
+			g.Result = ResultDraw
+		case moves[0].Token:
+			g.Result = ResultFirst
+		default:
+			g.Result = ResultSecond
+		}
+	}
+	return g
+}
+
+// Board rejoue la partie et renvoie sa grille, le premier coup étant joué avec le pion
+// engine.P1Token. Elle refuse un coup impossible, un coup joué après la fin de la
+// partie et un résultat contredit par la grille.
+func (g *Game) Board() (*engine.Board, error) {
+	b := engine.NewBoard()
+	token := engine.P1Token
+	for i, x := range g.Columns {
+		if finished, _, _ := b.Winner(); finished {
+			return nil, fmt.Errorf("coup %d : la partie est déjà terminée", i+1)
+		}
+		if _, err := b.Play(token, x); err != nil {
+			return nil, fmt.Errorf("coup %d (colonne %d) : %w", i+1, x+1, err)
+		}
+		token = engine.Opponent(token)
+	}
+
+	// Une partie peut s'arrêter avant la fin de la grille (abandon, égalité acceptée),
+	// mais un alignement ou une grille pleine impose le résultat
+	if finished, result, _ := b.Winner(); finished {
+		expected := ResultDraw
+		switch result {
+		case engine.P1Token:
+			expected = ResultFirst
+		case engine.P2Token:
+			expected = ResultSecond
+		}
+		if g.Result != expected && g.Result != ResultUnknown {
+			return nil, fmt.Errorf("résultat %s incohérent avec la grille (%s)", g.Result, expected)
+		}
+	}
+	return b, nil
+}
+
+// String écrit la partie complète, en-têtes compris. L'en-tête Result est toujours
+// écrit, juste avant les coups.
+func (g *Game) String() string {
+	var sb strings.Builder
+	for _, t := range g.Tags {
+		if t.Name == TagResult {
+			continue
+		}
+		fmt.Fprintf(&sb, "[%s %s]\n", t.Name, quote(t.Value))
+	}
+	fmt.Fprintf(&sb, "[%s %s]\n\n", TagResult, quote(g.result()))
+
+	line := 0
+	write := func(word string) {
+		if line > 0 && line+1+len(word) > 79 {
+			sb.WriteByte('\n')
+			line = 0
+		} else if line > 0 {
+			sb.WriteByte(' ')
+			line++
+		}
+		sb.WriteString(word)
+		line += len(word)
+	}
+	for i, x := range g.Columns {
+		if i%2 == 0 {
+			write(fmt.Sprintf("%d. %d", i/2+1, x+1))
+		} else {
+			write(strconv.Itoa(x + 1))
+		}
+	}
+	write(g.result())
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// Compact renvoie la forme compacte de la partie : les colonnes jouées à la suite,
+// puis le résultat s'il est connu. Les en-têtes sont perdus.
+func (g *Game) Compact() string {
+	var sb strings.Builder
+	for _, x := range g.Columns {
+		sb.WriteByte(byte('1' + x))
+	}
+	if result := g.result(); result != ResultUnknown {
+		sb.WriteByte(' ')
+		sb.WriteString(result)
+	}
+	return sb.String()
+}
+
+// result renvoie le résultat de la partie, ResultUnknown s'il n'est pas renseigné.
+func (g *Game) result() string {
+	if g.Result == "" {
+		return ResultUnknown
+	}
+	return g.Result
+}
+
+// quote met value entre guillemets, en protégeant les guillemets et les barres obliques.
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
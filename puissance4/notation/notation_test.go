@@ -0,0 +1,139 @@
+package notation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"puissance4/engine"
+)
+
+// drawnGame remplit toute la grille sans qu'aucun joueur n'aligne quatre pions.
+var drawnGame = []int{
+	2, 5, 3, 5, 6, 3, 5, 6, 5, 0, 0, 2, 4, 2, 5, 5, 4, 4, 3, 2, 6,
+	6, 4, 0, 2, 6, 2, 0, 3, 1, 0, 4, 0, 6, 1, 3, 4, 3, 1, 1, 1, 1,
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []int
+		result  string
+	}{
+		{"victoire du premier", []int{3, 3, 4, 4, 5, 5, 6}, ResultFirst},
+		{"victoire du second", []int{0, 3, 1, 3, 0, 3, 1, 3}, ResultSecond},
+		{"grille pleine", drawnGame, ResultDraw},
+		{"partie interrompue", []int{3, 2, 4}, ResultUnknown},
+		{"abandon", []int{3, 2, 4, 1}, ResultSecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{Columns: tt.columns, Result: tt.result}
+			g.Set(TagEvent, `Salle "test" \ 1`)
+			g.SetDate(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+			g.Set(TagVariant, VariantStandard)
+
+			parsed, err := Parse(g.String())
+			if err != nil {
+				t.Fatalf("Parse(String()) : %v\n%s", err, g)
+			}
+			if !reflect.DeepEqual(parsed.Columns, g.Columns) || parsed.Result != g.Result {
+				t.Errorf("partie relue %v %s, attendu %v %s", parsed.Columns, parsed.Result, g.Columns, g.Result)
+			}
+			if parsed.Get(TagEvent) != g.Get(TagEvent) || parsed.Get(TagDate) != "2026.10.18" {
+				t.Errorf("en-têtes relus %+v, attendu %+v", parsed.Tags, g.Tags)
+			}
+
+			compact, err := Parse(g.Compact())
+			if err != nil {
+				t.Fatalf("Parse(Compact()) : %v", err)
+			}
+			if !reflect.DeepEqual(compact.Columns, g.Columns) || compact.Result != g.Result {
+				t.Errorf("forme compacte relue %v %s, attendu %v %s", compact.Columns, compact.Result, g.Columns, g.Result)
+			}
+		})
+	}
+}
+
+func TestFromBoard(t *testing.T) {
+	b, err := engine.FromColumns(engine.P1Token, []int{0, 1, 0, 1, 0, 1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := FromBoard(b)
+	if g.Result != ResultFirst || g.Compact() != "1212121 1-0" {
+		t.Errorf("FromBoard = %q", g.Compact())
+	}
+}
+
+func TestParseAccepts(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		columns []int
+		result  string
+	}{
+		{"compacte", "4453", []int{3, 3, 4, 2}, ResultUnknown},
+		{"numéros collés", "1.4 4 2.5 3 *", []int{3, 3, 4, 2}, ResultUnknown},
+		{"numéro après une paire incomplète absent", "1. 4 4 2. 5", []int{3, 3, 4}, ResultUnknown},
+		{"commentaires", "1. 4 {centre} 4 ; réponse\n2. 5 3", []int{3, 3, 4, 2}, ResultUnknown},
+		{"résultat de l'en-tête", "[Result \"0-1\"]\n1. 4 3", []int{3, 2}, ResultSecond},
+		{"résultat déduit de la grille", "1. 4 4 2. 5 5 3. 6 6 4. 7", []int{3, 3, 4, 4, 5, 5, 6}, ResultFirst},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("Parse(%q) : %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(g.Columns, tt.columns) || g.Result != tt.result {
+				t.Errorf("Parse(%q) = %v %s, attendu %v %s", tt.text, g.Columns, g.Result, tt.columns, tt.result)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr error  // Erreur attendue, nil si seul le message compte
+		message string // Fragment attendu dans le message d'erreur
+	}{
+		{"vide", "", ErrNoMoves, ""},
+		{"en-têtes seuls", "[Event \"x\"]", ErrNoMoves, ""},
+		{"numéro sauté", "1. 4 4 3. 5 5", nil, "numéro de coup"},
+		{"numéro répété", "1. 4 4 1. 5 5", nil, "numéro de coup"},
+		{"numéro au milieu d'une paire", "1. 4 2. 4 5", nil, "numéro de coup"},
+		{"premier numéro faux", "2. 4 4", nil, "numéro de coup"},
+		{"numéro illisible", "1. 4 4 x. 5", nil, "invalide"},
+		{"colonne 0", "1. 0", nil, "colonnes de 1 à 7"},
+		{"colonne 8", "48", nil, "colonnes de 1 à 7"},
+		{"colonne pleine", "4444444", engine.ErrColumnFull, ""},
+		{"coup après la victoire", "12121212", nil, "déjà terminée"},
+		{"résultat en double", "4 1-0 1-0", nil, "en double"},
+		{"coup après le résultat", "4 * 4", nil, "après le résultat"},
+		{"résultat contredit par l'en-tête", "[Result \"1-0\"]\n4 0-1", nil, "différent de l'en-tête"},
+		{"résultat inconnu", "[Result \"2-0\"]\n4", nil, "inconnu"},
+		{"résultat contredit par la grille", "1212121 0-1", nil, "incohérent"},
+		{"variante inconnue", "[Variant \"8x8\"]\n4", ErrUnknownVariant, ""},
+		{"en-tête non fermé", "[Event \"x\"\n4", nil, "non fermé"},
+		{"en-tête sans guillemets", "[Event x]\n4", nil, "invalide"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.text)
+			if err == nil {
+				t.Fatalf("Parse(%q) accepte la partie", tt.text)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse(%q) : %v, attendu %v", tt.text, err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Parse(%q) : %q ne contient pas %q", tt.text, err, tt.message)
+			}
+		})
+	}
+}
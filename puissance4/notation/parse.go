@@ -0,0 +1,232 @@
+package notation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"puissance4/engine"
+)
+
+// Parse lit une partie notée, complète ou sous forme compacte, et vérifie qu'elle
+// peut être rejouée (voir Game.Board).
+func Parse(text string) (*Game, error) {
+	g := &Game{}
+	tagResult := ""
+	p := parser{text: text, line: 1}
+
+	for {
+		p.skipSpaceAndComments()
+		if p.done() {
+			break
+		}
+		if p.peek() == '[' {
+			tag, err := p.tag()
+			if err != nil {
+				return nil, err
+			}
+			if tag.Name == TagResult {
+				tagResult = tag.Value
+			}
+			g.Set(tag.Name, tag.Value)
+			continue
+		}
+
+		word, line := p.word()
+		switch {
+		case isResult(word):
+			if g.Result != "" {
+				return nil, fmt.Errorf("ligne %d : résultat %s en double", line, word)
+			}
+			g.Result = word
+		case g.Result != "":
+			return nil, fmt.Errorf("ligne %d : %q après le résultat", line, word)
+		case strings.HasSuffix(word, "."):
+			if err := checkMoveNumber(word, len(g.Columns)); err != nil {
+				return nil, fmt.Errorf("ligne %d : %w", line, err)
+			}
+		default:
+			columns, err := columns(word)
+			if err != nil {
+				return nil, fmt.Errorf("ligne %d : %w", line, err)
+			}
+			g.Columns = append(g.Columns, columns...)
+		}
+	}
+
+	if len(g.Columns) == 0 {
+		return nil, ErrNoMoves
+	}
+	switch {
+	case g.Result == "" && tagResult != "":
+		if !isResult(tagResult) {
+			return nil, fmt.Errorf("résultat %q inconnu", tagResult)
+		}
+		g.Result = tagResult
+	case g.Result == "":
+		g.Result = ResultUnknown
+	case tagResult != "" && tagResult != g.Result:
+		return nil, fmt.Errorf("résultat %s différent de l'en-tête %s", g.Result, tagResult)
+	}
+	if variant := g.Get(TagVariant); variant != "" && variant != VariantStandard {
+		return nil, fmt.Errorf("%w : %s", ErrUnknownVariant, variant)
+	}
+
+	b, err := g.Board()
+	if err != nil {
+		return nil, err
+	}
+	// Le résultat d'une partie terminée sur la grille peut être omis
+	if g.Result == ResultUnknown {
+		g.Result = FromBoard(b).Result
+	}
+	return g, nil
+}
+
+// isResult indique si word est l'un des résultats d'une partie.
+func isResult(word string) bool {
+	switch word {
+	case ResultFirst, ResultSecond, ResultDraw, ResultUnknown:
+		return true
+	}
+	return false
+}
+
+// checkMoveNumber vérifie qu'un numéro de coup est de la forme "12." et qu'il annonce
+// la paire de coups suivante, played coups ayant déjà été lus.
+func checkMoveNumber(word string, played int) error {
+	digits := strings.TrimSuffix(word, ".")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return fmt.Errorf("numéro de coup %q invalide", word)
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || played%2 != 0 || n != played/2+1 {
+		return fmt.Errorf("numéro de coup %q inattendu après %d coups", word, played)
+	}
+	return nil
+}
+
+// columns lit un coup ("4") ou une suite de coups compacte ("4453") et renvoie les
+// colonnes jouées, numérotées à partir de 0.
+func columns(word string) ([]int, error) {
+	cols := make([]int, 0, len(word))
+	for _, r := range word {
+		if r < '1' || r >= '1'+engine.Columns {
+			return nil, fmt.Errorf("coup %q invalide : colonnes de 1 à %d attendues", word, engine.Columns)
+		}
+		cols = append(cols, int(r-'1'))
+	}
+	return cols, nil
+}
+
+// parser parcourt le texte d'une partie en suivant le numéro de ligne, pour les erreurs.
+type parser struct {
+	text string // Texte restant à lire
+	line int    // Ligne courante
+}
+
+// done indique si tout le texte a été lu.
+func (p *parser) done() bool {
+	return p.text == ""
+}
+
+// peek renvoie le prochain caractère sans l'avancer.
+func (p *parser) peek() byte {
+	return p.text[0]
+}
+
+// advance avance de n octets en comptant les fins de ligne.
+func (p *parser) advance(n int) {
+	p.line += strings.Count(p.text[:n], "\n")
+	p.text = p.text[n:]
+}
+
+// skipSpaceAndComments saute les blancs et les commentaires.
+func (p *parser) skipSpaceAndComments() {
+	for !p.done() {
+		switch c := p.peek(); {
+		case c == '{':
+			end := strings.IndexByte(p.text, '}')
+			if end < 0 {
+				end = len(p.text) - 1
+			}
+			p.advance(end + 1)
+		case c == ';':
+			end := strings.IndexByte(p.text, '\n')
+			if end < 0 {
+				end = len(p.text)
+			}
+			p.advance(end)
+		case c < 0x80 && unicode.IsSpace(rune(c)):
+			p.advance(1)
+		default:
+			return
+		}
+	}
+}
+
+// word lit un mot, jusqu'au prochain blanc, commentaire ou en-tête. Un numéro de coup
+// collé au coup ("1.4") est coupé après le point.
+func (p *parser) word() (string, int) {
+	line := p.line
+	end := strings.IndexFunc(p.text, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '{' || r == ';' || r == '['
+	})
+	if end < 0 {
+		end = len(p.text)
+	}
+	if dot := strings.IndexByte(p.text[:end], '.'); dot >= 0 && dot < end-1 {
+		end = dot + 1
+	}
+	word := p.text[:end]
+	p.advance(end)
+	return word, line
+}
+
+// tag lit un en-tête [Nom "valeur"].
+func (p *parser) tag() (Tag, error) {
+	line := p.line
+	end, quoted, escaped := -1, false, false
+	for i := 1; i < len(p.text) && end < 0; i++ {
+		switch c := p.text[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == '\n':
+			return Tag{}, fmt.Errorf("ligne %d : en-tête non fermé", line)
+		case c == ']' && !quoted:
+			end = i
+		}
+	}
+	if end < 0 {
+		return Tag{}, fmt.Errorf("ligne %d : en-tête non fermé", line)
+	}
+	raw := strings.TrimSpace(p.text[1:end])
+	p.advance(end + 1)
+
+	name, value, ok := strings.Cut(raw, " ")
+	value = strings.TrimSpace(value)
+	if !ok || name == "" || len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return Tag{}, fmt.Errorf("ligne %d : en-tête %q invalide, [Nom \"valeur\"] attendu", line, raw)
+	}
+	return Tag{Name: name, Value: unquote(value[1 : len(value)-1])}, nil
+}
+
+// unquote retire les protections ajoutées par quote.
+func unquote(value string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range value {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,182 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limites d'une cadence, pour qu'une salle ne garde pas ses joueurs indéfiniment.
+const (
+	MaxInitialTime   = 3 * time.Hour    // Temps de réflexion maximal de chaque joueur
+	MaxIncrementTime = 5 * time.Minute  // Incrément maximal ajouté après chaque coup
+	MaxPerMoveTime   = 10 * time.Minute // Temps maximal par coup
+)
+
+// TimeControl est la cadence d'une partie. Elle prend l'une de ces formes :
+//   - Initial seul : chaque joueur dispose d'un temps total pour toute la partie ;
+//   - Initial et Increment : même chose, Increment secondes étant ajoutées au joueur
+//     après chacun de ses coups (cadence Fischer) ;
+//   - PerMove seul : chaque coup doit être joué en PerMove secondes, le temps non
+//     utilisé est perdu.
+//
+// La valeur nulle signifie une partie sans limite de temps.
+type TimeControl struct {
+	Initial   int `json:"initial,omitempty"`   // Temps de réflexion de chaque joueur pour la partie, en secondes
+	Increment int `json:"increment,omitempty"` // Secondes ajoutées après chaque coup joué
+	PerMove   int `json:"perMove,omitempty"`   // Temps fixe par coup en secondes, à la place d'Initial
+}
+
+// Enabled indique si la cadence limite le temps des joueurs.
+func (tc TimeControl) Enabled() bool {
+	return tc.Initial > 0 || tc.PerMove > 0
+}
+
+// Budget renvoie le temps dont dispose chaque joueur au début de la partie.
+func (tc TimeControl) Budget() time.Duration {
+	if tc.PerMove > 0 {
+		return time.Duration(tc.PerMove) * time.Second
+	}
+	return time.Duration(tc.Initial) * time.Second
+}
+
+// Validate vérifie que la cadence est cohérente et respecte les limites du protocole.
+func (tc TimeControl) Validate() error {
+	switch {
+	case tc.Initial < 0 || tc.Increment < 0 || tc.PerMove < 0:
+		return fmt.Errorf("cadence invalide : durée négative")
+	case tc.PerMove > 0 && (tc.Initial > 0 || tc.Increment > 0):
+		return fmt.Errorf("une cadence par coup ne se combine pas avec un temps total ni un incrément")
+	case tc.Increment > 0 && tc.Initial == 0:
+		return fmt.Errorf("un incrément demande un temps total")
+	case time.Duration(tc.Initial)*time.Second > MaxInitialTime:
+		return fmt.Errorf("temps total de %s trop long (maximum %s)", formatSeconds(tc.Initial), formatDuration(MaxInitialTime))
+	case time.Duration(tc.Increment)*time.Second > MaxIncrementTime:
+		return fmt.Errorf("incrément de %s trop long (maximum %s)", formatSeconds(tc.Increment), formatDuration(MaxIncrementTime))
+	case time.Duration(tc.PerMove)*time.Second > MaxPerMoveTime:
+		return fmt.Errorf("temps par coup de %s trop long (maximum %s)", formatSeconds(tc.PerMove), formatDuration(MaxPerMoveTime))
+	}
+	return nil
+}
+
+// String écrit la cadence sous la forme lue par ParseTimeControl : "5m", "3m+2s",
+// "20s/coup" ou "aucune" pour une partie sans limite.
+func (tc TimeControl) String() string {
+	switch {
+	case tc.PerMove > 0:
+		return formatSeconds(tc.PerMove) + "/coup"
+	case tc.Initial > 0 && tc.Increment > 0:
+		return formatSeconds(tc.Initial) + "+" + formatSeconds(tc.Increment)
+	case tc.Initial > 0:
+		return formatSeconds(tc.Initial)
+	}
+	return "aucune"
+}
+
+// ParseTimeControl lit une cadence écrite comme par TimeControl.String. Les durées
+// suivent time.ParseDuration ; à la manière des échecs, un nombre sans unité compte
+// en minutes pour le temps total et en secondes pour l'incrément ou le temps par coup
+// ("5+3" vaut "5m+3s"). Une chaîne vide, "aucune" ou "none" désigne une partie sans
+// limite de temps.
+func ParseTimeControl(s string) (TimeControl, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "aucune", "none", "0":
+		return TimeControl{}, nil
+	}
+
+	var tc TimeControl
+	if perMove, ok := cutSuffix(s, "/coup", "/move"); ok {
+		seconds, err := parseSeconds(perMove, time.Second)
+		if err != nil {
+			return TimeControl{}, err
+		}
+		tc.PerMove = seconds
+	} else {
+		initial, increment, hasIncrement := strings.Cut(s, "+")
+		seconds, err := parseSeconds(initial, time.Minute)
+		if err != nil {
+			return TimeControl{}, err
+		}
+		tc.Initial = seconds
+		if hasIncrement {
+			if tc.Increment, err = parseSeconds(increment, time.Second); err != nil {
+				return TimeControl{}, err
+			}
+		}
+	}
+	if !tc.Enabled() {
+		return TimeControl{}, fmt.Errorf("cadence %q sans temps de réflexion", s)
+	}
+	if err := tc.Validate(); err != nil {
+		return TimeControl{}, err
+	}
+	return tc, nil
+}
+
+// cutSuffix retire de s le premier des suffixes trouvé.
+func cutSuffix(s string, suffixes ...string) (string, bool) {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return strings.TrimSpace(strings.TrimSuffix(s, suffix)), true
+		}
+	}
+	return s, false
+}
+
+// parseSeconds lit une durée en secondes entières ; un nombre sans unité est compté en unit.
+func parseSeconds(s string, unit time.Duration) (int, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return int(time.Duration(n) * unit / time.Second), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 || d%time.Second != 0 {
+		return 0, fmt.Errorf("%q n'est pas une durée en secondes entières (par exemple 30s ou 5m)", s)
+	}
+	return int(d / time.Second), nil
+}
+
+// formatSeconds écrit une durée en secondes avec l'unité la plus lisible : "5m", "90s", "1h".
+func formatSeconds(seconds int) string {
+	switch {
+	case seconds != 0 && seconds%3600 == 0:
+		return strconv.Itoa(seconds/3600) + "h"
+	case seconds != 0 && seconds%60 == 0:
+		return strconv.Itoa(seconds/60) + "m"
+	}
+	return strconv.Itoa(seconds) + "s"
+}
+
+// formatDuration écrit d comme formatSeconds.
+func formatDuration(d time.Duration) string {
+	return formatSeconds(int(d / time.Second))
+}
+
+// PlayerClock est la pendule d'un joueur.
+type PlayerClock struct {
+	ID        int `json:"id"`        // ID du joueur
+	Remaining int `json:"remaining"` // Temps restant en millisecondes, à l'envoi du message
+}
+
+// ClockPayload représente la charge utile d'un message de type "clock", envoyé aux
+// joueurs et aux spectateurs à chaque changement de trait et à la fin de la partie.
+// La pendule du joueur Running continue de tourner après l'envoi : le client la
+// décompte lui-même jusqu'au message suivant.
+type ClockPayload struct {
+	Control TimeControl   `json:"control"` // Cadence de la salle
+	Players []PlayerClock `json:"players"` // Pendules des joueurs, triées par ID
+	Running int           `json:"running"` // ID du joueur dont la pendule tourne, -1 si elles sont arrêtées
+}
+
+// Remaining renvoie le temps restant du joueur id à l'envoi du message, et false si
+// la pendule de ce joueur n'y figure pas.
+func (c ClockPayload) Remaining(id int) (time.Duration, bool) {
+	for _, p := range c.Players {
+		if p.ID == id {
+			return time.Duration(p.Remaining) * time.Millisecond, true
+		}
+	}
+	return 0, false
+}
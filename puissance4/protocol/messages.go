@@ -0,0 +1,121 @@
+// Package protocol regroupe les structures échangées entre le client et le serveur.
+// Chaque message est une ligne JSON contenant un type et une charge utile.
+package protocol
+
+import (
+	"encoding/json"
+
+	"puissance4/engine"
+)
+
+// Message est une structure générique pour échanger des données entre le serveur et les clients.
+// Chaque message a un type (Type) et une charge utile (Payload) spécifique à ce type.
+type Message struct {
+	Type    string      `json:"type"`    // Le type de message, par exemple : "move", "color", "chat", etc.
+	Payload interface{} `json:"payload"` // Les données spécifiques au message, de type générique (interface{})
+}
+
+// MovePayload représente la charge utile d'un message de type "move".
+// Elle contient les coordonnées de déplacement. Le client n'envoie que X et Y,
+// le serveur y ajoute l'auteur du coup et son pion lorsqu'il le relaie.
+type MovePayload struct {
+	X     int `json:"x"`     // Coordonnée X du déplacement
+	Y     int `json:"y"`     // Coordonnée Y du déplacement
+	ID    int `json:"id"`    // ID du joueur qui a joué le coup (ajouté par le serveur)
+	Token int `json:"token"` // Pion du joueur qui a joué le coup (ajouté par le serveur)
+}
+
+// ColorPayload représente la charge utile d'un message de type "color".
+// Elle contient la couleur sélectionnée par un joueur.
+type ColorPayload struct {
+	Color int `json:"color"` // Couleur sélectionnée (représentée par un entier)
+	ID    int `json:"id"`    // ID du joueur qui a choisi la couleur (ajouté par le serveur)
+}
+
+// SelectedPayload représente la charge utile pour un message indiquant une sélection d'élément.
+type SelectedPayload struct {
+	Selected string `json:"selected"` // Élément sélectionné
+}
+
+// ChatMessage représente la charge utile d'un message de type "chat".
+// Elle contient le texte envoyé par un joueur dans le chat.
+type ChatMessage struct {
+	Text string `json:"text"` // Texte du message envoyé par le joueur
+	ID   int    `json:"id"`   // ID de l'auteur du message (ajouté par le serveur)
+}
+
+// Coordinate représente une position dans un espace 2D, associée à un joueur (ID).
+// C'est l'élément de l'historique d'une partie envoyé par le serveur.
+type Coordinate struct {
+	ID   int  // ID du joueur
+	X    int  // Coordonnée X
+	Y    int  // Coordonnée Y
+	Hint bool // Coup joué après avoir demandé un indice
+}
+
+// PlayerInfo décrit un joueur d'une salle tel qu'il est présenté aux spectateurs.
+type PlayerInfo struct {
+	ID     int    `json:"id"`               // ID du joueur
+	Name   string `json:"name"`             // Nom affiché
+	Color  int    `json:"color"`            // Couleur choisie, -1 tant qu'elle n'a pas été choisie
+	Token  int    `json:"token"`            // Pion attribué (engine.P1Token ou engine.P2Token), engine.NoToken avant la partie
+	Rating int    `json:"rating,omitempty"` // Cote du joueur, 0 s'il n'est pas connecté à un compte
+}
+
+// SpectatePayload représente la charge utile d'un message de type "spectate_state".
+// C'est l'état complet d'une salle, envoyé à un spectateur lorsqu'il arrive puis à chaque
+// changement qui ne se résume pas à un coup (nouveau joueur, couleur, nouvelle partie).
+type SpectatePayload struct {
+	RoomID      int           `json:"roomId"`      // Identifiant de la salle observée
+	RoomName    string        `json:"roomName"`    // Nom de la salle observée
+	Players     []PlayerInfo  `json:"players"`     // Joueurs de la salle, triés par ID
+	Moves       []engine.Move `json:"moves"`       // Coups de la partie en cours, dans l'ordre
+	CurrentTurn int           `json:"currentTurn"` // ID du joueur dont c'est le tour, -1 hors partie
+	GameOver    bool          `json:"gameOver"`    // Indique si la partie en cours est terminée
+
+	Clock *ClockPayload `json:"clock,omitempty"` // Pendules de la partie en cours, absentes dans une salle sans cadence
+}
+
+// Étapes d'une partie, indiquées dans la charge utile d'un message "resumed".
+const (
+	PhaseWaiting = "waiting" // En attente du second joueur
+	PhaseColor   = "color"   // Sélection des couleurs
+	PhaseShifumi = "shifumi" // Pierre/feuille/ciseaux pour désigner le premier joueur
+	PhasePlaying = "playing" // Partie en cours
+	PhaseOver    = "over"    // Partie terminée, en attente d'un rematch
+)
+
+// ResumePayload représente la charge utile d'un message de type "resumed".
+// C'est l'état complet de la partie, vu par le joueur qui reprend sa session, qui lui
+// permet de revenir exactement où il en était.
+type ResumePayload struct {
+	ID            int           `json:"id"`            // ID retrouvé par le client
+	Token         string        `json:"token"`         // Jeton de reprise, inchangé
+	RoomID        int           `json:"roomId"`        // Salle du client, -1 s'il était dans le lobby
+	RoomName      string        `json:"roomName"`      // Nom de la salle
+	Phase         string        `json:"phase"`         // Étape de la partie (PhaseWaiting, PhaseColor...)
+	Moves         []Coordinate  `json:"moves"`         // Coups de la partie en cours, dans l'ordre
+	YourTurn      bool          `json:"yourTurn"`      // Indique si c'est au tour du client
+	FirstPlayer   int           `json:"firstPlayer"`   // ID du joueur qui a commencé la partie
+	YourColor     int           `json:"yourColor"`     // Couleur du client, -1 si elle n'est pas choisie
+	OpponentColor int           `json:"opponentColor"` // Couleur de l'adversaire, -1 si elle n'est pas choisie
+	YourWins      int           `json:"yourWins"`      // Parties gagnées par le client dans la salle
+	OpponentWins  int           `json:"opponentWins"`  // Parties gagnées par l'adversaire dans la salle
+	Chat          []ChatMessage `json:"chat"`          // Derniers messages du chat de la salle
+	GameOver      bool          `json:"gameOver"`      // Indique si la partie en cours est terminée
+	NoHints       bool          `json:"noHints"`       // Indique si les indices sont interdits dans la salle
+
+	Clock *ClockPayload `json:"clock,omitempty"` // Pendules de la partie en cours, absentes dans une salle sans cadence
+}
+
+// DecodePayload désérialise le payload générique en une structure cible spécifique.
+// Cette fonction est utile lorsque payload est reçu sous forme d'interface{} et doit être converti
+// en une structure typée spécifique target.
+// Elle utilise un double passage (Marshal -> Unmarshal) pour garantir une conversion correcte.
+func DecodePayload(payload interface{}, target interface{}) error {
+	jsonData, err := json.Marshal(payload) // Re-marshal pour convertir en bytes
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, target)
+}
@@ -0,0 +1,485 @@
+package protocol
+
+import "time"
+
+// IDPayload représente la charge utile d'un message de type "id", envoyé à chaque nouvelle connexion.
+type IDPayload struct {
+	ID    int    `json:"id"`    // ID attribué à la connexion
+	Token string `json:"token"` // Jeton de reprise de session
+	Grace int    `json:"grace"` // Délai (en secondes) pendant lequel une session perdue peut être reprise
+}
+
+// ResumeRequest représente la charge utile d'un message de type "resume".
+type ResumeRequest struct {
+	Token string `json:"token"` // Jeton reçu avec le message "id" de la connexion perdue
+}
+
+// MessagePayload est la charge utile des messages qui ne transportent qu'un texte
+// destiné au joueur ("ready", "rematch_waiting", "restart_ok", "resume_failed").
+type MessagePayload struct {
+	Message string `json:"message"` // Texte à afficher
+}
+
+// RoomInfo décrit une salle dans la liste envoyée aux clients du lobby.
+type RoomInfo struct {
+	ID         int          `json:"id"`         // Identifiant de la salle
+	Name       string       `json:"name"`       // Nom de la salle
+	Players    int          `json:"players"`    // Nombre de joueurs présents
+	MaxPlayers int          `json:"maxPlayers"` // Nombre de places
+	Spectators int          `json:"spectators"` // Nombre de spectateurs
+	Locked     bool         `json:"locked"`     // Indique si la salle est protégée par un mot de passe
+	NoHints    bool         `json:"noHints"`    // Indique si les indices sont interdits dans la salle
+	Members    []PlayerInfo `json:"members"`    // Joueurs présents, avec leur nom et leur cote
+	Clock      TimeControl  `json:"clock"`      // Cadence des parties de la salle
+}
+
+// RoomListPayload représente la charge utile d'un message de type "room_list".
+type RoomListPayload struct {
+	Rooms []RoomInfo `json:"rooms"` // Salles ouvertes, triées par identifiant
+}
+
+// CreateRoomPayload représente la charge utile d'un message de type "create_room".
+type CreateRoomPayload struct {
+	Name     string `json:"name"`               // Nom de la salle, un nom par défaut est choisi s'il est vide
+	Password string `json:"password,omitempty"` // Mot de passe de la salle, vide pour une salle ouverte
+	NoHints  bool   `json:"noHints,omitempty"`  // Interdit les indices dans la salle, par exemple pour une partie classée
+
+	Clock TimeControl `json:"clock"` // Cadence des parties de la salle, sans limite de temps si elle est nulle
+}
+
+// RoomRequest représente la charge utile des messages "join_room" et "spectate".
+type RoomRequest struct {
+	ID       int    `json:"id"`                 // Identifiant de la salle visée
+	Password string `json:"password,omitempty"` // Mot de passe de la salle, si elle est verrouillée
+}
+
+// RoomJoinedPayload représente la charge utile d'un message de type "room_joined".
+type RoomJoinedPayload struct {
+	ID      int    `json:"id"`      // Identifiant de la salle rejointe
+	Name    string `json:"name"`    // Nom de la salle rejointe
+	Players int    `json:"players"` // Nombre de joueurs présents, arrivant compris
+	NoHints bool   `json:"noHints"` // Indique si les indices sont interdits dans la salle
+
+	Clock TimeControl `json:"clock"` // Cadence des parties de la salle
+}
+
+// AddBotPayload représente la charge utile d'un message de type "add_bot".
+type AddBotPayload struct {
+	Level string `json:"level,omitempty"` // Niveau du robot (nom ou numéro), vide pour le niveau par défaut du serveur
+}
+
+// QueuePositionPayload représente la charge utile d'un message de type "queue_position".
+type QueuePositionPayload struct {
+	Position      int `json:"position"`      // Position dans la file, à partir de 1
+	Waiting       int `json:"waiting"`       // Nombre de clients dans la file
+	EstimatedWait int `json:"estimatedWait"` // Attente estimée en secondes
+}
+
+// ReconnectingPayload représente la charge utile d'un message de type "opponent_reconnecting".
+type ReconnectingPayload struct {
+	Seconds int `json:"seconds"` // Délai laissé à l'adversaire pour se reconnecter
+}
+
+// CursorPayload représente la charge utile d'un message de type "cursor_update".
+type CursorPayload struct {
+	Color int `json:"color"` // Couleur survolée sur la grille des couleurs
+}
+
+// TokenUpdatePayload représente la charge utile d'un message de type "token_update".
+// Le client n'envoie que la position, le serveur y ajoute l'auteur et son pion.
+type TokenUpdatePayload struct {
+	Position int `json:"position"` // Colonne au-dessus de laquelle se trouve le pion
+	ID       int `json:"id"`       // ID du joueur (ajouté par le serveur)
+	Token    int `json:"token"`    // Pion du joueur (ajouté par le serveur)
+}
+
+// ColorSelectCompletePayload représente la charge utile d'un message de type "color_select_complete".
+type ColorSelectCompletePayload struct {
+	FirstPlayer int `json:"firstPlayer"` // ID du premier joueur à avoir choisi sa couleur
+}
+
+// ShifumiPlayer décrit le choix d'un joueur au pierre/feuille/ciseaux.
+type ShifumiPlayer struct {
+	ID        int    `json:"id"`        // ID du joueur
+	Selection string `json:"selection"` // Choix du joueur ("pierre", "papier" ou "ciseaux")
+}
+
+// ShifumiResultPayload représente la charge utile d'un message de type "shifumi_result",
+// envoyé lorsque les deux joueurs ont fait le même choix.
+type ShifumiResultPayload struct {
+	Result  string        `json:"result"`  // Toujours ResultDraw
+	Player1 ShifumiPlayer `json:"player1"` // Choix du premier joueur
+	Player2 ShifumiPlayer `json:"player2"` // Choix du second joueur
+}
+
+// ShifumiCompletePayload représente la charge utile d'un message de type "shifumi_complete".
+type ShifumiCompletePayload struct {
+	Winner      int           `json:"winner"`      // ID du gagnant du pierre/feuille/ciseaux
+	FirstPlayer int           `json:"firstPlayer"` // ID du joueur qui commence la partie
+	Player1     ShifumiPlayer `json:"player1"`     // Choix du premier joueur
+	Player2     ShifumiPlayer `json:"player2"`     // Choix du second joueur
+}
+
+// MoveRejectedPayload représente la charge utile d'un message de type "move_rejected".
+type MoveRejectedPayload struct {
+	X        int    `json:"x"`        // Colonne du coup refusé
+	Y        int    `json:"y"`        // Ligne envoyée par le client
+	Reason   string `json:"reason"`   // Raison du refus
+	YourTurn bool   `json:"yourTurn"` // Indique si c'est toujours au tour du joueur
+}
+
+// Résultats d'une partie ou d'un pierre/feuille/ciseaux.
+const (
+	ResultWin  = "win"  // Un joueur a gagné
+	ResultDraw = "draw" // Égalité
+)
+
+// Raisons d'une fin de partie qui ne se lit pas sur la grille.
+const (
+	ReasonTimeout   = "timeout"   // Le perdant a dépassé son temps de réflexion
+	ReasonResign    = "resign"    // Le perdant a abandonné
+	ReasonAgreement = "agreement" // Nulle acceptée par les deux joueurs
+	ReasonForfeit   = "forfeit"   // Le perdant a quitté la salle pendant la partie
+)
+
+// GameOverPayload représente la charge utile d'un message de type "game_over".
+type GameOverPayload struct {
+	Result string   `json:"result"`           // ResultWin ou ResultDraw
+	Winner int      `json:"winner"`           // ID du gagnant, -1 en cas d'égalité
+	Cells  [][2]int `json:"cells"`            // Cases de l'alignement gagnant, vide si la partie s'est terminée autrement
+	Reason string   `json:"reason,omitempty"` // Raison de la fin (ReasonTimeout...), vide pour un alignement ou une grille pleine
+}
+
+// OfferPayload représente la charge utile des messages "draw_offered", "draw_declined",
+// "takeback_requested" et "takeback_declined", envoyés aux deux joueurs de la salle.
+type OfferPayload struct {
+	ID int `json:"id"` // ID du joueur qui a fait la proposition
+}
+
+// AnswerPayload représente la charge utile des messages "answer_draw" et "answer_takeback".
+type AnswerPayload struct {
+	Accept bool `json:"accept"` // Proposition de l'adversaire acceptée
+}
+
+// TakebackPayload représente la charge utile d'un message de type "takeback" : le joueur
+// qui a demandé la reprise retrouve la position d'avant son dernier coup, et la réponse
+// de son adversaire est retirée avec lui.
+type TakebackPayload struct {
+	Moves       []Coordinate `json:"moves"`       // Coups retirés, du plus récent au plus ancien
+	CurrentTurn int          `json:"currentTurn"` // ID du joueur au trait après la reprise
+}
+
+// HistoryPayload représente la charge utile d'un message de type "sent_history" :
+// les coups de la partie indexés par numéro de tour.
+type HistoryPayload map[int]Coordinate
+
+// ListGamesRequest représente la charge utile d'un message de type "list_games".
+type ListGamesRequest struct {
+	Offset int `json:"offset"` // Nombre de parties récentes à sauter, pour parcourir l'archive page par page
+	Limit  int `json:"limit"`  // Nombre de parties demandées, 0 pour la valeur par défaut du serveur
+}
+
+// GameRequest représente la charge utile d'un message de type "get_game".
+type GameRequest struct {
+	ID int `json:"id"` // Numéro de la partie archivée
+}
+
+// ArchivedPlayer décrit un joueur d'une partie archivée.
+type ArchivedPlayer struct {
+	ID           int    `json:"id"`                     // ID du joueur pendant la partie
+	Name         string `json:"name"`                   // Nom affiché
+	Color        int    `json:"color"`                  // Couleur choisie
+	Token        int    `json:"token"`                  // Pion joué (engine.P1Token ou engine.P2Token)
+	Bot          bool   `json:"bot"`                    // Indique si le joueur était un robot du serveur
+	Account      bool   `json:"account,omitempty"`      // Indique si le joueur était connecté à son compte (Name est alors son pseudo)
+	Rating       int    `json:"rating,omitempty"`       // Cote avant la partie, 0 sans compte
+	RatingChange int    `json:"ratingChange,omitempty"` // Variation de la cote due à la partie, pour une partie classée
+}
+
+// ArchivedMove est un coup d'une partie archivée.
+type ArchivedMove struct {
+	ID   int       `json:"id"`             // ID du joueur qui a joué le coup
+	X    int       `json:"x"`              // Colonne
+	Y    int       `json:"y"`              // Ligne d'arrivée du pion
+	At   time.Time `json:"at"`             // Heure du coup
+	Hint bool      `json:"hint,omitempty"` // Coup joué après avoir demandé un indice
+}
+
+// GameRecord représente la charge utile d'un message de type "game_record" :
+// une partie terminée telle qu'elle a été archivée par le serveur.
+type GameRecord struct {
+	ID        int              `json:"id"`        // Numéro de la partie dans l'archive, à partir de 1
+	RoomName  string           `json:"roomName"`  // Nom de la salle où la partie s'est jouée
+	Players   []ArchivedPlayer `json:"players"`   // Joueurs, celui qui a commencé en premier
+	Shifumi   []ShifumiPlayer  `json:"shifumi"`   // Choix au pierre/feuille/ciseaux, vide pour un rematch (le perdant commence)
+	Starter   int              `json:"starter"`   // ID du joueur qui a commencé
+	Moves     []ArchivedMove   `json:"moves"`     // Coups, dans l'ordre
+	Result    string           `json:"result"`    // ResultWin ou ResultDraw
+	Winner    int              `json:"winner"`    // ID du gagnant, -1 en cas d'égalité
+	StartedAt time.Time        `json:"startedAt"` // Début de la partie
+	EndedAt   time.Time        `json:"endedAt"`   // Fin de la partie
+	Imported  bool             `json:"imported"`  // Partie importée depuis sa notation, et non jouée sur le serveur
+	Rated     bool             `json:"rated"`     // Partie classée : les cotes des joueurs ont été mises à jour
+	Notation  string           `json:"notation"`  // Partie en notation texte (paquet notation), ajoutée à l'envoi
+
+	Reason string      `json:"reason,omitempty"` // Raison de la fin, comme dans GameOverPayload
+	Clock  TimeControl `json:"clock"`            // Cadence de la partie
+}
+
+// Duration renvoie la durée de la partie.
+func (g GameRecord) Duration() time.Duration {
+	return g.EndedAt.Sub(g.StartedAt)
+}
+
+// Summary renvoie le résumé de la partie présenté dans la liste des parties archivées.
+func (g GameRecord) Summary() GameSummary {
+	players := make([]string, len(g.Players))
+	winner := ""
+	for i, p := range g.Players {
+		players[i] = p.Name
+		if p.ID == g.Winner {
+			winner = p.Name
+		}
+	}
+	hints := 0
+	for _, m := range g.Moves {
+		if m.Hint {
+			hints++
+		}
+	}
+	return GameSummary{
+		ID:       g.ID,
+		RoomName: g.RoomName,
+		Players:  players,
+		Result:   g.Result,
+		Winner:   winner,
+		Moves:    len(g.Moves),
+		Seconds:  int(g.Duration().Seconds()),
+		EndedAt:  g.EndedAt,
+		Imported: g.Imported,
+		Hints:    hints,
+		Rated:    g.Rated,
+	}
+}
+
+// ImportGamePayload représente la charge utile d'un message de type "import_game".
+type ImportGamePayload struct {
+	Notation string `json:"notation"` // Partie en notation texte, complète ou compacte
+}
+
+// GameSummary résume une partie archivée dans la liste envoyée aux clients.
+type GameSummary struct {
+	ID       int       `json:"id"`       // Numéro de la partie, à demander avec "get_game"
+	RoomName string    `json:"roomName"` // Nom de la salle
+	Players  []string  `json:"players"`  // Noms des joueurs, celui qui a commencé en premier
+	Result   string    `json:"result"`   // ResultWin ou ResultDraw
+	Winner   string    `json:"winner"`   // Nom du gagnant, vide en cas d'égalité
+	Moves    int       `json:"moves"`    // Nombre de coups joués
+	Seconds  int       `json:"seconds"`  // Durée de la partie en secondes
+	EndedAt  time.Time `json:"endedAt"`  // Fin de la partie
+	Imported bool      `json:"imported"` // Partie importée depuis sa notation
+	Hints    int       `json:"hints"`    // Nombre de coups joués après avoir demandé un indice
+	Rated    bool      `json:"rated"`    // Partie classée
+}
+
+// GameListPayload représente la charge utile d'un message de type "game_list".
+type GameListPayload struct {
+	Games  []GameSummary `json:"games"`  // Parties demandées, des plus récentes aux plus anciennes
+	Offset int           `json:"offset"` // Position de la première partie renvoyée
+	Total  int           `json:"total"`  // Nombre de parties dans l'archive
+}
+
+// LoginPayload représente la charge utile d'un message de type "login". Un pseudo libre
+// crée le compte avec le mot de passe donné ; un compte existant demande son mot de
+// passe, ou le jeton renvoyé par "logged_in" lors d'une connexion précédente.
+type LoginPayload struct {
+	Name     string `json:"name"`               // Pseudo du compte
+	Password string `json:"password,omitempty"` // Mot de passe du compte
+	Token    string `json:"token,omitempty"`    // Jeton de compte, à la place du mot de passe
+}
+
+// PlayerStats est le classement et le bilan d'un compte.
+type PlayerStats struct {
+	Rating    int `json:"rating"`    // Cote arrondie
+	Deviation int `json:"deviation"` // Incertitude sur la cote : elle diminue à chaque partie et augmente avec l'inactivité
+	Games     int `json:"games"`     // Parties classées jouées
+	Wins      int `json:"wins"`      // Parties classées gagnées
+	Losses    int `json:"losses"`    // Parties classées perdues
+	Draws     int `json:"draws"`     // Parties classées nulles
+}
+
+// AccountPayload représente la charge utile d'un message de type "logged_in".
+type AccountPayload struct {
+	Name    string      `json:"name"`    // Pseudo du compte
+	Token   string      `json:"token"`   // Jeton à conserver pour se reconnecter sans mot de passe
+	Created bool        `json:"created"` // Indique si le compte vient d'être créé
+	Stats   PlayerStats `json:"stats"`   // Classement du compte
+}
+
+// RatingUpdatePayload représente la charge utile d'un message de type "rating_update",
+// envoyé à chaque joueur d'une partie classée lorsqu'elle se termine.
+type RatingUpdatePayload struct {
+	Stats          PlayerStats `json:"stats"`          // Classement après la partie
+	Change         int         `json:"change"`         // Variation de la cote
+	Opponent       string      `json:"opponent"`       // Pseudo de l'adversaire
+	OpponentRating int         `json:"opponentRating"` // Cote de l'adversaire après la partie
+}
+
+// RoomPlayersPayload représente la charge utile d'un message de type "room_players",
+// envoyé aux joueurs d'une salle à l'arrivée d'un joueur et après chaque partie classée.
+type RoomPlayersPayload struct {
+	Players []PlayerInfo `json:"players"` // Joueurs de la salle, triés par ID
+}
+
+// LeaderboardRequest représente la charge utile d'un message de type "get_leaderboard".
+type LeaderboardRequest struct {
+	Limit int `json:"limit"` // Nombre de joueurs par classement, 10 par défaut
+}
+
+// LeaderboardEntry est une ligne d'un classement.
+type LeaderboardEntry struct {
+	Rank        int    `json:"rank"`                  // Place dans le classement, à partir de 1 ; les ex aequo partagent la même
+	Name        string `json:"name"`                  // Pseudo du joueur
+	Value       int    `json:"value"`                 // Valeur classée : cote, meilleure série de victoires ou nombre de parties
+	Games       int    `json:"games"`                 // Parties jouées sur le serveur
+	Provisional bool   `json:"provisional,omitempty"` // Cote encore incertaine, faute de parties classées
+}
+
+// LeaderboardPayload représente la charge utile d'un message de type "leaderboard".
+type LeaderboardPayload struct {
+	Ratings   []LeaderboardEntry `json:"ratings"`   // Meilleures cotes
+	Streaks   []LeaderboardEntry `json:"streaks"`   // Plus longues séries de victoires
+	MostGames []LeaderboardEntry `json:"mostGames"` // Joueurs les plus assidus
+	Players   int                `json:"players"`   // Nombre de joueurs ayant terminé au moins une partie
+}
+
+// PlayerStatsRequest représente la charge utile d'un message de type "get_player_stats".
+type PlayerStatsRequest struct {
+	Name string `json:"name"` // Pseudo du joueur, celui du client s'il est vide
+}
+
+// OrderRecord est le bilan d'un joueur dans les parties où il a joué en premier ou en second.
+type OrderRecord struct {
+	Games  int `json:"games"`
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+	Draws  int `json:"draws"`
+}
+
+// PlayerProfile représente la charge utile d'un message de type "player_stats" : les
+// statistiques d'un joueur sur toutes ses parties terminées, classées ou non.
+type PlayerProfile struct {
+	Name             string      `json:"name"`             // Pseudo du joueur
+	Rating           PlayerStats `json:"rating"`           // Classement et bilan des parties classées
+	Games            int         `json:"games"`            // Parties terminées
+	Wins             int         `json:"wins"`             // Parties gagnées
+	Losses           int         `json:"losses"`           // Parties perdues
+	Draws            int         `json:"draws"`            // Parties nulles
+	First            OrderRecord `json:"first"`            // Bilan en jouant le premier coup
+	Second           OrderRecord `json:"second"`           // Bilan en jouant le second
+	AverageMoves     float64     `json:"averageMoves"`     // Nombre moyen de coups par partie, des deux joueurs
+	AverageSeconds   int         `json:"averageSeconds"`   // Durée moyenne d'une partie en secondes
+	Openings         []int       `json:"openings"`         // Premiers coups joués dans chaque colonne, en jouant en premier
+	FavouriteOpening int         `json:"favouriteOpening"` // Colonne d'ouverture préférée, -1 sans partie jouée en premier
+	ShifumiWins      int         `json:"shifumiWins"`      // Pierre/feuille/ciseaux gagnés pour jouer en premier
+	ShifumiLosses    int         `json:"shifumiLosses"`    // Pierre/feuille/ciseaux perdus
+	Streak           int         `json:"streak"`           // Victoires consécutives en cours
+	BestStreak       int         `json:"bestStreak"`       // Plus longue série de victoires
+	LastGame         time.Time   `json:"lastGame"`         // Fin de la dernière partie
+}
+
+// Formats de tournoi.
+const (
+	FormatRoundRobin = "round_robin" // Toutes rondes : chaque joueur rencontre tous les autres
+	FormatKnockout   = "knockout"    // Élimination directe : le perdant de chaque match est éliminé
+)
+
+// États d'un tournoi.
+const (
+	TournamentRegistration = "registration" // Inscriptions ouvertes
+	TournamentRunning      = "running"      // Rondes en cours
+	TournamentFinished     = "finished"     // Vainqueur désigné
+	TournamentCancelled    = "cancelled"    // Annulé avant son lancement
+)
+
+// États d'un match de tournoi.
+const (
+	MatchPending  = "pending"  // En attente d'une salle
+	MatchPlaying  = "playing"  // Joueurs placés dans la salle du match
+	MatchFinished = "finished" // Résultat connu
+)
+
+// CreateTournamentPayload représente la charge utile d'un message de type "create_tournament".
+type CreateTournamentPayload struct {
+	Name       string `json:"name"`       // Nom du tournoi, un nom par défaut s'il est vide
+	Format     string `json:"format"`     // FormatRoundRobin ou FormatKnockout
+	BestOf     int    `json:"bestOf"`     // Parties par match (1, 3, 5 ou 7), 1 par défaut
+	MaxPlayers int    `json:"maxPlayers"` // Nombre maximal d'inscrits, le maximum du serveur s'il vaut 0
+
+	Clock TimeControl `json:"clock"` // Cadence des parties du tournoi, sans limite de temps si elle est nulle
+}
+
+// TournamentRequest représente la charge utile des messages qui désignent un tournoi.
+type TournamentRequest struct {
+	ID int `json:"id"` // Identifiant du tournoi
+}
+
+// TournamentInfo résume un tournoi dans la liste envoyée aux clients.
+type TournamentInfo struct {
+	ID         int    `json:"id"`         // Identifiant du tournoi
+	Name       string `json:"name"`       // Nom du tournoi
+	Organizer  string `json:"organizer"`  // Nom de l'organisateur
+	Format     string `json:"format"`     // FormatRoundRobin ou FormatKnockout
+	BestOf     int    `json:"bestOf"`     // Parties par match
+	Status     string `json:"status"`     // TournamentRegistration, TournamentRunning...
+	Players    int    `json:"players"`    // Nombre d'inscrits
+	MaxPlayers int    `json:"maxPlayers"` // Nombre maximal d'inscrits
+	Round      int    `json:"round"`      // Ronde en cours, 0 avant le lancement
+	Rounds     int    `json:"rounds"`     // Nombre de rondes prévues, 0 avant le lancement
+
+	Clock TimeControl `json:"clock"` // Cadence des parties du tournoi
+}
+
+// TournamentListPayload représente la charge utile d'un message de type "tournament_list".
+type TournamentListPayload struct {
+	Tournaments []TournamentInfo `json:"tournaments"` // Tournois, triés par identifiant
+}
+
+// TournamentPlayer est un inscrit d'un tournoi et son bilan.
+type TournamentPlayer struct {
+	ID         int     `json:"id"`                  // ID du client inscrit
+	Name       string  `json:"name"`                // Nom affiché
+	Rating     int     `json:"rating,omitempty"`    // Cote lors du lancement, 0 sans compte
+	Seed       int     `json:"seed"`                // Tête de série, à partir de 1 (0 avant le lancement)
+	Rank       int     `json:"rank"`                // Place au classement du tournoi, 0 avant le lancement
+	Points     float64 `json:"points"`              // Points de match : 1 par match gagné, 0,5 par match nul
+	GamePoints float64 `json:"gamePoints"`          // Points de partie, qui départagent les ex aequo
+	Wins       int     `json:"wins"`                // Matchs gagnés
+	Draws      int     `json:"draws"`               // Matchs nuls
+	Losses     int     `json:"losses"`              // Matchs perdus
+	Eliminated bool    `json:"eliminated"`          // Éliminé (élimination directe)
+	Withdrawn  bool    `json:"withdrawn,omitempty"` // A abandonné le tournoi ou s'est déconnecté
+}
+
+// TournamentMatch est un match entre deux inscrits, ou l'exemption d'un joueur.
+type TournamentMatch struct {
+	Round   int        `json:"round"`             // Ronde du match, à partir de 1
+	Players [2]int     `json:"players"`           // IDs des joueurs, le second vaut -1 pour une exemption
+	Names   [2]string  `json:"names"`             // Noms des joueurs
+	Scores  [2]float64 `json:"scores"`            // Points de partie de chaque joueur dans le match
+	Games   int        `json:"games"`             // Parties terminées
+	Status  string     `json:"status"`            // MatchPending, MatchPlaying ou MatchFinished
+	Winner  int        `json:"winner"`            // ID du vainqueur, -1 pour un match nul ou sans vainqueur
+	Forfeit bool       `json:"forfeit,omitempty"` // Match gagné par forfait
+	RoomID  int        `json:"roomId,omitempty"`  // Salle du match en cours, à observer en spectateur
+}
+
+// TournamentPayload représente la charge utile d'un message de type "tournament" :
+// l'état complet d'un tournoi, envoyé à ses inscrits et à ceux qui le suivent.
+type TournamentPayload struct {
+	TournamentInfo
+	OrganizerID int                `json:"organizerId"` // ID du client organisateur
+	Entrants    []TournamentPlayer `json:"entrants"`    // Inscrits, triés par place une fois le tournoi lancé
+	Matches     []TournamentMatch  `json:"matches"`     // Matchs de toutes les rondes jouées ou en cours
+	Winner      string             `json:"winner"`      // Nom du vainqueur, vide tant que le tournoi n'est pas terminé
+}
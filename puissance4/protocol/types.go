@@ -0,0 +1,89 @@
+package protocol
+
+// Types des messages envoyés par les clients au serveur.
+const (
+	TypeHello            = "hello"             // Poignée de main : version et capacités du client (HelloPayload)
+	TypeResume           = "resume"            // Reprise d'une session perdue (ResumeRequest)
+	TypeDisconnect       = "disconnect"        // Déconnexion volontaire, sans charge utile
+	TypeListRooms        = "list_rooms"        // Demande de la liste des salles, sans charge utile
+	TypeCreateRoom       = "create_room"       // Création d'une salle (CreateRoomPayload)
+	TypeJoinRoom         = "join_room"         // Arrivée dans une salle (RoomRequest)
+	TypeSpectate         = "spectate"          // Observation d'une salle (RoomRequest)
+	TypeLeaveRoom        = "leave_room"        // Retour au lobby, sans charge utile
+	TypeQuickPlay        = "quick_play"        // Entrée dans la file de partie rapide, sans charge utile
+	TypeCancelQuickPlay  = "cancel_quick_play" // Sortie de la file de partie rapide, sans charge utile
+	TypeReady            = "ready"             // Joueur prêt à jouer (sans charge utile) ; renvoyé par le serveur (MessagePayload)
+	TypeRestartReady     = "restartReady"      // Joueur prêt pour un rematch, sans charge utile
+	TypeResetAll         = "resetAll"          // Remise à zéro de la salle, sans charge utile
+	TypeRequireHistory   = "require_history"   // Demande de l'historique de la partie, sans charge utile
+	TypeSelected         = "selected"          // Choix au pierre/feuille/ciseaux (SelectedPayload)
+	TypeAddBot           = "add_bot"           // Invitation d'un robot à la place libre de la salle (AddBotPayload)
+	TypeListGames        = "list_games"        // Demande de la liste des parties archivées (ListGamesRequest)
+	TypeGetGame          = "get_game"          // Demande d'une partie archivée (GameRequest)
+	TypeImportGame       = "import_game"       // Ajout à l'archive d'une partie notée (ImportGamePayload)
+	TypeHint             = "hint"              // Indice demandé pour le coup en cours, sans charge utile
+	TypeLogin            = "login"             // Connexion à un compte, créé s'il n'existe pas (LoginPayload)
+	TypeGetLeaderboard   = "get_leaderboard"   // Demande des classements des joueurs (LeaderboardRequest)
+	TypeGetPlayerStats   = "get_player_stats"  // Demande des statistiques d'un joueur (PlayerStatsRequest)
+	TypeListTournaments  = "list_tournaments"  // Demande de la liste des tournois, sans charge utile
+	TypeCreateTournament = "create_tournament" // Création d'un tournoi par son organisateur (CreateTournamentPayload)
+	TypeJoinTournament   = "join_tournament"   // Inscription à un tournoi (TournamentRequest)
+	TypeLeaveTournament  = "leave_tournament"  // Désinscription, ou abandon d'un tournoi commencé (TournamentRequest)
+	TypeStartTournament  = "start_tournament"  // Lancement du tournoi par son organisateur (TournamentRequest)
+	TypeGetTournament    = "get_tournament"    // Demande de l'état d'un tournoi, suivi ensuite (TournamentRequest)
+	TypeResign           = "resign"            // Abandon de la partie en cours, sans charge utile
+	TypeOfferDraw        = "offer_draw"        // Proposition de nulle, ou acceptation de celle de l'adversaire, sans charge utile
+	TypeAnswerDraw       = "answer_draw"       // Réponse à la proposition de nulle de l'adversaire (AnswerPayload)
+	TypeRequestTakeback  = "request_takeback"  // Demande de reprise du dernier coup du joueur, sans charge utile
+	TypeAnswerTakeback   = "answer_takeback"   // Réponse à la demande de reprise de l'adversaire (AnswerPayload)
+)
+
+// Types des messages échangés dans les deux sens : le serveur relaie à l'adversaire
+// (et aux spectateurs) ce qu'un joueur lui envoie, en complétant la charge utile.
+const (
+	TypeColor        = "color"         // Couleur choisie (ColorPayload)
+	TypeCursorUpdate = "cursor_update" // Curseur sur la grille des couleurs (CursorPayload)
+	TypeTokenUpdate  = "token_update"  // Position du pion au-dessus de la grille (TokenUpdatePayload)
+	TypeMove         = "move"          // Coup joué (MovePayload)
+	TypeChat         = "chat"          // Message du chat (ChatMessage)
+)
+
+// Types des messages envoyés par le serveur aux clients.
+const (
+	TypeID                  = "id"                    // ID et jeton de session attribués à la connexion (IDPayload)
+	TypeError               = "error"                 // Message refusé (ErrorPayload)
+	TypeRoomList            = "room_list"             // Liste des salles (RoomListPayload)
+	TypeRoomJoined          = "room_joined"           // Arrivée dans une salle confirmée (RoomJoinedPayload)
+	TypeRoomLeft            = "room_left"             // Retour au lobby confirmé, sans charge utile
+	TypeQueuePosition       = "queue_position"        // Position dans la file de partie rapide (QueuePositionPayload)
+	TypeQueueLeft           = "queue_left"            // Sortie de la file confirmée, sans charge utile
+	TypeSpectateState       = "spectate_state"        // État complet d'une salle observée (SpectatePayload)
+	TypeResumed             = "resumed"               // Session reprise (ResumePayload)
+	TypeResumeFailed        = "resume_failed"         // Reprise impossible (MessagePayload)
+	TypeOpponentReconnect   = "opponent_reconnecting" // Adversaire déconnecté, place conservée (ReconnectingPayload)
+	TypeOpponentReconnected = "opponent_reconnected"  // Adversaire reconnecté, sans charge utile
+	TypeOtherDisconnected   = "other_disconnected"    // Adversaire parti, sans charge utile
+	TypeColorSelectComplete = "color_select_complete" // Couleurs choisies par les deux joueurs (ColorSelectCompletePayload)
+	TypeShifumiResult       = "shifumi_result"        // Égalité au pierre/feuille/ciseaux (ShifumiResultPayload)
+	TypeShifumiComplete     = "shifumi_complete"      // Premier joueur désigné (ShifumiCompletePayload)
+	TypeMoveRejected        = "move_rejected"         // Coup refusé par le serveur (MoveRejectedPayload)
+	TypeGameOver            = "game_over"             // Fin de partie (GameOverPayload)
+	TypeRematchWaiting      = "rematch_waiting"       // Adversaire en attente de rematch (MessagePayload)
+	TypeRestartOK           = "restart_ok"            // Rematch accepté par les deux joueurs (MessagePayload)
+	TypeSentHistory         = "sent_history"          // Historique de la partie (HistoryPayload)
+	TypeGameList            = "game_list"             // Parties archivées, des plus récentes aux plus anciennes (GameListPayload)
+	TypeGameRecord          = "game_record"           // Partie archivée complète, demandée ou importée (GameRecord)
+	TypeLoggedIn            = "logged_in"             // Connexion au compte acceptée (AccountPayload)
+	TypeRatingUpdate        = "rating_update"         // Cote mise à jour après une partie classée (RatingUpdatePayload)
+	TypeRoomPlayers         = "room_players"          // Joueurs de la salle, avec leur compte et leur cote (RoomPlayersPayload)
+	TypeLeaderboard         = "leaderboard"           // Classements des joueurs (LeaderboardPayload)
+	TypePlayerStats         = "player_stats"          // Statistiques d'un joueur (PlayerProfile)
+	TypeTournamentList      = "tournament_list"       // Tournois en cours d'inscription, en cours ou récemment terminés (TournamentListPayload)
+	TypeTournament          = "tournament"            // État complet d'un tournoi, à chaque changement (TournamentPayload)
+	TypeClock               = "clock"                 // Pendules des joueurs, à chaque changement de trait (ClockPayload)
+	TypeDrawOffered         = "draw_offered"          // Nulle proposée par un joueur (OfferPayload)
+	TypeDrawDeclined        = "draw_declined"         // Proposition de nulle refusée (OfferPayload)
+	TypeTakebackRequested   = "takeback_requested"    // Reprise demandée par un joueur (OfferPayload)
+	TypeTakebackDeclined    = "takeback_declined"     // Demande de reprise refusée (OfferPayload)
+	TypeTakeback            = "takeback"              // Coups retirés après une reprise acceptée (TakebackPayload)
+)
@@ -0,0 +1,107 @@
+package protocol
+
+import "fmt"
+
+// Version est la version du protocole parlée par ce paquet. Elle augmente à chaque
+// changement incompatible du format ou du sens des messages.
+const Version = 1
+
+// MinVersion est la plus ancienne version du protocole encore acceptée.
+const MinVersion = 1
+
+// Capacités optionnelles annoncées lors de la poignée de main.
+const (
+	CapabilityLobby       = "lobby"       // Salles multiples
+	CapabilityQuickPlay   = "quick_play"  // File de partie rapide
+	CapabilitySpectate    = "spectate"    // Mode spectateur
+	CapabilityResume      = "resume"      // Reprise de session
+	CapabilityPassword    = "password"    // Serveur et salles protégés par mot de passe
+	CapabilityBots        = "bots"        // Robots joueurs fournis par le serveur
+	CapabilityArchive     = "archive"     // Parties terminées archivées par le serveur
+	CapabilityHints       = "hints"       // Indices autorisés, sauf dans les salles qui les interdisent
+	CapabilityAccounts    = "accounts"    // Comptes de joueurs et parties classées
+	CapabilityStats       = "stats"       // Classements et statistiques des joueurs
+	CapabilityTournaments = "tournaments" // Tournois organisés par le serveur
+	CapabilityClock       = "clock"       // Cadences et pendules, la partie étant perdue au temps
+	CapabilityOffers      = "offers"      // Abandon, propositions de nulle et reprises de coup
+)
+
+// Capabilities renvoie les capacités prises en charge par cette version du protocole.
+func Capabilities() []string {
+	return []string{CapabilityLobby, CapabilityQuickPlay, CapabilitySpectate, CapabilityResume, CapabilityPassword, CapabilityBots, CapabilityArchive, CapabilityHints, CapabilityAccounts, CapabilityStats, CapabilityTournaments, CapabilityClock, CapabilityOffers}
+}
+
+// HelloPayload représente la charge utile d'un message de type "hello".
+// Le client l'envoie dès qu'il a reçu son ID, le serveur lui répond avec la sienne.
+type HelloPayload struct {
+	Version      int      `json:"version"`            // Version du protocole
+	Capabilities []string `json:"capabilities"`       // Capacités optionnelles prises en charge
+	Software     string   `json:"software"`           // Nom du logiciel, pour les journaux
+	Password     string   `json:"password,omitempty"` // Mot de passe du serveur, envoyé par le client
+}
+
+// NewHello construit la charge utile "hello" de ce paquet pour le logiciel software.
+func NewHello(software string) HelloPayload {
+	return HelloPayload{
+		Version:      Version,
+		Capabilities: Capabilities(),
+		Software:     software,
+	}
+}
+
+// HasCapability indique si la capacité name a été annoncée.
+func (h HelloPayload) HasCapability(name string) bool {
+	for _, c := range h.Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVersion vérifie qu'une version annoncée par l'autre partie est compatible avec ce paquet.
+// L'erreur renvoyée est destinée à être affichée telle quelle au joueur.
+func CheckVersion(version int) error {
+	switch {
+	case version < MinVersion:
+		return fmt.Errorf("version du protocole %d trop ancienne (minimum %d) : mettez à jour le client", version, MinVersion)
+	case version > Version:
+		return fmt.Errorf("version du protocole %d trop récente (maximum %d) : mettez à jour le serveur", version, Version)
+	}
+	return nil
+}
+
+// Codes d'erreur des messages "error".
+const (
+	ErrCodeHelloRequired       = "hello_required"       // Message reçu avant la poignée de main, la connexion est fermée
+	ErrCodeIncompatibleVersion = "incompatible_version" // Version du protocole refusée, la connexion est fermée
+	ErrCodeAuthFailed          = "auth_failed"          // Mot de passe du serveur incorrect, la connexion est fermée
+	ErrCodeUnknownType         = "unknown_type"         // Type de message inconnu
+	ErrCodeBadPayload          = "bad_payload"          // Charge utile illisible pour ce type de message
+	ErrCodeNotInRoom           = "not_in_room"          // Message de jeu envoyé depuis le lobby
+	ErrCodeRoom                = "room"                 // Opération sur les salles impossible
+	ErrCodeRoomPassword        = "room_password"        // Mot de passe de la salle manquant ou incorrect
+	ErrCodeSpectator           = "spectator"            // Message de jeu envoyé par un spectateur
+	ErrCodeDisabled            = "disabled"             // Fonctionnalité désactivée sur ce serveur
+	ErrCodeArchive             = "archive"              // Partie archivée introuvable ou archive illisible
+	ErrCodeAccount             = "account"              // Connexion au compte refusée (pseudo invalide, mot de passe incorrect...)
+	ErrCodeStats               = "stats"                // Joueur sans statistiques ou statistiques illisibles
+	ErrCodeTournament          = "tournament"           // Opération sur un tournoi impossible (complet, déjà commencé, réservée à l'organisateur...)
+	ErrCodeOffer               = "offer"                // Abandon, nulle ou reprise impossible (aucune partie en cours, aucune proposition à laquelle répondre...)
+)
+
+// ErrorPayload représente la charge utile d'un message de type "error".
+type ErrorPayload struct {
+	Code    string `json:"code"`    // Code de l'erreur (ErrCodeHelloRequired...)
+	Message string `json:"message"` // Description lisible, affichable au joueur
+	Type    string `json:"type"`    // Type du message refusé, vide s'il n'y en a pas
+}
+
+// Fatal indique si l'erreur entraîne la fermeture de la connexion par le serveur.
+func (e ErrorPayload) Fatal() bool {
+	switch e.Code {
+	case ErrCodeHelloRequired, ErrCodeIncompatibleVersion, ErrCodeAuthFailed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+// Package rating calcule le classement des joueurs avec le système Glicko de Mark
+// Glickman : chaque joueur a une cote (1500 au départ) et un écart, l'incertitude sur
+// cette cote. Un joueur nouveau ou inactif a un grand écart ; sa cote bouge beaucoup à
+// chaque partie, puis se stabilise à mesure qu'il joue.
+//
+// Les cotes sont mises à jour après chaque partie, sans attendre la fin d'une période
+// de classement : c'est la variante « en ligne » de Glicko, adaptée à un serveur de jeu.
+package rating
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Réglages du classement.
+const (
+	DefaultRating    = 1500.0 // Cote d'un nouveau joueur
+	DefaultDeviation = 350.0  // Écart d'un nouveau joueur, qui est aussi l'écart maximal
+	MinDeviation     = 30.0   // Écart minimal : la cote d'un joueur régulier continue de bouger
+	DecayPerDay      = 25.0   // Croissance de l'écart par jour d'inactivité (constante c de Glicko)
+)
+
+// Scores d'une partie, du point de vue du joueur classé.
+const (
+	Loss = 0.0
+	Draw = 0.5
+	Win  = 1.0
+)
+
+// q est la constante ln(10)/400 des formules de Glicko.
+var q = math.Ln10 / 400
+
+// Rating est le classement d'un joueur.
+type Rating struct {
+	Value     float64 `json:"value"`     // Cote
+	Deviation float64 `json:"deviation"` // Écart : la cote réelle est dans Value ± 2×Deviation à 95 %
+}
+
+// New renvoie le classement d'un nouveau joueur.
+func New() Rating {
+	return Rating{Value: DefaultRating, Deviation: DefaultDeviation}
+}
+
+// String renvoie la cote arrondie et son écart, par exemple « 1523 ± 84 ».
+func (r Rating) String() string {
+	return fmt.Sprintf("%d ± %d", r.Rounded(), int(math.Round(r.Deviation)))
+}
+
+// Rounded renvoie la cote arrondie à l'entier, telle qu'elle est affichée.
+func (r Rating) Rounded() int {
+	return int(math.Round(r.Value))
+}
+
+// Provisional indique si la cote est encore trop incertaine pour figurer dans un classement.
+func (r Rating) Provisional() bool {
+	return r.Deviation > 110
+}
+
+// Decay renvoie le classement après idle d'inactivité : l'écart augmente, sans dépasser
+// DefaultDeviation, car la cote d'un joueur absent est moins sûre.
+func (r Rating) Decay(idle time.Duration) Rating {
+	days := idle.Hours() / 24
+	if days <= 0 {
+		return r
+	}
+	r.Deviation = math.Min(math.Sqrt(r.Deviation*r.Deviation+DecayPerDay*DecayPerDay*days), DefaultDeviation)
+	return r
+}
+
+// Expected renvoie le score attendu de player contre opponent, entre 0 et 1.
+func Expected(player, opponent Rating) float64 {
+	return 1 / (1 + math.Pow(10, -g(opponent.Deviation)*(player.Value-opponent.Value)/400))
+}
+
+// Update renvoie le classement de player après une partie contre opponent, terminée
+// avec le score score (Win, Draw ou Loss) pour player. Le classement de l'adversaire
+// se calcule en inversant les rôles, avec le score 1 - score.
+func Update(player, opponent Rating, score float64) Rating {
+	gj := g(opponent.Deviation)
+	e := Expected(player, opponent)
+	d2 := 1 / (q * q * gj * gj * e * (1 - e))
+	denominator := 1/(player.Deviation*player.Deviation) + 1/d2
+
+	return Rating{
+		Value:     player.Value + q/denominator*gj*(score-e),
+		Deviation: math.Max(math.Sqrt(1/denominator), MinDeviation),
+	}
+}
+
+// g atténue l'effet d'une partie contre un adversaire dont la cote est incertaine.
+func g(deviation float64) float64 {
+	return 1 / math.Sqrt(1+3*q*q*deviation*deviation/(math.Pi*math.Pi))
+}
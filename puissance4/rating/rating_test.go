@@ -0,0 +1,113 @@
+package rating
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Exemple de l'article de Glickman (« The Glicko system », 1999) : un joueur coté 1500
+// avec un écart de 200 rencontre trois adversaires.
+var (
+	glickmanPlayer    = Rating{Value: 1500, Deviation: 200}
+	glickmanOpponents = []Rating{{1400, 30}, {1550, 100}, {1700, 300}}
+)
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestGAndExpected(t *testing.T) {
+	// Valeurs publiées dans l'exemple
+	wantG := []float64{0.9955, 0.9531, 0.7242}
+	wantE := []float64{0.639, 0.432, 0.303}
+	for i, opponent := range glickmanOpponents {
+		if got := g(opponent.Deviation); !near(got, wantG[i], 0.00005) {
+			t.Errorf("g(%v) = %.4f, attendu %.4f", opponent.Deviation, got, wantG[i])
+		}
+		if got := Expected(glickmanPlayer, opponent); !near(got, wantE[i], 0.0005) {
+			t.Errorf("Expected contre %v = %.3f, attendu %.3f", opponent, got, wantE[i])
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	// Chaque partie de l'exemple, jouée seule dans sa période de classement
+	tests := []struct {
+		name     string
+		opponent Rating
+		score    float64
+		want     Rating
+	}{
+		{"victoire", glickmanOpponents[0], Win, Rating{1563.43, 175.22}},
+		{"nulle", glickmanOpponents[1], Draw, Rating{1511.55, 175.72}},
+		{"défaite", glickmanOpponents[2], Loss, Rating{1455.96, 186.76}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Update(glickmanPlayer, tt.opponent, tt.score)
+			if !near(got.Value, tt.want.Value, 0.01) || !near(got.Deviation, tt.want.Deviation, 0.01) {
+				t.Errorf("Update = %.2f ± %.2f, attendu %.2f ± %.2f", got.Value, got.Deviation, tt.want.Value, tt.want.Deviation)
+			}
+		})
+	}
+}
+
+func TestUpdateSymmetric(t *testing.T) {
+	a, b := New(), New()
+
+	// Une nulle entre joueurs égaux ne change pas les cotes mais réduit l'incertitude
+	drawn := Update(a, b, Draw)
+	if drawn.Value != DefaultRating || drawn.Deviation >= DefaultDeviation {
+		t.Errorf("nulle entre égaux : %v", drawn)
+	}
+
+	// Le gagnant prend ce que perd le perdant quand les écarts sont égaux
+	winner, loser := Update(a, b, Win), Update(b, a, Loss)
+	if !near(winner.Value-DefaultRating, DefaultRating-loser.Value, 1e-9) || winner.Value <= DefaultRating {
+		t.Errorf("victoire : %v, défaite : %v", winner, loser)
+	}
+}
+
+func TestMinDeviation(t *testing.T) {
+	r := Rating{Value: 1800, Deviation: MinDeviation}
+	for i := 0; i < 50; i++ {
+		r = Update(r, Rating{Value: 1800, Deviation: MinDeviation}, Draw)
+	}
+	if r.Deviation != MinDeviation {
+		t.Errorf("écart %v après 50 parties, attendu le minimum %v", r.Deviation, MinDeviation)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	tests := []struct {
+		name      string
+		deviation float64
+		idle      time.Duration
+		want      float64
+	}{
+		{"sans inactivité", 50, 0, 50},
+		{"un jour", 50, 24 * time.Hour, math.Sqrt(50*50 + DecayPerDay*DecayPerDay)},
+		{"dix jours", 50, 10 * 24 * time.Hour, math.Sqrt(50*50 + 10*DecayPerDay*DecayPerDay)},
+		{"plafonné", 300, 365 * 24 * time.Hour, DefaultDeviation},
+		{"durée négative", 80, -time.Hour, 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Rating{Value: 1700, Deviation: tt.deviation}.Decay(tt.idle)
+			if r.Value != 1700 || !near(r.Deviation, tt.want, 1e-9) {
+				t.Errorf("Decay(%v) = %v, attendu 1700 ± %.2f", tt.idle, r, tt.want)
+			}
+		})
+	}
+
+	// L'écart croît avec l'inactivité
+	previous := 0.0
+	for days := 0; days <= 200; days += 20 {
+		d := Rating{Value: 1500, Deviation: MinDeviation}.Decay(time.Duration(days) * 24 * time.Hour).Deviation
+		if d < previous {
+			t.Errorf("écart %v après %d jours, inférieur à %v", d, days, previous)
+		}
+		previous = d
+	}
+}
@@ -0,0 +1,236 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"puissance4/engine"
+)
+
+// Dimensions de la grille, identiques à celles du paquet engine.
+const (
+	width  = engine.Columns
+	height = engine.Rows
+	cells  = width * height
+)
+
+// Chaque colonne occupe height+1 bits : la case supplémentaire en haut de la colonne
+// reste toujours vide, ce qui évite qu'un alignement ne déborde d'une colonne à l'autre.
+const (
+	bottomMask = uint64(0x40810204081)                    // Case du bas de chaque colonne
+	boardMask  = bottomMask * ((uint64(1) << height) - 1) // Toutes les cases de la grille
+)
+
+// Erreurs renvoyées lors de la construction d'une position.
+var (
+	ErrGameOver      = errors.New("la partie est déjà terminée")
+	ErrInvalidColumn = errors.New("colonne invalide ou pleine")
+)
+
+// Position représente une grille sous forme de bitboard, du point de vue du joueur
+// au trait. La valeur zéro est la grille vide, le premier joueur ayant le trait.
+// Une Position est une petite valeur qui peut être copiée librement.
+type Position struct {
+	current uint64 // Pions du joueur au trait
+	mask    uint64 // Cases occupées par l'un ou l'autre joueur
+	moves   int    // Nombre de pions posés
+}
+
+// ParseMoves construit une position à partir d'une suite de colonnes numérotées de 1 à 7,
+// par exemple "4453". Les espaces sont ignorés. Une suite dont un coup termine la partie est refusée.
+func ParseMoves(sequence string) (Position, error) {
+	var p Position
+	i := 0
+	for _, r := range sequence {
+		if r == ' ' || r == '\t' {
+			continue
+		}
+		i++
+		if r < '1' || r > '0'+width {
+			return Position{}, fmt.Errorf("coup %d : %q n'est pas une colonne (1 à %d)", i, r, width)
+		}
+		if err := p.playChecked(int(r - '1')); err != nil {
+			return Position{}, fmt.Errorf("coup %d : %w", i, err)
+		}
+	}
+	return p, nil
+}
+
+// FromColumns construit une position à partir d'une suite de colonnes numérotées à
+// partir de 0, comme celles renvoyées par engine.Board.ColumnSequence.
+func FromColumns(columns []int) (Position, error) {
+	var p Position
+	for i, x := range columns {
+		if err := p.playChecked(x); err != nil {
+			return Position{}, fmt.Errorf("coup %d : %w", i+1, err)
+		}
+	}
+	return p, nil
+}
+
+// FromBoard convertit la grille b en position, token étant le pion du joueur au trait.
+func FromBoard(b *engine.Board, token int) (Position, error) {
+	if token != engine.P1Token && token != engine.P2Token {
+		return Position{}, engine.ErrInvalidToken
+	}
+	if finished, _, _ := b.Winner(); finished {
+		return Position{}, ErrGameOver
+	}
+	var p Position
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			cell := b.Cell(x, y)
+			if cell == engine.NoToken {
+				continue
+			}
+			bit := uint64(1) << (x*(height+1) + height - 1 - y)
+			p.mask |= bit
+			if cell == token {
+				p.current |= bit
+			}
+			p.moves++
+		}
+	}
+	return p, nil
+}
+
+// playChecked joue la colonne x après avoir vérifié qu'elle est jouable et qu'elle ne termine pas la partie.
+func (p *Position) playChecked(x int) error {
+	if !p.CanPlay(x) {
+		return ErrInvalidColumn
+	}
+	if p.IsWinningMove(x) {
+		return ErrGameOver
+	}
+	p.Play(x)
+	return nil
+}
+
+// MoveCount renvoie le nombre de pions posés.
+func (p Position) MoveCount() int {
+	return p.moves
+}
+
+// CanPlay indique si la colonne x (à partir de 0) n'est pas pleine.
+func (p Position) CanPlay(x int) bool {
+	return x >= 0 && x < width && p.mask&topMaskColumn(x) == 0
+}
+
+// Play pose un pion du joueur au trait dans la colonne x, qui doit être jouable.
+// Le trait passe ensuite à l'adversaire.
+func (p *Position) Play(x int) {
+	p.playMove((p.mask + bottomMaskColumn(x)) & columnMask(x))
+}
+
+// IsWinningMove indique si jouer la colonne x fait gagner le joueur au trait.
+func (p Position) IsWinningMove(x int) bool {
+	return p.winningPositions()&p.possible()&columnMask(x) != 0
+}
+
+// Key renvoie une clé identifiant la position de façon unique.
+func (p Position) Key() uint64 {
+	return p.current + p.mask
+}
+
+// String renvoie la grille sous la même forme que engine.Board.String, le joueur
+// qui a commencé la partie étant représenté par "X".
+func (p Position) String() string {
+	first := p.current
+	if p.moves%2 == 1 {
+		first = p.current ^ p.mask
+	}
+	var sb strings.Builder
+	for y := height - 1; y >= 0; y-- {
+		for x := 0; x < width; x++ {
+			bit := uint64(1) << (x*(height+1) + y)
+			switch {
+			case p.mask&bit == 0:
+				sb.WriteByte('.')
+			case first&bit != 0:
+				sb.WriteByte('X')
+			default:
+				sb.WriteByte('O')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// playMove pose le pion décrit par le bit move.
+func (p *Position) playMove(move uint64) {
+	p.current ^= p.mask
+	p.mask |= move
+	p.moves++
+}
+
+// canWinNext indique si le joueur au trait peut gagner immédiatement.
+func (p Position) canWinNext() bool {
+	return p.winningPositions()&p.possible() != 0
+}
+
+// possible renvoie les cases où un pion peut être posé.
+func (p Position) possible() uint64 {
+	return (p.mask + bottomMask) & boardMask
+}
+
+// possibleNonLosingMoves renvoie les coups jouables qui ne laissent pas l'adversaire
+// gagner au coup suivant. Elle ne doit être appelée que si le joueur au trait ne peut
+// pas gagner immédiatement.
+func (p Position) possibleNonLosingMoves() uint64 {
+	possible := p.possible()
+	opponentWin := p.opponentWinningPositions()
+	forced := possible & opponentWin
+	if forced != 0 {
+		if forced&(forced-1) != 0 {
+			return 0 // Deux menaces à parer : la partie est perdue
+		}
+		possible = forced
+	}
+	return possible &^ (opponentWin >> 1) // Ne pas jouer sous une case gagnante de l'adversaire
+}
+
+// moveScore compte les cases gagnantes obtenues en jouant move, pour ordonner les coups.
+func (p Position) moveScore(move uint64) int {
+	return bits.OnesCount64(winningPositions(p.current|move, p.mask))
+}
+
+func (p Position) winningPositions() uint64 {
+	return winningPositions(p.current, p.mask)
+}
+
+func (p Position) opponentWinningPositions() uint64 {
+	return winningPositions(p.current^p.mask, p.mask)
+}
+
+// winningPositions renvoie les cases libres qui compléteraient un alignement de position.
+func winningPositions(position, mask uint64) uint64 {
+	// Vertical
+	r := (position << 1) & (position << 2) & (position << 3)
+
+	// Horizontal puis les deux diagonales
+	for _, shift := range [3]uint{height + 1, height, height + 2} {
+		pair := (position << shift) & (position << (2 * shift))
+		r |= pair & (position << (3 * shift))
+		r |= pair & (position >> shift)
+		pair = (position >> shift) & (position >> (2 * shift))
+		r |= pair & (position << shift)
+		r |= pair & (position >> (3 * shift))
+	}
+
+	return r & (boardMask ^ mask)
+}
+
+func topMaskColumn(x int) uint64 {
+	return uint64(1) << (height - 1 + x*(height+1))
+}
+
+func bottomMaskColumn(x int) uint64 {
+	return uint64(1) << (x * (height + 1))
+}
+
+func columnMask(x int) uint64 {
+	return ((uint64(1) << height) - 1) << (x * (height + 1))
+}
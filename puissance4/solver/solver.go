@@ -0,0 +1,362 @@
+// Package solver calcule la valeur exacte d'une position du puissance 4 en jeu parfait :
+// victoire, défaite ou nulle pour le joueur au trait, et le nombre de coups avant la fin.
+// La grille est représentée par des bitboards ; la recherche negamax avec élagage
+// alpha-bêta s'appuie sur une table de transposition, un ordre des coups favorisant
+// les menaces et une recherche par fenêtre nulle.
+// Il sert de base aux indices, à l'analyse des parties et au niveau parfait de l'ordinateur.
+//
+// Faute de livre d'ouvertures, le coût d'une recherche croît très vite avec le nombre
+// de cases libres : quelques dizaines de millisecondes à partir de 12 pions posés, de
+// quelques centaines de millisecondes à plusieurs secondes vers 8 pions, et bien plus
+// en deçà (plusieurs minutes pour la grille vide ou la position 44). Solver.MaxNodes et
+// Solver.Timeout bornent une recherche, qui renvoie alors ErrBudgetExceeded.
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrBudgetExceeded est renvoyée par une recherche interrompue parce qu'elle a dépassé
+// Solver.MaxNodes positions ou duré plus que Solver.Timeout.
+var ErrBudgetExceeded = errors.New("budget de recherche dépassé")
+
+// timeCheckInterval est le nombre de positions explorées entre deux lectures de l'horloge.
+const timeCheckInterval = 1 << 14
+
+// minScore est le plus petit score possible. Un score positif est une victoire du
+// joueur au trait : il vaut 22 moins le nombre de pions du gagnant au moment de sa
+// victoire, de sorte qu'une victoire rapide a un score plus élevé. Un score négatif
+// est une défaite, 0 une nulle.
+const minScore = -cells/2 + 3
+
+// Outcome est l'issue d'une position en jeu parfait, pour le joueur au trait.
+type Outcome int
+
+// Issues possibles d'une position.
+const (
+	Loss Outcome = -1
+	Draw Outcome = 0
+	Win  Outcome = 1
+)
+
+// String renvoie le nom de l'issue.
+func (o Outcome) String() string {
+	switch o {
+	case Win:
+		return "victoire"
+	case Loss:
+		return "défaite"
+	default:
+		return "nulle"
+	}
+}
+
+// Result est la valeur exacte d'une position pour le joueur au trait.
+type Result struct {
+	Score   int     // Score de la position, positif pour une victoire, négatif pour une défaite
+	Outcome Outcome // Issue de la partie en jeu parfait
+	Plies   int     // Nombre de demi-coups jusqu'à la fin de la partie en jeu parfait, coup final inclus
+}
+
+// newResult interprète score pour le joueur au trait d'une position de moves pions.
+func newResult(score, moves int) Result {
+	switch {
+	case score > 0:
+		// Le joueur au trait gagne avec son (22 - score)-ième pion ; il en a posé moves/2
+		return Result{Score: score, Outcome: Win, Plies: 2*(cells/2+1-score-moves/2) - 1}
+	case score < 0:
+		// L'adversaire gagne avec son (22 + score)-ième pion ; il en a posé (moves+1)/2
+		return Result{Score: score, Outcome: Loss, Plies: 2 * (cells/2 + 1 + score - (moves+1)/2)}
+	default:
+		return Result{Outcome: Draw, Plies: cells - moves}
+	}
+}
+
+// String décrit le résultat, par exemple « victoire en 7 demi-coups ».
+func (r Result) String() string {
+	if r.Outcome == Draw {
+		return "nulle"
+	}
+	return fmt.Sprintf("%s en %d demi-coups", r.Outcome, r.Plies)
+}
+
+// MoveResult est la valeur d'un coup : celle de la position obtenue, vue par le joueur qui l'a joué.
+type MoveResult struct {
+	Column int    // Colonne jouée, à partir de 0
+	Result Result // Valeur du coup pour le joueur qui le joue
+}
+
+// columnOrder explore d'abord les colonnes centrales.
+var columnOrder = [width]int{3, 2, 4, 1, 5, 0, 6}
+
+// Solver résout des positions. Sa table de transposition est conservée d'une recherche
+// à l'autre, ce qui accélère l'analyse de positions proches, par exemple les coups
+// successifs d'une même partie. Un Solver n'est pas prévu pour être utilisé par
+// plusieurs goroutines à la fois.
+type Solver struct {
+	MaxNodes uint64        // Positions explorées au plus par recherche (Solve, SolveWeak, Analyze...), 0 sans limite
+	Timeout  time.Duration // Durée maximale d'une recherche, 0 sans limite
+
+	table    *transpositionTable
+	nodes    uint64
+	limit    uint64    // Valeur de nodes à laquelle la recherche en cours s'arrête, 0 sans limite
+	deadline time.Time // Fin de la recherche en cours, zéro sans limite
+	aborted  bool      // La recherche en cours a dépassé son budget
+}
+
+// New crée un solveur avec une table de transposition vide.
+func New() *Solver {
+	return &Solver{table: newTranspositionTable()}
+}
+
+// Nodes renvoie le nombre de positions explorées depuis la création du solveur.
+func (s *Solver) Nodes() uint64 {
+	return s.nodes
+}
+
+// Reset vide la table de transposition et remet le compteur de positions à zéro.
+func (s *Solver) Reset() {
+	s.table.reset()
+	s.nodes = 0
+}
+
+// Solve renvoie la valeur exacte de la position p pour le joueur au trait.
+func (s *Solver) Solve(p Position) (Result, error) {
+	if p.moves >= cells {
+		return Result{}, ErrGameOver
+	}
+	s.startSearch()
+	score := s.solve(p, false)
+	if s.aborted {
+		return Result{}, ErrBudgetExceeded
+	}
+	return newResult(score, p.moves), nil
+}
+
+// SolveWeak renvoie seulement l'issue de la position p, sans la distance à la fin
+// de la partie. Elle est nettement plus rapide que Solve.
+func (s *Solver) SolveWeak(p Position) (Outcome, error) {
+	if p.moves >= cells {
+		return Draw, ErrGameOver
+	}
+	s.startSearch()
+	score := s.solve(p, true)
+	switch {
+	case s.aborted:
+		return Draw, ErrBudgetExceeded
+	case score > 0:
+		return Win, nil
+	case score < 0:
+		return Loss, nil
+	default:
+		return Draw, nil
+	}
+}
+
+// Analyze renvoie la valeur de chaque coup jouable dans la position p, par ordre de colonne.
+func (s *Solver) Analyze(p Position) ([]MoveResult, error) {
+	if p.moves >= cells {
+		return nil, ErrGameOver
+	}
+	s.startSearch()
+	var results []MoveResult
+	for x := 0; x < width; x++ {
+		if !p.CanPlay(x) {
+			continue
+		}
+		var score int
+		if p.IsWinningMove(x) {
+			score = (cells + 1 - p.moves) / 2
+		} else {
+			child := p
+			child.Play(x)
+			if child.moves == cells {
+				score = 0
+			} else {
+				score = -s.solve(child, false)
+			}
+		}
+		if s.aborted {
+			return nil, ErrBudgetExceeded
+		}
+		results = append(results, MoveResult{Column: x, Result: newResult(score, p.moves)})
+	}
+	return results, nil
+}
+
+// BestMove renvoie le meilleur coup de la position p et sa valeur. Entre plusieurs
+// coups de même valeur, la colonne la plus centrale est choisie.
+func (s *Solver) BestMove(p Position) (MoveResult, error) {
+	results, err := s.Analyze(p)
+	if err != nil {
+		return MoveResult{}, err
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Result.Score != results[j].Result.Score {
+			return results[i].Result.Score > results[j].Result.Score
+		}
+		return centerDistance(results[i].Column) < centerDistance(results[j].Column)
+	})
+	return results[0], nil
+}
+
+// startSearch fixe le budget de la recherche qui commence.
+func (s *Solver) startSearch() {
+	s.aborted = false
+	s.limit = 0
+	if s.MaxNodes > 0 {
+		s.limit = s.nodes + s.MaxNodes
+	}
+	s.deadline = time.Time{}
+	if s.Timeout > 0 {
+		s.deadline = time.Now().Add(s.Timeout)
+	}
+}
+
+// exhausted indique si la recherche en cours a dépassé son budget ; elle est alors
+// marquée comme interrompue.
+func (s *Solver) exhausted() bool {
+	switch {
+	case s.aborted:
+	case s.limit != 0 && s.nodes >= s.limit:
+		s.aborted = true
+	case !s.deadline.IsZero() && s.nodes%timeCheckInterval == 0 && time.Now().After(s.deadline):
+		s.aborted = true
+	}
+	return s.aborted
+}
+
+func centerDistance(x int) int {
+	d := x - width/2
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// solve cherche le score de p par une suite de recherches à fenêtre nulle qui
+// resserrent l'intervalle [min, max] jusqu'à trouver la valeur exacte.
+func (s *Solver) solve(p Position, weak bool) int {
+	if p.canWinNext() {
+		return (cells + 1 - p.moves) / 2
+	}
+	min := -(cells - p.moves) / 2
+	max := (cells + 1 - p.moves) / 2
+	if weak {
+		min, max = -1, 1
+	}
+	for min < max {
+		med := min + (max-min)/2
+		if med <= 0 && min/2 < med {
+			med = min / 2
+		} else if med >= 0 && max/2 > med {
+			med = max / 2
+		}
+		r := s.negamax(p, med, med+1)
+		if s.aborted {
+			return 0
+		}
+		if r <= med {
+			max = r
+		} else {
+			min = r
+		}
+	}
+	return min
+}
+
+// negamax renvoie le score de p si il est dans ]alpha, beta[, sinon une borne :
+// une valeur inférieure ou égale à alpha, ou supérieure ou égale à beta.
+// Le joueur au trait ne doit pas pouvoir gagner immédiatement. Une fois le budget
+// dépassé, elle renvoie 0 sans rien enregistrer dans la table de transposition.
+func (s *Solver) negamax(p Position, alpha, beta int) int {
+	s.nodes++
+	if s.exhausted() {
+		return 0
+	}
+
+	next := p.possibleNonLosingMoves()
+	if next == 0 {
+		return -(cells - p.moves) / 2 // L'adversaire gagne au coup suivant
+	}
+	if p.moves >= cells-2 {
+		return 0 // Ni l'un ni l'autre ne peut plus gagner
+	}
+
+	// L'adversaire ne peut pas gagner au coup suivant : borne inférieure
+	if min := -(cells - 2 - p.moves) / 2; alpha < min {
+		alpha = min
+		if alpha >= beta {
+			return alpha
+		}
+	}
+
+	// Nous ne pouvons pas gagner immédiatement : borne supérieure, affinée par la table
+	max := (cells - 1 - p.moves) / 2
+	if value := s.table.get(p.Key()); value != 0 {
+		max = int(value) + minScore - 1
+	}
+	if beta > max {
+		beta = max
+		if alpha >= beta {
+			return beta
+		}
+	}
+
+	var moves moveSorter
+	for i := width - 1; i >= 0; i-- {
+		if move := next & columnMask(columnOrder[i]); move != 0 {
+			moves.add(move, p.moveScore(move))
+		}
+	}
+
+	for move := moves.next(); move != 0; move = moves.next() {
+		child := p
+		child.playMove(move)
+		score := -s.negamax(child, -beta, -alpha)
+		if s.aborted {
+			return 0
+		}
+		if score >= beta {
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	s.table.put(p.Key(), int8(alpha-minScore+1))
+	return alpha
+}
+
+// moveSorter trie au plus width coups par score croissant. À score égal, le dernier
+// coup ajouté est rendu en premier.
+type moveSorter struct {
+	size    int
+	entries [width]struct {
+		move  uint64
+		score int
+	}
+}
+
+func (m *moveSorter) add(move uint64, score int) {
+	pos := m.size
+	m.size++
+	for ; pos > 0 && m.entries[pos-1].score > score; pos-- {
+		m.entries[pos] = m.entries[pos-1]
+	}
+	m.entries[pos].move = move
+	m.entries[pos].score = score
+}
+
+// next renvoie le coup de meilleur score restant, ou 0 s'il n'y en a plus.
+func (m *moveSorter) next() uint64 {
+	if m.size == 0 {
+		return 0
+	}
+	m.size--
+	return m.entries[m.size].move
+}
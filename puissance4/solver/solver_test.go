@@ -0,0 +1,243 @@
+package solver
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"puissance4/engine"
+)
+
+// referenceScore calcule le score de la grille b pour le joueur token, au trait, par
+// une recherche exhaustive sans élagage, indépendante des bitboards du solveur.
+func referenceScore(b *engine.Board, token int) int {
+	best := -cells
+	for x := 0; x < width; x++ {
+		y, err := b.Play(token, x)
+		if err != nil {
+			continue
+		}
+		var score int
+		finished, result, _ := b.CheckEnd(x, y)
+		switch {
+		case finished && result != engine.Equality:
+			score = cells/2 + 1 - (b.MoveCount()+1)/2
+		case finished:
+			score = 0
+		default:
+			score = -referenceScore(b, engine.Opponent(token))
+		}
+		b.Undo()
+		if score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// randomGame joue au hasard n coups qui ne terminent pas la partie et renvoie les
+// colonnes jouées.
+func randomGame(r *rand.Rand, n int) []int {
+	for {
+		b := engine.NewBoard()
+		token := engine.P1Token
+		for b.MoveCount() < n {
+			var playable []int
+			for x := 0; x < width; x++ {
+				y, err := b.Play(token, x)
+				if err != nil {
+					continue
+				}
+				if finished, _, _ := b.CheckEnd(x, y); !finished {
+					playable = append(playable, x)
+				}
+				b.Undo()
+			}
+			if len(playable) == 0 {
+				break
+			}
+			b.Play(token, playable[r.Intn(len(playable))])
+			token = engine.Opponent(token)
+		}
+		if b.MoveCount() == n {
+			return b.ColumnSequence()
+		}
+	}
+}
+
+func mustParse(t *testing.T, sequence string) Position {
+	t.Helper()
+	p, err := ParseMoves(sequence)
+	if err != nil {
+		t.Fatalf("ParseMoves(%q) : %v", sequence, err)
+	}
+	return p
+}
+
+func TestSolveKnownPositions(t *testing.T) {
+	tests := []struct {
+		sequence string
+		want     Result
+		best     []int // Meilleures colonnes possibles, à partir de 0
+	}{
+		// Trois pions alignés en bas, deux extrémités libres : victoire au 4e pion
+		{"445566", Result{Score: 18, Outcome: Win, Plies: 1}, []int{2, 6}},
+		// Double menace de l'adversaire : défaite au coup suivant, quoi qu'on joue
+		{"44556", Result{Score: -18, Outcome: Loss, Plies: 2}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sequence, func(t *testing.T) {
+			p := mustParse(t, tt.sequence)
+			s := New()
+			got, err := s.Solve(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Solve = %+v, attendu %+v", got, tt.want)
+			}
+			if weak, err := s.SolveWeak(p); err != nil || weak != tt.want.Outcome {
+				t.Errorf("SolveWeak = %v, %v, attendu %v", weak, err, tt.want.Outcome)
+			}
+			best, err := s.BestMove(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if best.Result.Score != got.Score {
+				t.Errorf("BestMove = %+v, score attendu %d", best, got.Score)
+			}
+			if tt.best != nil && !contains(tt.best, best.Column) {
+				t.Errorf("BestMove joue la colonne %d, attendu l'une de %v", best.Column, tt.best)
+			}
+		})
+	}
+}
+
+func contains(columns []int, x int) bool {
+	for _, c := range columns {
+		if c == x {
+			return true
+		}
+	}
+	return false
+}
+
+// TestSolveAgainstReference compare le solveur à la recherche exhaustive sur des fins de partie.
+func TestSolveAgainstReference(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := New()
+	for i := 0; i < 30; i++ {
+		columns := randomGame(r, 32+r.Intn(6))
+		p, err := FromColumns(columns)
+		if err != nil {
+			t.Fatalf("FromColumns(%v) : %v", columns, err)
+		}
+		b, _ := engine.FromColumns(engine.P1Token, columns)
+		token := engine.P1Token + len(columns)%2
+		want := referenceScore(b, token)
+
+		got, err := s.Solve(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Score != want {
+			t.Errorf("%v : score %d, attendu %d\n%s", columns, got.Score, want, p)
+		}
+
+		// Le meilleur coup atteint ce score, et aucun coup ne le dépasse
+		results, err := s.Analyze(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		max := -cells
+		for _, m := range results {
+			if m.Result.Score > max {
+				max = m.Result.Score
+			}
+		}
+		if max != want {
+			t.Errorf("%v : meilleur coup de score %d, attendu %d", columns, max, want)
+		}
+		if best, _ := s.BestMove(p); best.Result.Score != want {
+			t.Errorf("%v : BestMove de score %d, attendu %d", columns, best.Result.Score, want)
+		}
+	}
+}
+
+// TestSolveWeakConsistent vérifie que l'issue seule concorde avec la valeur exacte en milieu de partie.
+func TestSolveWeakConsistent(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 10; i++ {
+		columns := randomGame(r, 18+r.Intn(6))
+		p, _ := FromColumns(columns)
+		strong, err := New().Solve(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		weak, err := New().SolveWeak(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if weak != strong.Outcome {
+			t.Errorf("%v : SolveWeak = %v, Solve = %v", columns, weak, strong)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	p := mustParse(t, "43443555")
+
+	s := New()
+	s.MaxNodes = 1000
+	if _, err := s.SolveWeak(p); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("SolveWeak avec 1000 positions : %v, attendu %v", err, ErrBudgetExceeded)
+	}
+	if _, err := s.Analyze(p); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Analyze avec 1000 positions : %v, attendu %v", err, ErrBudgetExceeded)
+	}
+
+	// Une recherche interrompue ne fausse pas la table de transposition
+	s.MaxNodes = 0
+	got, err := s.SolveWeak(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := New().SolveWeak(p); got != want {
+		t.Errorf("SolveWeak après une recherche interrompue = %v, attendu %v", got, want)
+	}
+
+	timed := New()
+	timed.Timeout = 1
+	if _, err := timed.SolveWeak(p); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("SolveWeak avec un délai de 1 ns : %v, attendu %v", err, ErrBudgetExceeded)
+	}
+}
+
+func TestParseMovesRejects(t *testing.T) {
+	tests := []struct {
+		sequence string
+		wantErr  error
+	}{
+		{"1111111", ErrInvalidColumn},
+		{"4545454", ErrGameOver},
+		{"12a", nil},
+		{"8", nil},
+	}
+	for _, tt := range tests {
+		_, err := ParseMoves(tt.sequence)
+		if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+			t.Errorf("ParseMoves(%q) : %v, attendu %v", tt.sequence, err, tt.wantErr)
+		}
+	}
+	if p := mustParse(t, "4 4 5"); p.MoveCount() != 3 {
+		t.Errorf("ParseMoves ignore mal les espaces : %d pions", p.MoveCount())
+	}
+}
+
+func TestSolveFullBoard(t *testing.T) {
+	var p Position
+	p.moves = cells
+	if _, err := New().Solve(p); !errors.Is(err, ErrGameOver) {
+		t.Errorf("Solve sur une grille pleine : %v, attendu %v", err, ErrGameOver)
+	}
+}
@@ -0,0 +1,43 @@
+package solver
+
+// tableSize est le nombre d'entrées de la table de transposition (environ 20 Mo).
+// C'est un nombre premier impair : comme tableSize × 2³² dépasse 2⁴⁹, les 32 bits de
+// poids faible d'une clé et son indice dans la table suffisent à l'identifier.
+const tableSize = 4194301
+
+// transpositionTable mémorise une borne supérieure du score des positions déjà
+// explorées. Une nouvelle entrée remplace celle qui occupait la même place.
+type transpositionTable struct {
+	keys   []uint32
+	values []int8
+}
+
+func newTranspositionTable() *transpositionTable {
+	return &transpositionTable{
+		keys:   make([]uint32, tableSize),
+		values: make([]int8, tableSize),
+	}
+}
+
+func (t *transpositionTable) reset() {
+	for i := range t.keys {
+		t.keys[i] = 0
+		t.values[i] = 0
+	}
+}
+
+// put associe value, qui ne doit pas être nulle, à la clé key.
+func (t *transpositionTable) put(key uint64, value int8) {
+	i := key % tableSize
+	t.keys[i] = uint32(key)
+	t.values[i] = value
+}
+
+// get renvoie la valeur associée à key, ou 0 si elle est absente.
+func (t *transpositionTable) get(key uint64) int8 {
+	i := key % tableSize
+	if t.keys[i] != uint32(key) {
+		return 0
+	}
+	return t.values[i]
+}
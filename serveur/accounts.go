@@ -0,0 +1,446 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/bcrypt"
+
+	"puissance4/protocol"
+	"puissance4/rating"
+)
+
+// accountsFile est le nom de la base des comptes dans le répertoire de données.
+const accountsFile = "comptes.db"
+
+// Contraintes sur les comptes.
+const (
+	minAccountName     = 2  // Longueur minimale d'un pseudo, en caractères
+	maxAccountName     = 20 // Longueur maximale d'un pseudo, en caractères
+	minAccountPassword = 4  // Longueur minimale d'un mot de passe
+	maxAccountTokens   = 5  // Jetons de compte conservés, un par appareil où le joueur s'est connecté
+)
+
+// accountsBucket est le bucket BoltDB des comptes, indexés par leur pseudo en minuscules.
+var accountsBucket = []byte("accounts")
+
+// Refus d'une connexion à un compte, affichés tels quels au joueur.
+var (
+	errAccountName     = fmt.Errorf("pseudo invalide : %d à %d lettres, chiffres, espaces, tirets ou points", minAccountName, maxAccountName)
+	errAccountPassword = errors.New("mot de passe incorrect")
+	errAccountShort    = fmt.Errorf("mot de passe trop court (%d caractères au moins)", minAccountPassword)
+	errAccountUnknown  = errors.New("compte inconnu")
+	errAccountToken    = errors.New("jeton de compte expiré, reconnectez-vous avec le mot de passe")
+	errAccountInUse    = errors.New("ce compte est déjà connecté")
+	errAccountInRoom   = errors.New("connectez-vous à votre compte depuis le lobby")
+)
+
+// accounts conserve les comptes des joueurs. Elle vaut nil si le serveur n'a pas de
+// répertoire de données ou si les comptes sont désactivés : toutes les parties sont alors anonymes.
+var accounts *accountStore
+
+// accountStore est la base des comptes des joueurs, stockée dans une base BoltDB.
+type accountStore struct {
+	db *bolt.DB
+}
+
+// account est un compte de joueur tel qu'il est enregistré.
+type account struct {
+	Name         string        `json:"name"`         // Pseudo, avec ses majuscules
+	PasswordHash []byte        `json:"passwordHash"` // Empreinte bcrypt du mot de passe
+	TokenHashes  []string      `json:"tokenHashes"`  // Empreintes SHA-256 des jetons de compte, du plus ancien au plus récent
+	Rating       rating.Rating `json:"rating"`       // Classement après la dernière partie classée
+	Wins         int           `json:"wins"`         // Parties classées gagnées
+	Losses       int           `json:"losses"`       // Parties classées perdues
+	Draws        int           `json:"draws"`        // Parties classées nulles
+	CreatedAt    time.Time     `json:"createdAt"`    // Création du compte
+	LastGame     time.Time     `json:"lastGame"`     // Fin de la dernière partie classée, zéro s'il n'en a pas joué
+}
+
+// rating renvoie le classement actuel du compte : l'écart augmente avec l'inactivité.
+func (a account) rating(now time.Time) rating.Rating {
+	if a.LastGame.IsZero() {
+		return a.Rating
+	}
+	return a.Rating.Decay(now.Sub(a.LastGame))
+}
+
+// stats renvoie le classement et le bilan du compte tels qu'ils sont envoyés aux clients.
+func (a account) stats(now time.Time) protocol.PlayerStats {
+	r := a.rating(now)
+	return protocol.PlayerStats{
+		Rating:    r.Rounded(),
+		Deviation: int(r.Deviation + 0.5),
+		Games:     a.Wins + a.Losses + a.Draws,
+		Wins:      a.Wins,
+		Losses:    a.Losses,
+		Draws:     a.Draws,
+	}
+}
+
+// openAccounts ouvre, en la créant si besoin, la base des comptes du répertoire dir.
+func openAccounts(dir string) (*accountStore, error) {
+	db, err := bolt.Open(filepath.Join(dir, accountsFile), 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("ouverture de la base des comptes : %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(accountsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("préparation de la base des comptes : %w", err)
+	}
+	return &accountStore{db: db}, nil
+}
+
+// close ferme la base des comptes.
+func (s *accountStore) close() error {
+	return s.db.Close()
+}
+
+// accountKey renvoie la clé du compte name : deux pseudos qui ne diffèrent que par
+// leurs majuscules désignent le même compte.
+func accountKey(name string) []byte {
+	return []byte(strings.ToLower(name))
+}
+
+// loadAccount lit le compte de clé key dans la transaction tx. Le booléen est faux
+// s'il n'existe pas.
+func loadAccount(tx *bolt.Tx, key []byte) (account, bool, error) {
+	var a account
+	data := tx.Bucket(accountsBucket).Get(key)
+	if data == nil {
+		return a, false, nil
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return a, false, fmt.Errorf("compte %s illisible : %w", key, err)
+	}
+	return a, true, nil
+}
+
+// storeAccount enregistre le compte a dans la transaction tx.
+func storeAccount(tx *bolt.Tx, a account) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(accountsBucket).Put(accountKey(a.Name), data)
+}
+
+// get renvoie le compte name. Le booléen est faux s'il n'existe pas.
+func (s *accountStore) get(name string) (account, bool, error) {
+	var a account
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		a, found, err = loadAccount(tx, accountKey(name))
+		return err
+	})
+	return a, found, err
+}
+
+// all renvoie tous les comptes, par ordre alphabétique de leur clé.
+func (s *accountStore) all() ([]account, error) {
+	var all []account
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(accountsBucket).ForEach(func(key, data []byte) error {
+			var a account
+			if err := json.Unmarshal(data, &a); err != nil {
+				return fmt.Errorf("compte %s illisible : %w", key, err)
+			}
+			all = append(all, a)
+			return nil
+		})
+	})
+	return all, err
+}
+
+// login vérifie les identifiants de payload et renvoie le compte, créé si le pseudo est
+// libre et qu'un mot de passe est donné. Une connexion par mot de passe renvoie un nouveau
+// jeton de compte ; une connexion par jeton renvoie le même jeton.
+func (s *accountStore) login(payload protocol.LoginPayload) (a account, token string, created bool, err error) {
+	name := strings.TrimSpace(payload.Name)
+	if !validAccountName(name) {
+		return a, "", false, errAccountName
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		var found bool
+		a, found, err = loadAccount(tx, accountKey(name))
+		switch {
+		case err != nil:
+			return err
+		case payload.Token != "":
+			if !found {
+				return errAccountUnknown
+			}
+			if !a.hasToken(payload.Token) {
+				return errAccountToken
+			}
+			token = payload.Token
+			return nil
+		case !found:
+			if len(payload.Password) < minAccountPassword {
+				return errAccountShort
+			}
+			hash, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return err
+			}
+			a = account{Name: name, PasswordHash: hash, Rating: rating.New(), CreatedAt: time.Now()}
+			created = true
+		case bcrypt.CompareHashAndPassword(a.PasswordHash, []byte(payload.Password)) != nil:
+			return errAccountPassword
+		}
+
+		token, err = a.addToken()
+		if err != nil {
+			return err
+		}
+		return storeAccount(tx, a)
+	})
+	return a, token, created, err
+}
+
+// addToken crée un nouveau jeton de compte et en garde l'empreinte ; les jetons les plus
+// anciens au-delà de maxAccountTokens sont oubliés.
+func (a *account) addToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	a.TokenHashes = append(a.TokenHashes, tokenHash(token))
+	if len(a.TokenHashes) > maxAccountTokens {
+		a.TokenHashes = a.TokenHashes[len(a.TokenHashes)-maxAccountTokens:]
+	}
+	return token, nil
+}
+
+// hasToken indique si token est l'un des jetons du compte.
+func (a account) hasToken(token string) bool {
+	hash := tokenHash(token)
+	for _, h := range a.TokenHashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenHash renvoie l'empreinte d'un jeton de compte : la base ne conserve pas les jetons eux-mêmes.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// validAccountName indique si name peut servir de pseudo. Les noms donnés aux joueurs
+// anonymes (« Joueur 3 ») sont réservés.
+func validAccountName(name string) bool {
+	length := utf8.RuneCountInString(name)
+	if length < minAccountName || length > maxAccountName {
+		return false
+	}
+	if strings.HasPrefix(strings.ToLower(name), "joueur ") {
+		return false
+	}
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune(" -_.", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordGame met à jour les comptes first et second après une partie classée, où first
+// a obtenu le score score (rating.Win, rating.Draw ou rating.Loss). Elle renvoie les deux
+// comptes avant et après la partie.
+func (s *accountStore) recordGame(first, second string, score float64) (before, after [2]account, err error) {
+	now := time.Now()
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		for i, name := range [2]string{first, second} {
+			a, found, err := loadAccount(tx, accountKey(name))
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("compte %s introuvable", name)
+			}
+			before[i] = a
+		}
+
+		scores := [2]float64{score, 1 - score}
+		for i := range after {
+			a := before[i]
+			a.Rating = rating.Update(before[i].rating(now), before[1-i].rating(now), scores[i])
+			a.LastGame = now
+			switch scores[i] {
+			case rating.Win:
+				a.Wins++
+			case rating.Loss:
+				a.Losses++
+			default:
+				a.Draws++
+			}
+			after[i] = a
+		}
+		for _, a := range after {
+			if err := storeAccount(tx, a); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return before, after, err
+}
+
+// onlineAccount est le compte auquel un client connecté s'est identifié.
+type onlineAccount struct {
+	name   string // Pseudo du compte
+	rating int    // Cote arrondie, mise à jour après chaque partie classée
+}
+
+var (
+	clientAccounts = make(map[int]onlineAccount) // Comptes des clients identifiés, associés à leur ID
+	accountMux     sync.Mutex                    // Protège clientAccounts ; ne doit pas être tenu en verrouillant une salle
+)
+
+// accountOf renvoie le compte du client id. Le booléen est faux si le client est anonyme.
+func accountOf(id int) (onlineAccount, bool) {
+	accountMux.Lock()
+	defer accountMux.Unlock()
+	a, ok := clientAccounts[id]
+	return a, ok
+}
+
+// logout oublie le compte du client id, lorsqu'il se déconnecte.
+func logout(id int) {
+	accountMux.Lock()
+	defer accountMux.Unlock()
+	delete(clientAccounts, id)
+}
+
+// bindAccount associe le compte a au client id, sauf s'il est déjà utilisé par un autre client.
+func bindAccount(id int, a onlineAccount) error {
+	accountMux.Lock()
+	defer accountMux.Unlock()
+	for other, online := range clientAccounts {
+		if other != id && strings.EqualFold(online.name, a.name) {
+			return errAccountInUse
+		}
+	}
+	clientAccounts[id] = a
+	return nil
+}
+
+// handleLogin identifie le client id avec le compte demandé, créé si le pseudo est libre.
+// Le client doit être dans le lobby : son nom ne change pas au milieu d'une partie.
+func handleLogin(payload protocol.LoginPayload, id int) {
+	if accounts == nil {
+		sendDisabled(id, protocol.TypeLogin, "ce serveur ne gère pas de comptes")
+		return
+	}
+	if roomOf(id) != nil {
+		sendError(id, protocol.ErrCodeAccount, protocol.TypeLogin, errAccountInRoom.Error())
+		return
+	}
+
+	a, token, created, err := accounts.login(payload)
+	if err == nil {
+		err = bindAccount(id, onlineAccount{name: a.Name, rating: a.rating(time.Now()).Rounded()})
+	}
+	if err != nil {
+		logWarnf("Connexion du client %d au compte %q refusée : %v\n", id, payload.Name, err)
+		sendError(id, protocol.ErrCodeAccount, protocol.TypeLogin, err.Error())
+		return
+	}
+	if created {
+		logInfof("Compte %s créé par le client %d\n", a.Name, id)
+	}
+	logInfof("Client %d connecté au compte %s\n", id, a.Name)
+
+	sendToClient(id, protocol.Message{
+		Type: protocol.TypeLoggedIn,
+		Payload: protocol.AccountPayload{
+			Name:    a.Name,
+			Token:   token,
+			Created: created,
+			Stats:   a.stats(time.Now()),
+		},
+	})
+}
+
+// rateGame met à jour les cotes des joueurs de la partie terminée record, si elle est
+// classée : les deux joueurs sont connectés à leur compte et aucun n'a demandé d'indice.
+// Les variations sont reportées dans record avant son archivage, et chaque joueur
+// reçoit son nouveau classement.
+func (r *room) rateGame(record *protocol.GameRecord) {
+	if accounts == nil || record == nil || len(record.Players) != 2 {
+		return
+	}
+	for _, p := range record.Players {
+		if !p.Account {
+			return
+		}
+	}
+	for _, m := range record.Moves {
+		if m.Hint {
+			return
+		}
+	}
+
+	first, second := record.Players[0], record.Players[1]
+	score := rating.Draw
+	switch record.Winner {
+	case first.ID:
+		score = rating.Win
+	case second.ID:
+		score = rating.Loss
+	}
+	before, after, err := accounts.recordGame(first.Name, second.Name, score)
+	if err != nil {
+		logErrorf("Mise à jour des cotes de %s et %s impossible : %v\n", first.Name, second.Name, err)
+		return
+	}
+
+	now := time.Now()
+	record.Rated = true
+	for i := range record.Players {
+		p := &record.Players[i]
+		newRating := after[i].rating(now).Rounded()
+		p.RatingChange = newRating - before[i].rating(now).Rounded()
+
+		accountMux.Lock()
+		if online, ok := clientAccounts[p.ID]; ok && strings.EqualFold(online.name, p.Name) {
+			online.rating = newRating
+			clientAccounts[p.ID] = online
+		}
+		accountMux.Unlock()
+
+		sendToClient(p.ID, protocol.Message{
+			Type: protocol.TypeRatingUpdate,
+			Payload: protocol.RatingUpdatePayload{
+				Stats:          after[i].stats(now),
+				Change:         p.RatingChange,
+				Opponent:       after[1-i].Name,
+				OpponentRating: after[1-i].rating(now).Rounded(),
+			},
+		})
+	}
+	logInfof("Partie classée entre %s (%+d) et %s (%+d)\n", first.Name, record.Players[0].RatingChange, second.Name, record.Players[1].RatingChange)
+	r.broadcastRoomPlayers()
+}
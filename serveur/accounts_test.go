@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"puissance4/protocol"
+	"puissance4/rating"
+)
+
+// newTestAccounts ouvre une base de comptes temporaire et y crée le compte de chaque
+// joueur, auquel il est identifié.
+func newTestAccounts(t *testing.T, players map[*testPlayer]string) {
+	store, err := openAccounts(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := accounts
+	accounts = store
+	t.Cleanup(func() {
+		accounts = saved
+		store.close()
+	})
+
+	for p, name := range players {
+		a, _, _, err := store.login(protocol.LoginPayload{Name: name, Password: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bindAccount(p.id, onlineAccount{name: a.Name, rating: a.Rating.Rounded()}); err != nil {
+			t.Fatal(err)
+		}
+		id := p.id
+		t.Cleanup(func() { logout(id) })
+	}
+}
+
+// accountRating renvoie le classement enregistré du compte name.
+func accountRating(t *testing.T, name string) rating.Rating {
+	t.Helper()
+	a, ok, err := accounts.get(name)
+	if err != nil || !ok {
+		t.Fatalf("compte %s introuvable : %v", name, err)
+	}
+	return a.Rating
+}
+
+// TestRateGameHint vérifie qu'une partie entre deux comptes est classée, sauf si l'un
+// des joueurs a demandé un indice.
+func TestRateGameHint(t *testing.T) {
+	for _, hint := range []bool{false, true} {
+		name := "sans indice"
+		if hint {
+			name = "avec indice"
+		}
+		t.Run(name, func(t *testing.T) {
+			r, a, b := newTestGame(t, protocol.TimeControl{})
+			newTestAccounts(t, map[*testPlayer]string{a: "Alice", b: "Bruno"})
+			r.resetServerState() // La partie à archiver connaît désormais les comptes
+
+			for i := 0; i < 3; i++ {
+				if hint && i == 1 {
+					r.hint(a.id)
+				}
+				play(t, r, a, 0)
+				play(t, r, b, 1)
+			}
+			play(t, r, a, 0)
+			a.expect(t, protocol.TypeGameOver, nil)
+
+			alice, bruno := accountRating(t, "Alice"), accountRating(t, "Bruno")
+			if hint {
+				if alice != rating.New() || bruno != rating.New() {
+					t.Errorf("cotes modifiées après une partie avec indice : %v, %v", alice, bruno)
+				}
+				return
+			}
+			if alice.Value <= rating.DefaultRating || bruno.Value >= rating.DefaultRating {
+				t.Errorf("cotes après la victoire d'Alice : %v, %v", alice, bruno)
+			}
+			var update protocol.RatingUpdatePayload
+			a.expect(t, protocol.TypeRatingUpdate, &update)
+			if update.Change <= 0 || update.Opponent != "Bruno" {
+				t.Errorf("rating_update %+v", update)
+			}
+		})
+	}
+}
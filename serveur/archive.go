@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"puissance4/engine"
+	"puissance4/notation"
+	"puissance4/protocol"
+)
+
+// archiveFile est le nom du fichier de l'archive des parties dans le répertoire de données.
+const archiveFile = "parties.db"
+
+// Taille des pages de la liste des parties archivées.
+const (
+	defaultGameListLimit = 20  // Parties renvoyées quand le client n'en précise pas le nombre
+	maxGameListLimit     = 100 // Parties renvoyées au plus par message "game_list"
+)
+
+// gamesBucket est le bucket BoltDB des parties, indexées par leur numéro en big-endian
+// pour que l'ordre des clés soit celui des parties.
+var gamesBucket = []byte("games")
+
+// archive conserve les parties terminées. Elle vaut nil si le serveur n'a pas de
+// répertoire de données : les parties ne sont alors pas conservées.
+var archive *gameArchive
+
+// gameArchive est l'archive des parties terminées, stockée dans une base BoltDB.
+type gameArchive struct {
+	db *bolt.DB
+}
+
+// openArchive ouvre, en la créant si besoin, l'archive des parties du répertoire dir.
+func openArchive(dir string) (*gameArchive, error) {
+	db, err := bolt.Open(filepath.Join(dir, archiveFile), 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("ouverture de l'archive des parties : %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(gamesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("préparation de l'archive des parties : %w", err)
+	}
+	return &gameArchive{db: db}, nil
+}
+
+// close ferme la base de l'archive.
+func (a *gameArchive) close() error {
+	return a.db.Close()
+}
+
+// gameKey renvoie la clé de la partie numéro id.
+func gameKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// save ajoute la partie record à l'archive et lui attribue son numéro.
+func (a *gameArchive) save(record *protocol.GameRecord) error {
+	return a.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(gamesBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		record.ID = int(id)
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(gameKey(id), data)
+	})
+}
+
+// get renvoie la partie numéro id. Le booléen est faux si elle n'existe pas.
+func (a *gameArchive) get(id int) (protocol.GameRecord, bool, error) {
+	var record protocol.GameRecord
+	found := false
+	if id < 1 {
+		return record, false, nil
+	}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(gamesBucket).Get(gameKey(uint64(id)))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &record)
+	})
+	return record, found, err
+}
+
+// list renvoie au plus limit résumés de parties, des plus récentes aux plus anciennes,
+// en sautant les offset plus récentes, ainsi que le nombre total de parties archivées.
+func (a *gameArchive) list(offset, limit int) ([]protocol.GameSummary, int, error) {
+	games := []protocol.GameSummary{}
+	total := 0
+	err := a.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(gamesBucket)
+		total = bucket.Stats().KeyN
+		cursor := bucket.Cursor()
+		skipped := 0
+		for key, data := cursor.Last(); key != nil && len(games) < limit; key, data = cursor.Prev() {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			var record protocol.GameRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return fmt.Errorf("partie %d illisible : %w", binary.BigEndian.Uint64(key), err)
+			}
+			games = append(games, record.Summary())
+		}
+		return nil
+	})
+	return games, total, err
+}
+
+// each appelle fn pour chaque partie archivée, de la plus ancienne à la plus récente.
+func (a *gameArchive) each(fn func(record protocol.GameRecord)) error {
+	return a.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).ForEach(func(key, data []byte) error {
+			var record protocol.GameRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return fmt.Errorf("partie %d illisible : %w", binary.BigEndian.Uint64(key), err)
+			}
+			fn(record)
+			return nil
+		})
+	})
+}
+
+// newRecord prépare l'enregistrement de la partie qui commence, starter jouant le premier coup.
+// Doit être appelée avec r.mu verrouillé, une fois les pions attribués.
+func (r *room) newRecord(starter int) *protocol.GameRecord {
+	record := &protocol.GameRecord{
+		RoomName:  r.name,
+		Starter:   starter,
+		Moves:     []protocol.ArchivedMove{},
+		Winner:    -1,
+		StartedAt: time.Now(),
+		Clock:     r.timeControl,
+	}
+	for id, token := range r.playerTokens {
+		player := protocol.ArchivedPlayer{
+			ID:    id,
+			Name:  playerName(id),
+			Color: r.playerColors[id],
+			Token: token,
+			Bot:   isBot(id),
+		}
+		if a, ok := accountOf(id); ok {
+			player.Account, player.Rating = true, a.rating
+		}
+		if id == starter {
+			record.Players = append([]protocol.ArchivedPlayer{player}, record.Players...)
+		} else {
+			record.Players = append(record.Players, player)
+		}
+	}
+	return record
+}
+
+// closeRecord reporte le résultat result (pion gagnant ou engine.Equality) dans la partie
+// à archiver. Doit être appelée avec r.mu verrouillé.
+func (r *room) closeRecord(result int) {
+	if r.record == nil {
+		return
+	}
+	r.record.EndedAt = time.Now()
+	r.record.Result = protocol.ResultDraw
+	if result != engine.Equality {
+		r.record.Result = protocol.ResultWin
+		r.record.Winner = r.playerByToken(result)
+	}
+}
+
+// archiveGame enregistre une partie terminée. Une erreur d'écriture est journalisée
+// sans interrompre le serveur : seule la partie concernée est perdue.
+func archiveGame(record *protocol.GameRecord) {
+	if archive == nil || record == nil {
+		return
+	}
+	if err := archive.save(record); err != nil {
+		logErrorf("Archivage de la partie de la salle %s impossible : %v\n", record.RoomName, err)
+		return
+	}
+	logInfof("Partie %d archivée (salle %s, %d coups)\n", record.ID, record.RoomName, len(record.Moves))
+}
+
+// handleListGames envoie au client id une page de la liste des parties archivées.
+func handleListGames(payload protocol.ListGamesRequest, id int) {
+	if archive == nil {
+		sendDisabled(id, protocol.TypeListGames, "ce serveur n'archive pas les parties")
+		return
+	}
+	limit := payload.Limit
+	if limit <= 0 {
+		limit = defaultGameListLimit
+	}
+	limit = min(limit, maxGameListLimit)
+	offset := max(payload.Offset, 0)
+
+	games, total, err := archive.list(offset, limit)
+	if err != nil {
+		logErrorf("Lecture de l'archive des parties impossible : %v\n", err)
+		sendError(id, protocol.ErrCodeArchive, protocol.TypeListGames, "archive des parties illisible")
+		return
+	}
+	sendToClient(id, protocol.Message{
+		Type: protocol.TypeGameList,
+		Payload: protocol.GameListPayload{
+			Games:  games,
+			Offset: offset,
+			Total:  total,
+		},
+	})
+}
+
+// handleGetGame envoie au client id la partie archivée demandée.
+func handleGetGame(payload protocol.GameRequest, id int) {
+	if archive == nil {
+		sendDisabled(id, protocol.TypeGetGame, "ce serveur n'archive pas les parties")
+		return
+	}
+	record, found, err := archive.get(payload.ID)
+	switch {
+	case err != nil:
+		logErrorf("Lecture de la partie archivée %d impossible : %v\n", payload.ID, err)
+		sendError(id, protocol.ErrCodeArchive, protocol.TypeGetGame, fmt.Sprintf("partie %d illisible", payload.ID))
+	case !found:
+		sendError(id, protocol.ErrCodeArchive, protocol.TypeGetGame, fmt.Sprintf("partie %d introuvable", payload.ID))
+	default:
+		record.Notation = recordNotation(record).String()
+		sendToClient(id, protocol.Message{
+			Type:    protocol.TypeGameRecord,
+			Payload: record,
+		})
+	}
+}
+
+// handleImportGame ajoute à l'archive la partie notée envoyée par le client id et la
+// lui renvoie telle qu'elle a été archivée, avec son numéro.
+func handleImportGame(payload protocol.ImportGamePayload, id int) {
+	if archive == nil {
+		sendDisabled(id, protocol.TypeImportGame, "ce serveur n'archive pas les parties")
+		return
+	}
+	game, err := notation.Parse(payload.Notation)
+	if err != nil {
+		logWarnf("Partie importée par le client %d refusée : %v\n", id, err)
+		sendError(id, protocol.ErrCodeArchive, protocol.TypeImportGame, "partie illisible : "+err.Error())
+		return
+	}
+	if game.Result == notation.ResultUnknown {
+		sendError(id, protocol.ErrCodeArchive, protocol.TypeImportGame, "seules les parties terminées sont archivées")
+		return
+	}
+
+	record := importedRecord(game)
+	if err := archive.save(record); err != nil {
+		logErrorf("Archivage de la partie importée par le client %d impossible : %v\n", id, err)
+		sendError(id, protocol.ErrCodeArchive, protocol.TypeImportGame, "archive des parties illisible")
+		return
+	}
+	logInfof("Partie %d importée par le client %d (%d coups)\n", record.ID, id, len(record.Moves))
+
+	record.Notation = recordNotation(*record).String()
+	sendToClient(id, protocol.Message{
+		Type:    protocol.TypeGameRecord,
+		Payload: record,
+	})
+}
+
+// terminations associe aux raisons d'une fin de partie la valeur de l'en-tête
+// Termination de sa notation.
+var terminations = map[string]string{
+	protocol.ReasonTimeout:   notation.TerminationTime,
+	protocol.ReasonResign:    notation.TerminationResign,
+	protocol.ReasonAgreement: notation.TerminationAgreement,
+	protocol.ReasonForfeit:   notation.TerminationForfeit,
+}
+
+// recordNotation note la partie archivée record.
+func recordNotation(record protocol.GameRecord) *notation.Game {
+	game := &notation.Game{Result: notation.ResultUnknown}
+	game.Set(notation.TagEvent, record.RoomName)
+	game.SetDate(record.StartedAt)
+	names := [2]string{notation.TagFirst, notation.TagSecond}
+	colors := [2]string{notation.TagFirstColor, notation.TagSecondColor}
+	for i := 0; i < len(record.Players) && i < 2; i++ {
+		game.Set(names[i], record.Players[i].Name)
+	}
+	for i := 0; i < len(record.Players) && i < 2; i++ {
+		game.Set(colors[i], strconv.Itoa(record.Players[i].Color))
+	}
+	game.Set(notation.TagVariant, notation.VariantStandard)
+	if record.Clock.Enabled() {
+		game.Set(notation.TagTimeControl, record.Clock.String())
+	}
+	if termination, ok := terminations[record.Reason]; ok {
+		game.Set(notation.TagTermination, termination)
+	}
+	switch {
+	case record.Result == protocol.ResultDraw:
+		game.Result = notation.ResultDraw
+	case record.Winner == record.Starter:
+		game.Result = notation.ResultFirst
+	case record.Winner != -1:
+		game.Result = notation.ResultSecond
+	}
+	for _, m := range record.Moves {
+		game.Columns = append(game.Columns, m.X)
+	}
+	return game
+}
+
+// importedRecord construit la partie à archiver à partir de la partie notée game, déjà
+// vérifiée et terminée. Ses joueurs reçoivent les IDs 0 (premier joueur) et 1, qui ne désignent aucun client :
+// un joueur sans nom dans les en-têtes est appelé « Joueur 0 » ou « Joueur 1 », jamais du nom d'un client connecté.
+func importedRecord(game *notation.Game) *protocol.GameRecord {
+	date := time.Now()
+	if d, err := time.Parse(notation.DateLayout, game.Get(notation.TagDate)); err == nil {
+		date = d
+	}
+	record := &protocol.GameRecord{
+		RoomName:  game.Get(notation.TagEvent),
+		Starter:   0,
+		Moves:     []protocol.ArchivedMove{},
+		Winner:    -1,
+		StartedAt: date,
+		EndedAt:   date,
+		Imported:  true,
+	}
+	names := [2]string{notation.TagFirst, notation.TagSecond}
+	colors := [2]string{notation.TagFirstColor, notation.TagSecondColor}
+	for i := range names {
+		name := game.Get(names[i])
+		if name == "" {
+			name = fmt.Sprintf("Joueur %d", i)
+		}
+		color, err := strconv.Atoi(game.Get(colors[i]))
+		if err != nil {
+			color = i
+		}
+		record.Players = append(record.Players, protocol.ArchivedPlayer{ID: i, Name: name, Color: color, Token: engine.P1Token + i})
+	}
+
+	if clock, err := protocol.ParseTimeControl(game.Get(notation.TagTimeControl)); err == nil {
+		record.Clock = clock
+	}
+	for reason, termination := range terminations {
+		if game.Get(notation.TagTermination) == termination {
+			record.Reason = reason
+		}
+	}
+
+	b, _ := game.Board()
+	for i, m := range b.Moves() {
+		record.Moves = append(record.Moves, protocol.ArchivedMove{ID: i % 2, X: m.X, Y: m.Y, At: date})
+	}
+	switch game.Result {
+	case notation.ResultFirst:
+		record.Result, record.Winner = protocol.ResultWin, 0
+	case notation.ResultSecond:
+		record.Result, record.Winner = protocol.ResultWin, 1
+	default:
+		record.Result = protocol.ResultDraw
+	}
+	return record
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"puissance4/notation"
+	"puissance4/protocol"
+)
+
+// TestImportedRecordNames vérifie qu'une partie importée sans noms de joueurs ne prend
+// pas ceux des clients connectés dont les IDs coïncident avec ceux de ses joueurs.
+func TestImportedRecordNames(t *testing.T) {
+	accountMux.Lock()
+	clientAccounts[0] = onlineAccount{name: "Alice", rating: 1500}
+	clientAccounts[1] = onlineAccount{name: "Bob", rating: 1500}
+	accountMux.Unlock()
+	t.Cleanup(func() {
+		accountMux.Lock()
+		delete(clientAccounts, 0)
+		delete(clientAccounts, 1)
+		accountMux.Unlock()
+	})
+
+	tests := []struct {
+		name string
+		text string
+		want [2]string
+	}{
+		{"sans en-têtes", "1212121 1-0", [2]string{"Joueur 0", "Joueur 1"}},
+		{"second joueur nommé", "[Second \"Carole\"]\n1212121 1-0", [2]string{"Joueur 0", "Carole"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, err := notation.Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			record := importedRecord(game)
+			if len(record.Players) != 2 {
+				t.Fatalf("%d joueurs, attendu 2", len(record.Players))
+			}
+			for i, p := range record.Players {
+				if p.Name != tt.want[i] {
+					t.Errorf("joueur %d nommé %q, attendu %q", i, p.Name, tt.want[i])
+				}
+			}
+			if record.Winner != 0 || record.Result != protocol.ResultWin || len(record.Moves) != 7 {
+				t.Errorf("partie importée %+v", record)
+			}
+		})
+	}
+}
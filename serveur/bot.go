@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"puissance4/ai"
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// botThinkTime est le délai minimal avant chaque action d'un robot, pour que son
+// adversaire ait le temps de voir le coup précédent.
+const botThinkTime = 600 * time.Millisecond
+
+// botColorCount est le nombre de couleurs proposées par le client, parmi lesquelles
+// le robot choisit la sienne.
+const botColorCount = 9
+
+// botSelections sont les choix possibles au pierre/feuille/ciseaux.
+var botSelections = []string{"pierre", "papier", "ciseaux"}
+
+var (
+	bots          = make(map[int]*bot)          // Robots connectés, associés à leur ID de client.
+	botFillTimers = make(map[*room]*time.Timer) // Arrivée programmée d'un robot auprès d'un joueur seul.
+	botMux        sync.Mutex                    // Mutex protégeant bots et botFillTimers.
+)
+
+// bot est un joueur simulé par le serveur. Il est relié au serveur par une connexion
+// en mémoire et parle le même protocole qu'un vrai client : poignée de main, arrivée
+// dans la salle, couleur, pierre/feuille/ciseaux, coups et rematchs. Seul le choix de
+// ses coups, confié au paquet ai, le distingue d'un joueur humain.
+type bot struct {
+	id       int        // ID de client attribué par le serveur
+	roomID   int        // Salle à rejoindre
+	password string     // Mot de passe de la salle
+	player   *ai.Player // Choix des coups
+	rng      *rand.Rand // Choix de la couleur et du pierre/feuille/ciseaux
+	conn     net.Conn   // Extrémité de la connexion côté robot
+
+	out   chan protocol.Message // Messages à envoyer au serveur
+	moves chan botMove          // Coups calculés par le robot
+	done  chan struct{}         // Fermé lorsque le robot s'arrête
+
+	board         engine.Board // Grille de la partie en cours, tenue à jour comme le ferait un client
+	token         int          // Pion du robot, engine.NoToken hors partie
+	game          int          // Numéro de la partie en cours, augmenté à chaque reprise, pour écarter les coups calculés trop tard
+	firstPlayer   int          // ID du joueur qui commence en cas d'égalité
+	nextStarter   int          // ID du joueur qui commencera la prochaine partie
+	opponentColor int          // Couleur de l'adversaire, -1 tant qu'il ne l'a pas choisie
+}
+
+// botMove est un coup calculé par le robot pour la partie game.
+type botMove struct {
+	game  int // Partie pour laquelle le coup a été calculé
+	moves int // Nombre de pions sur la grille au moment du calcul
+	x     int // Colonne choisie, -1 si aucun coup n'est possible
+}
+
+// spawnBot connecte un robot de niveau level au serveur et l'envoie dans la salle r.
+// Le robot arrive comme n'importe quel client : il peut donc échouer à rejoindre la
+// salle si elle s'est remplie entre-temps, auquel cas il se déconnecte.
+func spawnBot(r *room, level ai.Level) error {
+	if r.playerCount() >= maxPlayersPerRoom {
+		return fmt.Errorf("la salle %d est complète", r.id)
+	}
+
+	serverConn, botConn := net.Pipe()
+	id, serverConn := registerClient(serverConn)
+	b := &bot{
+		id:            id,
+		roomID:        r.id,
+		password:      r.password,
+		player:        ai.NewPlayer(level, time.Now().UnixNano()),
+		rng:           rand.New(rand.NewSource(time.Now().UnixNano() + int64(id))),
+		conn:          botConn,
+		out:           make(chan protocol.Message, 16),
+		moves:         make(chan botMove, 1),
+		done:          make(chan struct{}),
+		token:         engine.NoToken,
+		firstPlayer:   -1,
+		nextStarter:   -1,
+		opponentColor: -1,
+	}
+
+	botMux.Lock()
+	bots[id] = b
+	botMux.Unlock()
+
+	logInfof("Robot %d (niveau %s) connecté pour la salle %d\n", id, level, r.id)
+	go handleClient(serverConn, id)
+	go b.run()
+	return nil
+}
+
+// isBot indique si le client id est un robot.
+func isBot(id int) bool {
+	botMux.Lock()
+	defer botMux.Unlock()
+	_, ok := bots[id]
+	return ok
+}
+
+// handleAddBot invite un robot dans la salle du client id, à la place libre.
+func handleAddBot(payload protocol.AddBotPayload, id int) {
+	if !config.Features.Bots {
+		sendDisabled(id, protocol.TypeAddBot, "les robots sont désactivés sur ce serveur")
+		return
+	}
+	r := roomOf(id)
+	if r == nil {
+		sendError(id, protocol.ErrCodeNotInRoom, protocol.TypeAddBot, "rejoignez une salle avant d'inviter un robot")
+		return
+	}
+	if r.isSpectator(id) {
+		sendError(id, protocol.ErrCodeSpectator, protocol.TypeAddBot, "les spectateurs ne peuvent pas inviter de robot")
+		return
+	}
+	if r.match != nil {
+		sendError(id, protocol.ErrCodeTournament, protocol.TypeAddBot, "les robots ne jouent pas les matchs de tournoi")
+		return
+	}
+
+	levelName := payload.Level
+	if levelName == "" {
+		levelName = config.Bots.Level
+	}
+	level, err := ai.ParseLevel(levelName)
+	if err != nil {
+		sendError(id, protocol.ErrCodeBadPayload, protocol.TypeAddBot, err.Error())
+		return
+	}
+
+	if err := spawnBot(r, level); err != nil {
+		logWarnf("Client %d ne peut pas inviter de robot : %v\n", id, err)
+		sendRoomError(id, err)
+		return
+	}
+	logInfof("Client %d a invité un robot dans la salle %d\n", id, r.id)
+}
+
+// scheduleBotFill programme l'arrivée d'un robot dans la salle r si son joueur y est
+// encore seul après config.Bots.FillAfter. Rien n'est programmé si les robots sont
+// désactivés, si ce délai est nul ou si la salle est celle d'un match de tournoi.
+func scheduleBotFill(r *room) {
+	delay := config.Bots.FillAfter.Duration
+	if !config.Features.Bots || delay <= 0 || r.match != nil {
+		return
+	}
+
+	botMux.Lock()
+	defer botMux.Unlock()
+	if timer, ok := botFillTimers[r]; ok {
+		timer.Stop()
+	}
+	botFillTimers[r] = time.AfterFunc(delay, func() {
+		botMux.Lock()
+		delete(botFillTimers, r)
+		botMux.Unlock()
+
+		lobbyMux.Lock()
+		open := rooms[r.id] == r
+		lobbyMux.Unlock()
+		if !open {
+			return
+		}
+		conns := r.connections()
+		if len(conns) != 1 {
+			return
+		}
+		for id := range conns {
+			if isBot(id) {
+				return
+			}
+		}
+
+		level, _ := ai.ParseLevel(config.Bots.Level)
+		if err := spawnBot(r, level); err != nil {
+			logWarnf("Aucun robot n'a pu rejoindre la salle %d : %v\n", r.id, err)
+		}
+	})
+}
+
+// run fait vivre le robot jusqu'à sa déconnexion : une goroutine lit les messages du
+// serveur, une autre lui écrit, et la boucle principale réagit aux messages reçus et
+// aux coups calculés. Lecture et écriture étant séparées, le robot ne bloque jamais
+// le serveur lorsqu'ils s'écrivent en même temps.
+func (b *bot) run() {
+	defer func() {
+		close(b.done)
+		b.conn.Close()
+		botMux.Lock()
+		delete(bots, b.id)
+		botMux.Unlock()
+		logInfof("Robot %d arrêté\n", b.id)
+	}()
+
+	incoming := make(chan protocol.Message, 16)
+	written := make(chan struct{})
+	go b.read(incoming)
+	go func() {
+		b.write()
+		close(written)
+	}()
+
+	for {
+		select {
+		case msg, ok := <-incoming:
+			if !ok {
+				return
+			}
+			if !b.handle(msg) {
+				// Laisser partir le message "disconnect" avant de fermer la connexion
+				select {
+				case <-written:
+				case <-time.After(time.Second):
+				}
+				return
+			}
+		case move := <-b.moves:
+			b.play(move)
+		}
+	}
+}
+
+// read transmet à incoming les messages envoyés par le serveur, puis le ferme
+// lorsque la connexion est coupée.
+func (b *bot) read(incoming chan<- protocol.Message) {
+	defer close(incoming)
+	reader := bufio.NewReader(b.conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var msg protocol.Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			logErrorf("Robot %d : message illisible : %v\n", b.id, err)
+			continue
+		}
+		select {
+		case incoming <- msg:
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// write envoie au serveur les messages du robot jusqu'à son arrêt ou jusqu'au message "disconnect".
+func (b *bot) write() {
+	for {
+		select {
+		case msg := <-b.out:
+			if err := sendJSONMessage(b.conn, msg); err != nil {
+				logErrorf("Robot %d : erreur lors de l'envoi du message %s : %v\n", b.id, msg.Type, err)
+				return
+			}
+			if msg.Type == protocol.TypeDisconnect {
+				return
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// send envoie un message au serveur.
+func (b *bot) send(msgType string, payload interface{}) {
+	select {
+	case b.out <- protocol.Message{Type: msgType, Payload: payload}:
+	case <-b.done:
+	}
+}
+
+// sendLater envoie un message au serveur après botThinkTime, comme le ferait un joueur
+// qui prend le temps de lire l'écran.
+func (b *bot) sendLater(msgType string, payload interface{}) {
+	time.AfterFunc(botThinkTime, func() { b.send(msgType, payload) })
+}
+
+// handle réagit au message msg du serveur. Elle renvoie false lorsque le robot doit s'arrêter.
+func (b *bot) handle(msg protocol.Message) bool {
+	switch msg.Type {
+	case protocol.TypeID:
+		hello := protocol.NewHello("robot puissance4")
+		hello.Password = serverPassword
+		b.send(protocol.TypeHello, hello)
+
+	case protocol.TypeHello:
+		b.send(protocol.TypeJoinRoom, protocol.RoomRequest{ID: b.roomID, Password: b.password})
+
+	case protocol.TypeRoomJoined:
+		b.send(protocol.TypeReady, nil)
+
+	case protocol.TypeReady:
+		// Les deux joueurs sont là : choisir une couleur différente de celle de l'adversaire
+		color := b.rng.Intn(botColorCount)
+		for color == b.opponentColor {
+			color = b.rng.Intn(botColorCount)
+		}
+		b.sendLater(protocol.TypeColor, protocol.ColorPayload{Color: color})
+
+	case protocol.TypeColor:
+		var payload protocol.ColorPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && payload.ID != b.id {
+			b.opponentColor = payload.Color
+		}
+
+	case protocol.TypeColorSelectComplete, protocol.TypeShifumiResult:
+		// Premier pierre/feuille/ciseaux, ou nouvel essai après une égalité
+		b.sendLater(protocol.TypeSelected, protocol.SelectedPayload{Selected: botSelections[b.rng.Intn(len(botSelections))]})
+
+	case protocol.TypeShifumiComplete:
+		var payload protocol.ShifumiCompletePayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil {
+			b.firstPlayer = payload.FirstPlayer
+			b.startGame(payload.FirstPlayer)
+		}
+
+	case protocol.TypeMove:
+		var payload protocol.MovePayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil || payload.ID == b.id || b.token == engine.NoToken {
+			break
+		}
+		if _, err := b.board.Play(engine.Opponent(b.token), payload.X); err != nil {
+			logErrorf("Robot %d : coup de l'adversaire impossible à suivre (%d) : %v\n", b.id, payload.X, err)
+			break
+		}
+		if finished, _, _ := b.board.Winner(); !finished {
+			b.think()
+		}
+
+	case protocol.TypeMoveRejected:
+		var payload protocol.MoveRejectedPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			break
+		}
+		logWarnf("Robot %d : coup refusé en colonne %d : %s\n", b.id, payload.X, payload.Reason)
+		if _, err := b.board.Undo(); err == nil && payload.YourTurn {
+			b.think()
+		}
+
+	case protocol.TypeGameOver:
+		var payload protocol.GameOverPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil {
+			break
+		}
+		// Le perdant commence la partie suivante, le premier joueur en cas d'égalité
+		switch payload.Winner {
+		case -1:
+			b.nextStarter = b.firstPlayer
+		case b.id:
+			b.nextStarter = -1
+		default:
+			b.nextStarter = b.id
+		}
+		b.token = engine.NoToken
+		b.sendLater(protocol.TypeRestartReady, nil)
+
+	case protocol.TypeRestartOK:
+		b.startGame(b.nextStarter)
+
+	case protocol.TypeDrawOffered:
+		// Le robot joue chaque partie jusqu'au bout : il refuse les nulles
+		var payload protocol.OfferPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && payload.ID != b.id {
+			b.sendLater(protocol.TypeAnswerDraw, protocol.AnswerPayload{Accept: false})
+		}
+
+	case protocol.TypeTakebackRequested:
+		// Il accepte en revanche de laisser rejouer un coup
+		var payload protocol.OfferPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil && payload.ID != b.id {
+			b.sendLater(protocol.TypeAnswerTakeback, protocol.AnswerPayload{Accept: true})
+		}
+
+	case protocol.TypeTakeback:
+		var payload protocol.TakebackPayload
+		if protocol.DecodePayload(msg.Payload, &payload) != nil || b.token == engine.NoToken {
+			break
+		}
+		for range payload.Moves {
+			b.board.Undo()
+		}
+		// Un coup en cours de calcul vaut pour une position qui n'existe plus
+		b.game++
+		if payload.CurrentTurn == b.id {
+			b.think()
+		}
+
+	case protocol.TypeOtherDisconnected:
+		// Le robot ne reste pas seul dans une salle : il libère sa place et le serveur
+		logInfof("Robot %d : l'adversaire est parti, déconnexion\n", b.id)
+		b.send(protocol.TypeDisconnect, nil)
+		return false
+
+	case protocol.TypeError:
+		var payload protocol.ErrorPayload
+		if protocol.DecodePayload(msg.Payload, &payload) == nil {
+			logWarnf("Robot %d : erreur %s : %s\n", b.id, payload.Code, payload.Message)
+		}
+		// Sans place dans la salle, le robot n'a plus rien à faire
+		if b.token == engine.NoToken && (payload.Type == "" || payload.Type == protocol.TypeHello || payload.Type == protocol.TypeJoinRoom) {
+			b.send(protocol.TypeDisconnect, nil)
+			return false
+		}
+	}
+	return true
+}
+
+// startGame commence une nouvelle partie dont starter joue le premier coup.
+// Un starter à -1 désigne l'adversaire du robot.
+func (b *bot) startGame(starter int) {
+	b.board.Reset()
+	b.game++
+	if starter == b.id {
+		b.token = engine.P1Token
+		b.think()
+	} else {
+		b.token = engine.P2Token
+	}
+}
+
+// think lance le calcul du prochain coup dans une goroutine, pour que le robot continue
+// de lire les messages du serveur pendant qu'il réfléchit.
+func (b *bot) think() {
+	board, err := engine.FromMoves(b.board.Moves())
+	if err != nil {
+		logErrorf("Robot %d : erreur lors de la copie de la grille : %v\n", b.id, err)
+		return
+	}
+	player, token := b.player, b.token
+	move := botMove{game: b.game, moves: b.board.MoveCount()}
+	go func() {
+		start := time.Now()
+		x, err := player.ChooseMove(board, token)
+		if err != nil {
+			logWarnf("Robot %d ne peut pas jouer : %v\n", b.id, err)
+			x = -1
+		}
+		if wait := botThinkTime - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+		move.x = x
+		select {
+		case b.moves <- move:
+		case <-b.done:
+		}
+	}()
+}
+
+// play joue le coup calculé move, s'il concerne toujours la position en cours.
+func (b *bot) play(move botMove) {
+	if move.x < 0 || move.game != b.game || move.moves != b.board.MoveCount() || b.token == engine.NoToken {
+		return
+	}
+	y, err := b.board.Play(b.token, move.x)
+	if err != nil {
+		logErrorf("Robot %d : coup impossible en colonne %d : %v\n", b.id, move.x, err)
+		return
+	}
+	b.send(protocol.TypeTokenUpdate, protocol.TokenUpdatePayload{Position: move.x})
+	b.send(protocol.TypeMove, protocol.MovePayload{X: move.x, Y: y})
+}
@@ -1,342 +1,753 @@
 package main
 
 import (
-	"encoding/json"
-	"log"
-	"net"
 	"strings"
+	"time"
+
+	"puissance4/engine"
+	"puissance4/protocol"
 )
 
+// gameMessageTypes sont les types de messages traités par la salle du client.
+var gameMessageTypes = map[string]bool{
+	protocol.TypeReady:           true,
+	protocol.TypeRestartReady:    true,
+	protocol.TypeResetAll:        true,
+	protocol.TypeRequireHistory:  true,
+	protocol.TypeSelected:        true,
+	protocol.TypeColor:           true,
+	protocol.TypeCursorUpdate:    true,
+	protocol.TypeTokenUpdate:     true,
+	protocol.TypeMove:            true,
+	protocol.TypeChat:            true,
+	protocol.TypeHint:            true,
+	protocol.TypeResign:          true,
+	protocol.TypeOfferDraw:       true,
+	protocol.TypeAnswerDraw:      true,
+	protocol.TypeRequestTakeback: true,
+	protocol.TypeAnswerTakeback:  true,
+}
+
 // processMessage traite les messages reçus d'un client en fonction de leur type.
+// Les messages du lobby (liste, création et choix de salle) sont traités directement,
+// les messages de jeu sont transmis à la salle du client. Un message refusé est
+// signalé au client par un message "error" portant un code.
+func processMessage(msg protocol.Message, id int) {
+	switch msg.Type {
+	case protocol.TypeListRooms:
+		sendRoomList(id)
+		return
+	case protocol.TypeCreateRoom:
+		var payload protocol.CreateRoomPayload
+		if decodePayload(msg, id, &payload) {
+			handleCreateRoom(payload, id)
+		}
+		return
+	case protocol.TypeJoinRoom:
+		var payload protocol.RoomRequest
+		if decodePayload(msg, id, &payload) {
+			handleJoinRoom(payload, id)
+		}
+		return
+	case protocol.TypeSpectate:
+		var payload protocol.RoomRequest
+		if decodePayload(msg, id, &payload) {
+			handleSpectateRoom(payload, id)
+		}
+		return
+	case protocol.TypeLeaveRoom:
+		handleLeaveRoom(id)
+		return
+	case protocol.TypeQuickPlay:
+		joinQueue(id)
+		return
+	case protocol.TypeCancelQuickPlay:
+		handleCancelQuickPlay(id)
+		return
+	case protocol.TypeAddBot:
+		var payload protocol.AddBotPayload
+		if decodePayload(msg, id, &payload) {
+			handleAddBot(payload, id)
+		}
+		return
+	case protocol.TypeListGames:
+		var payload protocol.ListGamesRequest
+		if decodePayload(msg, id, &payload) {
+			handleListGames(payload, id)
+		}
+		return
+	case protocol.TypeGetGame:
+		var payload protocol.GameRequest
+		if decodePayload(msg, id, &payload) {
+			handleGetGame(payload, id)
+		}
+		return
+	case protocol.TypeImportGame:
+		var payload protocol.ImportGamePayload
+		if decodePayload(msg, id, &payload) {
+			handleImportGame(payload, id)
+		}
+		return
+	case protocol.TypeLogin:
+		var payload protocol.LoginPayload
+		if decodePayload(msg, id, &payload) {
+			handleLogin(payload, id)
+		}
+		return
+	case protocol.TypeGetLeaderboard:
+		var payload protocol.LeaderboardRequest
+		if decodePayload(msg, id, &payload) {
+			handleGetLeaderboard(payload, id)
+		}
+		return
+	case protocol.TypeGetPlayerStats:
+		var payload protocol.PlayerStatsRequest
+		if decodePayload(msg, id, &payload) {
+			handleGetPlayerStats(payload, id)
+		}
+		return
+	case protocol.TypeListTournaments:
+		sendTournamentList(id)
+		return
+	case protocol.TypeCreateTournament:
+		var payload protocol.CreateTournamentPayload
+		if decodePayload(msg, id, &payload) {
+			handleCreateTournament(payload, id)
+		}
+		return
+	case protocol.TypeJoinTournament, protocol.TypeLeaveTournament, protocol.TypeStartTournament, protocol.TypeGetTournament:
+		var payload protocol.TournamentRequest
+		if decodePayload(msg, id, &payload) {
+			handleTournamentRequest(msg.Type, payload, id)
+		}
+		return
+	case protocol.TypeDisconnect:
+		disconnectClient(id)
+		return
+	case protocol.TypeHello:
+		logWarnf("Poignée de main répétée par le client %d ignorée\n", id)
+		return
+	case protocol.TypeReady:
+		// Un client qui n'a pas choisi de salle est placé dans la première salle libre
+		if roomOf(id) == nil {
+			r, err := openRoom()
+			if err != nil {
+				sendRoomError(id, err)
+				return
+			}
+			handleJoinRoom(protocol.RoomRequest{ID: r.id}, id)
+		}
+	}
+
+	if !gameMessageTypes[msg.Type] {
+		logWarnf("Type de message inconnu : %s\n", msg.Type)
+		sendError(id, protocol.ErrCodeUnknownType, msg.Type, "type de message inconnu : "+msg.Type)
+		return
+	}
+
+	r := roomOf(id)
+	if r == nil {
+		logWarnf("Message %s du client %d ignoré : il n'est dans aucune salle\n", msg.Type, id)
+		sendError(id, protocol.ErrCodeNotInRoom, msg.Type, "rejoignez une salle avant de jouer")
+		return
+	}
+	if r.isSpectator(id) {
+		logWarnf("Message %s du spectateur %d ignoré (salle %d)\n", msg.Type, id, r.id)
+		sendError(id, protocol.ErrCodeSpectator, msg.Type, "les spectateurs ne peuvent pas jouer")
+		return
+	}
+	r.processMessage(msg, id)
+}
+
+// processMessage traite un message de jeu envoyé par le joueur id de la salle.
 // Chaque type de message déclenche une action spécifique (par exemple, mise à jour de curseur, sélection de couleur, mouvement).
-func processMessage(msg Message, id int) {
+func (r *room) processMessage(msg protocol.Message, id int) {
 	switch msg.Type {
-	case "restartReady":
-		log.Printf("Joueur %d prêt à redémarrer.\n", id)
-		restartReadyChannel <- id // Envoyer l'ID dans le channel
-	case "cursor_update":
-		var payload map[string]int
-		if decodePayload(msg.Payload, &payload) == nil {
-			cursorUpdate(payload, id)
-		}
-	case "color":
-		var payload ColorPayload
-		if decodePayload(msg.Payload, &payload) == nil {
-			colorSelection(payload, id)
-		}
-	case "move":
-		var payload MovePayload
-		if decodePayload(msg.Payload, &payload) == nil {
-			move(payload, id)
-		}
-	case "ready":
-		ready(id)
-	case "disconnect":
-		disconnectClient(clients[id], id)
-	case "resetAll":
-		resetAll()
-	case "token_update":
-		log.Println("token client recu")
-		var payload map[string]int
-		if decodePayload(msg.Payload, &payload) == nil {
-			sendPosition(payload, id)
-		}
-	case "require_history":
-		sendHistory(id)
-	case "chat":
-		var payload ChatMessage
-		if decodePayload(msg.Payload, &payload) == nil {
-			broadcastChatMessage(id, payload.Text)
-		}
-	case "selected":
-		var payload SelectedPayload
-		if decodePayload(msg.Payload, &payload) == nil {
-			handleSelection(payload, id)
+	case protocol.TypeRestartReady:
+		if r.match != nil && matchFinished(r.match) {
+			sendError(id, protocol.ErrCodeTournament, msg.Type, "le match de tournoi est terminé")
+			return
+		}
+		logInfof("Joueur %d prêt à redémarrer.\n", id)
+		r.requestRestart(id) // Envoyer l'ID dans le channel
+	case protocol.TypeCursorUpdate:
+		var payload protocol.CursorPayload
+		if decodePayload(msg, id, &payload) {
+			r.cursorUpdate(payload, id)
+		}
+	case protocol.TypeColor:
+		var payload protocol.ColorPayload
+		if decodePayload(msg, id, &payload) {
+			r.colorSelection(payload, id)
+		}
+	case protocol.TypeMove:
+		var payload protocol.MovePayload
+		if decodePayload(msg, id, &payload) {
+			r.move(payload, id)
+		}
+	case protocol.TypeReady:
+		r.ready(id)
+	case protocol.TypeResetAll:
+		r.mu.Lock()
+		playing := r.inGame()
+		if !playing {
+			r.resetAll()
+		}
+		r.mu.Unlock()
+		if playing {
+			logWarnf("Commande 'resetAll' du client %d refusée : partie en cours (salle %d)\n", id, r.id)
+			sendError(id, protocol.ErrCodeRoom, protocol.TypeResetAll, errGameInProgress.Error())
+			return
+		}
+		logInfof("Commande 'resetAll' reçue. Salle %d réinitialisée\n", r.id)
+	case protocol.TypeTokenUpdate:
+		logDebugf("Position du pion reçue du client %d\n", id)
+		var payload protocol.TokenUpdatePayload
+		if decodePayload(msg, id, &payload) {
+			r.sendPosition(payload, id)
+		}
+	case protocol.TypeRequireHistory:
+		r.sendHistory(id)
+	case protocol.TypeChat:
+		if !config.Features.Chat {
+			sendDisabled(id, msg.Type, "le chat est désactivé sur ce serveur")
+			return
+		}
+		var payload protocol.ChatMessage
+		if decodePayload(msg, id, &payload) {
+			r.broadcastChatMessage(id, payload.Text)
+		}
+	case protocol.TypeHint:
+		if !config.Features.Hints {
+			sendDisabled(id, msg.Type, "les indices sont désactivés sur ce serveur")
+			return
+		}
+		r.hint(id)
+	case protocol.TypeSelected:
+		var payload protocol.SelectedPayload
+		if decodePayload(msg, id, &payload) {
+			r.handleSelection(payload, id)
+		}
+	case protocol.TypeResign:
+		r.resign(id)
+	case protocol.TypeOfferDraw:
+		r.offerDraw(id)
+	case protocol.TypeAnswerDraw:
+		var payload protocol.AnswerPayload
+		if decodePayload(msg, id, &payload) {
+			r.answerDraw(payload.Accept, id)
+		}
+	case protocol.TypeRequestTakeback:
+		r.requestTakeback(id)
+	case protocol.TypeAnswerTakeback:
+		var payload protocol.AnswerPayload
+		if decodePayload(msg, id, &payload) {
+			r.answerTakeback(payload.Accept, id)
 		}
 	default:
-		log.Printf("Type de message inconnu : %s\n", msg.Type)
+		logWarnf("Type de message inconnu : %s\n", msg.Type)
+		sendError(id, protocol.ErrCodeUnknownType, msg.Type, "type de message inconnu : "+msg.Type)
+	}
+}
+
+// decodePayload désérialise la charge utile de msg dans target.
+// En cas d'échec, le client id reçoit une erreur "bad_payload" et la fonction renvoie false.
+func decodePayload(msg protocol.Message, id int, target interface{}) bool {
+	if err := protocol.DecodePayload(msg.Payload, target); err != nil {
+		logWarnf("Charge utile invalide pour %s du client %d : %v\n", msg.Type, id, err)
+		sendError(id, protocol.ErrCodeBadPayload, msg.Type, "charge utile invalide : "+err.Error())
+		return false
 	}
+	return true
 }
 
 // Envoie les messages du chat d'un client vers l'autre
-func broadcastChatMessage(senderID int, text string) {
-	message := Message{
-		Type: "chat",
-		Payload: map[string]interface{}{
-			"id":   senderID,
-			"text": text,
-		},
+func (r *room) broadcastChatMessage(senderID int, text string) {
+	entry := protocol.ChatMessage{ID: senderID, Text: text}
+	message := protocol.Message{
+		Type:    protocol.TypeChat,
+		Payload: entry,
 	}
 
-	notifyPlayers(message) // Envoyer à tous les joueurs
-	log.Printf("Message de chat de %d : %s\n", senderID, text)
+	r.mu.Lock()
+	r.chatLog = append(r.chatLog, entry)
+	if len(r.chatLog) > maxChatLog {
+		r.chatLog = r.chatLog[len(r.chatLog)-maxChatLog:]
+	}
+	r.mu.Unlock()
+
+	r.notifyPlayers(message) // Envoyer à tous les joueurs de la salle
+	logDebugf("Message de chat de %d (salle %d) : %s\n", senderID, r.id, text)
 }
 
 // Envoie l'historique des coups de la partie pour le replay du client
-func sendHistory(id int) {
-	clientMux.Lock()
-	conn, ok := clients[id]
-	clientMux.Unlock()
-
-	if !ok {
-		log.Printf("Client %d introuvable pour l'envoi de l'historique\n", id)
-		return
+func (r *room) sendHistory(id int) {
+	r.mu.Lock()
+	history := make(protocol.HistoryPayload, len(r.historiquePartie))
+	for turn, coord := range r.historiquePartie {
+		history[turn] = coord
 	}
+	r.mu.Unlock()
 
 	// Envoyer l'historique au client demandeur
-	historyMessage := Message{
-		Type:    "sent_history",
-		Payload: historiquePartie,
-	}
-
-	if err := sendJSONMessage(conn, historyMessage); err != nil {
-		log.Printf("Erreur lors de l'envoi de l'historique au client %d : %v\n", id, err)
-	} else {
-		log.Printf("Historique envoyé au client %d\n", id)
-	}
+	sendToClient(id, protocol.Message{
+		Type:    protocol.TypeSentHistory,
+		Payload: history,
+	})
+	logDebugf("Historique envoyé au client %d\n", id)
 }
 
 // Envoie la position du cursor du jouer au dessus de la grille pendant la partie
-func sendPosition(payload map[string]int, id int) {
-	if position, ok := payload["position"]; ok {
-		// Notifier l'autre joueur de la position du curseur
-		notifyOtherPlayers(id, Message{
-			Type: "token_update",
-			Payload: map[string]int{
-				"position": position,
-			},
-		})
-		log.Printf("Mise à jour du curseur du joueur %d : position %d\n", id, position)
+func (r *room) sendPosition(payload protocol.TokenUpdatePayload, id int) {
+	r.mu.Lock()
+	token := r.playerTokens[id]
+	r.mu.Unlock()
+
+	// Notifier l'autre joueur et les spectateurs de la position du curseur
+	message := protocol.Message{
+		Type: protocol.TypeTokenUpdate,
+		Payload: protocol.TokenUpdatePayload{
+			Position: payload.Position,
+			ID:       id,
+			Token:    token,
+		},
 	}
+	r.notifyOtherPlayers(id, message)
+	r.notifySpectators(message)
+	logDebugf("Mise à jour du curseur du joueur %d : position %d\n", id, payload.Position)
 }
 
 // Envoie la position du curseur d'un client sur la grille de couleur a l'autre joueur
-func cursorUpdate(payload map[string]int, id int) {
-	if color, ok := payload["color"]; ok {
-		// Notifier l'autre joueur de la position du curseur
-		notifyOtherPlayers(id, Message{
-			Type: "cursor_update",
-			Payload: map[string]int{
-				"color": color,
-			},
-		})
-	}
-}
-
-// decodePayload désérialise le payload générique en une structure cible spécifique.
-// Cette fonction est utile lorsque payload est reçu sous forme d'interface{} et doit être converti
-// en une structure typée spécifique target.
-// Elle utilise un double passage (Marshal -> Unmarshal) pour garantir une conversion correcte.
-func decodePayload(payload interface{}, target interface{}) error {
-	jsonData, err := json.Marshal(payload) // Re-marshal pour convertir en bytes
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(jsonData, target)
+func (r *room) cursorUpdate(payload protocol.CursorPayload, id int) {
+	// Notifier l'autre joueur de la position du curseur
+	r.notifyOtherPlayers(id, protocol.Message{
+		Type:    protocol.TypeCursorUpdate,
+		Payload: payload,
+	})
 }
 
 // colorSelection gère la sélection de couleur par un joueur.
 // Elle met à jour la couleur choisie par le joueur, notifie les autres joueurs,
 // et vérifie si tous les joueurs ont terminé leur sélection.
-func colorSelection(payload ColorPayload, id int) {
+func (r *room) colorSelection(payload protocol.ColorPayload, id int) {
 	color := payload.Color
 
-	clientMux.Lock()
-	playerColors[id] = color
-	if firstPlayer == -1 {
-		firstPlayer = id
+	r.mu.Lock()
+	r.playerColors[id] = color
+	if r.firstPlayer == -1 {
+		r.firstPlayer = id
 	}
-	clientMux.Unlock()
+	firstPlayer := r.firstPlayer
+	r.mu.Unlock()
 
 	// Créer le message structuré pour la notification
-	message := Message{
-		Type: "color",
-		Payload: map[string]interface{}{
-			"id":    id,
-			"color": color,
+	message := protocol.Message{
+		Type: protocol.TypeColor,
+		Payload: protocol.ColorPayload{
+			ID:    id,
+			Color: color,
 		},
 	}
 
 	// Notifier les autres clients
-	notifyOtherPlayers(id, message)
+	r.notifyOtherPlayers(id, message)
+
+	r.broadcastSpectateState()
 
 	// Vérifier si tous les joueurs ont choisi leurs couleurs
-	if allPlayersSelectedColors() {
-		notifyPlayers(Message{
-			Type: "color_select_complete",
-			Payload: map[string]interface{}{
-				"firstPlayer": firstPlayer,
+	if r.allPlayersSelectedColors() {
+		r.notifyPlayers(protocol.Message{
+			Type: protocol.TypeColorSelectComplete,
+			Payload: protocol.ColorSelectCompletePayload{
+				FirstPlayer: firstPlayer,
 			},
 		})
-		log.Printf("Les deux joueurs ont choisi leurs couleurs. Le joueur %d commence la partie.", firstPlayer)
+		logInfof("Les deux joueurs de la salle %d ont choisi leurs couleurs. Le joueur %d commence la partie.", r.id, firstPlayer)
 	}
 }
 
 // move gère le déplacement effectué par un joueur.
-// La fonction enregistre le déplacement dans l'historique de la partie,
-// incrémente le numéro de tour, et notifie les autres joueurs du mouvement.
-func move(payload MovePayload, id int) {
-	x, y := payload.X, payload.Y
-	historiquePartie[turnPartie] = Coordinate{ID: id, X: x, Y: y}
-	turnPartie++
-
-	// Créer un message structuré pour la notification
-	message := Message{
-		Type: "move",
-		Payload: map[string]int{
-			"x": x,
-			"y": y,
+// Le serveur fait foi : il vérifie que c'est bien le tour du joueur, que la colonne existe
+// et n'est pas pleine, calcule lui-même la ligne d'arrivée du pion et refuse toute ligne
+// différente envoyée par le client. Un coup accepté est enregistré dans l'historique et
+// transmis à l'adversaire ; un coup refusé est signalé à son auteur par un message "move_rejected".
+// Si le coup termine la partie, un message "game_over" est envoyé à tous les joueurs.
+func (r *room) move(payload protocol.MovePayload, id int) {
+	x := payload.X
+
+	r.mu.Lock()
+	y, err := r.applyMove(id, x, payload.Y)
+	finished, result, cells := false, engine.Equality, [][2]int(nil)
+	var record *protocol.GameRecord
+	if err == nil {
+		// Un coup joué fait tomber les propositions en attente
+		r.clearOffers()
+		finished, result, cells = r.gameBoard.CheckEnd(x, y)
+		if finished {
+			r.endGame(result)
+			record, r.record = r.record, nil
+		} else {
+			r.currentTurn = r.otherPlayer(id)
+			r.switchClock(id, r.currentTurn)
+		}
+	}
+	yourTurn := r.currentTurn == id && !r.gameOver
+	token := r.playerTokens[id]
+	r.mu.Unlock()
+
+	if err != nil {
+		logWarnf("Mouvement refusé pour le joueur %d (%d, %d) : %v\n", id, x, payload.Y, err)
+		sendToClient(id, protocol.Message{
+			Type: protocol.TypeMoveRejected,
+			Payload: protocol.MoveRejectedPayload{
+				X:        x,
+				Y:        payload.Y,
+				Reason:   err.Error(),
+				YourTurn: yourTurn,
+			},
+		})
+		return
+	}
+
+	// Créer un message structuré pour la notification, avec l'auteur du coup et son pion
+	message := protocol.Message{
+		Type: protocol.TypeMove,
+		Payload: protocol.MovePayload{
+			X:     x,
+			Y:     y,
+			ID:    id,
+			Token: token,
 		},
 	}
 
-	// Notifier les autres joueurs avec un message JSON
-	notifyOtherPlayers(id, message)
+	// Notifier les autres joueurs et les spectateurs avec un message JSON
+	r.notifyOtherPlayers(id, message)
+	r.notifySpectators(message)
+
+	logDebugf("Mouvement reçu de %d : (%d, %d)\n", id, x, y)
+
+	r.broadcastClock()
+	if finished {
+		r.finishGame(result, cells, "", record)
+	}
+}
+
+// finishGame annonce la fin de la partie record, puis met à jour les cotes, l'archive,
+// les statistiques et le match de tournoi de la salle. reason précise une fin qui ne
+// se lit pas sur la grille (protocol.ReasonTimeout...), vide sinon.
+// Doit être appelée sans r.mu verrouillé, une fois endGame appelée.
+func (r *room) finishGame(result int, cells [][2]int, reason string, record *protocol.GameRecord) {
+	r.notifyGameOver(result, cells, reason)
+	r.rateGame(record)
+	archiveGame(record)
+	recordStats(record)
+	tournamentGameOver(r, record)
+}
+
+// applyMove valide puis joue le coup du joueur id dans la colonne x.
+// La ligne y envoyée par le client doit correspondre à celle calculée par le serveur.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) applyMove(id, x, y int) (int, error) {
+	if r.gameOver {
+		return -1, errGameOver
+	}
+	if r.currentTurn == -1 {
+		return -1, errGameNotStarted
+	}
+	if id != r.currentTurn {
+		return -1, errNotYourTurn
+	}
+	if r.timeExpired(id) {
+		return -1, errTimeExpired
+	}
+	if x < 0 || x >= engine.Columns {
+		return -1, engine.ErrColumnOutOfRange
+	}
+	landing, ok := r.gameBoard.LandingRow(x)
+	if !ok {
+		return -1, engine.ErrColumnFull
+	}
+	if y != landing {
+		return -1, errForgedRow
+	}
+
+	if _, err := r.gameBoard.Play(r.playerTokens[id], x); err != nil {
+		return -1, err
+	}
+	hint := r.hintRequested
+	r.hintRequested = false
+	r.historiquePartie[r.turnPartie] = protocol.Coordinate{ID: id, X: x, Y: landing, Hint: hint}
+	if r.record != nil {
+		r.record.Moves = append(r.record.Moves, protocol.ArchivedMove{ID: id, X: x, Y: landing, At: time.Now(), Hint: hint})
+	}
+	r.turnPartie++
+	return landing, nil
+}
+
+// hint enregistre que le joueur id a demandé un indice pour le coup qu'il va jouer.
+// L'indice est calculé par le client ; le serveur se contente de le refuser dans les
+// salles classées et de marquer le coup suivant du joueur dans l'historique.
+func (r *room) hint(id int) {
+	if r.noHints {
+		sendError(id, protocol.ErrCodeDisabled, protocol.TypeHint, "les indices sont interdits dans cette salle")
+		logWarnf("Indice refusé au joueur %d : salle %d sans indices\n", id, r.id)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gameOver || r.currentTurn != id {
+		logWarnf("Indice du joueur %d ignoré : ce n'est pas son tour (salle %d)\n", id, r.id)
+		return
+	}
+	r.hintRequested = true
+	logInfof("Le joueur %d demande un indice pour le coup %d (salle %d)\n", id, r.turnPartie+1, r.id)
+}
+
+// startGame prépare la grille pour une nouvelle partie commencée par le joueur starter.
+// Le joueur qui commence reçoit le pion engine.P1Token, son adversaire le pion engine.P2Token.
+func (r *room) startGame(starter int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gameBoard.Reset()
+	r.assignTokens(starter)
+	r.firstPlayer = starter
+	r.currentTurn = starter
+	r.nextStarter = -1
+	r.gameOver = false
+	r.hintRequested = false
+	r.clearOffers()
+	r.record = r.newRecord(starter)
+	r.startClock(starter)
+	logInfof("Nouvelle partie dans la salle %d : le joueur %d commence.\n", r.id, starter)
+}
 
-	log.Printf("Mouvement reçu de %d : (%d, %d)\n", id, x, y)
+// assignTokens donne le pion engine.P1Token au joueur starter, qui commence la partie,
+// et le pion engine.P2Token à son adversaire.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) assignTokens(starter int) {
+	r.playerTokens = make(map[int]int)
+	for id := range r.players {
+		if id == starter {
+			r.playerTokens[id] = engine.P1Token
+		} else {
+			r.playerTokens[id] = engine.P2Token
+		}
+	}
+}
+
+// endGame marque la partie comme terminée, met à jour les scores et désigne le joueur
+// qui commencera la suivante : le perdant, ou le premier joueur en cas d'égalité.
+// Le résultat est reporté dans la partie à archiver.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) endGame(result int) {
+	r.gameOver = true
+	r.currentTurn = -1
+	r.stopClock()
+	r.clearOffers()
+	r.nextStarter = r.firstPlayer
+	r.closeRecord(result)
+	if result != engine.Equality {
+		winner := r.playerByToken(result)
+		r.wins[winner]++
+		r.nextStarter = r.otherPlayer(winner)
+		return
+	}
+	// Une égalité compte comme une victoire pour les deux joueurs, comme dans le client
+	for id := range r.playerTokens {
+		r.wins[id]++
+	}
+}
+
+// concludeGame termine la partie sur une fin qui ne se lit pas sur la grille (reason :
+// protocol.ReasonTimeout...) et renvoie la partie à archiver, retirée de la salle.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) concludeGame(result int, reason string) *protocol.GameRecord {
+	r.endGame(result)
+	record := r.record
+	r.record = nil
+	if record != nil {
+		record.Reason = reason
+	}
+	return record
+}
+
+// notifyGameOver annonce la fin de partie à tous les joueurs, avec l'ID du gagnant
+// (-1 en cas d'égalité), les cases de l'alignement gagnant et la raison de la fin.
+// Le résultat d'une victoire a la même valeur que le pion gagnant.
+func (r *room) notifyGameOver(result int, cells [][2]int, reason string) {
+	r.mu.Lock()
+	winnerID := -1
+	if result != engine.Equality {
+		winnerID = r.playerByToken(result)
+	}
+	r.mu.Unlock()
+
+	outcome := protocol.ResultDraw
+	if winnerID != -1 {
+		outcome = protocol.ResultWin
+	}
+	if cells == nil {
+		cells = [][2]int{}
+	}
+
+	message := protocol.Message{
+		Type: protocol.TypeGameOver,
+		Payload: protocol.GameOverPayload{
+			Result: outcome,
+			Winner: winnerID,
+			Cells:  cells,
+			Reason: reason,
+		},
+	}
+	r.notifyPlayers(message)
+	r.notifySpectators(message)
+	logInfof("Partie terminée dans la salle %d : %s (gagnant : %d)\n", r.id, outcome, winnerID)
+}
+
+// otherPlayer renvoie l'ID de l'adversaire du joueur id, ou -1 s'il n'y en a pas.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) otherPlayer(id int) int {
+	for other := range r.playerTokens {
+		if other != id {
+			return other
+		}
+	}
+	return -1
+}
+
+// playerByToken renvoie l'ID du joueur qui joue avec le pion token, ou -1.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) playerByToken(token int) int {
+	for id, t := range r.playerTokens {
+		if t == token {
+			return id
+		}
+	}
+	return -1
 }
 
 // ready gère le signalement d'un joueur indiquant qu'il est prêt à jouer.
 // Elle met à jour l'état de préparation du joueur dans readyPlayers,
-// puis vérifie si tous les joueurs sont prêts pour démarrer la partie.
-func ready(id int) {
+// puis vérifie si tous les joueurs de la salle sont prêts pour démarrer la partie.
+func (r *room) ready(id int) {
 	// Mettre à jour l'état du joueur
-	clientMux.Lock()
-	readyPlayers[id] = true
-	clientMux.Unlock()
+	r.mu.Lock()
+	r.readyPlayers[id] = true
+	r.mu.Unlock()
 
 	// Vérifier si tous les joueurs sont prêts
-	if allPlayersReady() {
+	if r.allPlayersReady() {
 		// Créer un message structuré pour notifier les clients
-		message := Message{
-			Type: "ready",
-			Payload: map[string]string{
-				"message": "Deux joueurs sont connectés. Vous pouvez commencer à jouer.",
+		message := protocol.Message{
+			Type: protocol.TypeReady,
+			Payload: protocol.MessagePayload{
+				Message: "Deux joueurs sont connectés. Vous pouvez commencer à jouer.",
 			},
 		}
 
 		// Notifier tous les joueurs
-		notifyPlayers(message)
+		r.notifyPlayers(message)
 
-		log.Println("Tous les joueurs sont prêts. Notification envoyée.")
+		logInfof("Tous les joueurs de la salle %d sont prêts. Notification envoyée.\n", r.id)
 	}
 }
 
-// Est appelé par ready pour verifier si tous les joueurs connectés sont prêts à jouer.
-func allPlayersReady() bool {
-	clientMux.Lock()
-	defer clientMux.Unlock()
+// Est appelé par ready pour verifier si tous les joueurs de la salle sont prêts à jouer.
+func (r *room) allPlayersReady() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if len(clients) < 2 {
+	if len(r.players) < maxPlayersPerRoom {
 		return false // Pas assez de joueurs
 	}
 
-	for _, ready := range readyPlayers {
-		if !ready {
+	for id := range r.players {
+		if !r.readyPlayers[id] {
 			return false
 		}
 	}
 	return true
 }
 
-// Est appelé par colorSelection pour verifier si tous les joueurs connectés ont choisi leur couleur.
-func allPlayersSelectedColors() bool {
-	clientMux.Lock()
-	defer clientMux.Unlock()
+// Est appelé par colorSelection pour verifier si tous les joueurs de la salle ont choisi leur couleur.
+func (r *room) allPlayersSelectedColors() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if len(playerColors) < len(clients) {
+	if len(r.playerColors) < len(r.players) {
 		return false // Tous les joueurs n'ont pas encore sélectionné leur couleur
 	}
 
 	return true
 }
 
-// Appelé lorsque les clients sont déconnectés du serveur afin de remettre toutes les variables à zero.
-func resetAll() {
-	log.Println("Commande 'resetAll' reçue. Réinitialisation du serveur...")
-	clients = make(map[int]net.Conn)  // Liste des clients connectés
-	readyPlayers = make(map[int]bool) // Suivi des joueurs prêts
-	playerColors = make(map[int]int)
-	firstPlayer = -1 // -1 indique qu'aucun joueur n'a encore été désigné
-	historiquePartie = make(map[int]Coordinate)
-	playerSelections = make(map[int]string) // Table de hachage pour stocker les sélections des joueurs (par exemple, une chaîne représentant leur choix).
-
-	log.Printf("All server have been reset.")
-}
-
-// Appelé à la fin d'une partie lors du rematch pour recommencer une nouvelle partie
-func resetServerState() {
-	clientMux.Lock()
-	turnPartie = 0
-	historiquePartie = make(map[int]Coordinate)
-	defer clientMux.Unlock()
-
-	log.Println("Serveur pret pour une nouvelle partie")
-}
-
-// Appelé lorsqu'un client se déconnecte du serveur, notifie l'autre client pour qu'il se déconnecte
-// et reinitialise tout le serveur.
-func disconnectClient(conn net.Conn, id int) {
-
-	message := Message{
-		Type:    "other_disconnected",
-		Payload: nil,
+// Appelé lorsqu'un client se déconnecte du serveur : sa session est supprimée, il quitte la file
+// de partie rapide et sa salle, ce qui notifie son adversaire et remet la salle à zéro, puis sa
+// connexion est fermée.
+func disconnectClient(id int) {
+	endSession(id)
+	if leaveQueue(id) {
+		broadcastQueuePositions()
 	}
-
-	// Notifier tous les joueurs
-	notifyOtherPlayers(id, message)
+	leaveRoom(id)
+	leaveTournaments(id)
+	logout(id)
 
 	clientMux.Lock()
+	conn, ok := clients[id]
 	delete(clients, id)
-	delete(readyPlayers, id)
-	delete(playerColors, id)
-	err := conn.Close()
-	if err != nil {
+	remaining := len(clients)
+	clientMux.Unlock()
+	if !ok {
+		return
+	}
+
+	if err := conn.Close(); err != nil {
 		return
 	}
-	log.Printf("Client %d déconnecté\n", id)
+	logInfof("Client %d déconnecté\n", id)
 
 	// Vérifiez si tous les joueurs sont déconnectés
-	if len(clients) == 0 {
-		log.Println("Tous les joueurs sont déconnectés. Réinitialisation du serveur...")
-		resetAll()
-		restartWaitForRestart()
-		log.Println("En attente de connexions...")
+	if remaining == 0 {
+		logInfof("Tous les joueurs sont déconnectés.\n")
+		logInfof("En attente de connexions...\n")
 	}
-	clientMux.Unlock()
 }
 
 // Stock dans la table de hachage le coup effectué par un client au pierre/feuille/ciseaux
-func handleSelection(payload SelectedPayload, id int) {
-	clientMux.Lock()
-	playerSelections[id] = payload.Selected
-	nbSelections := len(playerSelections)
-	clientMux.Unlock()
+func (r *room) handleSelection(payload protocol.SelectedPayload, id int) {
+	r.mu.Lock()
+	r.playerSelections[id] = payload.Selected
+	nbSelections := len(r.playerSelections)
+	r.mu.Unlock()
 
-	log.Printf("Joueur %d a choisi : %s\n", id, payload.Selected)
+	logDebugf("Joueur %d a choisi : %s\n", id, payload.Selected)
 
 	// Vérifier si les deux joueurs ont fait leur sélection
-	if nbSelections == 2 {
-		determineWinner()
+	if nbSelections == maxPlayersPerRoom {
+		r.determineWinner()
 	}
 }
 
 // Appelé par handleSelection pour determiner le gagnant du jeu
 // Determine par la suite le joueur qui commence la partie et le notifie au client
-func determineWinner() {
+func (r *room) determineWinner() {
 	// Récupérer les sélections des deux joueurs
 	var player1Selection, player2Selection string
-	var player1ID, player2ID int
+	player1ID, player2ID := -1, -1
 
-	clientMux.Lock()
+	r.mu.Lock()
 	// S'assurer que nous avons bien deux sélections
-	if len(playerSelections) != 2 {
-		clientMux.Unlock()
+	if len(r.playerSelections) != maxPlayersPerRoom {
+		r.mu.Unlock()
 		return
 	}
 
 	// Récupérer les sélections de manière ordonnée
-	for id, selection := range playerSelections {
-		if player1ID == 0 {
+	for id, selection := range r.playerSelections {
+		if player1ID == -1 {
 			player1ID = id
 			player1Selection = selection
 		} else {
@@ -344,11 +755,11 @@ func determineWinner() {
 			player2Selection = selection
 		}
 	}
-	clientMux.Unlock()
+	r.mu.Unlock()
 
 	// Vérifier que les deux sélections sont valides
 	if player1Selection == "" || player2Selection == "" {
-		log.Printf("Erreur : sélections invalides (j1: %s, j2: %s)\n", player1Selection, player2Selection)
+		logErrorf("Erreur : sélections invalides (j1: %s, j2: %s)\n", player1Selection, player2Selection)
 		return
 	}
 
@@ -367,51 +778,44 @@ func determineWinner() {
 
 	// Si match nul, on refait une partie
 	if winnerID == -1 {
-		result := Message{
-			Type: "shifumi_result",
-			Payload: map[string]interface{}{
-				"result": "draw",
-				"player1": map[string]interface{}{
-					"id":        player1ID,
-					"selection": player1Selection,
-				},
-				"player2": map[string]interface{}{
-					"id":        player2ID,
-					"selection": player2Selection,
-				},
+		result := protocol.Message{
+			Type: protocol.TypeShifumiResult,
+			Payload: protocol.ShifumiResultPayload{
+				Result:  protocol.ResultDraw,
+				Player1: protocol.ShifumiPlayer{ID: player1ID, Selection: player1Selection},
+				Player2: protocol.ShifumiPlayer{ID: player2ID, Selection: player2Selection},
 			},
 		}
-		notifyPlayers(result)
-
-		// Réinitialiser explicitement les sélections pour le prochain tour
-		clientMux.Lock()
-		playerSelections = make(map[int]string)
-		clientMux.Unlock()
+		r.notifyPlayers(result)
 	} else {
-		// Le gagnant devient le premier joueur
-		firstPlayer = winnerID
+		// Le gagnant devient le premier joueur et la grille du serveur est préparée
+		r.startGame(winnerID)
+		r.mu.Lock()
+		if r.record != nil {
+			r.record.Shifumi = []protocol.ShifumiPlayer{
+				{ID: player1ID, Selection: player1Selection},
+				{ID: player2ID, Selection: player2Selection},
+			}
+		}
+		r.mu.Unlock()
 
 		// Notifier les joueurs du résultat et qui commence
-		result := Message{
-			Type: "shifumi_complete",
-			Payload: map[string]interface{}{
-				"winner":      winnerID,
-				"firstPlayer": winnerID,
-				"player1": map[string]interface{}{
-					"id":        player1ID,
-					"selection": player1Selection,
-				},
-				"player2": map[string]interface{}{
-					"id":        player2ID,
-					"selection": player2Selection,
-				},
+		result := protocol.Message{
+			Type: protocol.TypeShifumiComplete,
+			Payload: protocol.ShifumiCompletePayload{
+				Winner:      winnerID,
+				FirstPlayer: winnerID,
+				Player1:     protocol.ShifumiPlayer{ID: player1ID, Selection: player1Selection},
+				Player2:     protocol.ShifumiPlayer{ID: player2ID, Selection: player2Selection},
 			},
 		}
-		notifyPlayers(result)
+		r.notifyPlayers(result)
+		r.broadcastClock()
+		r.broadcastSpectateState()
 	}
 
 	// Réinitialiser les sélections pour la prochaine partie
-	clientMux.Lock()
-	playerSelections = make(map[int]string)
-	clientMux.Unlock()
+	r.mu.Lock()
+	r.playerSelections = make(map[int]string)
+	r.mu.Unlock()
 }
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// defaultTimeControl est la cadence des salles ouvertes par le serveur lui-même (partie
+// rapide, placement automatique), tirée de la configuration.
+var defaultTimeControl protocol.TimeControl
+
+// gameClock est la pendule d'une partie jouée avec une cadence. Seule la pendule du
+// joueur au trait tourne ; un timer fait tomber son drapeau lorsque son temps est écoulé.
+type gameClock struct {
+	remaining  map[int]time.Duration // Temps restant de chaque joueur, arrêté au début du tour en cours
+	running    int                   // ID du joueur dont la pendule tourne, -1 si elles sont arrêtées
+	since      time.Time             // Début du tour du joueur running
+	timer      *time.Timer           // Chute du drapeau du joueur running, nil si les pendules sont arrêtées
+	generation int                   // Change à chaque arrêt, pour ignorer un timer périmé
+	increments map[int]time.Duration // Incrément reçu par l'auteur de chaque coup, par numéro de coup, à retirer en cas de reprise
+}
+
+// startClock remet les pendules à la cadence de la salle et lance celle du joueur starter.
+// Elle ne fait rien dans une salle sans cadence.
+// Doit être appelée avec r.mu verrouillé, une fois les pions attribués.
+func (r *room) startClock(starter int) {
+	r.resetClock()
+	if !r.timeControl.Enabled() {
+		return
+	}
+	for id := range r.playerTokens {
+		r.clock.remaining[id] = r.timeControl.Budget()
+	}
+	r.runClock(starter)
+}
+
+// runClock lance la pendule du joueur id.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) runClock(id int) {
+	r.clock.running = id
+	r.clock.since = time.Now()
+	generation := r.clock.generation
+	r.clock.timer = time.AfterFunc(r.clock.remaining[id], func() {
+		r.flagFall(id, generation)
+	})
+}
+
+// stopClock arrête la pendule qui tourne et décompte le temps pris par son joueur.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) stopClock() {
+	if r.clock.timer == nil {
+		return
+	}
+	r.clock.timer.Stop()
+	r.clock.timer = nil
+	id := r.clock.running
+	r.clock.remaining[id] -= time.Since(r.clock.since)
+	if r.clock.remaining[id] < 0 {
+		r.clock.remaining[id] = 0
+	}
+	r.clock.running = -1
+	r.clock.generation++
+}
+
+// resetClock arrête les pendules et oublie le temps des joueurs.
+// Doit être appelée avec r.mu verrouillé (ou avant que la salle ne soit partagée).
+func (r *room) resetClock() {
+	r.stopClock()
+	r.clock.remaining = make(map[int]time.Duration)
+	r.clock.increments = make(map[int]time.Duration)
+	r.clock.running = -1
+}
+
+// switchClock arrête la pendule du joueur mover, qui vient de jouer, et lance celle de
+// son adversaire next. Le joueur mover reçoit l'incrément de la cadence ou, avec un
+// temps par coup, retrouve tout son temps pour le coup suivant. L'incrément est noté
+// avec le numéro du coup, pour être retiré si le coup est repris.
+// Doit être appelée avec r.mu verrouillé, après l'enregistrement du coup dans l'historique.
+func (r *room) switchClock(mover, next int) {
+	if r.clock.running != mover {
+		return
+	}
+	r.stopClock()
+	if r.timeControl.PerMove > 0 {
+		r.clock.remaining[mover] = r.timeControl.Budget()
+	} else {
+		increment := time.Duration(r.timeControl.Increment) * time.Second
+		r.clock.remaining[mover] += increment
+		r.clock.increments[r.turnPartie-1] = increment
+	}
+	r.runClock(next)
+}
+
+// takeBackIncrement retire à l'auteur du coup turn, qui vient d'être repris, l'incrément
+// que ce coup lui avait rapporté.
+// Doit être appelée avec r.mu verrouillé et les pendules arrêtées.
+func (r *room) takeBackIncrement(turn, author int) {
+	increment, ok := r.clock.increments[turn]
+	if !ok {
+		return
+	}
+	delete(r.clock.increments, turn)
+	r.clock.remaining[author] -= increment
+	if r.clock.remaining[author] < 0 {
+		r.clock.remaining[author] = 0
+	}
+}
+
+// timeExpired indique si le drapeau du joueur id est tombé, même si son timer n'a
+// pas encore été traité.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) timeExpired(id int) bool {
+	return r.clock.timer != nil && r.clock.running == id && time.Since(r.clock.since) >= r.clock.remaining[id]
+}
+
+// clockState renvoie l'état des pendules, ou nil dans une salle sans cadence ou
+// avant la première partie.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) clockState() *protocol.ClockPayload {
+	if !r.timeControl.Enabled() || len(r.clock.remaining) == 0 {
+		return nil
+	}
+	state := &protocol.ClockPayload{
+		Control: r.timeControl,
+		Players: make([]protocol.PlayerClock, 0, len(r.clock.remaining)),
+		Running: r.clock.running,
+	}
+	for id, remaining := range r.clock.remaining {
+		if id == r.clock.running {
+			remaining -= time.Since(r.clock.since)
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+		state.Players = append(state.Players, protocol.PlayerClock{ID: id, Remaining: int(remaining.Milliseconds())})
+	}
+	sort.Slice(state.Players, func(i, j int) bool { return state.Players[i].ID < state.Players[j].ID })
+	return state
+}
+
+// broadcastClock envoie l'état des pendules aux joueurs et aux spectateurs de la salle.
+func (r *room) broadcastClock() {
+	r.mu.Lock()
+	state := r.clockState()
+	r.mu.Unlock()
+	if state == nil {
+		return
+	}
+
+	message := protocol.Message{
+		Type:    protocol.TypeClock,
+		Payload: state,
+	}
+	r.notifyPlayers(message)
+	r.notifySpectators(message)
+}
+
+// flagFall fait perdre au temps le joueur id, dont le drapeau vient de tomber. Elle est
+// appelée par le timer de sa pendule et ignore un timer arrêté entre-temps (generation
+// périmée) ou une partie déjà terminée.
+func (r *room) flagFall(id, generation int) {
+	r.mu.Lock()
+	if r.clock.generation != generation || r.clock.running != id || r.gameOver {
+		r.mu.Unlock()
+		return
+	}
+	r.stopClock()
+	r.clock.remaining[id] = 0
+	result := engine.Opponent(r.playerTokens[id])
+	record := r.concludeGame(result, protocol.ReasonTimeout)
+	r.mu.Unlock()
+
+	logInfof("Temps écoulé pour le joueur %d (salle %d)\n", id, r.id)
+	r.broadcastClock()
+	r.finishGame(result, nil, protocol.ReasonTimeout, record)
+}
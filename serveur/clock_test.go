@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"puissance4/protocol"
+)
+
+// setRemaining relance la pendule du joueur p, au trait, avec le temps left.
+func setRemaining(r *room, p *testPlayer, left time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopClock()
+	r.clock.remaining[p.id] = left
+	r.runClock(p.id)
+}
+
+func TestFlagFall(t *testing.T) {
+	r, a, b := newTestGame(t, protocol.TimeControl{Initial: 60})
+	setRemaining(r, a, 20*time.Millisecond)
+
+	var over protocol.GameOverPayload
+	b.expect(t, protocol.TypeGameOver, &over)
+	if over.Winner != b.id || over.Reason != protocol.ReasonTimeout {
+		t.Errorf("game_over %+v, attendu la victoire du joueur %d au temps", over, b.id)
+	}
+	var clock protocol.ClockPayload
+	a.expect(t, protocol.TypeClock, &clock)
+	if left, _ := clock.Remaining(a.id); clock.Running != -1 || left != 0 {
+		t.Errorf("pendules %+v après la chute du drapeau", clock)
+	}
+}
+
+// TestLateMove vérifie qu'un coup arrivé après la fin du temps est refusé, même si le
+// timer de la pendule n'a pas encore fait tomber le drapeau.
+func TestLateMove(t *testing.T) {
+	r, a, _ := newTestGame(t, protocol.TimeControl{Initial: 60})
+	r.mu.Lock()
+	r.clock.timer.Stop() // Le timer n'a pas encore été traité
+	r.clock.since = time.Now().Add(-r.clock.remaining[a.id] - time.Second)
+	r.mu.Unlock()
+
+	r.move(protocol.MovePayload{X: 3, Y: 5}, a.id)
+	var rejected protocol.MoveRejectedPayload
+	a.expect(t, protocol.TypeMoveRejected, &rejected)
+	if rejected.Reason != errTimeExpired.Error() {
+		t.Errorf("coup refusé pour « %s », attendu « %s »", rejected.Reason, errTimeExpired)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gameBoard.MoveCount() != 0 {
+		t.Errorf("%d pions sur la grille après un coup hors délai", r.gameBoard.MoveCount())
+	}
+}
+
+// TestStaleFlagFall vérifie qu'un timer arrêté entre-temps ne fait pas perdre la partie.
+func TestStaleFlagFall(t *testing.T) {
+	r, a, b := newTestGame(t, protocol.TimeControl{Initial: 60})
+	r.mu.Lock()
+	generation := r.clock.generation
+	r.mu.Unlock()
+
+	play(t, r, a, 3)
+	// Timer de la pendule de a, arrêté par son coup
+	r.flagFall(a.id, generation)
+	r.mu.Lock()
+	current := r.clock.generation
+	r.mu.Unlock()
+	// Timer à jour, mais pour un joueur dont la pendule ne tourne pas
+	r.flagFall(a.id, current)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.inGame() || r.clock.running != b.id || r.clock.remaining[a.id] == 0 {
+		t.Errorf("partie en cours %v, pendule du joueur %d, temps de %d : %v", r.inGame(), r.clock.running, a.id, r.clock.remaining[a.id])
+	}
+}
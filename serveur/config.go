@@ -0,0 +1,367 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"puissance4/ai"
+	"puissance4/protocol"
+)
+
+// envPrefix préfixe les variables d'environnement qui surchargent la configuration.
+const envPrefix = "PUISSANCE4_"
+
+// duration permet d'écrire les délais du fichier de configuration sous la forme "30s" ou "2m".
+type duration struct {
+	time.Duration
+}
+
+// UnmarshalText lit un délai au format de time.ParseDuration.
+func (d *duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// serverConfig regroupe les réglages du serveur. Ils proviennent, par ordre de priorité
+// croissante, des valeurs par défaut, du fichier de configuration TOML, des variables
+// d'environnement PUISSANCE4_* et des options de la ligne de commande.
+type serverConfig struct {
+	Address   string         `toml:"address"`   // Adresse d'écoute, vide pour toutes les interfaces
+	Port      int            `toml:"port"`      // Port TCP du serveur
+	MaxRooms  int            `toml:"max_rooms"` // Nombre maximal de salles ouvertes, 0 pour illimité
+	LogLevel  string         `toml:"log_level"` // Niveau de journalisation (debug, info, warn, error)
+	DataDir   string         `toml:"data_dir"`  // Répertoire des données persistantes, vide pour n'en garder aucune
+	Password  string         `toml:"password"`  // Mot de passe du serveur, vide si le serveur est ouvert
+	WebSocket string         `toml:"websocket"` // Adresse d'écoute WebSocket, vide pour désactiver
+	HTTP      string         `toml:"http"`      // Adresse d'écoute de l'API HTTP des statistiques, vide pour désactiver
+	Timeouts  timeoutsConfig `toml:"timeouts"`  // Délais
+	TLS       tlsFileConfig  `toml:"tls"`       // Chiffrement des connexions
+	Features  featuresConfig `toml:"features"`  // Fonctionnalités activables
+	Bots      botsConfig     `toml:"bots"`      // Robots joueurs
+
+	// Cadence des salles ouvertes par le serveur (partie rapide, placement automatique),
+	// au format de protocol.ParseTimeControl ; vide pour des parties sans limite de temps
+	TimeControl string `toml:"time_control"`
+}
+
+// timeoutsConfig regroupe les délais appliqués aux connexions.
+type timeoutsConfig struct {
+	Grace     duration `toml:"grace"`     // Délai de reprise d'une session perdue, 0 pour désactiver
+	Handshake duration `toml:"handshake"` // Délai laissé au client pour envoyer "hello", 0 pour illimité
+	Idle      duration `toml:"idle"`      // Inactivité tolérée avant déconnexion, 0 pour illimité
+}
+
+// tlsFileConfig décrit le certificat utilisé pour chiffrer les connexions.
+type tlsFileConfig struct {
+	Cert string `toml:"cert"` // Chemin du certificat (PEM)
+	Key  string `toml:"key"`  // Chemin de la clé privée (PEM)
+	Dev  bool   `toml:"dev"`  // Certificat auto-signé généré au démarrage
+}
+
+// featuresConfig permet de désactiver certaines fonctionnalités du serveur.
+type featuresConfig struct {
+	QuickPlay   bool `toml:"quick_play"`  // File de partie rapide
+	Spectators  bool `toml:"spectators"`  // Mode spectateur
+	Chat        bool `toml:"chat"`        // Chat entre les joueurs d'une salle
+	Bots        bool `toml:"bots"`        // Robots invités par les joueurs ou placés à une place libre
+	Hints       bool `toml:"hints"`       // Indices demandés par les joueurs pendant la partie
+	Accounts    bool `toml:"accounts"`    // Comptes de joueurs et parties classées (avec un répertoire de données)
+	Tournaments bool `toml:"tournaments"` // Tournois organisés par les joueurs
+}
+
+// botsConfig règle les robots joueurs du serveur.
+type botsConfig struct {
+	Level     string   `toml:"level"`      // Niveau par défaut des robots (facile, moyen, difficile ou expert)
+	FillAfter duration `toml:"fill_after"` // Attente d'un joueur seul avant qu'un robot le rejoigne, 0 pour jamais
+}
+
+// config est la configuration active du serveur.
+var config = defaultConfig()
+
+// defaultConfig renvoie la configuration utilisée lorsque rien n'est précisé.
+func defaultConfig() serverConfig {
+	return serverConfig{
+		Port:     DefaultPort,
+		LogLevel: "info",
+		Timeouts: timeoutsConfig{
+			Grace:     duration{defaultGracePeriod},
+			Handshake: duration{defaultHandshakeTimeout},
+		},
+		Features: featuresConfig{
+			QuickPlay:   true,
+			Spectators:  true,
+			Chat:        true,
+			Bots:        true,
+			Hints:       true,
+			Accounts:    true,
+			Tournaments: true,
+		},
+		Bots: botsConfig{
+			Level: ai.Medium.String(),
+		},
+	}
+}
+
+// setting décrit un réglage modifiable par une option de la ligne de commande
+// et par une variable d'environnement.
+type setting struct {
+	name  string                                      // Nom de l'option ; la variable d'environnement en dérive
+	usage string                                      // Description affichée par -h
+	bool  bool                                        // Option booléenne, utilisable sans valeur
+	set   func(cfg *serverConfig, value string) error // Applique la valeur à la configuration
+}
+
+// env renvoie le nom de la variable d'environnement du réglage, par exemple PUISSANCE4_MAX_ROOMS.
+func (s setting) env() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.name, "-", "_"))
+}
+
+// settings liste les réglages disponibles en ligne de commande et dans l'environnement.
+var settings = []setting{
+	{name: "address", usage: "adresse d'écoute (vide pour toutes les interfaces)", set: func(cfg *serverConfig, v string) error {
+		cfg.Address = v
+		return nil
+	}},
+	{name: "port", usage: "port TCP du serveur", set: func(cfg *serverConfig, v string) error {
+		return parseInt(v, &cfg.Port)
+	}},
+	{name: "max-rooms", usage: "nombre maximal de salles ouvertes (0 pour illimité)", set: func(cfg *serverConfig, v string) error {
+		return parseInt(v, &cfg.MaxRooms)
+	}},
+	{name: "log-level", usage: "niveau de journalisation : debug, info, warn ou error", set: func(cfg *serverConfig, v string) error {
+		cfg.LogLevel = v
+		return nil
+	}},
+	{name: "data-dir", usage: "répertoire des données persistantes", set: func(cfg *serverConfig, v string) error {
+		cfg.DataDir = v
+		return nil
+	}},
+	{name: "password", usage: "mot de passe demandé aux clients lors de la connexion", set: func(cfg *serverConfig, v string) error {
+		cfg.Password = v
+		return nil
+	}},
+	{name: "ws", usage: "adresse d'écoute WebSocket, par exemple :8081 (vide pour désactiver)", set: func(cfg *serverConfig, v string) error {
+		cfg.WebSocket = v
+		return nil
+	}},
+	{name: "http", usage: "adresse d'écoute de l'API HTTP (JSON) des classements, par exemple :8082 (vide pour désactiver)", set: func(cfg *serverConfig, v string) error {
+		cfg.HTTP = v
+		return nil
+	}},
+	{name: "grace", usage: "durée pendant laquelle un joueur déconnecté peut reprendre sa partie (0 pour désactiver)", set: func(cfg *serverConfig, v string) error {
+		return parseDuration(v, &cfg.Timeouts.Grace)
+	}},
+	{name: "handshake-timeout", usage: "délai laissé au client pour se présenter (0 pour illimité)", set: func(cfg *serverConfig, v string) error {
+		return parseDuration(v, &cfg.Timeouts.Handshake)
+	}},
+	{name: "idle-timeout", usage: "inactivité tolérée avant de couper une connexion (0 pour illimité)", set: func(cfg *serverConfig, v string) error {
+		return parseDuration(v, &cfg.Timeouts.Idle)
+	}},
+	{name: "tls-cert", usage: "certificat TLS (PEM) du serveur", set: func(cfg *serverConfig, v string) error {
+		cfg.TLS.Cert = v
+		return nil
+	}},
+	{name: "tls-key", usage: "clé privée (PEM) du certificat TLS", set: func(cfg *serverConfig, v string) error {
+		cfg.TLS.Key = v
+		return nil
+	}},
+	{name: "tls-dev", bool: true, usage: "active TLS avec un certificat auto-signé généré au démarrage", set: func(cfg *serverConfig, v string) error {
+		return parseBool(v, &cfg.TLS.Dev)
+	}},
+	{name: "quick-play", bool: true, usage: "active la file de partie rapide", set: func(cfg *serverConfig, v string) error {
+		return parseBool(v, &cfg.Features.QuickPlay)
+	}},
+	{name: "spectators", bool: true, usage: "autorise les spectateurs", set: func(cfg *serverConfig, v string) error {
+		return parseBool(v, &cfg.Features.Spectators)
+	}},
+	{name: "chat", bool: true, usage: "active le chat entre les joueurs", set: func(cfg *serverConfig, v string) error {
+		return parseBool(v, &cfg.Features.Chat)
+	}},
+	{name: "bots", bool: true, usage: "autorise les robots joueurs", set: func(cfg *serverConfig, v string) error {
+		return parseBool(v, &cfg.Features.Bots)
+	}},
+	{name: "hints", bool: true, usage: "autorise les indices pendant la partie (hors salles classées)", set: func(cfg *serverConfig, v string) error {
+		return parseBool(v, &cfg.Features.Hints)
+	}},
+	{name: "accounts", bool: true, usage: "active les comptes de joueurs et les parties classées (avec -data-dir)", set: func(cfg *serverConfig, v string) error {
+		return parseBool(v, &cfg.Features.Accounts)
+	}},
+	{name: "tournaments", bool: true, usage: "autorise les tournois organisés par les joueurs", set: func(cfg *serverConfig, v string) error {
+		return parseBool(v, &cfg.Features.Tournaments)
+	}},
+	{name: "time-control", usage: "cadence des parties rapides, par exemple 5m, 3m+2s ou 20s/coup (vide pour aucune)", set: func(cfg *serverConfig, v string) error {
+		cfg.TimeControl = v
+		return nil
+	}},
+	{name: "bot-level", usage: "niveau par défaut des robots : facile, moyen, difficile ou expert", set: func(cfg *serverConfig, v string) error {
+		cfg.Bots.Level = v
+		return nil
+	}},
+	{name: "bot-fill-after", usage: "attente d'un joueur seul avant qu'un robot le rejoigne (0 pour jamais)", set: func(cfg *serverConfig, v string) error {
+		return parseDuration(v, &cfg.Bots.FillAfter)
+	}},
+}
+
+func parseInt(value string, target *int) error {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%q n'est pas un nombre entier", value)
+	}
+	*target = n
+	return nil
+}
+
+func parseBool(value string, target *bool) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%q n'est pas un booléen (true ou false)", value)
+	}
+	*target = b
+	return nil
+}
+
+func parseDuration(value string, target *duration) error {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%q n'est pas une durée (par exemple 30s ou 2m)", value)
+	}
+	target.Duration = d
+	return nil
+}
+
+// loadConfig lit la configuration du serveur à partir des arguments args, du fichier de
+// configuration indiqué par -config (ou PUISSANCE4_CONFIG) et de l'environnement.
+// Elle indique aussi si un réglage a été fourni par l'une de ces sources.
+func loadConfig(args []string) (serverConfig, bool, error) {
+	fs := flag.NewFlagSet("serveur", flag.ExitOnError)
+	configPath := fs.String("config", "", "fichier de configuration TOML")
+
+	// Les options sont appliquées en dernier, pour l'emporter sur le fichier et l'environnement
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+	for _, s := range settings {
+		s := s
+		record := func(value string) error {
+			var scratch serverConfig
+			if err := s.set(&scratch, value); err != nil {
+				return err
+			}
+			flagValues = append(flagValues, flagValue{s, value})
+			return nil
+		}
+		usage := fmt.Sprintf("%s (variable %s)", s.usage, s.env())
+		if s.bool {
+			fs.BoolFunc(s.name, usage, record)
+		} else {
+			fs.Func(s.name, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return serverConfig{}, false, err
+	}
+
+	cfg := defaultConfig()
+	configured := false
+
+	path := *configPath
+	if path == "" {
+		path = os.Getenv(envPrefix + "CONFIG")
+	}
+	if path != "" {
+		meta, err := toml.DecodeFile(path, &cfg)
+		if err != nil {
+			return serverConfig{}, false, fmt.Errorf("lecture de %s : %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, 0, len(undecoded))
+			for _, key := range undecoded {
+				keys = append(keys, key.String())
+			}
+			sort.Strings(keys)
+			return serverConfig{}, false, fmt.Errorf("%s : réglages inconnus %s", path, strings.Join(keys, ", "))
+		}
+		configured = true
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env()); ok {
+			if err := s.set(&cfg, value); err != nil {
+				return serverConfig{}, false, fmt.Errorf("%s : %w", s.env(), err)
+			}
+			configured = true
+		}
+	}
+
+	for _, fv := range flagValues {
+		if err := fv.setting.set(&cfg, fv.value); err != nil {
+			return serverConfig{}, false, fmt.Errorf("-%s : %w", fv.setting.name, err)
+		}
+		configured = true
+	}
+
+	return cfg, configured, cfg.validate()
+}
+
+// validate vérifie la cohérence de la configuration.
+func (cfg serverConfig) validate() error {
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		return fmt.Errorf("port %d invalide (1 à 65535)", cfg.Port)
+	}
+	if cfg.MaxRooms < 0 {
+		return fmt.Errorf("nombre maximal de salles %d invalide", cfg.MaxRooms)
+	}
+	if _, err := parseLogLevel(cfg.LogLevel); err != nil {
+		return err
+	}
+	if _, err := ai.ParseLevel(cfg.Bots.Level); err != nil {
+		return err
+	}
+	if _, err := protocol.ParseTimeControl(cfg.TimeControl); err != nil {
+		return fmt.Errorf("cadence %q invalide : %w", cfg.TimeControl, err)
+	}
+	if (cfg.TLS.Cert == "") != (cfg.TLS.Key == "") {
+		return fmt.Errorf("le certificat et la clé TLS doivent être indiqués ensemble")
+	}
+	return nil
+}
+
+// apply rend la configuration active pour l'ensemble du serveur.
+func (cfg serverConfig) apply() error {
+	config = cfg
+	logLevel, _ = parseLogLevel(cfg.LogLevel)
+	sessionGracePeriod = cfg.Timeouts.Grace.Duration
+	webSocketAddress = cfg.WebSocket
+	statsHTTPAddress = cfg.HTTP
+	tlsCertFile = cfg.TLS.Cert
+	tlsKeyFile = cfg.TLS.Key
+	tlsDevMode = cfg.TLS.Dev
+	serverPassword = cfg.Password
+	defaultTimeControl, _ = protocol.ParseTimeControl(cfg.TimeControl)
+
+	if cfg.DataDir != "" {
+		if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+			return fmt.Errorf("création du répertoire de données : %w", err)
+		}
+	}
+	return nil
+}
+
+// stdinIsTerminal indique si l'entrée standard est un terminal interactif.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
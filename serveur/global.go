@@ -1,42 +1,27 @@
 package main
 
-// DefaultPort définit le port par défaut utilisé par le serveur.
-const DefaultPort = "8080"
-
-// SelectedPayload représente la charge utile pour un message indiquant une sélection d'élément.
-type SelectedPayload struct {
-	Selected string `json:"selected"` // Élément sélectionné
-}
-
-// Message est une structure générique pour échanger des données entre le serveur et les clients.
-// Chaque message a un type (Type) et une charge utile (Payload) spécifique à ce type.
-type Message struct {
-	Type    string      `json:"type"`    // Le type de message, par exemple : "move", "color", "chat", etc.
-	Payload interface{} `json:"payload"` // Les données spécifiques au message, de type générique (interface{})
-}
+import "errors"
 
-// MovePayload représente la charge utile d'un message de type "move".
-// Elle contient les coordonnées de déplacement.
-type MovePayload struct {
-	X int `json:"x"` // Coordonnée X du déplacement
-	Y int `json:"y"` // Coordonnée Y du déplacement
-}
-
-// ColorPayload représente la charge utile d'un message de type "color".
-// Elle contient la couleur sélectionnée par un joueur.
-type ColorPayload struct {
-	Color int `json:"color"` // Couleur sélectionnée (représentée par un entier)
-}
+// DefaultPort définit le port par défaut utilisé par le serveur.
+const DefaultPort = 8080
 
-// Coordinate représente une position dans un espace 2D, associée à un joueur (ID).
-type Coordinate struct {
-	ID int // ID du joueur
-	X  int // Coordonnée X
-	Y  int // Coordonnée Y
-}
+// Erreurs renvoyées lorsqu'un coup envoyé par un client est refusé pour une raison
+// propre au déroulement de la partie. Les règles de la grille elles-mêmes
+// (colonne pleine, hors grille) sont vérifiées par le paquet engine.
+var (
+	errGameNotStarted = errors.New("la partie n'a pas commencé")
+	errGameOver       = errors.New("la partie est terminée")
+	errNotYourTurn    = errors.New("ce n'est pas votre tour")
+	errForgedRow      = errors.New("ligne incohérente avec la gravité")
+	errTimeExpired    = errors.New("temps de réflexion écoulé")
+	errGameInProgress = errors.New("une partie est en cours")
+)
 
-// ChatMessage représente la charge utile d'un message de type "chat".
-// Elle contient le texte envoyé par un joueur dans le chat.
-type ChatMessage struct {
-	Text string `json:"text"` // Texte du message envoyé par le joueur
-}
+// Erreurs renvoyées lorsqu'un abandon, une proposition de nulle ou une demande de
+// reprise est refusé.
+var (
+	errNoGame            = errors.New("aucune partie en cours")
+	errNoOffer           = errors.New("aucune proposition de l'adversaire en attente")
+	errOfferPending      = errors.New("une proposition attend déjà une réponse")
+	errNothingToTakeBack = errors.New("aucun coup à reprendre")
+)
@@ -1,3 +1,18 @@
-module puissancequatre
-
-go 1.21.3
\ No newline at end of file
+module puissancequatre
+
+go 1.21.3
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	puissance4 v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/crypto v0.14.0
+)
+
+require golang.org/x/sys v0.13.0 // indirect
+
+replace puissance4 => ../puissance4
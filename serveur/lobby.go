@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"puissance4/protocol"
+)
+
+var (
+	rooms       = make(map[int]*room) // Salles ouvertes sur le serveur, associées à leur ID.
+	clientRooms = make(map[int]*room) // Salle dans laquelle se trouve chaque client, absent s'il est dans le lobby.
+	nextRoomID  = 1                   // Identifiant de la prochaine salle créée.
+	lobbyMux    sync.Mutex            // Mutex protégeant rooms, clientRooms et nextRoomID.
+)
+
+// roomOf renvoie la salle du client id, ou nil s'il est dans le lobby.
+func roomOf(id int) *room {
+	lobbyMux.Lock()
+	defer lobbyMux.Unlock()
+	return clientRooms[id]
+}
+
+// createRoom ouvre une nouvelle salle, protégée par password s'il n'est pas vide, où les
+// indices sont interdits si noHints est vrai. Un nom par défaut est choisi si name est vide. Elle échoue si le serveur a atteint
+// son nombre maximal de salles. Les parties s'y jouent à la cadence timeControl.
+func createRoom(name, password string, noHints bool, timeControl protocol.TimeControl) (*room, error) {
+	return createMatchRoom(name, password, noHints, timeControl, nil)
+}
+
+// createMatchRoom ouvre une salle comme createRoom. Si match n'est pas nil, la salle est
+// celle d'un match de tournoi : seuls ses deux joueurs peuvent y entrer.
+func createMatchRoom(name, password string, noHints bool, timeControl protocol.TimeControl, match *tournamentMatch) (*room, error) {
+	lobbyMux.Lock()
+	defer lobbyMux.Unlock()
+
+	if config.MaxRooms > 0 && len(rooms) >= config.MaxRooms {
+		return nil, fmt.Errorf("le serveur a atteint sa limite de %d salles", config.MaxRooms)
+	}
+	id := nextRoomID
+	nextRoomID++
+	if name == "" {
+		name = fmt.Sprintf("Salle %d", id)
+	}
+	r := newRoom(id, name, password, noHints, timeControl)
+	r.match = match
+	rooms[id] = r
+	logInfof("Salle %d (%s) créée, verrouillée : %v, sans indices : %v, cadence : %s\n", id, name, r.locked(), noHints, timeControl)
+	return r, nil
+}
+
+// joinRoom place le client id dans la salle roomID, si password est le mot de passe de la salle.
+func joinRoom(id, roomID int, password string) error {
+	conn, ok := clientConn(id)
+	if !ok {
+		return fmt.Errorf("client %d introuvable", id)
+	}
+
+	lobbyMux.Lock()
+	defer lobbyMux.Unlock()
+
+	if current, ok := clientRooms[id]; ok {
+		return fmt.Errorf("vous êtes déjà dans la salle %d", current.id)
+	}
+	r, ok := rooms[roomID]
+	if !ok {
+		return fmt.Errorf("la salle %d n'existe pas", roomID)
+	}
+	if !r.checkPassword(password) {
+		return errRoomPassword
+	}
+	if r.match != nil && !r.match.has(id) {
+		return fmt.Errorf("la salle %d est réservée à un match de tournoi", roomID)
+	}
+	if !r.addPlayer(id, conn) {
+		return fmt.Errorf("la salle %d est complète", roomID)
+	}
+	clientRooms[id] = r
+	logInfof("Client %d a rejoint la salle %d\n", id, roomID)
+	return nil
+}
+
+// leaveRoom retire le client id de sa salle, qu'il y soit joueur ou spectateur.
+// La salle est fermée lorsqu'elle n'a plus de joueurs, et ses spectateurs reviennent au lobby.
+func leaveRoom(id int) {
+	lobbyMux.Lock()
+	r, ok := clientRooms[id]
+	delete(clientRooms, id)
+	lobbyMux.Unlock()
+	if !ok {
+		return
+	}
+
+	if r.isSpectator(id) {
+		r.removeSpectator(id)
+		logInfof("Client %d n'observe plus la salle %d\n", id, r.id)
+		return
+	}
+
+	remaining := r.removePlayer(id)
+	logInfof("Client %d a quitté la salle %d\n", id, r.id)
+	if r.match != nil {
+		matchPlayerLeft(r.match, id)
+	}
+
+	if remaining == 1 {
+		scheduleBotFill(r)
+	}
+	if remaining == 0 {
+		lobbyMux.Lock()
+		// La salle a pu être rejointe entre-temps
+		closed := r.playerCount() == 0
+		if closed {
+			delete(rooms, r.id)
+			logInfof("Salle %d fermée\n", r.id)
+		}
+		lobbyMux.Unlock()
+		if closed {
+			r.closeSpectators()
+			// Une place s'est libérée pour les matchs de tournoi en attente d'une salle
+			startPendingMatches()
+		}
+	}
+}
+
+// openRoom renvoie une salle ouverte disposant d'une place libre, en en créant une au besoin.
+// Les salles des matchs de tournoi ne sont jamais proposées.
+// Elle permet aux clients qui envoient "ready" sans avoir choisi de salle d'être placés automatiquement.
+func openRoom() (*room, error) {
+	lobbyMux.Lock()
+	ids := make([]int, 0, len(rooms))
+	for id := range rooms {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, roomID := range ids {
+		if r := rooms[roomID]; !r.locked() && r.match == nil && r.playerCount() < maxPlayersPerRoom {
+			lobbyMux.Unlock()
+			return r, nil
+		}
+	}
+	lobbyMux.Unlock()
+	return createRoom("", "", false, defaultTimeControl)
+}
+
+// roomList renvoie la description des salles ouvertes, triées par identifiant.
+func roomList() []protocol.RoomInfo {
+	lobbyMux.Lock()
+	defer lobbyMux.Unlock()
+
+	list := make([]protocol.RoomInfo, 0, len(rooms))
+	for _, r := range rooms {
+		list = append(list, protocol.RoomInfo{
+			ID:         r.id,
+			Name:       r.name,
+			Players:    r.playerCount(),
+			MaxPlayers: maxPlayersPerRoom,
+			Spectators: len(r.spectatorIDs()),
+			Locked:     r.locked(),
+			NoHints:    r.noHints,
+			Members:    r.members(),
+			Clock:      r.timeControl,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// sendRoomList envoie la liste des salles au client id.
+func sendRoomList(id int) {
+	sendToClient(id, protocol.Message{
+		Type: protocol.TypeRoomList,
+		Payload: protocol.RoomListPayload{
+			Rooms: roomList(),
+		},
+	})
+}
+
+// handleCreateRoom crée une salle à la demande du client id et l'y place.
+func handleCreateRoom(payload protocol.CreateRoomPayload, id int) {
+	if err := payload.Clock.Validate(); err != nil {
+		logWarnf("Client %d ne peut pas créer de salle : %v\n", id, err)
+		sendError(id, protocol.ErrCodeBadPayload, protocol.TypeCreateRoom, err.Error())
+		return
+	}
+	r, err := createRoom(payload.Name, payload.Password, payload.NoHints, payload.Clock)
+	if err != nil {
+		logWarnf("Client %d ne peut pas créer de salle : %v\n", id, err)
+		sendRoomError(id, err)
+		return
+	}
+	handleJoinRoom(protocol.RoomRequest{ID: r.id, Password: payload.Password}, id)
+}
+
+// handleJoinRoom place le client id dans la salle demandée et lui confirme son arrivée,
+// ou lui renvoie un message "error" si c'est impossible. Elle renvoie false dans ce cas.
+func handleJoinRoom(payload protocol.RoomRequest, id int) bool {
+	roomID := payload.ID
+	if err := joinRoom(id, roomID, payload.Password); err != nil {
+		logWarnf("Client %d ne peut pas rejoindre la salle %d : %v\n", id, roomID, err)
+		sendRoomError(id, err)
+		return false
+	}
+	// Un client entré dans une salle n'attend plus de partie rapide
+	handleCancelQuickPlay(id)
+
+	r := roomOf(id)
+	sendToClient(id, protocol.Message{
+		Type: protocol.TypeRoomJoined,
+		Payload: protocol.RoomJoinedPayload{
+			ID:      r.id,
+			Name:    r.name,
+			Players: r.playerCount(),
+			NoHints: r.noHints,
+			Clock:   r.timeControl,
+		},
+	})
+	r.broadcastRoomPlayers()
+	r.broadcastSpectateState()
+
+	// Un joueur seul pourra être rejoint par un robot
+	if r.playerCount() == 1 {
+		scheduleBotFill(r)
+	}
+	return true
+}
+
+// handleLeaveRoom fait revenir le client id dans le lobby.
+func handleLeaveRoom(id int) {
+	leaveRoom(id)
+	sendToClient(id, protocol.Message{
+		Type:    protocol.TypeRoomLeft,
+		Payload: nil,
+	})
+}
+
+// sendRoomError signale au client id qu'une opération sur les salles a échoué.
+func sendRoomError(id int, err error) {
+	code := protocol.ErrCodeRoom
+	if errors.Is(err, errRoomPassword) {
+		code = protocol.ErrCodeRoomPassword
+	}
+	sendError(id, code, "", err.Error())
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Niveaux de journalisation, du plus bavard au plus discret.
+const (
+	levelDebug = iota // Détail de chaque message échangé
+	levelInfo         // Connexions, salles et parties
+	levelWarn         // Messages refusés et connexions rejetées
+	levelError        // Erreurs réseau ou internes uniquement
+)
+
+// levelNames associe le nom d'un niveau, tel qu'il apparaît dans la configuration, à sa valeur.
+var levelNames = map[string]int{
+	"debug": levelDebug,
+	"info":  levelInfo,
+	"warn":  levelWarn,
+	"error": levelError,
+}
+
+// logLevel est le niveau en dessous duquel les messages du journal sont ignorés.
+var logLevel = levelInfo
+
+// parseLogLevel convertit le nom d'un niveau de journalisation en sa valeur.
+func parseLogLevel(name string) (int, error) {
+	level, ok := levelNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("niveau de journalisation inconnu %q (debug, info, warn ou error)", name)
+	}
+	return level, nil
+}
+
+// logf écrit un message dans le journal si son niveau est suffisant.
+func logf(level int, format string, args ...interface{}) {
+	if level < logLevel {
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// logDebugf journalise le détail d'un échange avec un client.
+func logDebugf(format string, args ...interface{}) {
+	logf(levelDebug, format, args...)
+}
+
+// logInfof journalise un événement normal du serveur.
+func logInfof(format string, args ...interface{}) {
+	logf(levelInfo, format, args...)
+}
+
+// logWarnf journalise un message refusé ou une situation anormale sans gravité.
+func logWarnf(format string, args ...interface{}) {
+	logf(levelWarn, format, args...)
+}
+
+// logErrorf journalise une erreur.
+func logErrorf(format string, args ...interface{}) {
+	logf(levelError, format, args...)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
@@ -11,39 +12,96 @@ import (
 )
 
 func main() {
+	cfg, configured, err := loadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatal("Erreur de configuration : ", err)
+	}
 
-	// Demander à l'utilisateur un port via le terminal
-	fmt.Printf("Entrez le port du serveur [par defaut: %s] : ", DefaultPort)
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input) // Supprimer les espaces ou sauts de ligne
+	// Demander le port dans le terminal seulement si rien n'a été configuré
+	if !configured && stdinIsTerminal() {
+		cfg.Port = promptPort()
+	}
+	if err := cfg.apply(); err != nil {
+		log.Fatal("Erreur de configuration : ", err)
+	}
 
-	// Utiliser le port par défaut si aucun input n'est fourni
-	port := DefaultPort
-	if input != "" {
-		if _, err := strconv.Atoi(input); err == nil {
-			port = input
-		} else {
-			log.Fatalf("Erreur : '%s' n'est pas un port valide.", input)
-		}
+	tlsCfg, err := tlsConfig()
+	if err != nil {
+		log.Fatal("Erreur de configuration TLS : ", err)
 	}
 
 	// Obtenir l'adresse IP locale
 	localIP := getLocalIP()
 
 	// Écoute sur le port spécifié
-	address := ":" + port
+	address := net.JoinHostPort(cfg.Address, strconv.Itoa(cfg.Port))
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		log.Fatal("Erreur lors de l'écoute :", err)
 	}
+	if tlsCfg != nil {
+		listener = tls.NewListener(listener, tlsCfg)
+	}
 	defer listener.Close()
 
 	// Afficher l'IP et le port du serveur
-	log.Printf("Serveur démarré. Adresse : %s:%s\n", localIP, port)
-	log.Println("En attente de connexions...")
+	logInfof("Serveur démarré. Adresse : %s:%d\n", localIP, cfg.Port)
+	if serverPassword != "" {
+		logInfof("Connexion protégée par mot de passe\n")
+	}
+	if cfg.DataDir != "" {
+		archive, err = openArchive(cfg.DataDir)
+		if err != nil {
+			log.Fatal("Erreur de l'archive des parties : ", err)
+		}
+		defer archive.close()
+		logInfof("Données persistantes dans %s (parties archivées dans %s)\n", cfg.DataDir, archiveFile)
 
-	waitForRestart()
+		if cfg.Features.Accounts {
+			accounts, err = openAccounts(cfg.DataDir)
+			if err != nil {
+				log.Fatal("Erreur de la base des comptes : ", err)
+			}
+			defer accounts.close()
+			logInfof("Comptes des joueurs dans %s\n", accountsFile)
+
+			playerStats, err = loadStats(archive)
+			if err != nil {
+				log.Fatal("Erreur des statistiques des joueurs : ", err)
+			}
+			logInfof("Statistiques de %d joueur(s) chargées\n", len(playerStats.players))
+		}
+	}
+	logInfof("En attente de connexions...\n")
+
+	// Les clients WebSocket partagent les salles et l'état du jeu des clients TCP
+	if webSocketAddress != "" {
+		go startWebSocketServer(webSocketAddress, tlsCfg)
+	}
+	if statsHTTPAddress != "" {
+		if playerStats == nil {
+			logWarnf("API des classements sans effet : les comptes sont désactivés ou le serveur n'a pas de répertoire de données\n")
+		}
+		go startStatsServer(statsHTTPAddress, tlsCfg)
+	}
 
 	startServer(listener)
 }
+
+// promptPort demande à l'utilisateur un port via le terminal.
+func promptPort() int {
+	fmt.Printf("Entrez le port du serveur [par defaut: %d] : ", DefaultPort)
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input) // Supprimer les espaces ou sauts de ligne
+
+	// Utiliser le port par défaut si aucun input n'est fourni
+	if input == "" {
+		return DefaultPort
+	}
+	port, err := strconv.Atoi(input)
+	if err != nil || port < 1 || port > 65535 {
+		log.Fatalf("Erreur : '%s' n'est pas un port valide.", input)
+	}
+	return port
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"puissance4/protocol"
+)
+
+// defaultEstimatedWait est l'attente annoncée tant qu'aucune partie rapide n'a encore été formée.
+const defaultEstimatedWait = 30 * time.Second
+
+var (
+	matchQueue     []int                     // IDs des clients en attente d'une partie rapide, dans l'ordre d'arrivée.
+	queuedAt       = make(map[int]time.Time) // Heure d'entrée dans la file de chaque client en attente.
+	averageWait    time.Duration             // Moyenne glissante des attentes observées avant de trouver un adversaire.
+	quickPlayCount int                       // Nombre de parties rapides formées, utilisé pour nommer les salles.
+	matchmakingMux sync.Mutex                // Mutex protégeant la file d'attente et ses statistiques.
+)
+
+// joinQueue place le client id dans la file de partie rapide puis tente de former des paires.
+func joinQueue(id int) {
+	if !config.Features.QuickPlay {
+		sendDisabled(id, protocol.TypeQuickPlay, "la partie rapide est désactivée sur ce serveur")
+		return
+	}
+	if r := roomOf(id); r != nil {
+		sendRoomError(id, fmt.Errorf("vous êtes déjà dans la salle %d", r.id))
+		return
+	}
+
+	matchmakingMux.Lock()
+	if _, ok := queuedAt[id]; ok {
+		matchmakingMux.Unlock()
+		return
+	}
+	matchQueue = append(matchQueue, id)
+	queuedAt[id] = time.Now()
+	matchmakingMux.Unlock()
+
+	logInfof("Client %d en file d'attente pour une partie rapide\n", id)
+	matchPlayers()
+}
+
+// leaveQueue retire le client id de la file de partie rapide.
+// Elle renvoie false s'il n'y était pas.
+func leaveQueue(id int) bool {
+	matchmakingMux.Lock()
+	defer matchmakingMux.Unlock()
+
+	if _, ok := queuedAt[id]; !ok {
+		return false
+	}
+	delete(queuedAt, id)
+	for i, queued := range matchQueue {
+		if queued == id {
+			matchQueue = append(matchQueue[:i], matchQueue[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// handleCancelQuickPlay retire le client id de la file et le renvoie au lobby.
+func handleCancelQuickPlay(id int) {
+	if leaveQueue(id) {
+		logInfof("Client %d a quitté la file d'attente\n", id)
+		sendToClient(id, protocol.Message{
+			Type:    protocol.TypeQueueLeft,
+			Payload: nil,
+		})
+		broadcastQueuePositions()
+	}
+}
+
+// matchPlayers forme des paires avec les clients de la file, dans l'ordre d'arrivée,
+// et place chaque paire dans une nouvelle salle. Les clients restants reçoivent
+// ensuite leur position dans la file.
+func matchPlayers() {
+	for {
+		matchmakingMux.Lock()
+		if len(matchQueue) < maxPlayersPerRoom {
+			matchmakingMux.Unlock()
+			break
+		}
+		pair := []int{matchQueue[0], matchQueue[1]}
+		matchQueue = matchQueue[2:]
+		now := time.Now()
+		since := make(map[int]time.Time, len(pair))
+		for _, id := range pair {
+			since[id] = queuedAt[id]
+			recordWait(now.Sub(queuedAt[id]))
+			delete(queuedAt, id)
+		}
+		quickPlayCount++
+		name := fmt.Sprintf("Partie rapide %d", quickPlayCount)
+		matchmakingMux.Unlock()
+
+		r, err := createRoom(name, "", false, defaultTimeControl)
+		if err != nil {
+			// Plus de salle disponible : les deux clients reviennent au lobby
+			logWarnf("Partie rapide impossible pour les clients %d et %d : %v\n", pair[0], pair[1], err)
+			for _, id := range pair {
+				sendRoomError(id, err)
+			}
+			continue
+		}
+		joined := make([]int, 0, len(pair))
+		for _, id := range pair {
+			if handleJoinRoom(protocol.RoomRequest{ID: r.id}, id) {
+				joined = append(joined, id)
+			}
+		}
+		if len(joined) < len(pair) {
+			requeue(r, joined, since)
+			continue
+		}
+		logInfof("Partie rapide : clients %d et %d placés dans la salle %d\n", pair[0], pair[1], r.id)
+	}
+
+	broadcastQueuePositions()
+}
+
+// requeue annule une partie rapide dont un joueur n'a pas pu rejoindre la salle r, par
+// exemple parce qu'il s'est déconnecté entre-temps : les clients joined, déjà placés dans
+// la salle, reviennent en tête de file avec leur heure d'arrivée since, et la salle est fermée.
+func requeue(r *room, joined []int, since map[int]time.Time) {
+	logWarnf("Partie rapide de la salle %d annulée : un joueur n'a pas pu la rejoindre\n", r.id)
+	for _, id := range joined {
+		handleLeaveRoom(id)
+	}
+
+	// La salle n'est fermée par leaveRoom que si un joueur l'a quittée
+	lobbyMux.Lock()
+	if rooms[r.id] == r && r.playerCount() == 0 {
+		delete(rooms, r.id)
+		logInfof("Salle %d fermée\n", r.id)
+	}
+	lobbyMux.Unlock()
+
+	matchmakingMux.Lock()
+	matchQueue = append(append([]int(nil), joined...), matchQueue...)
+	for _, id := range joined {
+		queuedAt[id] = since[id]
+	}
+	matchmakingMux.Unlock()
+}
+
+// recordWait met à jour la moyenne glissante des attentes.
+// Doit être appelée avec matchmakingMux verrouillé.
+func recordWait(wait time.Duration) {
+	if averageWait == 0 {
+		averageWait = wait
+		return
+	}
+	averageWait = (averageWait*3 + wait) / 4
+}
+
+// estimatedWait estime l'attente d'un client à la position donnée (à partir de 1) :
+// chaque paire devant lui doit être formée avant la sienne.
+// Doit être appelée avec matchmakingMux verrouillé.
+func estimatedWait(position int) time.Duration {
+	base := averageWait
+	if base == 0 {
+		base = defaultEstimatedWait
+	}
+	return base * time.Duration((position+1)/2)
+}
+
+// broadcastQueuePositions envoie à chaque client en attente sa position dans la file,
+// la taille de la file et l'attente estimée en secondes.
+func broadcastQueuePositions() {
+	matchmakingMux.Lock()
+	messages := make(map[int]protocol.Message, len(matchQueue))
+	for i, id := range matchQueue {
+		messages[id] = protocol.Message{
+			Type: protocol.TypeQueuePosition,
+			Payload: protocol.QueuePositionPayload{
+				Position:      i + 1,
+				Waiting:       len(matchQueue),
+				EstimatedWait: int(estimatedWait(i + 1).Seconds()),
+			},
+		}
+	}
+	matchmakingMux.Unlock()
+
+	for id, msg := range messages {
+		sendToClient(id, msg)
+	}
+}
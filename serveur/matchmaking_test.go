@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"puissance4/protocol"
+)
+
+// TestMatchPlayersRequeue vérifie qu'un joueur apparié à un client déconnecté revient en
+// tête de file au lieu de rester seul dans la salle de la partie rapide.
+func TestMatchPlayersRequeue(t *testing.T) {
+	saved := config
+	config.Features.Bots = false
+	t.Cleanup(func() { config = saved })
+
+	a, b := newTestPlayer(t), newTestPlayer(t)
+	const ghost = 1 << 30 // Client parti sans avoir quitté la file
+	arrived := time.Now().Add(-time.Minute)
+
+	matchmakingMux.Lock()
+	matchQueue = []int{a.id, ghost}
+	queuedAt = map[int]time.Time{a.id: arrived, ghost: arrived}
+	matchmakingMux.Unlock()
+	t.Cleanup(func() {
+		leaveRoom(a.id)
+		leaveRoom(b.id)
+		matchmakingMux.Lock()
+		matchQueue, queuedAt = nil, make(map[int]time.Time)
+		matchmakingMux.Unlock()
+	})
+
+	lobbyMux.Lock()
+	roomsBefore := len(rooms)
+	lobbyMux.Unlock()
+
+	matchPlayers()
+	a.expect(t, protocol.TypeRoomLeft, nil)
+	var position protocol.QueuePositionPayload
+	a.expect(t, protocol.TypeQueuePosition, &position)
+	if position.Position != 1 || position.Waiting != 1 {
+		t.Errorf("position %+v, attendu seul en tête de file", position)
+	}
+	if roomOf(a.id) != nil {
+		t.Errorf("le joueur %d reste dans la salle %d", a.id, roomOf(a.id).id)
+	}
+	matchmakingMux.Lock()
+	since := queuedAt[a.id]
+	matchmakingMux.Unlock()
+	if !since.Equal(arrived) {
+		t.Errorf("heure d'arrivée %v, attendu %v", since, arrived)
+	}
+	lobbyMux.Lock()
+	roomsAfter := len(rooms)
+	lobbyMux.Unlock()
+	if roomsAfter != roomsBefore {
+		t.Errorf("%d salles après l'annulation, attendu %d", roomsAfter, roomsBefore)
+	}
+
+	// Le joueur suivant forme la paire avec lui
+	joinQueue(b.id)
+	a.expect(t, protocol.TypeRoomJoined, nil)
+	if ra, rb := roomOf(a.id), roomOf(b.id); ra == nil || ra != rb {
+		t.Errorf("joueurs dans les salles %v et %v, attendu la même", ra, rb)
+	}
+	matchmakingMux.Lock()
+	defer matchmakingMux.Unlock()
+	if len(matchQueue) != 0 || len(queuedAt) != 0 {
+		t.Errorf("file %v après l'appariement, attendu vide", matchQueue)
+	}
+}
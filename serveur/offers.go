@@ -0,0 +1,223 @@
+package main
+
+import (
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// clearOffers retire les propositions de nulle et les demandes de reprise en attente.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) clearOffers() {
+	r.drawOffer = -1
+	r.takebackRequest = -1
+}
+
+// inGame indique si une partie est en cours dans la salle.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) inGame() bool {
+	return !r.gameOver && r.currentTurn != -1
+}
+
+// sendOfferError signale au joueur id que son message de type msgType a été refusé.
+func sendOfferError(id int, msgType string, err error) {
+	logWarnf("Message %s du joueur %d refusé : %v\n", msgType, id, err)
+	sendError(id, protocol.ErrCodeOffer, msgType, err.Error())
+}
+
+// resign fait perdre la partie en cours au joueur id, qui l'abandonne. Un joueur peut
+// abandonner à tout moment de la partie, même lorsque ce n'est pas son tour.
+func (r *room) resign(id int) {
+	r.mu.Lock()
+	if !r.inGame() {
+		r.mu.Unlock()
+		sendOfferError(id, protocol.TypeResign, errNoGame)
+		return
+	}
+	result := engine.Opponent(r.playerTokens[id])
+	record := r.concludeGame(result, protocol.ReasonResign)
+	r.mu.Unlock()
+
+	logInfof("Le joueur %d abandonne la partie (salle %d)\n", id, r.id)
+	r.broadcastClock()
+	r.finishGame(result, nil, protocol.ReasonResign, record)
+}
+
+// offerDraw enregistre la proposition de nulle du joueur id et l'annonce aux deux
+// joueurs. Si son adversaire avait déjà proposé la nulle, elle vaut acceptation.
+// La proposition tombe dès qu'un coup est joué.
+func (r *room) offerDraw(id int) {
+	r.mu.Lock()
+	offer := r.drawOffer
+	var err error
+	switch {
+	case !r.inGame():
+		err = errNoGame
+	case offer == id:
+		err = errOfferPending
+	case offer == -1:
+		r.drawOffer = id
+	}
+	r.mu.Unlock()
+
+	switch {
+	case err != nil:
+		sendOfferError(id, protocol.TypeOfferDraw, err)
+	case offer != -1:
+		r.answerDraw(true, id)
+	default:
+		logInfof("Le joueur %d propose la nulle (salle %d)\n", id, r.id)
+		r.notifyPlayers(protocol.Message{
+			Type:    protocol.TypeDrawOffered,
+			Payload: protocol.OfferPayload{ID: id},
+		})
+	}
+}
+
+// answerDraw répond à la proposition de nulle de l'adversaire du joueur id : la partie
+// se termine par une égalité si elle est acceptée, les deux joueurs sont prévenus du
+// refus sinon.
+func (r *room) answerDraw(accept bool, id int) {
+	r.mu.Lock()
+	offer := r.drawOffer
+	if !r.inGame() || offer == -1 || offer == id {
+		r.mu.Unlock()
+		sendOfferError(id, protocol.TypeAnswerDraw, errNoOffer)
+		return
+	}
+	r.drawOffer = -1
+	if !accept {
+		r.mu.Unlock()
+		logInfof("Le joueur %d refuse la nulle (salle %d)\n", id, r.id)
+		r.notifyPlayers(protocol.Message{
+			Type:    protocol.TypeDrawDeclined,
+			Payload: protocol.OfferPayload{ID: offer},
+		})
+		return
+	}
+	record := r.concludeGame(engine.Equality, protocol.ReasonAgreement)
+	r.mu.Unlock()
+
+	logInfof("Nulle acceptée par le joueur %d (salle %d)\n", id, r.id)
+	r.broadcastClock()
+	r.finishGame(engine.Equality, nil, protocol.ReasonAgreement, record)
+}
+
+// requestTakeback enregistre la demande de reprise du joueur id et l'annonce aux deux
+// joueurs. Le joueur doit avoir déjà joué un coup dans la partie ; la demande tombe dès
+// qu'un coup est joué.
+func (r *room) requestTakeback(id int) {
+	r.mu.Lock()
+	var err error
+	switch {
+	case !r.inGame():
+		err = errNoGame
+	case r.takebackRequest != -1:
+		err = errOfferPending
+	case r.takebackLength(id) == 0:
+		err = errNothingToTakeBack
+	default:
+		r.takebackRequest = id
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		sendOfferError(id, protocol.TypeRequestTakeback, err)
+		return
+	}
+	logInfof("Le joueur %d demande à reprendre son coup (salle %d)\n", id, r.id)
+	r.notifyPlayers(protocol.Message{
+		Type:    protocol.TypeTakebackRequested,
+		Payload: protocol.OfferPayload{ID: id},
+	})
+}
+
+// answerTakeback répond à la demande de reprise de l'adversaire du joueur id : si elle
+// est acceptée, les coups sont retirés de la partie et les joueurs comme les spectateurs
+// reçoivent la position rétablie ; les deux joueurs sont prévenus du refus sinon.
+func (r *room) answerTakeback(accept bool, id int) {
+	r.mu.Lock()
+	requester := r.takebackRequest
+	if !r.inGame() || requester == -1 || requester == id {
+		r.mu.Unlock()
+		sendOfferError(id, protocol.TypeAnswerTakeback, errNoOffer)
+		return
+	}
+	r.takebackRequest = -1
+	if !accept {
+		r.mu.Unlock()
+		logInfof("Le joueur %d refuse la reprise (salle %d)\n", id, r.id)
+		r.notifyPlayers(protocol.Message{
+			Type:    protocol.TypeTakebackDeclined,
+			Payload: protocol.OfferPayload{ID: requester},
+		})
+		return
+	}
+	undone := r.takeBack(requester)
+	r.mu.Unlock()
+
+	logInfof("Reprise acceptée par le joueur %d : %d coup(s) retiré(s) (salle %d)\n", id, len(undone), r.id)
+	r.notifyPlayers(protocol.Message{
+		Type: protocol.TypeTakeback,
+		Payload: protocol.TakebackPayload{
+			Moves:       undone,
+			CurrentTurn: requester,
+		},
+	})
+	r.broadcastClock()
+	r.broadcastSpectateState()
+}
+
+// takebackLength renvoie le nombre de coups à retirer pour revenir avant le dernier coup
+// du joueur id : ce coup, et la réponse de son adversaire s'il a déjà joué. Elle renvoie
+// 0 si le joueur n'a encore joué aucun coup.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) takebackLength(id int) int {
+	for turn := r.turnPartie - 1; turn >= 0; turn-- {
+		if r.historiquePartie[turn].ID == id {
+			return r.turnPartie - turn
+		}
+	}
+	return 0
+}
+
+// takeBack retire de la grille, de l'historique et de la partie à archiver les coups
+// qui suivent le dernier coup du joueur requester, ce coup compris, puis lui rend le
+// trait et relance sa pendule. Chaque auteur d'un coup retiré perd l'incrément que ce
+// coup lui avait rapporté. Elle renvoie les coups retirés, du plus récent au plus ancien.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) takeBack(requester int) []protocol.Coordinate {
+	clocked := r.clock.timer != nil
+	if clocked {
+		r.stopClock()
+	}
+
+	n := r.takebackLength(requester)
+	undone := make([]protocol.Coordinate, 0, n)
+	for i := 0; i < n; i++ {
+		r.turnPartie--
+		move := r.historiquePartie[r.turnPartie]
+		undone = append(undone, move)
+		delete(r.historiquePartie, r.turnPartie)
+		if _, err := r.gameBoard.Undo(); err != nil {
+			logErrorf("Reprise impossible sur la grille de la salle %d : %v\n", r.id, err)
+		}
+		if clocked {
+			r.takeBackIncrement(r.turnPartie, move.ID)
+		}
+	}
+	if r.record != nil && len(r.record.Moves) >= n {
+		r.record.Moves = r.record.Moves[:len(r.record.Moves)-n]
+	}
+	r.currentTurn = requester
+	r.hintRequested = false
+	r.clearOffers()
+
+	// Le joueur rejoue avec le temps qu'il lui restait, ou tout son temps par coup
+	if clocked {
+		if r.timeControl.PerMove > 0 {
+			r.clock.remaining[requester] = r.timeControl.Budget()
+		}
+		r.runClock(requester)
+	}
+	return undone
+}
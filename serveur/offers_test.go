@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// clockTolerance couvre le temps écoulé entre deux instructions d'un test sur les pendules.
+const clockTolerance = 500 * time.Millisecond
+
+// testMessage est un message reçu par un joueur de test, sa charge utile restant à décoder.
+type testMessage struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// testPlayer est un client branché sur le serveur par un net.Pipe, dont les messages
+// reçus sont lus au fil de l'eau pour ne jamais bloquer les écritures du serveur.
+type testPlayer struct {
+	id       int
+	messages chan testMessage
+}
+
+// expect attend le prochain message de type msgType et décode sa charge utile dans
+// payload, s'il n'est pas nil. Les messages d'autres types sont ignorés.
+func (p *testPlayer) expect(t *testing.T, msgType string, payload interface{}) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case m := <-p.messages:
+			if m.Type != msgType {
+				continue
+			}
+			if payload != nil {
+				if err := json.Unmarshal(m.Payload, payload); err != nil {
+					t.Fatalf("message %s illisible : %v", msgType, err)
+				}
+			}
+			return
+		case <-timeout:
+			t.Fatalf("le joueur %d n'a pas reçu de message %s", p.id, msgType)
+		}
+	}
+}
+
+// expectError attend un message "error" de code offer refusant un message de type msgType.
+func (p *testPlayer) expectError(t *testing.T, msgType string) {
+	t.Helper()
+	var e protocol.ErrorPayload
+	p.expect(t, protocol.TypeError, &e)
+	if e.Code != protocol.ErrCodeOffer || e.Type != msgType {
+		t.Errorf("erreur %+v, attendu le code %s pour %s", e, protocol.ErrCodeOffer, msgType)
+	}
+}
+
+// newTestPlayer enregistre un client relié au serveur par un net.Pipe.
+func newTestPlayer(t *testing.T) *testPlayer {
+	serverConn, clientConn := net.Pipe()
+	id, _ := registerClient(serverConn)
+	p := &testPlayer{id: id, messages: make(chan testMessage, 256)}
+	go func() {
+		scanner := bufio.NewScanner(clientConn)
+		for scanner.Scan() {
+			var m testMessage
+			if json.Unmarshal(scanner.Bytes(), &m) == nil {
+				p.messages <- m
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		clientMux.Lock()
+		delete(clients, id)
+		clientMux.Unlock()
+		serverConn.Close()
+		clientConn.Close()
+	})
+	return p
+}
+
+// newTestGame ouvre une salle à la cadence tc et y lance une partie entre deux joueurs
+// de test, le premier ayant le trait.
+func newTestGame(t *testing.T, tc protocol.TimeControl) (*room, *testPlayer, *testPlayer) {
+	a, b := newTestPlayer(t), newTestPlayer(t)
+	r := newRoom(1, "test", "", false, tc)
+	for _, p := range []*testPlayer{a, b} {
+		conn, _ := clientConn(p.id)
+		r.addPlayer(p.id, conn)
+	}
+	r.mu.Lock()
+	r.playerTokens = map[int]int{a.id: engine.P1Token, b.id: engine.P2Token}
+	r.firstPlayer = a.id
+	r.mu.Unlock()
+	r.resetServerState()
+	t.Cleanup(func() {
+		r.mu.Lock()
+		r.stopClock()
+		r.mu.Unlock()
+	})
+	return r, a, b
+}
+
+// play joue le coup du joueur p dans la colonne x.
+func play(t *testing.T, r *room, p *testPlayer, x int) {
+	t.Helper()
+	r.mu.Lock()
+	y, _ := r.gameBoard.LandingRow(x)
+	r.mu.Unlock()
+	r.move(protocol.MovePayload{X: x, Y: y}, p.id)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := r.gameBoard.LastMove(); !ok || last.X != x {
+		t.Fatalf("coup du joueur %d en colonne %d refusé", p.id, x)
+	}
+}
+
+// remaining renvoie le temps restant du joueur p, décompté jusqu'à maintenant.
+func remaining(r *room, p *testPlayer) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	left := r.clock.remaining[p.id]
+	if r.clock.running == p.id {
+		left -= time.Since(r.clock.since)
+	}
+	return left
+}
+
+// checkRemaining vérifie que le joueur p dispose de want, au temps écoulé près.
+func checkRemaining(t *testing.T, r *room, p *testPlayer, want time.Duration) {
+	t.Helper()
+	if got := remaining(r, p); got > want || got < want-clockTolerance {
+		t.Errorf("joueur %d : %v restant, attendu %v", p.id, got, want)
+	}
+}
+
+func TestResign(t *testing.T) {
+	r, a, b := newTestGame(t, protocol.TimeControl{Initial: 60})
+	play(t, r, a, 3)
+
+	// L'abandon est possible hors de son tour
+	r.resign(a.id)
+	var over protocol.GameOverPayload
+	b.expect(t, protocol.TypeGameOver, &over)
+	if over.Winner != b.id || over.Reason != protocol.ReasonResign {
+		t.Errorf("game_over %+v, attendu la victoire du joueur %d par abandon", over, b.id)
+	}
+	r.mu.Lock()
+	running, inGame := r.clock.running, r.inGame()
+	r.mu.Unlock()
+	if inGame || running != -1 {
+		t.Errorf("partie en cours %v, pendule du joueur %d après l'abandon", inGame, running)
+	}
+
+	r.resign(b.id)
+	b.expectError(t, protocol.TypeResign)
+}
+
+func TestDrawOffer(t *testing.T) {
+	r, a, b := newTestGame(t, protocol.TimeControl{})
+
+	r.answerDraw(true, b.id)
+	b.expectError(t, protocol.TypeAnswerDraw)
+
+	r.offerDraw(a.id)
+	var offer protocol.OfferPayload
+	b.expect(t, protocol.TypeDrawOffered, &offer)
+	if offer.ID != a.id {
+		t.Errorf("nulle proposée par %d, attendu %d", offer.ID, a.id)
+	}
+	r.offerDraw(a.id)
+	a.expectError(t, protocol.TypeOfferDraw)
+	r.answerDraw(true, a.id)
+	a.expectError(t, protocol.TypeAnswerDraw)
+
+	r.answerDraw(false, b.id)
+	a.expect(t, protocol.TypeDrawDeclined, &offer)
+
+	// Un coup joué fait tomber la proposition
+	r.offerDraw(a.id)
+	play(t, r, a, 3)
+	r.answerDraw(true, b.id)
+	b.expectError(t, protocol.TypeAnswerDraw)
+
+	// Deux propositions croisées valent acceptation
+	r.offerDraw(b.id)
+	r.offerDraw(a.id)
+	var over protocol.GameOverPayload
+	a.expect(t, protocol.TypeGameOver, &over)
+	if over.Result != protocol.ResultDraw || over.Winner != -1 || over.Reason != protocol.ReasonAgreement {
+		t.Errorf("game_over %+v, attendu une nulle par accord", over)
+	}
+}
+
+func TestTakeback(t *testing.T) {
+	r, a, b := newTestGame(t, protocol.TimeControl{})
+
+	r.requestTakeback(a.id)
+	a.expectError(t, protocol.TypeRequestTakeback)
+
+	play(t, r, a, 3)
+	play(t, r, b, 4)
+	r.requestTakeback(a.id)
+	b.expect(t, protocol.TypeTakebackRequested, nil)
+	r.answerTakeback(true, a.id)
+	a.expectError(t, protocol.TypeAnswerTakeback)
+
+	r.answerTakeback(true, b.id)
+	var takeback protocol.TakebackPayload
+	a.expect(t, protocol.TypeTakeback, &takeback)
+	if takeback.CurrentTurn != a.id || len(takeback.Moves) != 2 ||
+		takeback.Moves[0].ID != b.id || takeback.Moves[1].ID != a.id {
+		t.Errorf("reprise %+v, attendu les deux derniers coups et le trait au joueur %d", takeback, a.id)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gameBoard.MoveCount() != 0 || r.turnPartie != 0 || len(r.historiquePartie) != 0 || len(r.record.Moves) != 0 {
+		t.Errorf("%d pions, %d coups dans l'historique après la reprise", r.gameBoard.MoveCount(), len(r.historiquePartie))
+	}
+	if r.currentTurn != a.id {
+		t.Errorf("trait au joueur %d, attendu %d", r.currentTurn, a.id)
+	}
+}
+
+func TestTakebackDeclined(t *testing.T) {
+	r, a, b := newTestGame(t, protocol.TimeControl{})
+	play(t, r, a, 3)
+	r.requestTakeback(a.id)
+	r.requestTakeback(a.id)
+	a.expectError(t, protocol.TypeRequestTakeback)
+	r.answerTakeback(false, b.id)
+	var offer protocol.OfferPayload
+	a.expect(t, protocol.TypeTakebackDeclined, &offer)
+	if offer.ID != a.id {
+		t.Errorf("reprise refusée au joueur %d, attendu %d", offer.ID, a.id)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gameBoard.MoveCount() != 1 {
+		t.Errorf("%d pions après un refus, attendu 1", r.gameBoard.MoveCount())
+	}
+}
+
+// TestTakebackClock vérifie que chaque coup repris rend l'incrément qu'il avait rapporté.
+func TestTakebackClock(t *testing.T) {
+	const initial, increment = 60 * time.Second, 5 * time.Second
+	tc := protocol.TimeControl{Initial: 60, Increment: 5}
+
+	t.Run("coup et réponse", func(t *testing.T) {
+		r, a, b := newTestGame(t, tc)
+		play(t, r, a, 3)
+		play(t, r, b, 4)
+		checkRemaining(t, r, a, initial+increment)
+		checkRemaining(t, r, b, initial+increment)
+
+		r.requestTakeback(a.id)
+		r.answerTakeback(true, b.id)
+		checkRemaining(t, r, a, initial)
+		checkRemaining(t, r, b, initial)
+		r.mu.Lock()
+		running := r.clock.running
+		r.mu.Unlock()
+		if running != a.id {
+			t.Errorf("pendule du joueur %d lancée, attendu %d", running, a.id)
+		}
+
+		// Les coups rejoués rapportent de nouveau leur incrément
+		play(t, r, a, 2)
+		checkRemaining(t, r, a, initial+increment)
+	})
+
+	t.Run("coup sans réponse", func(t *testing.T) {
+		r, a, b := newTestGame(t, tc)
+		play(t, r, a, 3)
+		play(t, r, b, 4)
+		play(t, r, a, 3)
+		r.requestTakeback(a.id)
+		r.answerTakeback(true, b.id)
+		checkRemaining(t, r, a, initial+increment)
+		checkRemaining(t, r, b, initial+increment)
+	})
+
+	t.Run("temps par coup", func(t *testing.T) {
+		r, a, b := newTestGame(t, protocol.TimeControl{PerMove: 20})
+		play(t, r, a, 3)
+		play(t, r, b, 4)
+		r.requestTakeback(a.id)
+		r.answerTakeback(true, b.id)
+		checkRemaining(t, r, a, 20*time.Second)
+		checkRemaining(t, r, b, 20*time.Second)
+	})
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// maxPlayersPerRoom est le nombre de joueurs nécessaires pour lancer une partie dans une salle.
+const maxPlayersPerRoom = 2
+
+// maxChatLog est le nombre de messages du chat conservés par salle pour les reprises de session.
+const maxChatLog = 50
+
+// room représente une salle de jeu : deux joueurs au plus, leur partie en cours,
+// son historique et le canal de redémarrage qui lui est propre.
+// Chaque salle est indépendante, les messages ne sont diffusés qu'à ses joueurs
+// et, pour les coups et les résultats, à ses spectateurs.
+type room struct {
+	id       int              // Identifiant unique de la salle
+	name     string           // Nom affiché dans le lobby
+	password string           // Mot de passe exigé pour rejoindre ou observer la salle, vide si elle est ouverte
+	noHints  bool             // Indices interdits dans la salle (partie classée)
+	match    *tournamentMatch // Match de tournoi joué dans la salle, nil pour une salle ordinaire
+
+	timeControl protocol.TimeControl // Cadence des parties de la salle, nulle pour des parties sans limite de temps
+
+	mu                    sync.Mutex                  // Mutex protégeant l'état de la salle
+	players               map[int]net.Conn            // Connexions des joueurs de la salle, associées à leur ID.
+	spectators            map[int]net.Conn            // Connexions des spectateurs, qui observent la partie sans y jouer.
+	readyPlayers          map[int]bool                // Indique si un joueur est prêt à jouer.
+	playerColors          map[int]int                 // Couleur choisie par chaque joueur.
+	firstPlayer           int                         // ID du premier joueur à jouer, -1 s'il n'a pas encore été défini.
+	historiquePartie      map[int]protocol.Coordinate // Historique des coups de la partie, indexé par numéro de tour.
+	turnPartie            int                         // Numéro du tour actuel dans la partie.
+	playerSelections      map[int]string              // Choix de chaque joueur au pierre/feuille/ciseaux.
+	gameBoard             engine.Board                // Grille de la partie en cours, tenue par le serveur qui fait foi.
+	playerTokens          map[int]int                 // Pion (engine.P1Token ou engine.P2Token) attribué à chaque joueur.
+	currentTurn           int                         // ID du joueur dont c'est le tour, -1 tant que la partie n'a pas commencé.
+	nextStarter           int                         // ID du joueur qui commencera la prochaine partie.
+	gameOver              bool                        // Indique si la partie en cours est terminée.
+	wins                  map[int]int                 // Parties gagnées par chaque joueur depuis son arrivée dans la salle.
+	chatLog               []protocol.ChatMessage      // Derniers messages du chat, renvoyés lors d'une reprise de session.
+	record                *protocol.GameRecord        // Partie en cours, archivée lorsqu'elle se termine, nil hors partie.
+	hintRequested         bool                        // Le joueur au trait a demandé un indice pour le coup en cours.
+	drawOffer             int                         // ID du joueur qui propose la nulle, -1 sans proposition en cours.
+	takebackRequest       int                         // ID du joueur qui demande une reprise, -1 sans demande en cours.
+	clock                 gameClock                   // Pendules de la partie en cours, dans une salle avec une cadence.
+	restartReadyChannel   chan int                    // Signale qu'un joueur est prêt pour un rematch.
+	restartControlChannel chan struct{}               // Permet d'arrêter la goroutine waitForRestart de la salle.
+}
+
+// newRoom crée une salle vide et lance sa goroutine de gestion des rematchs.
+// Une salle créée avec un mot de passe non vide est verrouillée ; avec noHints, les
+// indices y sont refusés. Ses parties se jouent à la cadence timeControl.
+func newRoom(id int, name, password string, noHints bool, timeControl protocol.TimeControl) *room {
+	r := &room{
+		id:                    id,
+		name:                  name,
+		password:              password,
+		noHints:               noHints,
+		timeControl:           timeControl,
+		players:               make(map[int]net.Conn),
+		spectators:            make(map[int]net.Conn),
+		restartReadyChannel:   make(chan int, maxPlayersPerRoom),
+		restartControlChannel: make(chan struct{}),
+	}
+	r.resetAll()
+	r.waitForRestart()
+	return r
+}
+
+// playerCount renvoie le nombre de joueurs présents dans la salle.
+func (r *room) playerCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.players)
+}
+
+// addPlayer ajoute un joueur à la salle. Elle renvoie false si la salle est pleine.
+func (r *room) addPlayer(id int, conn net.Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.players) >= maxPlayersPerRoom {
+		return false
+	}
+	r.players[id] = conn
+	return true
+}
+
+// removePlayer retire un joueur de la salle, prévient son adversaire et remet
+// la partie à zéro. Un joueur qui quitte une partie en cours la perd par forfait :
+// elle est classée et archivée comme une autre. Elle renvoie le nombre de joueurs
+// restant dans la salle.
+func (r *room) removePlayer(id int) int {
+	r.mu.Lock()
+	forfeit := r.inGame() && r.playerTokens[id] != engine.NoToken
+	result := engine.Equality
+	var record *protocol.GameRecord
+	if forfeit {
+		result = engine.Opponent(r.playerTokens[id])
+		record = r.concludeGame(result, protocol.ReasonForfeit)
+	}
+	delete(r.players, id)
+	remaining := len(r.players)
+	r.mu.Unlock()
+
+	if forfeit {
+		logInfof("Le joueur %d quitte la partie en cours et la perd par forfait (salle %d)\n", id, r.id)
+		r.broadcastClock()
+		r.finishGame(result, nil, protocol.ReasonForfeit, record)
+	}
+	r.notifyPlayers(protocol.Message{
+		Type:    protocol.TypeOtherDisconnected,
+		Payload: nil,
+	})
+
+	r.mu.Lock()
+	r.resetAll()
+	r.mu.Unlock()
+
+	if remaining > 0 {
+		r.restartWaitForRestart()
+	} else {
+		r.stopWaitForRestart()
+	}
+	r.broadcastSpectateState()
+	return remaining
+}
+
+// connections renvoie une copie des connexions des joueurs de la salle,
+// afin de pouvoir leur écrire sans garder le verrou.
+func (r *room) connections() map[int]net.Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conns := make(map[int]net.Conn, len(r.players))
+	for id, conn := range r.players {
+		conns[id] = conn
+	}
+	return conns
+}
+
+// notifyPlayers envoie un message structuré au format JSON à tous les joueurs de la salle.
+func (r *room) notifyPlayers(message protocol.Message) {
+	r.notifyOtherPlayers(-1, message)
+}
+
+// notifyOtherPlayers envoie un message structuré au format JSON à tous les joueurs de la salle
+// sauf au joueur spécifié par `senderID`.
+func (r *room) notifyOtherPlayers(senderID int, message interface{}) {
+	// Convertir le message en JSON
+	jsonMessage, err := json.Marshal(message)
+	if err != nil {
+		logErrorf("Erreur lors de la sérialisation du message JSON : %v\n", err)
+		return
+	}
+
+	// Parcourir les joueurs et envoyer le message à tous sauf l'expéditeur
+	for id, conn := range r.connections() {
+		if id == senderID {
+			continue
+		}
+		if err := writeLine(conn, jsonMessage); err != nil {
+			logErrorf("Erreur lors de l'envoi au client %d : %v\n", id, err)
+		} else {
+			logDebugf("Message envoyé au client %d (salle %d) : %s\n", id, r.id, string(jsonMessage))
+		}
+	}
+}
+
+// waitForRestart gère l'état de préparation des joueurs pour redémarrer une partie.
+// Cette fonction est exécutée dans une goroutine et écoute deux canaux :
+// 1. restartReadyChannel pour savoir quels joueurs sont prêts à un rematch.
+// 2. restartControlChannel pour redemarrer proprement la fonction.
+func (r *room) waitForRestart() {
+	r.mu.Lock()
+	readyChannel, controlChannel := r.restartReadyChannel, r.restartControlChannel
+	r.mu.Unlock()
+
+	go func() {
+		readyPlayersList := make(map[int]bool) // Suivi des joueurs prêts
+
+		for {
+			select {
+			case id := <-readyChannel:
+				readyPlayersList[id] = true
+				logInfof("Joueur %d prêt pour un rematch (salle %d).\n", id, r.id)
+
+				r.notifyOtherPlayers(id, protocol.Message{
+					Type: protocol.TypeRematchWaiting,
+					Payload: protocol.MessagePayload{
+						Message: "L'autre joueur est en attente de rematch",
+					},
+				})
+
+				// Vérifie si tous les joueurs sont prêts
+				if count := r.playerCount(); len(readyPlayersList) == count && count == maxPlayersPerRoom {
+					logInfof("Tous les joueurs de la salle %d sont prêts. Redémarrage de la partie.\n", r.id)
+
+					// Réinitialise l'état pour une nouvelle partie avant d'autoriser les coups
+					r.resetServerState()
+
+					// Notifier tous les joueurs que la partie peut redémarrer
+					r.notifyPlayers(protocol.Message{
+						Type: protocol.TypeRestartOK,
+						Payload: protocol.MessagePayload{
+							Message: "Tous les joueurs sont prêts. La partie peut redémarrer.",
+						},
+					})
+					r.broadcastClock()
+					r.broadcastSpectateState()
+
+					readyPlayersList = make(map[int]bool) // Réinitialise pour la prochaine partie
+				}
+
+			case <-controlChannel:
+				logDebugf("Arrêt de la fonction waitForRestart de la salle %d.\n", r.id)
+				return // Termine la goroutine
+			}
+		}
+	}()
+}
+
+// requestRestart signale que le joueur id est prêt pour un rematch.
+func (r *room) requestRestart(id int) {
+	r.mu.Lock()
+	readyChannel := r.restartReadyChannel
+	r.mu.Unlock()
+
+	select {
+	case readyChannel <- id:
+	default:
+		logWarnf("Demande de rematch du joueur %d ignorée (salle %d).\n", id, r.id)
+	}
+}
+
+// stopWaitForRestart arrête proprement la goroutine créée par waitForRestart
+// Elle ferme le canal restartControlChannel pour signaler l'arrêt
+func (r *room) stopWaitForRestart() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Signale l'arrêt de la goroutine
+	close(r.restartControlChannel)
+
+	// Crée de nouveaux canaux pour redémarrer la goroutine plus tard
+	r.restartControlChannel = make(chan struct{})
+	r.restartReadyChannel = make(chan int, maxPlayersPerRoom)
+}
+
+// restartWaitForRestart redémarre la logique gérée par waitForRestart, elle permet de gerer les deconnexion des clients
+// Elle arrête d'abord la goroutine existante, réinitialise les canaux utilisés,
+// puis relance la fonction waitForRestart dans une nouvelle goroutine.
+func (r *room) restartWaitForRestart() {
+	logDebugf("Relance de la fonction waitForRestart de la salle %d...\n", r.id)
+	r.stopWaitForRestart() // Arrête la fonction existante
+	r.waitForRestart()     // Relance une nouvelle goroutine
+}
+
+// resetAll remet à zéro tout l'état de jeu de la salle, en conservant ses joueurs.
+// Doit être appelée avec r.mu verrouillé (ou avant que la salle ne soit partagée).
+func (r *room) resetAll() {
+	r.readyPlayers = make(map[int]bool)
+	r.playerColors = make(map[int]int)
+	r.firstPlayer = -1 // -1 indique qu'aucun joueur n'a encore été désigné
+	r.historiquePartie = make(map[int]protocol.Coordinate)
+	r.turnPartie = 0
+	r.playerSelections = make(map[int]string)
+	r.gameBoard.Reset()
+	r.playerTokens = make(map[int]int)
+	r.currentTurn = -1
+	r.nextStarter = -1
+	r.gameOver = false
+	r.wins = make(map[int]int)
+	r.chatLog = nil
+	r.record = nil
+	r.hintRequested = false
+	r.clearOffers()
+	r.resetClock()
+}
+
+// resetServerState est appelé à la fin d'une partie lors du rematch pour recommencer une nouvelle partie.
+// Comme dans startGame, le joueur qui commence, le perdant de la partie précédente, reçoit le pion engine.P1Token.
+func (r *room) resetServerState() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.turnPartie = 0
+	r.historiquePartie = make(map[int]protocol.Coordinate)
+	r.gameBoard.Reset()
+	r.gameOver = false
+	r.hintRequested = false
+	r.clearOffers()
+	r.currentTurn = r.nextStarter
+	if r.currentTurn == -1 {
+		r.currentTurn = r.firstPlayer
+	}
+	r.assignTokens(r.currentTurn)
+	r.record = r.newRecord(r.currentTurn)
+	r.startClock(r.currentTurn)
+
+	logInfof("Salle %d prête pour une nouvelle partie\n", r.id)
+}
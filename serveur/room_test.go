@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// TestRematchTokens vérifie qu'après un rematch le perdant, qui commence, joue avec
+// le pion engine.P1Token, comme le croient les clients.
+func TestRematchTokens(t *testing.T) {
+	r, a, b := newTestGame(t, protocol.TimeControl{})
+	for i := 0; i < 3; i++ {
+		play(t, r, a, 0)
+		play(t, r, b, 1)
+	}
+	play(t, r, a, 0)
+	var over protocol.GameOverPayload
+	b.expect(t, protocol.TypeGameOver, &over)
+	if over.Winner != a.id {
+		t.Fatalf("victoire du joueur %d, attendu %d", over.Winner, a.id)
+	}
+
+	for _, p := range []*testPlayer{a, b} {
+		r.processMessage(protocol.Message{Type: protocol.TypeRestartReady}, p.id)
+	}
+	b.expect(t, protocol.TypeRestartOK, nil)
+
+	r.mu.Lock()
+	turn, tokens := r.currentTurn, r.playerTokens
+	infos := r.playerInfos()
+	r.mu.Unlock()
+	if turn != b.id || tokens[b.id] != engine.P1Token || tokens[a.id] != engine.P2Token {
+		t.Fatalf("trait au joueur %d, pions %v : attendu le perdant %d avec le pion %d", turn, tokens, b.id, engine.P1Token)
+	}
+	for _, info := range infos {
+		if info.Token != tokens[info.ID] {
+			t.Errorf("room_players annonce le pion %d pour le joueur %d, attendu %d", info.Token, info.ID, tokens[info.ID])
+		}
+	}
+
+	play(t, r, b, 3)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if got := r.gameBoard.Cell(3, engine.Rows-1); got != engine.P1Token {
+		t.Errorf("premier pion du rematch %d, attendu %d", got, engine.P1Token)
+	}
+	if r.record == nil || r.record.Starter != b.id {
+		t.Errorf("partie à archiver commencée par %v, attendu %d", r.record, b.id)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+var (
+	tlsCertFile    string // Chemin du certificat TLS du serveur
+	tlsKeyFile     string // Chemin de la clé privée du certificat TLS
+	tlsDevMode     bool   // Génère un certificat auto-signé au démarrage, pour le développement
+	serverPassword string // Mot de passe exigé lors de la poignée de main, vide si le serveur est ouvert
+)
+
+// errRoomPassword est renvoyée lorsqu'un client tente d'entrer dans une salle verrouillée
+// sans en donner le bon mot de passe.
+var errRoomPassword = errors.New("mot de passe de la salle incorrect")
+
+// checkPassword compare en temps constant le mot de passe given au mot de passe attendu.
+// Un mot de passe attendu vide accepte tout le monde.
+func checkPassword(expected, given string) bool {
+	if expected == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(given)) == 1
+}
+
+// locked indique si la salle est protégée par un mot de passe.
+func (r *room) locked() bool {
+	return r.password != ""
+}
+
+// checkPassword indique si password permet d'entrer dans la salle.
+func (r *room) checkPassword(password string) bool {
+	return checkPassword(r.password, password)
+}
+
+// tlsConfig construit la configuration TLS du serveur à partir des options de lancement.
+// Elle renvoie nil si TLS n'est pas activé.
+func tlsConfig() (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	switch {
+	case tlsCertFile != "" || tlsKeyFile != "":
+		if tlsCertFile == "" || tlsKeyFile == "" {
+			return nil, errors.New("les options -tls-cert et -tls-key doivent être utilisées ensemble")
+		}
+		cert, err = tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("chargement du certificat TLS : %w", err)
+		}
+	case tlsDevMode:
+		cert, err = selfSignedCertificate()
+		if err != nil {
+			return nil, fmt.Errorf("génération du certificat auto-signé : %w", err)
+		}
+		logInfof("Certificat auto-signé généré (mode développement), les clients doivent l'accepter explicitement\n")
+	default:
+		return nil, nil
+	}
+
+	fingerprint := sha256.Sum256(cert.Certificate[0])
+	logInfof("TLS activé, empreinte SHA-256 du certificat : %s\n", hex.EncodeToString(fingerprint[:]))
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// selfSignedCertificate génère un certificat auto-signé valable un an pour localhost,
+// le nom de la machine et son adresse IP locale.
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Puissance 4 (développement)"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	if ip := net.ParseIP(getLocalIP()); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
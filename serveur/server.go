@@ -4,213 +4,263 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net"
 	"strings"
 	"sync"
+	"time"
+
+	"puissance4/protocol"
 )
 
+// defaultHandshakeTimeout est le délai par défaut laissé à un client pour envoyer "hello".
+const defaultHandshakeTimeout = 10 * time.Second
+
 var (
-	clients               = make(map[int]net.Conn)                            // Table de hachage pour stocker les connexions actives des joueurs, associées à leur ID unique.
-	readyPlayers          = make(map[int]bool)                                // Table de hachage pour indiquer si un joueur est prêt à jouer.
-	clientMux             sync.Mutex                                          // Mutex utilisé pour synchroniser l'accès à des ressources partagées (comme les maps) entre plusieurs goroutines.
-	playerColors                                   = make(map[int]int)        // Table de hachage pour associer chaque joueur (par son ID) à une couleur spécifique (représentée par un entier).
-	firstPlayer           int                      = -1                       // ID du premier joueur à jouer. Initialisé à -1 pour indiquer qu'il n'a pas encore été défini.
-	historiquePartie                               = make(map[int]Coordinate) // Historique des coordonnées des parties jouées, associant un entier (par exemple, un tour ou une action) à une structure `Coordinate`.
-	turnPartie            int                      = 0                        // Numéro du tour actuel dans la partie (par exemple, 0 pour le début).
-	restartReadyChannel                            = make(chan int, 2)        // Canal avec une capacité tamponnée de 2 pour signaler que deux joueurs sont prêts à redémarrer une partie.
-	restartControlChannel                          = make(chan struct{})      // Canal non tamponné pour contrôler ou signaler un redémarrage ou une réinitialisation.
-	playerSelections                               = make(map[int]string)     // Table de hachage pour stocker les sélections des joueurs (par exemple, une chaîne représentant leur choix).
+	clients      = make(map[int]net.Conn) // Table de hachage pour stocker les connexions actives des clients, associées à leur ID unique.
+	nextClientID int                      // ID attribué au prochain client, partagé par les connexions TCP et WebSocket.
+	clientMux    sync.Mutex               // Mutex utilisé pour synchroniser l'accès à la table des connexions entre plusieurs goroutines.
 )
 
 // startServer démarre le serveur et gère les connexions des clients.
 // Pour chaque nouvelle connexion acceptée, un ID unique est attribué au client,
-// et la connexion est ajoutée à la table de hachage `clients`.
+// et la connexion est ajoutée à la table de hachage `clients`. Le client arrive
+// dans le lobby, d'où il peut choisir, créer ou rejoindre une salle.
 // La fonction lance ensuite une goroutine `handleClient` pour gérer la communication avec ce client.
 func startServer(listener net.Listener) {
-	clientID := 0
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			log.Println("Erreur lors de l'acceptation d'une connexion :", err)
+			logErrorf("Erreur lors de l'acceptation d'une connexion : %v\n", err)
 			continue
 		}
 
-		clientMux.Lock()
-		clients[clientID] = conn
-		clientMux.Unlock()
-
-		log.Printf("Client %d connecté", clientID)
+		clientID, conn := registerClient(conn)
+		logInfof("Client %d connecté", clientID)
 
 		go handleClient(conn, clientID)
-		clientID++
 	}
 }
 
+// registerClient attribue un ID unique à la connexion conn et l'ajoute à la table `clients`.
+// Elle renvoie la connexion enregistrée, dont les écritures sont sérialisées : c'est
+// elle que le serveur doit utiliser pour écrire au client.
+func registerClient(conn net.Conn) (int, net.Conn) {
+	locked := &lockedConn{Conn: conn}
+	clientMux.Lock()
+	defer clientMux.Unlock()
+	id := nextClientID
+	nextClientID++
+	clients[id] = locked
+	return id, locked
+}
+
+// lockedConn sérialise les écritures sur une connexion. Les messages destinés à un client
+// partent de plusieurs goroutines (sa propre boucle de lecture, celles de son adversaire,
+// l'horloge, les parties observées) : chaque ligne JSON doit être écrite d'un seul tenant.
+type lockedConn struct {
+	net.Conn
+	writeMu sync.Mutex // Une seule écriture à la fois sur la connexion
+}
+
+// Write écrit p sur la connexion, après la fin de toute autre écriture en cours.
+func (c *lockedConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.Write(p)
+}
+
 // handleClient gère la communication avec un client spécifique après sa connexion.
-// Cette fonction envoie d'abord un message initial contenant l'ID du client,
-// puis entre dans une boucle pour lire, désérialiser et traiter les messages envoyés par le client.
-// En cas d'erreur ou de déconnexion, le client est déconnecté proprement.
+// Cette fonction envoie d'abord un message initial contenant l'ID du client et son jeton
+// de reprise, puis entre dans une boucle pour lire, désérialiser et traiter les messages
+// envoyés par le client. Le premier message doit être la poignée de main "hello" ;
+// un message "resume" rattache ensuite la connexion à une session perdue.
+// En cas d'erreur ou de déconnexion, le client garde sa place pendant le délai de grâce
+// s'il est en partie, sinon il est déconnecté proprement.
 func handleClient(conn net.Conn, id int) {
-	defer disconnectClient(conn, id)
+	defer func() { connectionLost(id, conn) }()
 
 	reader := bufio.NewReader(conn)
 
 	// Envoyer l'ID au client en utilisant JSON
-	initialMessage := Message{
-		Type:    "id",
-		Payload: map[string]int{"id": id},
+	initialMessage := protocol.Message{
+		Type: protocol.TypeID,
+		Payload: protocol.IDPayload{
+			ID:    id,
+			Token: newSession(id),
+			Grace: int(sessionGracePeriod.Seconds()),
+		},
 	}
 	if err := sendJSONMessage(conn, initialMessage); err != nil {
-		log.Printf("Erreur lors de l'envoi de l'ID au client %d : %v\n", id, err)
+		logErrorf("Erreur lors de l'envoi de l'ID au client %d : %v\n", id, err)
 		return
 	}
 
 	// Boucle principale pour lire et traiter les messages
+	handshakeDone := false
 	for {
+		// Laisser au client un délai pour se présenter, puis pour chaque message
+		timeout := config.Timeouts.Idle.Duration
+		if !handshakeDone {
+			timeout = config.Timeouts.Handshake.Duration
+		}
+		deadline := time.Time{}
+		if timeout > 0 {
+			deadline = time.Now().Add(timeout)
+		}
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			logErrorf("Erreur lors de la mise en place du délai de lecture du client %d : %v\n", id, err)
+		}
+
 		message, err := reader.ReadString('\n')
 		if err != nil {
-			log.Printf("Erreur de lecture du client %d : %v\n", id, err)
+			logErrorf("Erreur de lecture du client %d : %v\n", id, err)
 			return
 		}
 
 		message = strings.TrimSpace(message)
 
 		// Désérialiser le message JSON
-		var msg Message
+		var msg protocol.Message
 		if err := json.Unmarshal([]byte(message), &msg); err != nil {
-			log.Printf("Erreur de décodage JSON pour le client %d : %v\n", id, err)
+			logErrorf("Erreur de décodage JSON pour le client %d : %v\n", id, err)
 			continue // Ignorer ce message et passer au suivant
 		}
 
+		// Poignée de main : aucun autre message n'est accepté avant une version compatible
+		if !handshakeDone {
+			if !handshake(msg, id) {
+				return
+			}
+			handshakeDone = true
+			continue
+		}
+
+		// Reprendre une session perdue : la connexion prend l'ID de la session
+		if msg.Type == protocol.TypeResume {
+			var payload protocol.ResumeRequest
+			if decodePayload(msg, id, &payload) {
+				id = resumeSession(conn, id, payload.Token)
+			}
+			continue
+		}
+
 		// Traiter le message via processMessage
 		processMessage(msg, id)
 	}
 }
 
-// sendJSONMessage envoie un message structuré au format JSON à travers une connexion réseau (net.Conn).
-func sendJSONMessage(conn net.Conn, msg Message) error {
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("erreur de sérialisation JSON : %w", err)
+// handshake vérifie la poignée de main "hello" du client id et lui répond avec la version
+// et les capacités du serveur. Un client qui envoie autre chose, ou dont la version du
+// protocole est incompatible ou qui ne donne pas le mot de passe du serveur, reçoit une
+// erreur lisible et la fonction renvoie false :
+// la connexion doit alors être fermée.
+func handshake(msg protocol.Message, id int) bool {
+	if msg.Type != protocol.TypeHello {
+		logWarnf("Client %d : message %s reçu avant la poignée de main\n", id, msg.Type)
+		sendError(id, protocol.ErrCodeHelloRequired, msg.Type, "client trop ancien : mettez à jour le jeu pour vous connecter à ce serveur")
+		return false
 	}
 
-	_, err = conn.Write(append(jsonData, '\n')) // Ajout de '\n' pour délimiter les messages
-	return err
-}
+	var hello protocol.HelloPayload
+	if !decodePayload(msg, id, &hello) {
+		return false
+	}
+	if err := protocol.CheckVersion(hello.Version); err != nil {
+		logWarnf("Client %d refusé (%s) : %v\n", id, hello.Software, err)
+		sendError(id, protocol.ErrCodeIncompatibleVersion, msg.Type, err.Error())
+		return false
+	}
+	if !checkPassword(serverPassword, hello.Password) {
+		logWarnf("Client %d refusé (%s) : mot de passe du serveur incorrect\n", id, hello.Software)
+		sendError(id, protocol.ErrCodeAuthFailed, msg.Type, "mot de passe du serveur incorrect")
+		return false
+	}
 
-// notifyPlayers envoie un message structuré au format JSON à tous les clients connectés.
-func notifyPlayers(message Message) {
-	clientMux.Lock()
-	defer clientMux.Unlock()
+	logInfof("Client %d : %s, protocole v%d, capacités %v\n", id, hello.Software, hello.Version, hello.Capabilities)
+	reply := protocol.NewHello("serveur puissance4")
+	reply.Capabilities = serverCapabilities()
+	sendToClient(id, protocol.Message{
+		Type:    protocol.TypeHello,
+		Payload: reply,
+	})
+	return true
+}
 
-	// Convertir le message en JSON
-	jsonMessage, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Erreur lors de la sérialisation du message JSON : %v\n", err)
-		return
+// serverCapabilities renvoie les capacités du protocole activées dans la configuration du serveur.
+func serverCapabilities() []string {
+	enabled := map[string]bool{
+		protocol.CapabilityQuickPlay:   config.Features.QuickPlay,
+		protocol.CapabilitySpectate:    config.Features.Spectators,
+		protocol.CapabilityResume:      sessionGracePeriod > 0,
+		protocol.CapabilityBots:        config.Features.Bots,
+		protocol.CapabilityArchive:     archive != nil,
+		protocol.CapabilityHints:       config.Features.Hints,
+		protocol.CapabilityAccounts:    accounts != nil,
+		protocol.CapabilityStats:       playerStats != nil,
+		protocol.CapabilityTournaments: config.Features.Tournaments,
 	}
-
-	// Envoyer le message JSON à tous les clients
-	for id, conn := range clients {
-		_, err := conn.Write(append(jsonMessage, '\n')) // Ajouter '\n' pour délimiter le message
-		if err != nil {
-			log.Printf("Erreur lors de l'envoi au client %d : %v\n", id, err)
-		} else {
-			log.Printf("Message envoyé au client %d : %s\n", id, string(jsonMessage))
+	var capabilities []string
+	for _, c := range protocol.Capabilities() {
+		if on, ok := enabled[c]; !ok || on {
+			capabilities = append(capabilities, c)
 		}
 	}
+	return capabilities
 }
 
-// notifyOtherPlayers envoie un message structuré au format JSON à tous les clients connectés
-// sauf au client spécifié par `senderID`.
-func notifyOtherPlayers(senderID int, message interface{}) {
-	clientMux.Lock()
-	defer clientMux.Unlock()
+// sendDisabled signale au client id que la fonctionnalité demandée par un message
+// de type msgType est désactivée sur ce serveur.
+func sendDisabled(id int, msgType, message string) {
+	logWarnf("Message %s du client %d refusé : fonctionnalité désactivée\n", msgType, id)
+	sendError(id, protocol.ErrCodeDisabled, msgType, message)
+}
 
-	// Convertir le message en JSON
-	jsonMessage, err := json.Marshal(message)
+// sendError signale au client id que son message de type rejectedType a été refusé.
+func sendError(id int, code, rejectedType, message string) {
+	sendToClient(id, protocol.Message{
+		Type: protocol.TypeError,
+		Payload: protocol.ErrorPayload{
+			Code:    code,
+			Message: message,
+			Type:    rejectedType,
+		},
+	})
+}
+
+// sendJSONMessage envoie un message structuré au format JSON à travers une connexion réseau (net.Conn).
+func sendJSONMessage(conn net.Conn, msg protocol.Message) error {
+	jsonData, err := json.Marshal(msg)
 	if err != nil {
-		log.Printf("Erreur lors de la sérialisation du message JSON : %v\n", err)
-		return
+		return fmt.Errorf("erreur de sérialisation JSON : %w", err)
 	}
 
-	// Parcourir les clients et envoyer le message à tous sauf l'expéditeur
-	for id, conn := range clients {
-		if id != senderID {
-			_, err := conn.Write(append(jsonMessage, '\n')) // Ajouter '\n' pour marquer la fin du message
-			if err != nil {
-				log.Printf("Erreur lors de l'envoi au client %d : %v\n", id, err)
-			} else {
-				log.Printf("Message envoyé au client %d : %s\n", id, string(jsonMessage))
-			}
-		}
-	}
+	return writeLine(conn, jsonData)
 }
 
-// waitForRestart gère l'état de préparation des joueurs pour redémarrer une partie.
-// Cette fonction est exécutée dans une goroutine et écoute deux canaux :
-// 1. restartReadyChannel pour savoir quels joueurs sont prêts à un rematch.
-// 2. restartControlChannel pour redemarrer proprement la fonction.
-func waitForRestart() {
-	go func() {
-		readyPlayersList := make(map[int]bool) // Suivi des joueurs prêts
-
-		for {
-			select {
-			case id := <-restartReadyChannel:
-				readyPlayersList[id] = true
-				log.Printf("Joueur %d prêt pour un rematch.\n", id)
-
-				notifyOtherPlayers(id, Message{
-					Type: "rematch_waiting",
-					Payload: map[string]string{
-						"message": "L'autre joueur est en attente de rematch",
-					},
-				})
-
-				// Vérifie si tous les joueurs sont prêts
-				if len(readyPlayersList) == len(clients) && len(clients) == 2 {
-					log.Println("Tous les joueurs sont prêts. Redémarrage de la partie.")
-
-					// Notifier tous les joueurs que la partie peut redémarrer
-					notifyPlayers(Message{
-						Type: "restart_ok",
-						Payload: map[string]string{
-							"message": "Tous les joueurs sont prêts. La partie peut redémarrer.",
-						},
-					})
-
-					// Réinitialise l'état pour une nouvelle partie
-					resetServerState()
-					readyPlayersList = make(map[int]bool) // Réinitialise pour la prochaine partie
-				}
-
-			case <-restartControlChannel:
-				log.Println("Arrêt de la fonction waitForRestart.")
-				return // Termine la goroutine
-			}
-		}
-	}()
+// writeLine écrit un message JSON déjà sérialisé sur la connexion d'un client, suivi de
+// '\n' pour le délimiter. Tous les envois aux joueurs et aux spectateurs passent par elle.
+func writeLine(conn net.Conn, jsonData []byte) error {
+	line := make([]byte, 0, len(jsonData)+1)
+	_, err := conn.Write(append(append(line, jsonData...), '\n'))
+	return err
 }
 
-// stopWaitForRestart arrête proprement la goroutine créée par waitForRestart
-// Elle ferme le canal restartControlChannel pour signaler l'arrêt
-func stopWaitForRestart() {
-	// Signale l'arrêt de la goroutine
-	close(restartControlChannel)
-
-	// Crée un nouveau canal pour redémarrer la goroutine plus tard
-	restartControlChannel = make(chan struct{})
+// sendToClient envoie un message au client id, s'il est toujours connecté.
+func sendToClient(id int, msg protocol.Message) {
+	conn, ok := clientConn(id)
+	if !ok {
+		logWarnf("Client %d introuvable pour l'envoi du message %s\n", id, msg.Type)
+		return
+	}
+	if err := sendJSONMessage(conn, msg); err != nil {
+		logErrorf("Erreur lors de l'envoi au client %d : %v\n", id, err)
+	}
 }
 
-// restartWaitForRestart redémarre la logique gérée par waitForRestart, elle permet de gerer les deconnexion des clients
-// Elle arrête d'abord la goroutine existante, réinitialise les canaux utilisés,
-// puis relance la fonction waitForRestart dans une nouvelle goroutine.
-func restartWaitForRestart() {
-	log.Println("Relance de la fonction waitForRestart...")
-	stopWaitForRestart() // Arrête la fonction existante
-	close(restartReadyChannel)
-	restartReadyChannel = make(chan int, 2)
-	waitForRestart() // Relance une nouvelle goroutine
+// clientConn renvoie la connexion du client id.
+func clientConn(id int) (net.Conn, bool) {
+	clientMux.Lock()
+	defer clientMux.Unlock()
+	conn, ok := clients[id]
+	return conn, ok
 }
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"sync"
+	"time"
+
+	"puissance4/protocol"
+)
+
+// defaultGracePeriod est la durée par défaut pendant laquelle la place d'un joueur déconnecté est conservée.
+const defaultGracePeriod = 30 * time.Second
+
+// sessionGracePeriod est la durée pendant laquelle un joueur dont la connexion a été
+// perdue peut reprendre sa partie avec son jeton de session.
+var sessionGracePeriod = defaultGracePeriod
+
+// session associe un jeton de reprise à l'ID d'un client.
+type session struct {
+	id    int         // ID du client
+	token string      // Jeton de reprise envoyé avec le message "id"
+	timer *time.Timer // Expiration du délai de grâce, nil tant que le client est connecté
+}
+
+var (
+	sessions     = make(map[string]*session) // Sessions associées à leur jeton.
+	sessionsByID = make(map[int]*session)    // Sessions associées à l'ID de leur client.
+	sessionMux   sync.Mutex                  // Mutex protégeant sessions et sessionsByID.
+)
+
+// newSession crée la session du client id et renvoie son jeton de reprise.
+func newSession(id int) string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		logErrorf("Erreur lors de la génération du jeton de session : %v\n", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	sessionMux.Lock()
+	defer sessionMux.Unlock()
+	s := &session{id: id, token: token}
+	sessions[token] = s
+	sessionsByID[id] = s
+	return token
+}
+
+// endSession supprime la session du client id.
+func endSession(id int) {
+	sessionMux.Lock()
+	defer sessionMux.Unlock()
+	if s, ok := sessionsByID[id]; ok {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		delete(sessions, s.token)
+		delete(sessionsByID, id)
+	}
+}
+
+// connectionLost est appelée lorsque la connexion conn du client id est perdue.
+// Un joueur assis dans une salle garde sa place pendant sessionGracePeriod et son
+// adversaire est prévenu ; les autres clients sont déconnectés immédiatement.
+func connectionLost(id int, conn net.Conn) {
+	// La connexion a déjà été fermée volontairement ou remplacée par une reprise
+	if current, ok := clientConn(id); !ok || current != conn {
+		return
+	}
+
+	r := roomOf(id)
+	if r == nil || r.isSpectator(id) || sessionGracePeriod <= 0 {
+		disconnectClient(id)
+		return
+	}
+
+	sessionMux.Lock()
+	s, ok := sessionsByID[id]
+	if ok {
+		s.timer = time.AfterFunc(sessionGracePeriod, func() { expireSession(id) })
+	}
+	sessionMux.Unlock()
+	if !ok {
+		disconnectClient(id)
+		return
+	}
+
+	conn.Close()
+	logInfof("Connexion du client %d perdue, place conservée %v (salle %d)\n", id, sessionGracePeriod, r.id)
+	r.notifyOtherPlayers(id, protocol.Message{
+		Type: protocol.TypeOpponentReconnect,
+		Payload: protocol.ReconnectingPayload{
+			Seconds: int(sessionGracePeriod.Seconds()),
+		},
+	})
+}
+
+// expireSession déconnecte définitivement le client id si sa session n'a pas été reprise à temps.
+func expireSession(id int) {
+	sessionMux.Lock()
+	s, ok := sessionsByID[id]
+	suspended := ok && s.timer != nil
+	sessionMux.Unlock()
+	if !suspended {
+		return
+	}
+
+	logWarnf("Délai de reprise expiré pour le client %d\n", id)
+	disconnectClient(id)
+}
+
+// resumeSession rattache la connexion conn, ouverte sous l'ID tempID, à la session du jeton token.
+// Le client retrouve son ancien ID, sa place dans sa salle et l'état complet de sa partie.
+// Elle renvoie l'ID à utiliser pour la suite de la connexion.
+func resumeSession(conn net.Conn, tempID int, token string) int {
+	sessionMux.Lock()
+	s, ok := sessions[token]
+	if ok && s.id == tempID {
+		ok = false
+	}
+	if ok && s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	sessionMux.Unlock()
+
+	if !ok {
+		logWarnf("Reprise de session refusée pour le client %d\n", tempID)
+		sendToClient(tempID, protocol.Message{
+			Type: protocol.TypeResumeFailed,
+			Payload: protocol.MessagePayload{
+				Message: "session expirée",
+			},
+		})
+		return tempID
+	}
+	id := s.id
+
+	// Remplacer l'ancienne connexion, qui a pu rester ouverte côté serveur, par la nouvelle
+	clientMux.Lock()
+	old := clients[id]
+	clients[id] = conn
+	delete(clients, tempID)
+	clientMux.Unlock()
+	if old != nil && old != conn {
+		old.Close()
+	}
+	endSession(tempID)
+
+	r := roomOf(id)
+	if r != nil {
+		r.replaceConn(id, conn)
+	}
+	logInfof("Client %d a repris sa session (connexion %d)\n", id, tempID)
+
+	payload := protocol.ResumePayload{ID: id, Token: token, RoomID: -1}
+	if r != nil {
+		payload = r.resumeState(id)
+		payload.Token = token
+	}
+	sendToClient(id, protocol.Message{
+		Type:    protocol.TypeResumed,
+		Payload: payload,
+	})
+
+	if r != nil {
+		r.notifyOtherPlayers(id, protocol.Message{
+			Type:    protocol.TypeOpponentReconnected,
+			Payload: nil,
+		})
+		sendToClient(id, protocol.Message{
+			Type:    protocol.TypeRoomPlayers,
+			Payload: protocol.RoomPlayersPayload{Players: r.members()},
+		})
+	}
+	return id
+}
+
+// replaceConn remplace la connexion du joueur ou du spectateur id après une reprise de session.
+func (r *room) replaceConn(id int, conn net.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.players[id]; ok {
+		r.players[id] = conn
+	}
+	if _, ok := r.spectators[id]; ok {
+		r.spectators[id] = conn
+	}
+}
+
+// resumeState construit l'état complet de la partie tel que le voit le joueur id :
+// phase, grille, tour, couleurs, scores et derniers messages du chat.
+func (r *room) resumeState(id int) protocol.ResumePayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	opponent := -1
+	for other := range r.players {
+		if other != id {
+			opponent = other
+		}
+	}
+
+	state := protocol.ResumePayload{
+		ID:            id,
+		RoomID:        r.id,
+		RoomName:      r.name,
+		Phase:         r.phase(),
+		Moves:         make([]protocol.Coordinate, 0, r.turnPartie),
+		YourTurn:      r.currentTurn == id,
+		FirstPlayer:   r.firstPlayer,
+		YourColor:     -1,
+		OpponentColor: -1,
+		YourWins:      r.wins[id],
+		OpponentWins:  r.wins[opponent],
+		Chat:          append([]protocol.ChatMessage(nil), r.chatLog...),
+		GameOver:      r.gameOver,
+		NoHints:       r.noHints,
+		Clock:         r.clockState(),
+	}
+	if color, ok := r.playerColors[id]; ok {
+		state.YourColor = color
+	}
+	if color, ok := r.playerColors[opponent]; ok {
+		state.OpponentColor = color
+	}
+	for turn := 0; turn < r.turnPartie; turn++ {
+		state.Moves = append(state.Moves, r.historiquePartie[turn])
+	}
+	return state
+}
+
+// phase renvoie l'étape de la partie en cours dans la salle.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) phase() string {
+	switch {
+	case len(r.players) < maxPlayersPerRoom || len(r.readyPlayers) < maxPlayersPerRoom:
+		return protocol.PhaseWaiting
+	case len(r.playerColors) < maxPlayersPerRoom:
+		return protocol.PhaseColor
+	case r.gameOver:
+		return protocol.PhaseOver
+	case len(r.playerTokens) == 0:
+		return protocol.PhaseShifumi
+	default:
+		return protocol.PhasePlaying
+	}
+}
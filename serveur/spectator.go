@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// playerName renvoie le nom affiché d'un joueur : le pseudo de son compte, ou un nom
+// tiré de son ID s'il est anonyme.
+func playerName(id int) string {
+	if a, ok := accountOf(id); ok {
+		return a.name
+	}
+	return fmt.Sprintf("Joueur %d", id)
+}
+
+// playerRating renvoie la cote du joueur id, 0 s'il est anonyme.
+func playerRating(id int) int {
+	a, _ := accountOf(id)
+	return a.rating
+}
+
+// addSpectator ajoute un spectateur à la salle. Il ne prend pas de place de joueur.
+func (r *room) addSpectator(id int, conn net.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spectators[id] = conn
+}
+
+// removeSpectator retire un spectateur de la salle.
+func (r *room) removeSpectator(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.spectators, id)
+}
+
+// isSpectator indique si le client id observe la salle sans y jouer.
+func (r *room) isSpectator(id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.spectators[id]
+	return ok
+}
+
+// spectatorIDs renvoie les IDs des spectateurs de la salle.
+func (r *room) spectatorIDs() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]int, 0, len(r.spectators))
+	for id := range r.spectators {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// notifySpectators envoie un message structuré au format JSON à tous les spectateurs de la salle.
+func (r *room) notifySpectators(message protocol.Message) {
+	jsonMessage, err := json.Marshal(message)
+	if err != nil {
+		logErrorf("Erreur lors de la sérialisation du message JSON : %v\n", err)
+		return
+	}
+
+	r.mu.Lock()
+	conns := make(map[int]net.Conn, len(r.spectators))
+	for id, conn := range r.spectators {
+		conns[id] = conn
+	}
+	r.mu.Unlock()
+
+	for id, conn := range conns {
+		if err := writeLine(conn, jsonMessage); err != nil {
+			logErrorf("Erreur lors de l'envoi au spectateur %d : %v\n", id, err)
+		}
+	}
+}
+
+// spectateState construit l'état complet de la salle à partir de ses joueurs et de historiquePartie.
+func (r *room) spectateState() protocol.SpectatePayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := protocol.SpectatePayload{
+		RoomID:      r.id,
+		RoomName:    r.name,
+		Players:     r.playerInfos(),
+		Moves:       make([]engine.Move, 0, r.turnPartie),
+		CurrentTurn: r.currentTurn,
+		GameOver:    r.gameOver,
+		Clock:       r.clockState(),
+	}
+
+	for turn := 0; turn < r.turnPartie; turn++ {
+		coord := r.historiquePartie[turn]
+		state.Moves = append(state.Moves, engine.Move{
+			Token: r.playerTokens[coord.ID],
+			X:     coord.X,
+			Y:     coord.Y,
+		})
+	}
+	return state
+}
+
+// playerInfos décrit les joueurs de la salle, triés par ID.
+// Doit être appelée avec r.mu verrouillé.
+func (r *room) playerInfos() []protocol.PlayerInfo {
+	players := make([]protocol.PlayerInfo, 0, len(r.players))
+	for id := range r.players {
+		color, ok := r.playerColors[id]
+		if !ok {
+			color = -1
+		}
+		players = append(players, protocol.PlayerInfo{
+			ID:     id,
+			Name:   playerName(id),
+			Color:  color,
+			Token:  r.playerTokens[id],
+			Rating: playerRating(id),
+		})
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+	return players
+}
+
+// members renvoie la description des joueurs de la salle.
+func (r *room) members() []protocol.PlayerInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.playerInfos()
+}
+
+// broadcastRoomPlayers envoie aux joueurs de la salle leurs noms et leurs cotes.
+func (r *room) broadcastRoomPlayers() {
+	r.notifyPlayers(protocol.Message{
+		Type:    protocol.TypeRoomPlayers,
+		Payload: protocol.RoomPlayersPayload{Players: r.members()},
+	})
+}
+
+// sendSpectateState envoie l'état complet de la salle au spectateur id.
+func (r *room) sendSpectateState(id int) {
+	sendToClient(id, protocol.Message{
+		Type:    protocol.TypeSpectateState,
+		Payload: r.spectateState(),
+	})
+}
+
+// broadcastSpectateState envoie l'état complet de la salle à tous ses spectateurs.
+func (r *room) broadcastSpectateState() {
+	if len(r.spectatorIDs()) == 0 {
+		return
+	}
+	r.notifySpectators(protocol.Message{
+		Type:    protocol.TypeSpectateState,
+		Payload: r.spectateState(),
+	})
+}
+
+// spectateRoom place le client id dans la salle roomID en tant que spectateur,
+// si password est le mot de passe de la salle.
+func spectateRoom(id, roomID int, password string) error {
+	conn, ok := clientConn(id)
+	if !ok {
+		return fmt.Errorf("client %d introuvable", id)
+	}
+
+	lobbyMux.Lock()
+	defer lobbyMux.Unlock()
+
+	if current, ok := clientRooms[id]; ok {
+		// Un spectateur qui redemande la même salle reçoit à nouveau son état complet
+		if current.id == roomID && current.isSpectator(id) {
+			return nil
+		}
+		return fmt.Errorf("vous êtes déjà dans la salle %d", current.id)
+	}
+	r, ok := rooms[roomID]
+	if !ok {
+		return fmt.Errorf("la salle %d n'existe pas", roomID)
+	}
+	if !r.checkPassword(password) {
+		return errRoomPassword
+	}
+	r.addSpectator(id, conn)
+	clientRooms[id] = r
+	logInfof("Client %d observe la salle %d\n", id, roomID)
+	return nil
+}
+
+// handleSpectateRoom place le client id en spectateur de la salle demandée et lui envoie
+// l'état de la partie en cours, ou lui renvoie un message "error" si c'est impossible.
+func handleSpectateRoom(payload protocol.RoomRequest, id int) {
+	if !config.Features.Spectators {
+		sendDisabled(id, protocol.TypeSpectate, "le mode spectateur est désactivé sur ce serveur")
+		return
+	}
+	roomID := payload.ID
+	if err := spectateRoom(id, roomID, payload.Password); err != nil {
+		logWarnf("Client %d ne peut pas observer la salle %d : %v\n", id, roomID, err)
+		sendRoomError(id, err)
+		return
+	}
+	handleCancelQuickPlay(id)
+	roomOf(id).sendSpectateState(id)
+}
+
+// closeSpectators renvoie au lobby les spectateurs d'une salle qui vient d'être fermée.
+func (r *room) closeSpectators() {
+	ids := r.spectatorIDs()
+
+	lobbyMux.Lock()
+	for _, id := range ids {
+		if clientRooms[id] == r {
+			delete(clientRooms, id)
+		}
+	}
+	lobbyMux.Unlock()
+
+	for _, id := range ids {
+		r.removeSpectator(id)
+		sendToClient(id, protocol.Message{
+			Type:    protocol.TypeRoomLeft,
+			Payload: nil,
+		})
+	}
+}
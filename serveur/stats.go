@@ -0,0 +1,422 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"puissance4/engine"
+	"puissance4/protocol"
+)
+
+// Taille des classements envoyés aux clients.
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 50
+)
+
+// Chemins de l'API HTTP des classements.
+const (
+	leaderboardPath = "/api/leaderboard"
+	playersPath     = "/api/players/"
+)
+
+// statsHTTPAddress est l'adresse d'écoute de l'API HTTP des classements, vide pour la désactiver.
+var statsHTTPAddress string
+
+// playerStats rassemble les statistiques des joueurs connectés à un compte. Elle vaut
+// nil si les comptes sont désactivés : les joueurs anonymes n'ont pas de statistiques,
+// leur nom ne survivant pas à leur connexion.
+var playerStats *statsBook
+
+// statsBook tient le bilan de chaque compte sur toutes ses parties terminées. Il est
+// reconstruit au démarrage depuis l'archive, puis complété à chaque partie archivée.
+type statsBook struct {
+	mu      sync.Mutex
+	players map[string]*playerRecord // Bilans, indexés par le pseudo en minuscules
+}
+
+// playerRecord est le bilan d'un joueur.
+type playerRecord struct {
+	name          string
+	first         protocol.OrderRecord // Parties où le joueur a joué le premier coup
+	second        protocol.OrderRecord // Parties où il a joué en second
+	moves         int                  // Coups joués dans ses parties, des deux joueurs
+	seconds       int                  // Durée totale de ses parties
+	openings      [engine.Columns]int  // Colonnes de ses premiers coups, en jouant en premier
+	shifumiWins   int
+	shifumiLosses int
+	streak        int // Victoires consécutives en cours
+	bestStreak    int
+	lastGame      time.Time
+}
+
+// games renvoie le nombre de parties terminées du joueur.
+func (p *playerRecord) games() int {
+	return p.first.Games + p.second.Games
+}
+
+// loadStats reconstruit les statistiques des joueurs à partir de l'archive des parties.
+func loadStats(a *gameArchive) (*statsBook, error) {
+	book := &statsBook{players: make(map[string]*playerRecord)}
+	if a == nil {
+		return book, nil
+	}
+	err := a.each(func(record protocol.GameRecord) {
+		book.add(record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lecture de l'archive des parties : %w", err)
+	}
+	return book, nil
+}
+
+// add reporte la partie terminée record dans le bilan de ses joueurs connectés à un
+// compte. Les parties importées sont ignorées : leurs joueurs ne sont que des noms.
+func (b *statsBook) add(record protocol.GameRecord) {
+	if record.Imported || len(record.Players) != 2 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range record.Players {
+		if !p.Account || p.Bot {
+			continue
+		}
+		key := strings.ToLower(p.Name)
+		player, ok := b.players[key]
+		if !ok {
+			player = &playerRecord{name: p.Name}
+			b.players[key] = player
+		}
+		player.addGame(record, p.ID)
+	}
+}
+
+// addGame ajoute au bilan la partie record, jouée avec l'ID id.
+func (p *playerRecord) addGame(record protocol.GameRecord, id int) {
+	starter := record.Starter == id
+	order := &p.second
+	if starter {
+		order = &p.first
+		if len(record.Moves) > 0 && record.Moves[0].X >= 0 && record.Moves[0].X < engine.Columns {
+			p.openings[record.Moves[0].X]++
+		}
+	}
+	if len(record.Shifumi) > 0 {
+		// Le pierre/feuille/ciseaux désigne celui qui commence
+		if starter {
+			p.shifumiWins++
+		} else {
+			p.shifumiLosses++
+		}
+	}
+
+	order.Games++
+	switch record.Winner {
+	case id:
+		order.Wins++
+		p.streak++
+		p.bestStreak = max(p.bestStreak, p.streak)
+	case -1:
+		order.Draws++
+		p.streak = 0
+	default:
+		order.Losses++
+		p.streak = 0
+	}
+	p.moves += len(record.Moves)
+	p.seconds += int(record.Duration().Seconds())
+	p.lastGame = record.EndedAt
+}
+
+// profile renvoie les statistiques du joueur, sans son classement.
+func (p *playerRecord) profile() protocol.PlayerProfile {
+	profile := protocol.PlayerProfile{
+		Name:             p.name,
+		Games:            p.games(),
+		Wins:             p.first.Wins + p.second.Wins,
+		Losses:           p.first.Losses + p.second.Losses,
+		Draws:            p.first.Draws + p.second.Draws,
+		First:            p.first,
+		Second:           p.second,
+		Openings:         append([]int(nil), p.openings[:]...),
+		FavouriteOpening: -1,
+		ShifumiWins:      p.shifumiWins,
+		ShifumiLosses:    p.shifumiLosses,
+		Streak:           p.streak,
+		BestStreak:       p.bestStreak,
+		LastGame:         p.lastGame,
+	}
+	if profile.Games > 0 {
+		profile.AverageMoves = float64(p.moves) / float64(profile.Games)
+		profile.AverageSeconds = p.seconds / profile.Games
+	}
+	for x, n := range p.openings {
+		if n > 0 && (profile.FavouriteOpening < 0 || n > p.openings[profile.FavouriteOpening]) {
+			profile.FavouriteOpening = x
+		}
+	}
+	return profile
+}
+
+// profile renvoie les statistiques du compte name. Le booléen est faux si le compte n'existe pas.
+func (b *statsBook) profile(name string) (protocol.PlayerProfile, bool, error) {
+	a, found, err := accounts.get(name)
+	if err != nil || !found {
+		return protocol.PlayerProfile{}, false, err
+	}
+
+	b.mu.Lock()
+	player, ok := b.players[strings.ToLower(a.Name)]
+	if !ok {
+		player = &playerRecord{name: a.Name}
+	}
+	profile := player.profile()
+	b.mu.Unlock()
+
+	profile.Rating = a.stats(time.Now())
+	return profile, true, nil
+}
+
+// leaderboard renvoie les limit premiers joueurs de chaque classement : cote (parmi les
+// comptes qui ont joué une partie classée), meilleure série de victoires et nombre de parties.
+func (b *statsBook) leaderboard(limit int) (protocol.LeaderboardPayload, error) {
+	all, err := accounts.all()
+	if err != nil {
+		return protocol.LeaderboardPayload{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var ratings []protocol.LeaderboardEntry
+	for _, a := range all {
+		stats := a.stats(now)
+		if stats.Games == 0 {
+			continue
+		}
+		ratings = append(ratings, protocol.LeaderboardEntry{
+			Name:        a.Name,
+			Value:       stats.Rating,
+			Games:       b.gamesOf(a.Name),
+			Provisional: a.rating(now).Provisional(),
+		})
+	}
+
+	var streaks, mostGames []protocol.LeaderboardEntry
+	for _, p := range b.players {
+		if p.bestStreak > 0 {
+			streaks = append(streaks, protocol.LeaderboardEntry{Name: p.name, Value: p.bestStreak, Games: p.games()})
+		}
+		mostGames = append(mostGames, protocol.LeaderboardEntry{Name: p.name, Value: p.games(), Games: p.games()})
+	}
+
+	return protocol.LeaderboardPayload{
+		Ratings:   rankEntries(ratings, limit),
+		Streaks:   rankEntries(streaks, limit),
+		MostGames: rankEntries(mostGames, limit),
+		Players:   len(b.players),
+	}, nil
+}
+
+// gamesOf renvoie le nombre de parties terminées du joueur name.
+// Doit être appelée avec b.mu verrouillé.
+func (b *statsBook) gamesOf(name string) int {
+	if p, ok := b.players[strings.ToLower(name)]; ok {
+		return p.games()
+	}
+	return 0
+}
+
+// rankEntries trie entries par valeur décroissante, puis par pseudo, numérote les places
+// (les ex aequo partagent la même) et garde les limit premières.
+func rankEntries(entries []protocol.LeaderboardEntry, limit int) []protocol.LeaderboardEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			return entries[i].Value > entries[j].Value
+		}
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && entries[i].Value == entries[i-1].Value {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	if entries == nil {
+		entries = []protocol.LeaderboardEntry{}
+	}
+	return entries
+}
+
+// leaderboardLimit ramène le nombre de joueurs demandé par classement entre 1 et maxLeaderboardLimit.
+func leaderboardLimit(limit int) int {
+	if limit <= 0 {
+		return defaultLeaderboardLimit
+	}
+	return min(limit, maxLeaderboardLimit)
+}
+
+// recordStats reporte la partie terminée record dans les statistiques des joueurs.
+func recordStats(record *protocol.GameRecord) {
+	if playerStats == nil || record == nil {
+		return
+	}
+	playerStats.add(*record)
+}
+
+// handleGetLeaderboard envoie les classements au client id.
+func handleGetLeaderboard(payload protocol.LeaderboardRequest, id int) {
+	if playerStats == nil {
+		sendDisabled(id, protocol.TypeGetLeaderboard, "ce serveur ne tient pas de classement")
+		return
+	}
+	board, err := playerStats.leaderboard(leaderboardLimit(payload.Limit))
+	if err != nil {
+		logErrorf("Lecture des classements impossible : %v\n", err)
+		sendError(id, protocol.ErrCodeStats, protocol.TypeGetLeaderboard, "classements illisibles")
+		return
+	}
+	sendToClient(id, protocol.Message{
+		Type:    protocol.TypeLeaderboard,
+		Payload: board,
+	})
+}
+
+// handleGetPlayerStats envoie au client id les statistiques du joueur demandé, ou les
+// siennes si aucun pseudo n'est donné.
+func handleGetPlayerStats(payload protocol.PlayerStatsRequest, id int) {
+	if playerStats == nil {
+		sendDisabled(id, protocol.TypeGetPlayerStats, "ce serveur ne tient pas de statistiques")
+		return
+	}
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		a, ok := accountOf(id)
+		if !ok {
+			sendError(id, protocol.ErrCodeStats, protocol.TypeGetPlayerStats, "connectez-vous à un compte ou indiquez un pseudo")
+			return
+		}
+		name = a.name
+	}
+
+	profile, found, err := playerStats.profile(name)
+	switch {
+	case err != nil:
+		logErrorf("Lecture des statistiques de %s impossible : %v\n", name, err)
+		sendError(id, protocol.ErrCodeStats, protocol.TypeGetPlayerStats, "statistiques illisibles")
+	case !found:
+		sendError(id, protocol.ErrCodeStats, protocol.TypeGetPlayerStats, fmt.Sprintf("joueur %s inconnu", name))
+	default:
+		sendToClient(id, protocol.Message{
+			Type:    protocol.TypePlayerStats,
+			Payload: profile,
+		})
+	}
+}
+
+// startStatsServer sert les classements et les statistiques des joueurs en JSON sur
+// address : GET /api/leaderboard?limit=10 et GET /api/players/{pseudo}.
+func startStatsServer(address string, tlsCfg *tls.Config) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(leaderboardPath, serveLeaderboard)
+	mux.HandleFunc(playersPath, servePlayerStats)
+	server := &http.Server{
+		Addr:      address,
+		Handler:   mux,
+		TLSConfig: tlsCfg,
+	}
+
+	var err error
+	if tlsCfg != nil {
+		logInfof("API des classements : https://%s%s\n", address, leaderboardPath)
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		logInfof("API des classements : http://%s%s\n", address, leaderboardPath)
+		err = server.ListenAndServe()
+	}
+	if err != nil {
+		log.Fatal("Erreur lors de l'écoute de l'API HTTP :", err)
+	}
+}
+
+// serveLeaderboard répond à GET /api/leaderboard.
+func serveLeaderboard(w http.ResponseWriter, req *http.Request) {
+	if !checkStatsRequest(w, req) {
+		return
+	}
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	board, err := playerStats.leaderboard(leaderboardLimit(limit))
+	if err != nil {
+		logErrorf("Lecture des classements impossible : %v\n", err)
+		writeJSONError(w, http.StatusInternalServerError, "classements illisibles")
+		return
+	}
+	writeJSON(w, http.StatusOK, board)
+}
+
+// servePlayerStats répond à GET /api/players/{pseudo}.
+func servePlayerStats(w http.ResponseWriter, req *http.Request) {
+	if !checkStatsRequest(w, req) {
+		return
+	}
+	name, err := url.PathUnescape(strings.TrimPrefix(req.URL.EscapedPath(), playersPath))
+	if err != nil || strings.TrimSpace(name) == "" {
+		writeJSONError(w, http.StatusBadRequest, "pseudo manquant")
+		return
+	}
+	profile, found, err := playerStats.profile(name)
+	switch {
+	case err != nil:
+		logErrorf("Lecture des statistiques de %s impossible : %v\n", name, err)
+		writeJSONError(w, http.StatusInternalServerError, "statistiques illisibles")
+	case !found:
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("joueur %s inconnu", name))
+	default:
+		writeJSON(w, http.StatusOK, profile)
+	}
+}
+
+// checkStatsRequest refuse les requêtes autres que GET, et toutes les requêtes si le
+// serveur ne tient pas de statistiques. Elle indique si la requête peut être traitée.
+func checkStatsRequest(w http.ResponseWriter, req *http.Request) bool {
+	// Les pages web hébergées ailleurs peuvent lire les classements, comme elles peuvent se connecter en WebSocket
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	switch {
+	case req.Method != http.MethodGet:
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "seule la méthode GET est acceptée")
+		return false
+	case playerStats == nil:
+		writeJSONError(w, http.StatusServiceUnavailable, "ce serveur ne tient pas de classement")
+		return false
+	}
+	return true
+}
+
+// writeJSON envoie value en JSON avec le code HTTP status.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logDebugf("Réponse HTTP interrompue : %v\n", err)
+	}
+}
+
+// writeJSONError envoie une erreur {"error": message} avec le code HTTP status.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
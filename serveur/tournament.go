@@ -0,0 +1,929 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"puissance4/protocol"
+)
+
+const (
+	maxTournamentPlayers   = 32              // Nombre maximal d'inscrits dans un tournoi
+	maxFinishedTournaments = 10              // Tournois terminés ou annulés conservés pour être consultés
+	matchCloseDelay        = 5 * time.Second // Délai avant la fermeture de la salle d'un match terminé
+)
+
+// validBestOf sont les nombres de parties par match acceptés.
+var validBestOf = map[int]bool{1: true, 3: true, 5: true, 7: true}
+
+// tournament est un tournoi organisé par un client : ses inscrits, ses rondes et les
+// clients qui suivent son déroulement.
+type tournament struct {
+	id            int                  // Identifiant du tournoi
+	name          string               // Nom affiché
+	format        string               // protocol.FormatRoundRobin ou protocol.FormatKnockout
+	bestOf        int                  // Parties par match
+	timeControl   protocol.TimeControl // Cadence des parties
+	maxPlayers    int                  // Nombre maximal d'inscrits
+	organizer     int                  // ID du client organisateur
+	organizerName string               // Nom de l'organisateur lors de la création
+	status        string               // protocol.TournamentRegistration, TournamentRunning...
+	players       []*tournamentPlayer  // Inscrits, dans l'ordre d'inscription
+	rounds        [][]*tournamentMatch // Matchs de chaque ronde créée
+	schedule      [][][2]int           // Appariements de chaque ronde en toutes rondes (têtes de série, -1 pour une exemption)
+	totalRounds   int                  // Rondes prévues, connues au lancement
+	winner        string               // Nom du vainqueur
+	watchers      map[int]bool         // Clients qui suivent le tournoi sans y jouer
+	finishedAt    time.Time            // Fin ou annulation du tournoi
+}
+
+// tournamentPlayer est un inscrit d'un tournoi et son bilan.
+type tournamentPlayer struct {
+	id           int     // ID du client inscrit
+	name         string  // Nom lors de l'inscription
+	rating       int     // Cote lors de l'inscription, 0 sans compte
+	seed         int     // Tête de série, à partir de 1, attribuée au lancement
+	points       float64 // Points de match
+	gamePoints   float64 // Points de partie
+	wins         int     // Matchs gagnés
+	draws        int     // Matchs nuls
+	losses       int     // Matchs perdus
+	eliminated   bool    // Éliminé (élimination directe)
+	eliminatedIn int     // Ronde de l'élimination, 0 tant que le joueur est en lice
+	withdrawn    bool    // A abandonné le tournoi ou s'est déconnecté
+}
+
+// tournamentMatch est un match d'un tournoi, joué en une ou plusieurs parties dans une
+// salle réservée à ses deux joueurs. Une exemption est un match sans second joueur.
+type tournamentMatch struct {
+	t       *tournament          // Tournoi du match
+	round   int                  // Ronde du match, à partir de 1
+	players [2]*tournamentPlayer // players[1] vaut nil pour une exemption
+	scores  [2]float64           // Points de partie : 1 par victoire, 0,5 par égalité
+	games   int                  // Parties terminées
+	status  string               // protocol.MatchPending, MatchPlaying ou MatchFinished
+	winner  int                  // Place (0 ou 1) du vainqueur, -1 pour un match nul ou sans vainqueur
+	forfeit bool                 // Match perdu par forfait
+	room    *room                // Salle du match, nil tant qu'elle n'est pas ouverte ou une fois fermée
+	closed  bool                 // Le match est terminé et sa salle fermée : la ronde peut avancer
+}
+
+var (
+	tournaments      = make(map[int]*tournament) // Tournois du serveur, associés à leur ID.
+	nextTournamentID = 1                         // Identifiant du prochain tournoi créé.
+	// Mutex protégeant les tournois et leurs matchs. Il peut être tenu en créant une salle,
+	// mais jamais en y plaçant ou en en retirant un joueur.
+	tournamentsMux sync.Mutex
+)
+
+// tournamentUpdate est l'état d'un tournoi à envoyer, préparé sous tournamentsMux et
+// envoyé une fois le verrou relâché.
+type tournamentUpdate struct {
+	recipients []int
+	payload    protocol.TournamentPayload
+}
+
+// has indique si le client id est l'un des joueurs du match. Elle peut être appelée sans
+// verrou : les joueurs d'un match ne changent pas.
+func (m *tournamentMatch) has(id int) bool {
+	return m.index(id) != -1
+}
+
+// index renvoie la place (0 ou 1) du joueur id dans le match, -1 s'il n'y joue pas.
+func (m *tournamentMatch) index(id int) int {
+	for i, p := range m.players {
+		if p != nil && p.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchFinished indique si le match m est terminé : sa salle n'accepte plus de rematch.
+func matchFinished(m *tournamentMatch) bool {
+	tournamentsMux.Lock()
+	defer tournamentsMux.Unlock()
+	return m.status == protocol.MatchFinished
+}
+
+// result indique si le match est joué et renvoie la place de son vainqueur (-1 pour un
+// match nul). Un match est gagné dès qu'un joueur a plus de la moitié des points possibles.
+// Après bestOf parties sans vainqueur, le match est nul en toutes rondes ; en élimination
+// directe, les joueurs se départagent en mort subite.
+func (m *tournamentMatch) result() (int, bool) {
+	need := float64(m.t.bestOf) / 2
+	switch {
+	case m.scores[0] > need:
+		return 0, true
+	case m.scores[1] > need:
+		return 1, true
+	case m.games < m.t.bestOf:
+		return -1, false
+	case m.scores[0] > m.scores[1]:
+		return 0, true
+	case m.scores[1] > m.scores[0]:
+		return 1, true
+	case m.t.format == protocol.FormatRoundRobin:
+		return -1, true
+	}
+	return -1, false
+}
+
+// player renvoie l'inscrit id du tournoi, nil s'il n'est pas inscrit.
+func (t *tournament) player(id int) *tournamentPlayer {
+	for _, p := range t.players {
+		if p.id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// over indique si le tournoi est terminé ou annulé.
+func (t *tournament) over() bool {
+	return t.status == protocol.TournamentFinished || t.status == protocol.TournamentCancelled
+}
+
+// currentRound renvoie les matchs de la ronde en cours, nil avant le lancement.
+func (t *tournament) currentRound() []*tournamentMatch {
+	if len(t.rounds) == 0 {
+		return nil
+	}
+	return t.rounds[len(t.rounds)-1]
+}
+
+// standings renvoie les inscrits dans l'ordre du classement du tournoi : points de match,
+// puis points de partie, victoires et tête de série. En élimination directe, les joueurs
+// encore en lice passent devant, puis ceux éliminés le plus tard.
+func (t *tournament) standings() []*tournamentPlayer {
+	list := append([]*tournamentPlayer(nil), t.players...)
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if t.format == protocol.FormatKnockout && a.eliminatedIn != b.eliminatedIn {
+			return a.eliminatedIn == 0 || (b.eliminatedIn != 0 && a.eliminatedIn > b.eliminatedIn)
+		}
+		if a.points != b.points {
+			return a.points > b.points
+		}
+		if a.gamePoints != b.gamePoints {
+			return a.gamePoints > b.gamePoints
+		}
+		if a.wins != b.wins {
+			return a.wins > b.wins
+		}
+		return a.seed < b.seed
+	})
+	return list
+}
+
+// info renvoie le résumé du tournoi présenté dans la liste des tournois.
+func (t *tournament) info() protocol.TournamentInfo {
+	return protocol.TournamentInfo{
+		ID:         t.id,
+		Name:       t.name,
+		Organizer:  t.organizerName,
+		Format:     t.format,
+		BestOf:     t.bestOf,
+		Status:     t.status,
+		Players:    len(t.players),
+		MaxPlayers: t.maxPlayers,
+		Round:      len(t.rounds),
+		Rounds:     t.totalRounds,
+		Clock:      t.timeControl,
+	}
+}
+
+// payload renvoie l'état complet du tournoi.
+func (t *tournament) payload() protocol.TournamentPayload {
+	payload := protocol.TournamentPayload{
+		TournamentInfo: t.info(),
+		OrganizerID:    t.organizer,
+		Entrants:       []protocol.TournamentPlayer{},
+		Matches:        []protocol.TournamentMatch{},
+		Winner:         t.winner,
+	}
+	players := t.players
+	if t.status != protocol.TournamentRegistration {
+		players = t.standings()
+	}
+	for i, p := range players {
+		entrant := protocol.TournamentPlayer{
+			ID:         p.id,
+			Name:       p.name,
+			Rating:     p.rating,
+			Seed:       p.seed,
+			Points:     p.points,
+			GamePoints: p.gamePoints,
+			Wins:       p.wins,
+			Draws:      p.draws,
+			Losses:     p.losses,
+			Eliminated: p.eliminated,
+			Withdrawn:  p.withdrawn,
+		}
+		if p.seed > 0 {
+			entrant.Rank = i + 1
+		}
+		payload.Entrants = append(payload.Entrants, entrant)
+	}
+	for _, round := range t.rounds {
+		for _, m := range round {
+			match := protocol.TournamentMatch{
+				Round:   m.round,
+				Players: [2]int{-1, -1},
+				Scores:  m.scores,
+				Games:   m.games,
+				Status:  m.status,
+				Winner:  -1,
+				Forfeit: m.forfeit,
+			}
+			for i, p := range m.players {
+				if p != nil {
+					match.Players[i], match.Names[i] = p.id, p.name
+				}
+			}
+			if m.status == protocol.MatchFinished && m.winner != -1 {
+				match.Winner = m.players[m.winner].id
+			}
+			if m.room != nil && m.status == protocol.MatchPlaying {
+				match.RoomID = m.room.id
+			}
+			payload.Matches = append(payload.Matches, match)
+		}
+	}
+	return payload
+}
+
+// update prépare l'envoi de l'état du tournoi à ses joueurs en lice, à son organisateur,
+// à ceux qui le suivent et aux clients extra.
+func (t *tournament) update(extra ...int) tournamentUpdate {
+	seen := make(map[int]bool)
+	var recipients []int
+	add := func(id int) {
+		if !seen[id] {
+			seen[id] = true
+			recipients = append(recipients, id)
+		}
+	}
+	for _, p := range t.players {
+		if !p.withdrawn {
+			add(p.id)
+		}
+	}
+	add(t.organizer)
+	for id := range t.watchers {
+		add(id)
+	}
+	for _, id := range extra {
+		add(id)
+	}
+	return tournamentUpdate{recipients: recipients, payload: t.payload()}
+}
+
+// send envoie l'état du tournoi aux clients concernés encore connectés.
+func (u tournamentUpdate) send() {
+	message := protocol.Message{Type: protocol.TypeTournament, Payload: u.payload}
+	for _, id := range u.recipients {
+		if _, ok := clientConn(id); ok {
+			sendToClient(id, message)
+		}
+	}
+}
+
+// activeTournamentOf renvoie le tournoi pas encore terminé auquel le client id est inscrit
+// et qu'il n'a pas abandonné, ou nil. Doit être appelée avec tournamentsMux verrouillé.
+func activeTournamentOf(id int) *tournament {
+	for _, t := range tournaments {
+		if p := t.player(id); p != nil && !p.withdrawn && !t.over() {
+			return t
+		}
+	}
+	return nil
+}
+
+// newTournamentPlayer inscrit le client id sous son nom actuel.
+func newTournamentPlayer(id int) *tournamentPlayer {
+	return &tournamentPlayer{id: id, name: playerName(id), rating: playerRating(id)}
+}
+
+// tournamentList renvoie le résumé des tournois du serveur, triés par identifiant.
+func tournamentList() []protocol.TournamentInfo {
+	tournamentsMux.Lock()
+	defer tournamentsMux.Unlock()
+
+	list := make([]protocol.TournamentInfo, 0, len(tournaments))
+	for _, t := range tournaments {
+		list = append(list, t.info())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// sendTournamentList envoie la liste des tournois au client id.
+func sendTournamentList(id int) {
+	if !config.Features.Tournaments {
+		sendDisabled(id, protocol.TypeListTournaments, "les tournois sont désactivés sur ce serveur")
+		return
+	}
+	sendToClient(id, protocol.Message{
+		Type: protocol.TypeTournamentList,
+		Payload: protocol.TournamentListPayload{
+			Tournaments: tournamentList(),
+		},
+	})
+}
+
+// handleCreateTournament crée un tournoi organisé par le client id, qui y est inscrit d'office.
+func handleCreateTournament(payload protocol.CreateTournamentPayload, id int) {
+	if !config.Features.Tournaments {
+		sendDisabled(id, protocol.TypeCreateTournament, "les tournois sont désactivés sur ce serveur")
+		return
+	}
+
+	format := payload.Format
+	if format == "" {
+		format = protocol.FormatRoundRobin
+	}
+	bestOf := payload.BestOf
+	if bestOf == 0 {
+		bestOf = 1
+	}
+	maxPlayers := payload.MaxPlayers
+	if maxPlayers == 0 {
+		maxPlayers = maxTournamentPlayers
+	}
+	var err error
+	switch {
+	case format != protocol.FormatRoundRobin && format != protocol.FormatKnockout:
+		err = fmt.Errorf("format de tournoi inconnu : %s", format)
+	case !validBestOf[bestOf]:
+		err = fmt.Errorf("un match se joue en 1, 3, 5 ou 7 parties, pas %d", bestOf)
+	case maxPlayers < 2 || maxPlayers > maxTournamentPlayers:
+		err = fmt.Errorf("un tournoi accueille de 2 à %d joueurs", maxTournamentPlayers)
+	default:
+		err = payload.Clock.Validate()
+	}
+	if err != nil {
+		sendError(id, protocol.ErrCodeBadPayload, protocol.TypeCreateTournament, err.Error())
+		return
+	}
+
+	organizer := newTournamentPlayer(id)
+	tournamentsMux.Lock()
+	if t := activeTournamentOf(id); t != nil {
+		tournamentsMux.Unlock()
+		sendError(id, protocol.ErrCodeTournament, protocol.TypeCreateTournament,
+			fmt.Sprintf("vous êtes déjà inscrit au tournoi %d", t.id))
+		return
+	}
+	t := &tournament{
+		id:            nextTournamentID,
+		name:          strings.TrimSpace(payload.Name),
+		format:        format,
+		bestOf:        bestOf,
+		timeControl:   payload.Clock,
+		maxPlayers:    maxPlayers,
+		organizer:     id,
+		organizerName: organizer.name,
+		status:        protocol.TournamentRegistration,
+		players:       []*tournamentPlayer{organizer},
+		watchers:      make(map[int]bool),
+	}
+	nextTournamentID++
+	if t.name == "" {
+		t.name = fmt.Sprintf("Tournoi %d", t.id)
+	}
+	tournaments[t.id] = t
+	u := t.update()
+	tournamentsMux.Unlock()
+
+	logInfof("Tournoi %d (%s, %s, %d partie(s) par match, cadence %s) créé par le client %d\n", t.id, t.name, format, bestOf, payload.Clock, id)
+	u.send()
+}
+
+// handleTournamentRequest traite les messages du client id qui désignent un tournoi :
+// inscription, désinscription ou abandon, lancement et suivi.
+func handleTournamentRequest(msgType string, payload protocol.TournamentRequest, id int) {
+	if !config.Features.Tournaments {
+		sendDisabled(id, msgType, "les tournois sont désactivés sur ce serveur")
+		return
+	}
+
+	var entrant *tournamentPlayer
+	if msgType == protocol.TypeJoinTournament {
+		entrant = newTournamentPlayer(id)
+	}
+
+	tournamentsMux.Lock()
+	t, ok := tournaments[payload.ID]
+	if !ok {
+		tournamentsMux.Unlock()
+		sendError(id, protocol.ErrCodeTournament, msgType, fmt.Sprintf("le tournoi %d n'existe pas", payload.ID))
+		return
+	}
+	var err error
+	var starts, finished []*tournamentMatch
+	switch msgType {
+	case protocol.TypeJoinTournament:
+		err = t.join(entrant)
+	case protocol.TypeLeaveTournament:
+		finished, err = t.leave(id)
+	case protocol.TypeStartTournament:
+		starts, err = t.start(id)
+	case protocol.TypeGetTournament:
+		if t.player(id) == nil && id != t.organizer {
+			t.watchers[id] = true
+		}
+	}
+	if err != nil {
+		tournamentsMux.Unlock()
+		logWarnf("Message %s du client %d refusé (tournoi %d) : %v\n", msgType, id, payload.ID, err)
+		sendError(id, protocol.ErrCodeTournament, msgType, err.Error())
+		return
+	}
+	u := t.update(id)
+	tournamentsMux.Unlock()
+
+	u.send()
+	for _, m := range finished {
+		afterMatch(m)
+	}
+	for _, m := range starts {
+		startMatch(m)
+	}
+}
+
+// join inscrit entrant au tournoi. Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) join(entrant *tournamentPlayer) error {
+	switch {
+	case t.status != protocol.TournamentRegistration:
+		return errors.New("les inscriptions à ce tournoi sont closes")
+	case t.player(entrant.id) != nil:
+		return errors.New("vous êtes déjà inscrit à ce tournoi")
+	case len(t.players) >= t.maxPlayers:
+		return fmt.Errorf("le tournoi est complet (%d joueurs)", t.maxPlayers)
+	}
+	if other := activeTournamentOf(entrant.id); other != nil {
+		return fmt.Errorf("vous êtes déjà inscrit au tournoi %d", other.id)
+	}
+	t.players = append(t.players, entrant)
+	delete(t.watchers, entrant.id)
+	logInfof("Client %d inscrit au tournoi %d\n", entrant.id, t.id)
+	return nil
+}
+
+// leave désinscrit le client id du tournoi, ou le fait abandonner un tournoi commencé.
+// Pendant les inscriptions, l'organisateur annule le tournoi. Elle renvoie le match
+// perdu par forfait, à terminer avec afterMatch.
+// Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) leave(id int) ([]*tournamentMatch, error) {
+	p := t.player(id)
+	switch {
+	case t.status == protocol.TournamentRegistration && id == t.organizer:
+		t.cancel()
+		return nil, nil
+	case t.over():
+		return nil, errors.New("ce tournoi est terminé")
+	case p == nil || p.withdrawn:
+		delete(t.watchers, id)
+		return nil, nil
+	case t.status == protocol.TournamentRegistration:
+		for i, other := range t.players {
+			if other == p {
+				t.players = append(t.players[:i], t.players[i+1:]...)
+				break
+			}
+		}
+		logInfof("Client %d désinscrit du tournoi %d\n", id, t.id)
+		return nil, nil
+	}
+	return t.withdraw(p), nil
+}
+
+// cancel annule le tournoi avant son lancement. Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) cancel() {
+	t.status = protocol.TournamentCancelled
+	t.finishedAt = time.Now()
+	logInfof("Tournoi %d annulé\n", t.id)
+	pruneTournaments()
+}
+
+// withdraw fait abandonner le joueur p : il perd par forfait son match de la ronde en cours
+// s'il n'est pas terminé, et n'est plus apparié ensuite. Elle renvoie ce match, à terminer
+// avec afterMatch. Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) withdraw(p *tournamentPlayer) []*tournamentMatch {
+	p.withdrawn = true
+	if t.format == protocol.FormatKnockout && !p.eliminated {
+		p.eliminated, p.eliminatedIn = true, len(t.rounds)
+	}
+	logInfof("Client %d abandonne le tournoi %d\n", p.id, t.id)
+
+	for _, m := range t.currentRound() {
+		i := m.index(p.id)
+		if i == -1 || m.status == protocol.MatchFinished || m.players[1] == nil {
+			continue
+		}
+		absent := [2]bool{}
+		absent[i] = true
+		t.forfeit(m, absent)
+		return []*tournamentMatch{m}
+	}
+	return nil
+}
+
+// start lance le tournoi à la demande de son organisateur id : les têtes de série sont
+// attribuées par cote, puis par ordre d'inscription, et la première ronde est créée.
+// Elle renvoie les matchs à ouvrir avec startMatch.
+// Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) start(id int) ([]*tournamentMatch, error) {
+	switch {
+	case id != t.organizer:
+		return nil, errors.New("seul l'organisateur peut lancer le tournoi")
+	case t.status != protocol.TournamentRegistration:
+		return nil, errors.New("ce tournoi a déjà commencé")
+	case len(t.players) < 2:
+		return nil, errors.New("il faut au moins deux inscrits pour lancer le tournoi")
+	}
+
+	seeds := append([]*tournamentPlayer(nil), t.players...)
+	sort.SliceStable(seeds, func(i, j int) bool { return seeds[i].rating > seeds[j].rating })
+	for i, p := range seeds {
+		p.seed = i + 1
+	}
+
+	if t.format == protocol.FormatRoundRobin {
+		t.schedule = roundRobinSchedule(len(seeds))
+		t.totalRounds = len(t.schedule)
+	} else {
+		t.totalRounds = bits.Len(uint(len(seeds) - 1))
+	}
+	t.status = protocol.TournamentRunning
+	logInfof("Tournoi %d lancé avec %d joueurs en %d ronde(s)\n", t.id, len(seeds), t.totalRounds)
+	return t.nextRound(), nil
+}
+
+// roundRobinSchedule renvoie les appariements de chaque ronde d'un tournoi toutes rondes
+// entre n joueurs, désignés par leur tête de série à partir de 0, selon la méthode du
+// cercle : le premier joueur reste fixe et les autres tournent d'une place à chaque ronde.
+// Avec un nombre impair de joueurs, l'adversaire -1 est une exemption.
+func roundRobinSchedule(n int) [][][2]int {
+	circle := make([]int, n)
+	for i := range circle {
+		circle[i] = i
+	}
+	if n%2 == 1 {
+		circle = append(circle, -1)
+	}
+	size := len(circle)
+
+	schedule := make([][][2]int, size-1)
+	for round := range schedule {
+		for i := 0; i < size/2; i++ {
+			a, b := circle[i], circle[size-1-i]
+			if a == -1 {
+				a, b = b, a
+			}
+			schedule[round] = append(schedule[round], [2]int{a, b})
+		}
+		last := circle[size-1]
+		copy(circle[2:], circle[1:size-1])
+		circle[1] = last
+	}
+	return schedule
+}
+
+// bySeed renvoie l'inscrit de tête de série seed (à partir de 0), nil pour une exemption.
+// Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) bySeed(seed int) *tournamentPlayer {
+	for _, p := range t.players {
+		if p.seed == seed+1 {
+			return p
+		}
+	}
+	return nil
+}
+
+// nextRound crée la ronde suivante, ou termine le tournoi s'il n'en reste plus. En
+// élimination directe, les meilleures têtes de série sont exemptées pour que le nombre
+// de joueurs qualifiés soit une puissance de deux, puis les autres joueurs en lice sont
+// appariés par tête de série, la meilleure contre la moins bonne.
+// Elle renvoie les matchs à ouvrir avec startMatch.
+// Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) nextRound() []*tournamentMatch {
+	round := len(t.rounds) + 1
+	var pairs [][2]*tournamentPlayer
+	if t.format == protocol.FormatRoundRobin {
+		if round > t.totalRounds {
+			t.finish()
+			return nil
+		}
+		for _, pair := range t.schedule[round-1] {
+			pairs = append(pairs, [2]*tournamentPlayer{t.bySeed(pair[0]), t.bySeed(pair[1])})
+		}
+	} else {
+		var alive []*tournamentPlayer
+		for _, p := range t.players {
+			if !p.eliminated {
+				alive = append(alive, p)
+			}
+		}
+		if len(alive) <= 1 {
+			t.finish()
+			return nil
+		}
+		sort.Slice(alive, func(i, j int) bool { return alive[i].seed < alive[j].seed })
+		byes := 1<<bits.Len(uint(len(alive)-1)) - len(alive)
+		for _, p := range alive[:byes] {
+			pairs = append(pairs, [2]*tournamentPlayer{p, nil})
+		}
+		alive = alive[byes:]
+		for i := 0; i < len(alive)/2; i++ {
+			pairs = append(pairs, [2]*tournamentPlayer{alive[i], alive[len(alive)-1-i]})
+		}
+	}
+
+	var matches, starts []*tournamentMatch
+	for _, pair := range pairs {
+		m := &tournamentMatch{t: t, round: round, players: pair, status: protocol.MatchPending, winner: -1}
+		if pair[1] == nil {
+			// Une exemption qualifie le joueur sans lui rapporter de point
+			m.status, m.winner, m.closed = protocol.MatchFinished, 0, true
+		} else {
+			starts = append(starts, m)
+		}
+		matches = append(matches, m)
+	}
+	t.rounds = append(t.rounds, matches)
+	logInfof("Tournoi %d : ronde %d, %d match(s)\n", t.id, round, len(starts))
+	return starts
+}
+
+// finish termine le tournoi et désigne son vainqueur, le premier du classement.
+// Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) finish() {
+	t.status = protocol.TournamentFinished
+	t.finishedAt = time.Now()
+	if standings := t.standings(); len(standings) > 0 && !standings[0].eliminated {
+		t.winner = standings[0].name
+	}
+	logInfof("Tournoi %d terminé, vainqueur : %s\n", t.id, t.winner)
+	pruneTournaments()
+}
+
+// pruneTournaments oublie les tournois terminés ou annulés les plus anciens au-delà de
+// maxFinishedTournaments. Doit être appelée avec tournamentsMux verrouillé.
+func pruneTournaments() {
+	var over []*tournament
+	for _, t := range tournaments {
+		if t.over() {
+			over = append(over, t)
+		}
+	}
+	sort.Slice(over, func(i, j int) bool { return over[i].finishedAt.Before(over[j].finishedAt) })
+	for len(over) > maxFinishedTournaments {
+		delete(tournaments, over[0].id)
+		over = over[1:]
+	}
+}
+
+// finishMatch termine le match m, gagné par le joueur à la place winner (-1 pour un match
+// nul, ou sans vainqueur s'il est perdu par forfait), et reporte son résultat au classement.
+// Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) finishMatch(m *tournamentMatch, winner int) {
+	m.status, m.winner = protocol.MatchFinished, winner
+	for i, p := range m.players {
+		p.gamePoints += m.scores[i]
+		switch {
+		case i == winner:
+			p.points++
+			p.wins++
+		case winner == -1 && !m.forfeit:
+			p.points += 0.5
+			p.draws++
+		default:
+			p.losses++
+			if t.format == protocol.FormatKnockout && !p.eliminated {
+				p.eliminated, p.eliminatedIn = true, m.round
+			}
+		}
+	}
+	logInfof("Tournoi %d : match %s - %s terminé (%.1f - %.1f)\n", t.id, m.players[0].name, m.players[1].name, m.scores[0], m.scores[1])
+}
+
+// forfeit fait perdre le match m par forfait aux joueurs absents. Si les deux le sont,
+// le match n'a pas de vainqueur. Doit être appelée avec tournamentsMux verrouillé.
+func (t *tournament) forfeit(m *tournamentMatch, absent [2]bool) {
+	m.forfeit = true
+	winner := -1
+	switch {
+	case absent[0] && absent[1]:
+	case absent[0]:
+		winner = 1
+	default:
+		winner = 0
+	}
+	t.finishMatch(m, winner)
+}
+
+// startMatch ouvre la salle du match m et y place ses deux joueurs, qui quittent leur
+// salle ou la file de partie rapide. Un joueur absent ou qui a abandonné perd par forfait.
+// Si le serveur n'a plus de salle libre, le match attend qu'une salle se ferme.
+func startMatch(m *tournamentMatch) {
+	tournamentsMux.Lock()
+	t := m.t
+	if m.status != protocol.MatchPending || t.status != protocol.TournamentRunning {
+		tournamentsMux.Unlock()
+		return
+	}
+
+	absent := [2]bool{}
+	for i, p := range m.players {
+		_, online := clientConn(p.id)
+		absent[i] = p.withdrawn || !online
+	}
+	if absent[0] || absent[1] {
+		t.forfeit(m, absent)
+		u := t.update()
+		tournamentsMux.Unlock()
+		u.send()
+		afterMatch(m)
+		return
+	}
+
+	name := fmt.Sprintf("%s - ronde %d : %s - %s", t.name, m.round, m.players[0].name, m.players[1].name)
+	r, err := createMatchRoom(name, "", true, t.timeControl, m)
+	if err != nil {
+		tournamentsMux.Unlock()
+		logWarnf("Tournoi %d : le match %s - %s attend une salle : %v\n", t.id, m.players[0].name, m.players[1].name, err)
+		return
+	}
+	m.room, m.status = r, protocol.MatchPlaying
+	ids := [2]int{m.players[0].id, m.players[1].id}
+	u := t.update()
+	tournamentsMux.Unlock()
+
+	for _, id := range ids {
+		if leaveQueue(id) {
+			sendToClient(id, protocol.Message{
+				Type:    protocol.TypeQueueLeft,
+				Payload: nil,
+			})
+			broadcastQueuePositions()
+		}
+		if roomOf(id) != nil {
+			handleLeaveRoom(id)
+		}
+		handleJoinRoom(protocol.RoomRequest{ID: r.id}, id)
+	}
+	u.send()
+}
+
+// startPendingMatches ouvre les matchs des rondes en cours qui attendent une salle.
+func startPendingMatches() {
+	tournamentsMux.Lock()
+	var pending []*tournamentMatch
+	for _, t := range tournaments {
+		if t.status != protocol.TournamentRunning {
+			continue
+		}
+		for _, m := range t.currentRound() {
+			if m.status == protocol.MatchPending {
+				pending = append(pending, m)
+			}
+		}
+	}
+	tournamentsMux.Unlock()
+
+	for _, m := range pending {
+		startMatch(m)
+	}
+}
+
+// afterMatch ferme la salle du match terminé m après matchCloseDelay, le temps pour les
+// joueurs de voir le résultat de la dernière partie, puis fait avancer le tournoi.
+func afterMatch(m *tournamentMatch) {
+	tournamentsMux.Lock()
+	r := m.room
+	tournamentsMux.Unlock()
+
+	if r == nil {
+		closeMatch(m)
+		return
+	}
+	time.AfterFunc(matchCloseDelay, func() {
+		for id := range r.connections() {
+			handleLeaveRoom(id)
+		}
+		closeMatch(m)
+	})
+}
+
+// closeMatch marque la salle du match m comme fermée et, si c'était le dernier match de
+// la ronde, crée la ronde suivante et ouvre ses matchs.
+func closeMatch(m *tournamentMatch) {
+	tournamentsMux.Lock()
+	t := m.t
+	m.room, m.closed = nil, true
+	var starts []*tournamentMatch
+	roundOver := t.status == protocol.TournamentRunning
+	for _, other := range t.currentRound() {
+		if !other.closed {
+			roundOver = false
+		}
+	}
+	if roundOver {
+		starts = t.nextRound()
+	}
+	u := t.update()
+	tournamentsMux.Unlock()
+
+	u.send()
+	for _, next := range starts {
+		startMatch(next)
+	}
+}
+
+// tournamentGameOver reporte au match de la salle r le résultat de la partie record.
+// Tant que le match n'est pas joué, les joueurs enchaînent les parties avec la demande
+// de rematch habituelle ; sinon, la salle est fermée et le tournoi avance.
+func tournamentGameOver(r *room, record *protocol.GameRecord) {
+	m := r.match
+	if m == nil || record == nil {
+		return
+	}
+
+	tournamentsMux.Lock()
+	t := m.t
+	if m.status != protocol.MatchPlaying {
+		tournamentsMux.Unlock()
+		return
+	}
+	m.games++
+	if i := m.index(record.Winner); i != -1 {
+		m.scores[i]++
+	} else {
+		m.scores[0] += 0.5
+		m.scores[1] += 0.5
+	}
+	winner, done := m.result()
+	if done {
+		t.finishMatch(m, winner)
+	}
+	u := t.update()
+	tournamentsMux.Unlock()
+
+	u.send()
+	if done {
+		afterMatch(m)
+	}
+}
+
+// matchPlayerLeft est appelée lorsque le joueur id quitte la salle du match m : s'il était
+// en cours, il le perd par forfait.
+func matchPlayerLeft(m *tournamentMatch, id int) {
+	tournamentsMux.Lock()
+	t := m.t
+	i := m.index(id)
+	if m.status != protocol.MatchPlaying || i == -1 {
+		tournamentsMux.Unlock()
+		return
+	}
+	absent := [2]bool{}
+	absent[i] = true
+	t.forfeit(m, absent)
+	u := t.update()
+	tournamentsMux.Unlock()
+
+	logInfof("Tournoi %d : client %d a quitté son match, perdu par forfait\n", t.id, id)
+	u.send()
+	afterMatch(m)
+}
+
+// leaveTournaments retire le client id, qui se déconnecte, des tournois : il est désinscrit
+// de ceux qui n'ont pas commencé (qui sont annulés s'il les organise) et abandonne les autres.
+func leaveTournaments(id int) {
+	tournamentsMux.Lock()
+	var updates []tournamentUpdate
+	var finished []*tournamentMatch
+	for _, t := range tournaments {
+		delete(t.watchers, id)
+		if t.over() || (t.player(id) == nil && (id != t.organizer || t.status != protocol.TournamentRegistration)) {
+			continue
+		}
+		forfeited, _ := t.leave(id)
+		finished = append(finished, forfeited...)
+		updates = append(updates, t.update())
+	}
+	tournamentsMux.Unlock()
+
+	for _, u := range updates {
+		u.send()
+	}
+	for _, m := range finished {
+		afterMatch(m)
+	}
+}
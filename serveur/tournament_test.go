@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"puissance4/protocol"
+)
+
+func TestRoundRobinSchedule(t *testing.T) {
+	for n := 2; n <= 9; n++ {
+		t.Run(fmt.Sprintf("%d joueurs", n), func(t *testing.T) {
+			schedule := roundRobinSchedule(n)
+			wantRounds := n - 1
+			if n%2 == 1 {
+				wantRounds = n
+			}
+			if len(schedule) != wantRounds {
+				t.Fatalf("%d rondes, attendu %d", len(schedule), wantRounds)
+			}
+
+			met := make(map[[2]int]int)
+			byes := make(map[int]int)
+			for round, pairs := range schedule {
+				seen := make(map[int]bool)
+				for _, pair := range pairs {
+					for _, p := range pair {
+						if p == -1 {
+							continue
+						}
+						if p < 0 || p >= n {
+							t.Fatalf("ronde %d : joueur %d inconnu", round+1, p)
+						}
+						if seen[p] {
+							t.Errorf("ronde %d : le joueur %d joue deux fois", round+1, p)
+						}
+						seen[p] = true
+					}
+					switch {
+					case pair[0] == -1:
+						t.Errorf("ronde %d : exemption en première place %v", round+1, pair)
+					case pair[1] == -1:
+						byes[pair[0]]++
+					default:
+						a, b := pair[0], pair[1]
+						if a > b {
+							a, b = b, a
+						}
+						met[[2]int{a, b}]++
+					}
+				}
+				if len(seen) != n {
+					t.Errorf("ronde %d : %d joueurs appariés, attendu %d", round+1, len(seen), n)
+				}
+			}
+
+			for a := 0; a < n; a++ {
+				for b := a + 1; b < n; b++ {
+					if met[[2]int{a, b}] != 1 {
+						t.Errorf("les joueurs %d et %d se rencontrent %d fois", a, b, met[[2]int{a, b}])
+					}
+				}
+				if wantByes := n % 2; byes[a] != wantByes {
+					t.Errorf("le joueur %d est exempté %d fois, attendu %d", a, byes[a], wantByes)
+				}
+			}
+		})
+	}
+}
+
+// newTestTournament crée un tournoi de n inscrits, de cotes décroissantes : le joueur
+// d'ID i est la tête de série i+1.
+func newTestTournament(format string, bestOf, n int) *tournament {
+	t := &tournament{id: 1, format: format, bestOf: bestOf, status: protocol.TournamentRegistration}
+	for i := 0; i < n; i++ {
+		t.players = append(t.players, &tournamentPlayer{id: i, name: fmt.Sprintf("Joueur %d", i), rating: 2000 - i})
+	}
+	return t
+}
+
+func TestKnockoutFirstRound(t *testing.T) {
+	tests := []struct {
+		players, byes, rounds int
+	}{
+		{2, 0, 1},
+		{3, 1, 2},
+		{4, 0, 2},
+		{5, 3, 3},
+		{6, 2, 3},
+		{7, 1, 3},
+		{8, 0, 3},
+		{9, 7, 4},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d joueurs", tt.players), func(t *testing.T) {
+			tour := newTestTournament(protocol.FormatKnockout, 1, tt.players)
+			tournamentsMux.Lock()
+			starts, err := tour.start(0)
+			tournamentsMux.Unlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tour.totalRounds != tt.rounds {
+				t.Errorf("%d rondes, attendu %d", tour.totalRounds, tt.rounds)
+			}
+			matches := tour.currentRound()
+			if len(matches) != tt.byes+len(starts) || len(starts) != (tt.players-tt.byes)/2 {
+				t.Fatalf("%d matchs dont %d à jouer, attendu %d exemptions", len(matches), len(starts), tt.byes)
+			}
+
+			// Les meilleures têtes de série sont exemptées, puis la meilleure restante
+			// affronte la moins bonne
+			for i, m := range matches[:tt.byes] {
+				if m.players[0].seed != i+1 || m.players[1] != nil || m.status != protocol.MatchFinished || m.winner != 0 {
+					t.Errorf("exemption %d : %+v", i, m)
+				}
+			}
+			for i, m := range starts {
+				wantSeeds := [2]int{tt.byes + i + 1, tt.players - i}
+				if m.players[0].seed != wantSeeds[0] || m.players[1].seed != wantSeeds[1] {
+					t.Errorf("match %d : têtes de série %d - %d, attendu %v", i, m.players[0].seed, m.players[1].seed, wantSeeds)
+				}
+			}
+			// Le nombre de qualifiés pour la ronde suivante est une puissance de deux
+			if qualified := tt.byes + len(starts); qualified&(qualified-1) != 0 {
+				t.Errorf("%d qualifiés après la première ronde", qualified)
+			}
+		})
+	}
+}
+
+func TestMatchResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		bestOf     int
+		scores     [2]float64
+		games      int
+		wantWinner int
+		wantDone   bool
+	}{
+		{"majorité atteinte", protocol.FormatKnockout, 3, [2]float64{2, 0}, 2, 0, true},
+		{"majorité du second", protocol.FormatRoundRobin, 3, [2]float64{0.5, 2}, 3, 1, true},
+		{"match en cours", protocol.FormatKnockout, 3, [2]float64{1, 1}, 2, -1, false},
+		{"avantage après bestOf", protocol.FormatRoundRobin, 3, [2]float64{1.5, 1}, 3, 0, true},
+		{"nul en toutes rondes", protocol.FormatRoundRobin, 1, [2]float64{0.5, 0.5}, 1, -1, true},
+		{"mort subite en élimination", protocol.FormatKnockout, 1, [2]float64{0.5, 0.5}, 1, -1, false},
+		{"mort subite décidée", protocol.FormatKnockout, 1, [2]float64{0.5, 1.5}, 2, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &tournamentMatch{t: &tournament{format: tt.format, bestOf: tt.bestOf}, scores: tt.scores, games: tt.games}
+			winner, done := m.result()
+			if winner != tt.wantWinner || done != tt.wantDone {
+				t.Errorf("result() = %d, %v, attendu %d, %v", winner, done, tt.wantWinner, tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestForfeit(t *testing.T) {
+	tests := []struct {
+		name       string
+		absent     [2]bool
+		wantWinner int
+		wantLosses [2]int
+	}{
+		{"premier absent", [2]bool{true, false}, 1, [2]int{1, 0}},
+		{"second absent", [2]bool{false, true}, 0, [2]int{0, 1}},
+		{"deux absents", [2]bool{true, true}, -1, [2]int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tour := newTestTournament(protocol.FormatKnockout, 1, 2)
+			m := &tournamentMatch{t: tour, round: 1, players: [2]*tournamentPlayer{tour.players[0], tour.players[1]}}
+			tour.forfeit(m, tt.absent)
+			if m.winner != tt.wantWinner || !m.forfeit || m.status != protocol.MatchFinished {
+				t.Errorf("match %+v, attendu le vainqueur %d par forfait", m, tt.wantWinner)
+			}
+			for i, p := range m.players {
+				// Un forfait ne rapporte jamais de demi-point, même sans vainqueur
+				if p.losses != tt.wantLosses[i] || p.draws != 0 || p.eliminated != (tt.wantLosses[i] == 1) {
+					t.Errorf("joueur %d : %+v", i, p)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneTournaments(t *testing.T) {
+	tournamentsMux.Lock()
+	defer tournamentsMux.Unlock()
+	saved := tournaments
+	defer func() { tournaments = saved }()
+
+	tournaments = make(map[int]*tournament)
+	start := time.Now()
+	for id := 1; id <= maxFinishedTournaments+2; id++ {
+		tournaments[id] = &tournament{id: id, status: protocol.TournamentFinished, finishedAt: start.Add(time.Duration(id) * time.Second)}
+	}
+	tournaments[100] = &tournament{id: 100, status: protocol.TournamentRunning}
+	pruneTournaments()
+
+	if len(tournaments) != maxFinishedTournaments+1 {
+		t.Errorf("%d tournois conservés, attendu %d", len(tournaments), maxFinishedTournaments+1)
+	}
+	for _, id := range []int{1, 2} {
+		if _, ok := tournaments[id]; ok {
+			t.Errorf("le tournoi %d, le plus ancien, est conservé", id)
+		}
+	}
+	if _, ok := tournaments[100]; !ok {
+		t.Error("le tournoi en cours est oublié")
+	}
+}
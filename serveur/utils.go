@@ -1,7 +1,6 @@
 package main
 
 import (
-	"log"
 	"net"
 )
 
@@ -12,7 +11,7 @@ import (
 func getLocalIP() string {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
 	if err != nil {
-		log.Println("Erreur lors de la récupération de l'adresse IP :", err)
+		logErrorf("Erreur lors de la récupération de l'adresse IP : %v\n", err)
 		return "inconnue"
 	}
 	defer conn.Close()
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// webSocketPath est le chemin HTTP sur lequel les clients WebSocket se connectent.
+const webSocketPath = "/ws"
+
+// webSocketAddress est l'adresse d'écoute du point d'accès WebSocket, vide pour le désactiver.
+var webSocketAddress string
+
+// upgrader transforme une requête HTTP en connexion WebSocket.
+// Toutes les origines sont acceptées pour que des pages web hébergées ailleurs puissent se connecter.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// wsConn adapte une connexion WebSocket à l'interface net.Conn : chaque message texte
+// reçu est lu comme une ligne terminée par '\n', et chaque ligne écrite est envoyée
+// dans son propre message. Les clients WebSocket passent ainsi par le même handleClient
+// et le même processMessage que les clients TCP.
+type wsConn struct {
+	ws      *websocket.Conn
+	pending []byte     // Reste du dernier message reçu, pas encore lu
+	writeMu sync.Mutex // Une seule écriture à la fois sur la connexion WebSocket
+}
+
+// Read lit le message WebSocket en cours, ou attend le suivant.
+func (c *wsConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		messageType, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		if messageType != websocket.TextMessage && messageType != websocket.BinaryMessage {
+			continue
+		}
+		c.pending = append(bytes.TrimRight(data, "\r\n"), '\n')
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write envoie chaque ligne de p dans un message texte WebSocket distinct.
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		if err := c.ws.WriteMessage(websocket.TextMessage, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close ferme la connexion WebSocket.
+func (c *wsConn) Close() error {
+	return c.ws.Close()
+}
+
+// LocalAddr renvoie l'adresse locale de la connexion.
+func (c *wsConn) LocalAddr() net.Addr {
+	return c.ws.LocalAddr()
+}
+
+// RemoteAddr renvoie l'adresse du client.
+func (c *wsConn) RemoteAddr() net.Addr {
+	return c.ws.RemoteAddr()
+}
+
+// SetDeadline fixe les délais de lecture et d'écriture.
+func (c *wsConn) SetDeadline(t time.Time) error {
+	if err := c.ws.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.ws.SetWriteDeadline(t)
+}
+
+// SetReadDeadline fixe le délai de lecture.
+func (c *wsConn) SetReadDeadline(t time.Time) error {
+	return c.ws.SetReadDeadline(t)
+}
+
+// SetWriteDeadline fixe le délai d'écriture.
+func (c *wsConn) SetWriteDeadline(t time.Time) error {
+	return c.ws.SetWriteDeadline(t)
+}
+
+// handleWebSocket accepte une connexion WebSocket et la traite comme un client TCP.
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logErrorf("Erreur lors de l'ouverture d'une connexion WebSocket : %v\n", err)
+		return
+	}
+
+	id, conn := registerClient(&wsConn{ws: ws})
+	logInfof("Client %d connecté (WebSocket, %s)", id, conn.RemoteAddr())
+	handleClient(conn, id)
+}
+
+// startWebSocketServer écoute les connexions WebSocket sur address, à côté du serveur TCP.
+// Si tlsCfg n'est pas nil, les clients doivent se connecter en wss://.
+func startWebSocketServer(address string, tlsCfg *tls.Config) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(webSocketPath, handleWebSocket)
+	server := &http.Server{
+		Addr:      address,
+		Handler:   mux,
+		TLSConfig: tlsCfg,
+	}
+
+	var err error
+	if tlsCfg != nil {
+		logInfof("Point d'accès WebSocket : wss://%s%s\n", address, webSocketPath)
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		logInfof("Point d'accès WebSocket : ws://%s%s\n", address, webSocketPath)
+		err = server.ListenAndServe()
+	}
+	if err != nil {
+		log.Fatal("Erreur lors de l'écoute WebSocket :", err)
+	}
+}